@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go-module-builder/internal/auth"
+	"go-module-builder/internal/model"
+	"go-module-builder/internal/modulemanager"
+	"go-module-builder/internal/storage"
+
+	"github.com/justinas/nosurf"
+)
+
+// sessionCookieName is the cookie holding the login session token.
+const sessionCookieName = "gows_admin_session"
+
+type contextKey string
+
+// userContextKey stores the signed-in *model.User in the request context.
+const userContextKey contextKey = "user"
+
+// currentUser returns the user signed in for the request, or nil outside requireLogin.
+func (app *adminApplication) currentUser(r *http.Request) *model.User {
+	user, _ := r.Context().Value(userContextKey).(*model.User)
+	return user
+}
+
+// manager returns the module manager acting for the request's user, so the audit log
+// records who made each change.
+func (app *adminApplication) manager(r *http.Request) *modulemanager.ModuleManager {
+	if user := app.currentUser(r); user != nil {
+		return app.moduleManager.As(user.Username)
+	}
+	return app.moduleManager
+}
+
+// requireLogin lets requests with a valid session through, with the user in their
+// context, and sends everyone else to the login page.
+func (app *adminApplication) requireLogin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := app.sessionUser(r)
+		if user == nil {
+			app.redirectToLogin(w, r)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userContextKey, user)))
+	})
+}
+
+// sessionUser returns the account of the request's session. Sessions of removed
+// accounts, or from before a password change, are ended.
+func (app *adminApplication) sessionUser(r *http.Request) *model.User {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return nil
+	}
+	session, ok := app.sessions.Get(cookie.Value)
+	if !ok {
+		return nil
+	}
+	user, err := app.users.LoadUser(session.Username)
+	if err != nil && !errors.Is(err, storage.ErrUserNotFound) {
+		app.logger.Error("Failed to load the user of a session", "username", session.Username, "error", err)
+		return nil // Keep the session; the store may be back on the next request
+	}
+	if !session.ValidFor(user) {
+		app.logger.Info("Ending session of a removed user or changed password", "username", session.Username)
+		app.sessions.Delete(cookie.Value)
+		return nil
+	}
+	return user
+}
+
+// redirectToLogin answers a request without a valid session: HTMX requests load the
+// login page, API requests get a 401, and pages redirect to the login form, which
+// returns to the page after signing in.
+func (app *adminApplication) redirectToLogin(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Header.Get("HX-Request") == "true":
+		w.Header().Set("HX-Redirect", "/admin/login")
+		w.WriteHeader(http.StatusUnauthorized)
+	case strings.HasPrefix(r.URL.Path, "/api/"):
+		http.Error(w, "Unauthorized - Sign in to the admin server", http.StatusUnauthorized)
+	default:
+		target := "/admin/login"
+		if r.Method == http.MethodGet && r.URL.Path != "/" {
+			target += "?next=" + url.QueryEscape(r.URL.RequestURI())
+		}
+		http.Redirect(w, r, target, http.StatusSeeOther)
+	}
+}
+
+// loginFormHandler displays the login form.
+func (app *adminApplication) loginFormHandler(w http.ResponseWriter, r *http.Request) {
+	if app.sessionUser(r) != nil {
+		http.Redirect(w, r, safeNext(r.URL.Query().Get("next")), http.StatusSeeOther)
+		return
+	}
+	app.renderLogin(w, r, http.StatusOK, "", "")
+}
+
+// loginHandler checks the submitted credentials and starts a session.
+func (app *adminApplication) loginHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.logger.Error("loginHandler: Error parsing form data", "error", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	username := r.PostForm.Get("username")
+
+	user, err := auth.Authenticate(app.users, username, r.PostForm.Get("password"))
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		app.logger.Warn("Failed admin login", "username", username, "remote_addr", r.RemoteAddr)
+		app.renderLogin(w, r, http.StatusUnauthorized, username, "Invalid username or password.")
+		return
+	}
+	if err != nil {
+		app.logger.Error("loginHandler: Error checking credentials", "error", err, "username", username)
+		app.renderLogin(w, r, http.StatusInternalServerError, username, "Signing in failed, please try again.")
+		return
+	}
+
+	token, err := app.sessions.Create(user.Username)
+	if err != nil {
+		app.logger.Error("loginHandler: Error creating session", "error", err, "username", user.Username)
+		app.renderLogin(w, r, http.StatusInternalServerError, username, "Signing in failed, please try again.")
+		return
+	}
+	app.setSessionCookie(w, r, token, int(app.sessions.TTL().Seconds()))
+	app.logger.Info("Admin login", "username", user.Username, "remote_addr", r.RemoteAddr)
+	http.Redirect(w, r, safeNext(r.PostForm.Get("next")), http.StatusSeeOther)
+}
+
+// logoutHandler ends the session and returns to the login form.
+func (app *adminApplication) logoutHandler(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		app.sessions.Delete(cookie.Value)
+	}
+	app.setSessionCookie(w, r, "", -1)
+	if user := app.currentUser(r); user != nil {
+		app.logger.Info("Admin logout", "username", user.Username)
+	}
+	http.Redirect(w, r, "/admin/login", http.StatusSeeOther)
+}
+
+// renderLogin writes the login page with an optional error message.
+func (app *adminApplication) renderLogin(w http.ResponseWriter, r *http.Request, status int, username, errorMsg string) {
+	ts, ok := app.templateCache["login.html"]
+	if !ok {
+		app.logger.Error("Template login.html not found in cache")
+		http.Error(w, "Internal Server Error - Template not found", http.StatusInternalServerError)
+		return
+	}
+	next := r.URL.Query().Get("next")
+	if r.Method == http.MethodPost {
+		next = r.PostForm.Get("next")
+	}
+	data := map[string]any{
+		"CSRFToken": nosurf.Token(r),
+		"Next":      next,
+		"Username":  username,
+		"Error":     errorMsg,
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := ts.ExecuteTemplate(w, "login.html", data); err != nil {
+		app.logger.Error("Error executing login template", "error", err)
+	}
+}
+
+// flashSuccess stores a success message for the next page the request's session loads.
+func (app *adminApplication) flashSuccess(r *http.Request, message string) {
+	app.addFlash(r, auth.Flash{Success: message})
+}
+
+// flashError stores an error message for the next page the request's session loads.
+func (app *adminApplication) flashError(r *http.Request, message string) {
+	app.addFlash(r, auth.Flash{Error: message})
+}
+
+func (app *adminApplication) addFlash(r *http.Request, flash auth.Flash) {
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		app.sessions.AddFlash(cookie.Value, flash)
+	}
+}
+
+// setSessionCookie sets (or, with a negative maxAge, clears) the session cookie. It is
+// only sent over HTTPS when the admin server uses TLS or admin_server.secureCookies is set,
+// and its SameSite mode is admin_server.sameSite.
+func (app *adminApplication) setSessionCookie(w http.ResponseWriter, r *http.Request, token string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   app.secureCookies || r.TLS != nil,
+		SameSite: app.sameSite,
+	})
+}
+
+// safeNext returns the local path to go to after signing in, so the login form cannot
+// be used to redirect to another site.
+func safeNext(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, `/\`) || strings.HasPrefix(next, "/admin/login") {
+		return "/"
+	}
+	return next
+}
+
+// parseSameSite reads the admin_server.sameSite setting for the session and CSRF
+// cookies. "none" is not accepted: it would send them with requests from other sites.
+func parseSameSite(value string) (http.SameSite, error) {
+	switch strings.ToLower(value) {
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	}
+	return 0, fmt.Errorf("invalid admin_server.sameSite %q: use \"lax\" or \"strict\"", value)
+}
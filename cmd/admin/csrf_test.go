@@ -0,0 +1,153 @@
+package main
+
+import (
+	"html"
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-module-builder/internal/auth"
+	"go-module-builder/internal/model"
+	"go-module-builder/internal/modulemanager"
+	"go-module-builder/internal/storage"
+)
+
+// newTestServer starts the admin routes with the given accounts, all with the password
+// "password123", and returns the server and its application.
+func newTestServer(t *testing.T, accounts ...*model.User) (*httptest.Server, *adminApplication) {
+	t.Helper()
+	root := t.TempDir()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store, err := storage.Open(storage.BackendJSON, filepath.Join(root, ".module_metadata"), logger)
+	if err != nil {
+		t.Fatalf("storage.Open failed: %v", err)
+	}
+	users, err := storage.NewJSONUserStore(filepath.Join(root, storage.DefaultUsersDir))
+	if err != nil {
+		t.Fatalf("NewJSONUserStore failed: %v", err)
+	}
+	for _, account := range accounts {
+		user, err := auth.NewUser(account.Username, "password123")
+		if err != nil {
+			t.Fatalf("NewUser failed: %v", err)
+		}
+		user.Role = account.Role
+		user.Groups = account.Groups
+		if err := users.SaveUser(user); err != nil {
+			t.Fatalf("SaveUser failed: %v", err)
+		}
+	}
+
+	app := &adminApplication{
+		logger:        logger,
+		moduleStore:   store,
+		projectRoot:   root,
+		moduleManager: modulemanager.NewManager(store, logger, root, filepath.Join(root, "modules")),
+		// The login page only shows the token, so the test can send it back
+		templateCache: map[string]*template.Template{
+			"login.html": template.Must(template.New("login.html").Parse(`{{ .CSRFToken }}`)),
+		},
+		users:    users,
+		sessions: auth.NewSessions(time.Hour),
+		sameSite: http.SameSiteLaxMode,
+	}
+	srv := httptest.NewServer(app.routes())
+	t.Cleanup(srv.Close)
+	return srv, app
+}
+
+// signIn returns a client signed in to srv as username, along with the CSRF token its
+// pages carry.
+func signIn(t *testing.T, srv *httptest.Server, username string) (*http.Client, string) {
+	t.Helper()
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar}
+	resp, err := client.Get(srv.URL + "/admin/login")
+	if err != nil {
+		t.Fatalf("GET /admin/login failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	token := html.UnescapeString(strings.TrimSpace(string(body)))
+
+	resp, err = client.PostForm(srv.URL+"/admin/login", url.Values{"csrf_token": {token}, "username": {username}, "password": {"password123"}})
+	if err != nil {
+		t.Fatalf("POST /admin/login failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.Request.URL.Path != "/" {
+		t.Fatalf("Signing in as %s ended on %s, want /", username, resp.Request.URL.Path)
+	}
+	return client, token
+}
+
+// newCSRFTestServer starts the admin routes with one admin account and returns a client
+// signed in as it, along with the CSRF token its pages carry.
+func newCSRFTestServer(t *testing.T) (*httptest.Server, *http.Client, string) {
+	t.Helper()
+	srv, _ := newTestServer(t, &model.User{Username: "root", Role: model.RoleAdmin})
+	client, token := signIn(t, srv, "root")
+	return srv, client, token
+}
+
+func TestCrossOriginRequestsAreRejected(t *testing.T) {
+	srv, client, token := newCSRFTestServer(t)
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		header      string // X-CSRF-Token
+	}{
+		{"preview without token", http.MethodPost, "/api/admin/preview/mod", "application/json", `{"filename":"base.html","content":""}`, ""},
+		{"save without token", http.MethodPut, "/api/admin/modules/mod/templates/base.html", "text/plain", "<p>pwned</p>", ""},
+		{"save with a wrong token", http.MethodPut, "/api/admin/modules/mod/templates/base.html", "text/plain", "<p>pwned</p>", "not-the-token"},
+		{"API form post with the token in the body", http.MethodPost, "/api/admin/preview/mod", "application/x-www-form-urlencoded", "csrf_token=" + url.QueryEscape(token), ""},
+		{"form post without token", http.MethodPost, "/admin/modules/new", "application/x-www-form-urlencoded", "moduleName=Pwned", ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tc.method, srv.URL+tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", tc.contentType)
+			req.Header.Set("Origin", "https://attacker.example")
+			if tc.header != "" {
+				req.Header.Set("X-CSRF-Token", tc.header)
+			}
+			resp, err := client.Do(req) // Carries the session cookie, as a browser would
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusForbidden {
+				t.Errorf("Status = %d, want %d", resp.StatusCode, http.StatusForbidden)
+			}
+		})
+	}
+}
+
+func TestAPIRequestsWithCSRFHeaderAreAccepted(t *testing.T) {
+	srv, client, token := newCSRFTestServer(t)
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/admin/preview/missing", strings.NewReader(`{"filename":"base.html","content":""}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-CSRF-Token", token)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	// The request reaches the handler, which does not know the module
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Status = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
@@ -12,10 +13,15 @@ import (
 	"path/filepath"
 	"regexp" // Added for slug validation
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"go-module-builder/internal/model" // Import model package
+	"go-module-builder/internal/modulemanager"
+	"go-module-builder/internal/storage"
+	"go-module-builder/internal/templating"
+	"go-module-builder/pkg/fsutils"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/justinas/nosurf"
@@ -23,10 +29,44 @@ import (
 
 // DashboardPageData holds all data needed for the dashboard template (layout + content)
 type DashboardPageData struct {
-	CurrentYear           int // Moved here for layout footer
-	ActiveInactiveModules []*model.Module
-	SoftDeletedModules    []*model.Module
-	Error                 string // To display errors if module loading fails
+	CurrentYear     int             // Moved here for layout footer
+	Groups          []ModuleGroup   // Published, draft and disabled modules, in that order
+	ArchivedModules []*model.Module // Soft-deleted modules
+	Error           string          // To display errors if module loading fails
+}
+
+// ModuleGroup is a dashboard list of the modules with one lifecycle status.
+type ModuleGroup struct {
+	Title   string
+	Status  model.ModuleStatus
+	Modules []*model.Module
+}
+
+// newDashboardPageData groups the modules user may view by status for the dashboard lists.
+func newDashboardPageData(modules []*model.Module, user *model.User) DashboardPageData {
+	data := DashboardPageData{
+		Groups: []ModuleGroup{
+			{Title: "Published Modules", Status: model.StatusPublished},
+			{Title: "Draft Modules", Status: model.StatusDraft},
+			{Title: "Disabled Modules", Status: model.StatusDisabled},
+		},
+		ArchivedModules: make([]*model.Module, 0),
+	}
+	for _, mod := range modules {
+		if user != nil && !user.Can(model.RoleViewer, mod) {
+			continue // Outside the user's groups
+		}
+		if mod.IsArchived() {
+			data.ArchivedModules = append(data.ArchivedModules, mod)
+			continue
+		}
+		for i := range data.Groups {
+			if data.Groups[i].Status == mod.Status {
+				data.Groups[i].Modules = append(data.Groups[i].Modules, mod)
+			}
+		}
+	}
+	return data
 }
 
 // PreviewRequestData defines the structure for the preview API request body.
@@ -39,29 +79,27 @@ type PreviewRequestData struct {
 func (app *adminApplication) dashboardHandler(w http.ResponseWriter, r *http.Request) {
 	data := app.newTemplateData(r, "dashboard")
 
-	pageData := DashboardPageData{
-		CurrentYear:           time.Now().Year(),
-		ActiveInactiveModules: make([]*model.Module, 0),
-		SoftDeletedModules:    make([]*model.Module, 0),
-	}
+	pageData := newDashboardPageData(nil, nil)
+	pageData.CurrentYear = time.Now().Year()
 
 	if app.moduleStore == nil {
 		app.logger.Warn("Module store is not initialized in dashboard handler")
 		pageData.Error = "Module storage not available."
 	} else {
 		modules, err := app.moduleStore.ReadAll()
+		if corrupt, ok := storage.AsCorruptModules(err); ok {
+			// Show the modules that did load and flag the ones that didn't
+			app.logger.Error("Some module metadata could not be loaded", "moduleIDs", corrupt.IDs(), "error", err)
+			pageData.Error = fmt.Sprintf("Metadata for %d module(s) could not be loaded: %s", len(corrupt.Modules), strings.Join(corrupt.IDs(), ", "))
+			err = nil
+		}
 		if err != nil {
 			app.logger.Error("Failed to read modules from store", "error", err)
 			pageData.Error = "Failed to load module list."
 		} else {
-			for _, mod := range modules {
-				if !mod.IsActive && strings.Contains(mod.Directory, "modules_removed") {
-					pageData.SoftDeletedModules = append(pageData.SoftDeletedModules, mod)
-				} else {
-					pageData.ActiveInactiveModules = append(pageData.ActiveInactiveModules, mod)
-				}
-			}
-			app.logger.Debug("Processed modules for dashboard", "active/inactive_count", len(pageData.ActiveInactiveModules), "soft_deleted_count", len(pageData.SoftDeletedModules))
+			pageData = newDashboardPageData(modules, app.currentUser(r))
+			pageData.CurrentYear = time.Now().Year()
+			app.logger.Debug("Processed modules for dashboard", "count", len(modules), "archived_count", len(pageData.ArchivedModules))
 		}
 	}
 	data["Page"] = pageData
@@ -96,6 +134,10 @@ func (app *adminApplication) moduleCreateFormHandler(w http.ResponseWriter, r *h
 	data["FormError"] = r.URL.Query().Get("error")
 	data["ModuleName"] = r.URL.Query().Get("moduleName")
 	data["CustomSlug"] = r.URL.Query().Get("customSlug")
+	data["Layout"] = r.URL.Query().Get("layout")
+	data["Layouts"] = app.layoutNames()
+	data["PublishAt"] = r.URL.Query().Get("publishAt")
+	data["UnpublishAt"] = r.URL.Query().Get("unpublishAt")
 
 	err := ts.ExecuteTemplate(w, "layout.html", data)
 	if err != nil {
@@ -115,14 +157,25 @@ func (app *adminApplication) moduleCreateHandler(w http.ResponseWriter, r *http.
 
 	moduleName := r.PostForm.Get("moduleName")
 	customSlug := r.PostForm.Get("customSlug")
+	layout := r.PostForm.Get("layout")
+	publishAtValue := r.PostForm.Get("publishAt")
+	unpublishAtValue := r.PostForm.Get("unpublishAt")
+
+	// formValues refill the form when it is shown again after an error.
+	formValues := url.Values{
+		"moduleName":  {moduleName},
+		"customSlug":  {customSlug},
+		"layout":      {layout},
+		"publishAt":   {publishAtValue},
+		"unpublishAt": {unpublishAtValue},
+	}
+	redirectToForm := func(errorMsg string) {
+		app.flashError(r, errorMsg)
+		http.Redirect(w, r, "/admin/modules/new?"+formValues.Encode(), http.StatusSeeOther)
+	}
 
 	if moduleName == "" {
-		errorMsg := "Module Name is required."
-		app.FlashErrorMessage = errorMsg
-		redirectURL := fmt.Sprintf("/admin/modules/new?moduleName=%s&customSlug=%s",
-			url.QueryEscape(moduleName),
-			url.QueryEscape(customSlug))
-		http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+		redirectToForm("Module Name is required.")
 		return
 	}
 
@@ -131,33 +184,143 @@ func (app *adminApplication) moduleCreateHandler(w http.ResponseWriter, r *http.
 		isValidSlug, _ := regexp.MatchString(`^[a-z0-9]+(?:-[a-z0-9]+)*$`, customSlug)
 		if !isValidSlug {
 			app.logger.Warn("Invalid custom slug format provided", "customSlug", customSlug)
-			errorMsg := "Invalid Custom Slug format. Use lowercase letters, numbers, and hyphens. Must start and end with a letter or number."
-			app.FlashErrorMessage = errorMsg
-			redirectURL := fmt.Sprintf("/admin/modules/new?moduleName=%s&customSlug=%s",
-				url.QueryEscape(moduleName),
-				url.QueryEscape(customSlug))
-			http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+			redirectToForm("Invalid Custom Slug format. Use lowercase letters, numbers, and hyphens. Must start and end with a letter or number.")
 			return
 		}
 	}
 
+	publishAt, err := modulemanager.ParseScheduleTime(publishAtValue)
+	if err != nil {
+		redirectToForm(fmt.Sprintf("Invalid publish time: %v", err))
+		return
+	}
+	unpublishAt, err := modulemanager.ParseScheduleTime(unpublishAtValue)
+	if err != nil {
+		redirectToForm(fmt.Sprintf("Invalid unpublish time: %v", err))
+		return
+	}
+	if publishAt != nil && unpublishAt != nil && !unpublishAt.After(*publishAt) {
+		redirectToForm("The unpublish time must be after the publish time.")
+		return
+	}
+
 	if app.moduleManager == nil {
 		app.logger.Error("ModuleManager not initialized in admin application")
 		http.Error(w, "Internal Server Error - Configuration Error", http.StatusInternalServerError)
 		return
 	}
 
-	createdModule, err := app.moduleManager.CreateModule(moduleName, customSlug)
+	// Check the layout before creating anything; the default layout is left empty
+	if layout != "" {
+		if err := templating.ValidateLayout(app.moduleManager.LayoutsDir(), layout); err != nil {
+			app.logger.Warn("Invalid layout selected for new module", "layout", layout, "error", err)
+			formValues.Del("layout")
+			redirectToForm(fmt.Sprintf("Invalid layout: %v", err))
+			return
+		}
+	}
+
+	createdModule, err := app.manager(r).CreateModule(moduleName, customSlug)
 	if err != nil {
 		app.logger.Error("Error creating module via manager", "error", err, "moduleName", moduleName, "customSlug", customSlug)
-		app.FlashErrorMessage = fmt.Sprintf("Failed to create module '%s': %v", moduleName, err)
-		redirectURL := fmt.Sprintf("/admin/modules/new?moduleName=%s&customSlug=%s",
-			url.QueryEscape(moduleName),
-			url.QueryEscape(customSlug))
-		http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+		redirectToForm(fmt.Sprintf("Failed to create module '%s': %v", moduleName, err))
 		return
 	}
-	app.FlashSuccessMessage = fmt.Sprintf("Module '%s' created successfully.", createdModule.Name)
+	if layout != "" {
+		if err := app.manager(r).UpdateModule(createdModule.ID, "", "", "", layout, ""); err != nil {
+			app.logger.Error("Error setting layout of new module", "error", err, "moduleID", createdModule.ID, "layout", layout)
+			app.flashError(r, fmt.Sprintf("Module '%s' created, but setting its layout failed: %v", createdModule.Name, err))
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+	}
+	if publishAt != nil || unpublishAt != nil {
+		if _, err := app.manager(r).SetModuleSchedule(createdModule.ID, publishAt, unpublishAt); err != nil {
+			app.logger.Error("Error setting schedule of new module", "error", err, "moduleID", createdModule.ID)
+			app.flashError(r, fmt.Sprintf("Module '%s' created, but setting its schedule failed: %v", createdModule.Name, err))
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+	}
+	app.flashSuccess(r, fmt.Sprintf("Module '%s' created successfully.", createdModule.Name))
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// moduleExportHandler sends a module's archive (see ModuleManager.ExportModule) as a download.
+func (app *adminApplication) moduleExportHandler(w http.ResponseWriter, r *http.Request) {
+	moduleID := chi.URLParam(r, "moduleID")
+	if app.moduleManager == nil {
+		app.logger.Error("ModuleManager not initialized in admin application")
+		http.Error(w, "Internal Server Error - Configuration Error", http.StatusInternalServerError)
+		return
+	}
+	module, err := app.moduleManager.GetStore().LoadModule(moduleID)
+	if err != nil {
+		app.logger.Warn("Module to export not found", "moduleID", moduleID, "error", err)
+		http.NotFound(w, r)
+		return
+	}
+
+	// Build the archive first, so a failure isn't sent as a truncated download
+	var archive bytes.Buffer
+	if err := app.moduleManager.ExportModule(moduleID, &archive); err != nil {
+		app.logger.Error("Error exporting module via manager", "moduleID", moduleID, "error", err)
+		http.Error(w, "Failed to export module", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", module.Slug+".zip"))
+	w.Header().Set("Content-Length", fmt.Sprint(archive.Len()))
+	if _, err := archive.WriteTo(w); err != nil {
+		app.logger.Error("Error sending module archive", "moduleID", moduleID, "error", err)
+	}
+}
+
+// moduleImportHandler adds the module in an uploaded archive and returns to the dashboard.
+func (app *adminApplication) moduleImportHandler(w http.ResponseWriter, r *http.Request) {
+	// Allow for the multipart overhead on top of the largest accepted archive
+	r.Body = http.MaxBytesReader(w, r.Body, modulemanager.MaxArchiveSize+1<<20)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		app.logger.Warn("Error parsing module import form", "error", err)
+		app.flashError(r, "Could not read the uploaded archive. It may be too large.")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	file, header, err := r.FormFile("archive")
+	if err != nil {
+		app.flashError(r, "Choose a module archive (.zip) to import.")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	defer file.Close()
+
+	if app.moduleManager == nil {
+		app.logger.Error("ModuleManager not initialized in admin application")
+		http.Error(w, "Internal Server Error - Configuration Error", http.StatusInternalServerError)
+		return
+	}
+	result, err := app.manager(r).ImportModule(file, header.Size)
+	if err != nil {
+		app.logger.Error("Error importing module via manager", "filename", header.Filename, "error", err)
+		app.flashError(r, fmt.Sprintf("Failed to import '%s': %v", header.Filename, err))
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	message := fmt.Sprintf("Module '%s' imported.", result.Module.Name)
+	if result.Module.ID != result.OriginalID {
+		message += " Its ID was already in use, so it was given a new one."
+	}
+	if result.Module.Slug != result.OriginalSlug {
+		message += fmt.Sprintf(" Its slug was already in use; it is served at /%s.", result.Module.Slug)
+	}
+	if result.Module.Layout != result.OriginalLayout {
+		message += fmt.Sprintf(" Layout '%s' does not exist here, so it uses the default layout.", result.OriginalLayout)
+	}
+	if len(result.DroppedFiles) > 0 {
+		message += fmt.Sprintf(" Go files are not imported, so these were left out: %s.", strings.Join(result.DroppedFiles, ", "))
+	}
+	app.flashSuccess(r, message)
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
@@ -205,7 +368,7 @@ func (app *adminApplication) moduleDeleteHandler(w http.ResponseWriter, r *http.
 		app.logger.Warn("moduleDeleteHandler: Could not load module before deletion for name", "moduleID", moduleID, "error", loadErr)
 	}
 
-	err = app.moduleManager.DeleteModule(moduleID, forceDelete)
+	err = app.manager(r).DeleteModule(moduleID, forceDelete)
 	if err != nil {
 		app.logger.Error("moduleDeleteHandler: Error deleting module via manager", "error", err, "moduleID", moduleID, "force", forceDelete)
 		errorMessage := fmt.Sprintf("Failed to delete module '%s': %v", moduleNameForMessage, err)
@@ -219,6 +382,10 @@ func (app *adminApplication) moduleDeleteHandler(w http.ResponseWriter, r *http.
 
 	// Fetch all modules again to reflect the deletion for the partial update.
 	allModules, err := app.moduleStore.ReadAll()
+	if corrupt, ok := storage.AsCorruptModules(err); ok {
+		app.logger.Error("moduleDeleteHandler: Some module metadata could not be loaded for refresh", "moduleIDs", corrupt.IDs(), "error", err)
+		err = nil
+	}
 	if err != nil {
 		app.logger.Error("moduleDeleteHandler: Module deleted, but failed to read all modules for refresh", "error", err, "moduleID", moduleID)
 		// Deletion succeeded, but list refresh for client will fail.
@@ -238,21 +405,12 @@ func (app *adminApplication) moduleDeleteHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
-	dashboardPageData := DashboardPageData{
-		ActiveInactiveModules: make([]*model.Module, 0),
-		SoftDeletedModules:    make([]*model.Module, 0),
-	}
-	for _, mod := range allModules {
-		if !mod.IsActive && strings.Contains(mod.Directory, "modules_removed") {
-			dashboardPageData.SoftDeletedModules = append(dashboardPageData.SoftDeletedModules, mod)
-		} else {
-			dashboardPageData.ActiveInactiveModules = append(dashboardPageData.ActiveInactiveModules, mod)
-		}
-	}
+	dashboardPageData := newDashboardPageData(allModules, app.currentUser(r))
 
 	partialData := map[string]any{
-		"Page":      dashboardPageData,
-		"CSRFToken": nosurf.Token(r),
+		"Page":        dashboardPageData,
+		"CSRFToken":   nosurf.Token(r),
+		"CurrentUser": app.currentUser(r),
 	}
 
 	tmpl, ok := app.templateCache["module_dashboard_lists.html"]
@@ -285,6 +443,110 @@ func (app *adminApplication) moduleDeleteHandler(w http.ResponseWriter, r *http.
 	}
 }
 
+// moduleDuplicateHandler clones a module (see ModuleManager.CloneModule) and returns the
+// refreshed dashboard lists. The copy is named "<name> (copy)" and served under its ID
+// until it is renamed in the editor.
+func (app *adminApplication) moduleDuplicateHandler(w http.ResponseWriter, r *http.Request) {
+	moduleID := chi.URLParam(r, "moduleID")
+	if app.moduleManager == nil {
+		app.logger.Error("moduleDuplicateHandler: ModuleManager not initialized")
+		app.triggerHXError(w, "Internal Server Error - Configuration Error")
+		return
+	}
+
+	clone, err := app.manager(r).CloneModule(moduleID, "", "")
+	if err != nil {
+		app.logger.Error("moduleDuplicateHandler: Error cloning module via manager", "error", err, "moduleID", moduleID)
+		app.triggerHXError(w, fmt.Sprintf("Failed to duplicate module: %v", err))
+		return
+	}
+	app.writeDashboardListsPartial(w, r, fmt.Sprintf("Module duplicated as '%s' (ID: %s).", clone.Name, clone.ID))
+}
+
+// moduleStatusHandler moves a module to the posted lifecycle status (see
+// ModuleManager.SetModuleStatus) and returns the refreshed dashboard lists.
+func (app *adminApplication) moduleStatusHandler(w http.ResponseWriter, r *http.Request) {
+	moduleID := chi.URLParam(r, "moduleID")
+	if err := r.ParseForm(); err != nil {
+		app.logger.Error("moduleStatusHandler: Error parsing form", "error", err, "moduleID", moduleID)
+		app.triggerHXError(w, "Bad Request - Could not parse form")
+		return
+	}
+	if app.moduleManager == nil {
+		app.logger.Error("moduleStatusHandler: ModuleManager not initialized")
+		app.triggerHXError(w, "Internal Server Error - Configuration Error")
+		return
+	}
+
+	status := model.ModuleStatus(r.PostForm.Get("status"))
+	module, err := app.manager(r).SetModuleStatus(moduleID, status)
+	if err != nil {
+		app.logger.Error("moduleStatusHandler: Error changing module status via manager", "error", err, "moduleID", moduleID, "status", status)
+		app.triggerHXManagerError(w, err, fmt.Sprintf("Failed to change module status: %v", err))
+		return
+	}
+	app.writeDashboardListsPartial(w, r, fmt.Sprintf("Module '%s' is now %s.", module.Name, module.Status))
+}
+
+// moduleRestoreHandler brings back a soft-deleted module (see ModuleManager.RestoreModule)
+// and returns the refreshed dashboard lists.
+func (app *adminApplication) moduleRestoreHandler(w http.ResponseWriter, r *http.Request) {
+	moduleID := chi.URLParam(r, "moduleID")
+	if app.moduleManager == nil {
+		app.logger.Error("moduleRestoreHandler: ModuleManager not initialized")
+		app.triggerHXError(w, "Internal Server Error - Configuration Error")
+		return
+	}
+
+	module, err := app.manager(r).RestoreModule(moduleID)
+	if err != nil {
+		app.logger.Error("moduleRestoreHandler: Error restoring module via manager", "error", err, "moduleID", moduleID)
+		app.triggerHXManagerError(w, err, fmt.Sprintf("Failed to restore module: %v", err))
+		return
+	}
+	app.writeDashboardListsPartial(w, r, fmt.Sprintf("Module '%s' (ID: %s) restored as disabled. Publish it to serve it again.", module.Name, module.ID))
+}
+
+// writeDashboardListsPartial renders the dashboard's module lists for an HTMX swap of
+// #dashboard-module-lists-container, with a success message for the action that changed them.
+func (app *adminApplication) writeDashboardListsPartial(w http.ResponseWriter, r *http.Request, successMessage string) {
+	allModules, err := app.moduleStore.ReadAll()
+	if corrupt, ok := storage.AsCorruptModules(err); ok {
+		app.logger.Error("Some module metadata could not be loaded for refresh", "moduleIDs", corrupt.IDs(), "error", err)
+		err = nil
+	}
+	if err != nil {
+		app.logger.Error("Failed to read all modules for dashboard refresh", "error", err)
+		escapedMessage, _ := json.Marshal(successMessage + " Dashboard list refresh failed.")
+		w.Header().Set("HX-Trigger", fmt.Sprintf(`{"showMessage": {"message": %s, "type": "success"}}`, escapedMessage))
+		w.Header().Set("HX-Reswap", "none")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	dashboardPageData := newDashboardPageData(allModules, app.currentUser(r))
+
+	tmpl, ok := app.templateCache["module_dashboard_lists.html"]
+	if !ok {
+		app.logger.Error("Partial template 'module_dashboard_lists.html' not found in cache")
+		app.triggerHXError(w, "Internal Server Error - UI component missing")
+		return
+	}
+
+	escapedMessage, _ := json.Marshal(successMessage)
+	w.Header().Set("HX-Trigger", fmt.Sprintf(`{"showMessage": {"message": %s, "type": "success"}}`, escapedMessage))
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	partialData := map[string]any{
+		"Page":        dashboardPageData,
+		"CSRFToken":   nosurf.Token(r),
+		"CurrentUser": app.currentUser(r),
+	}
+	if err := tmpl.Execute(w, partialData); err != nil {
+		app.logger.Error("Error executing dashboard partial template", "error", err)
+	}
+}
+
 // moduleEditFormHandler loads module data and renders the editor page.
 func (app *adminApplication) moduleEditFormHandler(w http.ResponseWriter, r *http.Request) {
 	moduleID := chi.URLParam(r, "moduleID")
@@ -334,7 +596,12 @@ func (app *adminApplication) moduleEditFormHandler(w http.ResponseWriter, r *htt
 	data := app.newTemplateData(r, "edit") // "edit" nav item is for context.
 	data["CurrentYear"] = time.Now().Year()
 	data["ModuleData"] = module
-	// Flash messages (PageError, PageSuccess) are handled by newTemplateData.
+	data["CanEdit"] = app.currentUser(r).Can(model.RoleEditor, module)       // Viewers get the editor read-only
+	data["CanPublish"] = app.currentUser(r).Can(model.RolePublisher, module) // Scheduling is a publisher action
+	data["Layouts"] = app.layoutNames()
+	data["PublishAt"] = scheduleInputValue(module.PublishAt)
+	data["UnpublishAt"] = scheduleInputValue(module.UnpublishAt)
+	// Flash messages (FlashError, FlashSuccess) are handled by newTemplateData.
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	err = ts.ExecuteTemplate(w, "layout.html", data) // layout.html is the entry point for cached templates
@@ -354,6 +621,12 @@ func (app *adminApplication) getModuleTemplateContentHandler(w http.ResponseWrit
 		http.Error(w, "Bad Request - Missing moduleID or filename", http.StatusBadRequest)
 		return
 	}
+	file, err := fsutils.ParseTemplateFilename(filename)
+	if err != nil {
+		app.logger.Warn("Invalid filename in get template content request", "moduleID", moduleID, "filename", filename, "error", err)
+		http.Error(w, "Bad Request - Invalid filename", http.StatusBadRequest)
+		return
+	}
 
 	if app.moduleManager == nil || app.moduleManager.GetStore() == nil {
 		app.logger.Error("Module manager or store not initialized for get template content")
@@ -373,7 +646,7 @@ func (app *adminApplication) getModuleTemplateContentHandler(w http.ResponseWrit
 	} else {
 		moduleBasePath = filepath.Join(app.projectRoot, module.Directory)
 	}
-	templateFilePath := filepath.Join(moduleBasePath, "templates", filename)
+	templateFilePath := file.Path(filepath.Join(moduleBasePath, "templates"))
 
 	foundInMeta := false
 	for _, tmplMeta := range module.Templates {
@@ -400,6 +673,7 @@ func (app *adminApplication) getModuleTemplateContentHandler(w http.ResponseWrit
 	}
 
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("ETag", revisionETag(module.Revision))
 	_, err = w.Write(contentBytes)
 	if err != nil {
 		app.logger.Error("Error writing template content response", "error", err, "moduleID", moduleID, "filename", filename)
@@ -407,7 +681,7 @@ func (app *adminApplication) getModuleTemplateContentHandler(w http.ResponseWrit
 }
 
 // modulePreviewHandler renders a module preview using potentially modified template content.
-// It performs its own template parsing on each request for the preview.
+// It parses the module's templates on each request, through the same renderer as the main server.
 func (app *adminApplication) modulePreviewHandler(w http.ResponseWriter, r *http.Request) {
 	moduleID := chi.URLParam(r, "moduleID")
 	if moduleID == "" {
@@ -441,153 +715,51 @@ func (app *adminApplication) modulePreviewHandler(w http.ResponseWriter, r *http
 		return
 	}
 
-	var moduleBasePath string
-	if filepath.IsAbs(module.Directory) {
-		moduleBasePath = module.Directory
-	} else {
-		moduleBasePath = filepath.Join(app.projectRoot, module.Directory)
-	}
-	moduleTemplatesPath := filepath.Join(moduleBasePath, "templates")
-
-	previewTmplSet := template.New(moduleID + "_preview_admin")
-	if len(module.Templates) == 0 {
-		app.logger.Warn("No templates defined in module metadata for preview", "moduleID", moduleID)
-	}
-
-	for _, tmplMeta := range module.Templates {
-		filePath := filepath.Join(moduleTemplatesPath, tmplMeta.Name)
-		var currentFileContent string
-		if tmplMeta.Name == reqData.Filename {
-			currentFileContent = reqData.Content
-		} else {
-			contentBytes, readErr := os.ReadFile(filePath)
-			if readErr != nil {
-				app.logger.Error("Failed to read module template for preview", "path", filePath, "error", readErr)
-				http.Error(w, "Internal Server Error - Cannot read module template", http.StatusInternalServerError)
-				return
-			}
-			currentFileContent = string(contentBytes)
-		}
-		templateName := strings.TrimSuffix(tmplMeta.Name, filepath.Ext(tmplMeta.Name))
-		// If the file itself defines a template (e.g. {{define "foo"}}), that definition is added to the set.
-		// The template created by .New(templateName) is also added to the set.
-		// It's generally better to use unique names for New() if you're not relying on ParseFiles behavior.
-		// However, for this ad-hoc parsing, ensuring each {{define}} is unique is key.
-		if existing := previewTmplSet.Lookup(templateName); existing != nil && tmplMeta.Name != "base.html" { // Allow base.html to redefine "page"
-			app.logger.Warn("Template name conflict during preview parsing, might be overwritten", "name", templateName)
-		}
-		_, err = previewTmplSet.New(templateName).Parse(currentFileContent)
-		if err != nil {
-			app.logger.Error("Failed to parse module template string for preview", "templateName", templateName, "error", err)
-			http.Error(w, fmt.Sprintf("Internal Server Error - Template parse error for %s", templateName), http.StatusInternalServerError)
-			return
-		}
+	if _, err := fsutils.ParseTemplateFilename(reqData.Filename); err != nil || filepath.Ext(reqData.Filename) == ".js" {
+		app.logger.Warn("Unsupported file type for preview", "filename", reqData.Filename)
+		http.Error(w, "Unsupported file type for preview", http.StatusBadRequest)
+		return
 	}
 
+	// The preview is the module page as the main server renders it (inside the layout),
+	// with the edited file's unsaved content in place of the file on disk.
 	var buf bytes.Buffer
-	var finalHtmlOutput string
-	var renderErr error
-
-	if strings.HasSuffix(reqData.Filename, ".css") {
-		buf.WriteString("<style type=\"text/css\">\n")
-		buf.WriteString(reqData.Content)
-		buf.WriteString("\n</style>")
-		finalHtmlOutput = buf.String()
-	} else if strings.HasSuffix(reqData.Filename, ".html") || strings.HasSuffix(reqData.Filename, ".tmpl") {
-		var entryPointTemplateName string
-		var executionData any = module // Default data for direct template execution
-
-		if reqData.Filename == "base.html" {
-			entryPointTemplateName = "page" // base.html defines "page"
-			app.logger.Debug("Attempting to execute 'page' template for base.html preview", "filename", reqData.Filename)
-
-			renderedSubContent, subRenderErr := app.renderAdminPreviewSubTemplates(previewTmplSet, module)
-			if subRenderErr != nil {
-				renderErr = fmt.Errorf("failed to render sub-templates for page preview: %w", subRenderErr)
-			} else {
-				executionData = map[string]any{
-					"Module":          module,
-					"RenderedContent": template.HTML(renderedSubContent),
-				}
-			}
-		} else { // For other HTML/TMPL files (e.g., content.html), render them directly.
-			entryPointTemplateName = strings.TrimSuffix(reqData.Filename, filepath.Ext(reqData.Filename))
-			app.logger.Debug("Attempting to execute direct HTML/TMPL preview", "filename", reqData.Filename, "templateName", entryPointTemplateName)
+	renderer := app.renderer.Strict()
+	previewTmplSet, renderErr := renderer.ParseModule(module, map[string]string{reqData.Filename: reqData.Content})
+
+	// Declared data sources (file or local endpoint) are previewed; Go Data functions
+	// in handler.go are only compiled into the main server.
+	templateData := templating.ModuleInstanceData{Module: module}
+	if renderErr == nil && app.dataResolver != nil {
+		templateData.Data, renderErr = app.dataResolver.Load(r, module)
+		if renderErr != nil {
+			app.logger.Warn("Skipping preview rendering, module data failed to load", "moduleID", moduleID, "error", renderErr)
+			renderErr = fmt.Errorf("failed to load module data: %w", renderErr)
 		}
-
-		if renderErr == nil { // Proceed only if sub-template rendering (if any) was successful
-			entryPointTemplate := previewTmplSet.Lookup(entryPointTemplateName)
-			if entryPointTemplate != nil {
-				err = entryPointTemplate.Execute(&buf, executionData)
-				if err != nil {
-					renderErr = fmt.Errorf("failed executing template '%s': %w", entryPointTemplateName, err)
-				}
-			} else {
-				renderErr = fmt.Errorf("template '%s' not found for preview", entryPointTemplateName)
-			}
-		}
-		if renderErr == nil {
-			finalHtmlOutput = buf.String()
-		}
-	} else {
-		app.logger.Warn("Unsupported file type for preview", "filename", reqData.Filename)
-		http.Error(w, "Unsupported file type for preview", http.StatusBadRequest)
-		return
+	}
+	if renderErr == nil {
+		renderErr = renderer.RenderModulePage(&buf, previewTmplSet, templateData, &templating.LayoutData{})
 	}
 
 	if renderErr != nil {
 		app.logger.Error("Error during preview rendering", "moduleID", moduleID, "filename", reqData.Filename, "error", renderErr)
-		errorMsg := fmt.Sprintf("<pre style='color:red; font-family:monospace;'>Preview Rendering Error:\n%s</pre>", renderErr.Error())
+		errorMsg := fmt.Sprintf("<pre style='color:red; font-family:monospace;'>Preview Rendering Error:\n%s</pre>", template.HTMLEscapeString(renderErr.Error()))
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte(errorMsg))
 		return
 	}
 
-	app.logger.Debug("Preview buffer content after execution", "moduleID", moduleID, "filename", reqData.Filename, "bufferString", finalHtmlOutput)
+	app.logger.Debug("Preview rendered", "moduleID", moduleID, "filename", reqData.Filename, "bytes", buf.Len())
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	_, finalWriteErr := w.Write([]byte(finalHtmlOutput))
-	if finalWriteErr != nil {
-		app.logger.Error("Error writing preview response", "error", finalWriteErr, "moduleID", moduleID)
-	}
-}
-
-// renderAdminPreviewSubTemplates renders the non-base HTML/TMPL templates for a module in their specified order.
-// It uses an already parsed template set.
-func (app *adminApplication) renderAdminPreviewSubTemplates(previewTmplSet *template.Template, module *model.Module) (string, error) {
-	var subTemplatesToRender []model.Template
-	for _, tmplMeta := range module.Templates {
-		if !tmplMeta.IsBase && tmplMeta.Name != "base.html" && (strings.HasSuffix(tmplMeta.Name, ".html") || strings.HasSuffix(tmplMeta.Name, ".tmpl")) {
-			subTemplatesToRender = append(subTemplatesToRender, tmplMeta)
-		}
-	}
-
-	sort.SliceStable(subTemplatesToRender, func(i, j int) bool {
-		return subTemplatesToRender[i].Order < subTemplatesToRender[j].Order
-	})
-
-	var renderedSubContentBuf bytes.Buffer
-	for _, subTmplMeta := range subTemplatesToRender {
-		subTemplateName := strings.TrimSuffix(subTmplMeta.Name, filepath.Ext(subTmplMeta.Name))
-
-		// Note: The previewTmplSet already contains the potentially modified content
-		// for the `editingFilename` because it was parsed in modulePreviewHandler.
-		// So, we just need to ensure we execute the correct template from the set.
-		subTmpl := previewTmplSet.Lookup(subTemplateName)
-		if subTmpl != nil {
-			// Sub-templates are executed with 'module' as their direct context
-			if err := subTmpl.Execute(&renderedSubContentBuf, module); err != nil {
-				app.logger.Error("Error executing sub-template for preview", "name", subTemplateName, "moduleID", module.ID, "error", err)
-				return "", fmt.Errorf("failed executing sub-template %s: %w", subTemplateName, err)
-			}
-		} else {
-			app.logger.Warn("Sub-template not found in preview set during ordered rendering", "name", subTemplateName, "moduleID", module.ID)
-		}
+	if _, err := buf.WriteTo(w); err != nil {
+		app.logger.Error("Error writing preview response", "error", err, "moduleID", moduleID)
 	}
-	return renderedSubContentBuf.String(), nil
 }
 
 // saveModuleTemplateContentHandler saves the provided content to a specific module template file.
+// The request must send the module revision the content was edited at as If-Match:
+// without it the save is refused with 428, and if the module has been saved since with 409.
 func (app *adminApplication) saveModuleTemplateContentHandler(w http.ResponseWriter, r *http.Request) {
 	moduleID := chi.URLParam(r, "moduleID")
 	filename := chi.URLParam(r, "filename")
@@ -597,6 +769,26 @@ func (app *adminApplication) saveModuleTemplateContentHandler(w http.ResponseWri
 		http.Error(w, "Bad Request - Missing moduleID or filename", http.StatusBadRequest)
 		return
 	}
+	if _, err := fsutils.ParseTemplateFilename(filename); err != nil {
+		app.logger.Warn("Invalid filename in save template content request", "moduleID", moduleID, "filename", filename, "error", err)
+		http.Error(w, "Bad Request - Invalid filename", http.StatusBadRequest)
+		return
+	}
+
+	// The editor sends the revision it loaded the file at; a save without one could
+	// silently overwrite someone else's changes.
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		app.logger.Warn("Rejected template save without If-Match", "moduleID", moduleID, "filename", filename)
+		http.Error(w, "Precondition Required - Send the module revision the file was loaded at as If-Match.", http.StatusPreconditionRequired)
+		return
+	}
+	revision, err := strconv.Atoi(strings.Trim(ifMatch, `"`))
+	if err != nil {
+		app.logger.Warn("Rejected template save with an invalid If-Match", "moduleID", moduleID, "filename", filename, "ifMatch", ifMatch)
+		http.Error(w, "Conflict - The module was modified by someone else. Reload the file before saving.", http.StatusConflict)
+		return
+	}
 
 	newContentBytes, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -604,7 +796,6 @@ func (app *adminApplication) saveModuleTemplateContentHandler(w http.ResponseWri
 		http.Error(w, "Internal Server Error - Failed to read content", http.StatusInternalServerError)
 		return
 	}
-	// newContent := string(newContentBytes) // Keeping as bytes for os.WriteFile
 
 	if app.moduleManager == nil || app.moduleManager.GetStore() == nil {
 		app.logger.Error("Module manager or store not initialized for save template")
@@ -618,14 +809,6 @@ func (app *adminApplication) saveModuleTemplateContentHandler(w http.ResponseWri
 		return
 	}
 
-	var moduleBasePath string
-	if filepath.IsAbs(module.Directory) {
-		moduleBasePath = module.Directory
-	} else {
-		moduleBasePath = filepath.Join(app.projectRoot, module.Directory)
-	}
-	templateFilePath := filepath.Join(moduleBasePath, "templates", filename)
-
 	foundInMeta := false
 	for _, tmplMeta := range module.Templates {
 		if tmplMeta.Name == filename {
@@ -639,26 +822,21 @@ func (app *adminApplication) saveModuleTemplateContentHandler(w http.ResponseWri
 		return
 	}
 
-	// Using 0666 for file permissions; consider if this needs to be more restrictive.
-	err = os.WriteFile(templateFilePath, newContentBytes, 0666)
+	// The manager checks the revision, records the replaced version and writes the file
+	// as one step, so rejected saves leave neither a snapshot nor a changed file.
+	module, err = app.manager(r).SaveTemplate(moduleID, filename, newContentBytes, revision)
 	if err != nil {
-		app.logger.Error("Failed to write template file", "moduleID", moduleID, "filename", filename, "path", templateFilePath, "error", err)
-		http.Error(w, "Internal Server Error - Failed to save file", http.StatusInternalServerError)
+		app.logger.Error("Failed to save template", "moduleID", moduleID, "filename", filename, "error", err)
+		if storage.IsConflict(err) {
+			http.Error(w, "Conflict - The module was modified by someone else. Reload the file before saving.", http.StatusConflict)
+		} else {
+			http.Error(w, "Internal Server Error - Failed to save file", http.StatusInternalServerError)
+		}
 		return
 	}
 
-	// The commented-out activeEditSessions logic was here and has been removed as it's unused.
-	// For simplicity, we're not managing in-memory edit sessions on save for now.
-	// The next time the editor loads this file, it will fetch the updated content from disk.
-
-	// Update the module's LastUpdated timestamp in metadata
-	module.LastUpdated = time.Now()
-	if err := app.moduleManager.GetStore().SaveModule(module); err != nil {
-		app.logger.Error("Failed to update module metadata after saving template", "moduleID", moduleID, "filename", filename, "error", err)
-		// This non-fatal error for the save operation is logged.
-	}
-
-	app.logger.Info("Successfully saved template file", "moduleID", moduleID, "filename", filename, "path", templateFilePath)
+	app.logger.Info("Successfully saved template file", "moduleID", moduleID, "filename", filename)
+	w.Header().Set("ETag", revisionETag(module.Revision))
 	w.WriteHeader(http.StatusOK)
 	fmt.Fprintf(w, "File %s saved successfully.", filename)
 }
@@ -699,22 +877,9 @@ func (app *adminApplication) moduleAddTemplateHandler(w http.ResponseWriter, r *
 		return
 	}
 
-	if strings.Contains(newTemplateName, "/") || strings.Contains(newTemplateName, "\\") {
-		app.logger.Warn("moduleAddTemplateHandler: Invalid characters in template name", "templateName", newTemplateName, "moduleID", moduleID)
-		errorMessage := "Template name cannot contain slashes."
-		triggerEvent := fmt.Sprintf(`{"showMessage": {"message": "%s", "type": "error"}}`, errorMessage)
-		w.Header().Set("HX-Trigger", triggerEvent)
-		w.Header().Set("HX-Reswap", "none")
-		w.WriteHeader(http.StatusOK)
-		return
-	}
-	if !strings.HasSuffix(newTemplateName, ".html") && !strings.HasSuffix(newTemplateName, ".css") && !strings.HasSuffix(newTemplateName, ".tmpl") && !strings.HasSuffix(newTemplateName, ".js") {
-		app.logger.Warn("moduleAddTemplateHandler: Suspicious template extension", "templateName", newTemplateName, "moduleID", moduleID)
-		errorMessage := "Template name should have a common extension (e.g., .html, .css, .tmpl, .js)."
-		triggerEvent := fmt.Sprintf(`{"showMessage": {"message": "%s", "type": "error"}}`, errorMessage)
-		w.Header().Set("HX-Trigger", triggerEvent)
-		w.Header().Set("HX-Reswap", "none")
-		w.WriteHeader(http.StatusOK)
+	if _, err := fsutils.ParseTemplateFilename(newTemplateName); err != nil {
+		app.logger.Warn("moduleAddTemplateHandler: Invalid template name", "templateName", newTemplateName, "moduleID", moduleID, "error", err)
+		app.triggerHXError(w, "Invalid template name: use letters, digits, '.', '-' and '_' with an extension of "+strings.Join(fsutils.TemplateExtensions, ", ")+".")
 		return
 	}
 
@@ -728,15 +893,10 @@ func (app *adminApplication) moduleAddTemplateHandler(w http.ResponseWriter, r *
 		return
 	}
 
-	addedModule, err := app.moduleManager.AddTemplate(moduleID, newTemplateName)
+	addedModule, err := app.manager(r).AddTemplate(moduleID, newTemplateName)
 	if err != nil {
 		app.logger.Error("moduleAddTemplateHandler: Error adding template via manager", "error", err, "moduleID", moduleID, "templateName", newTemplateName)
-		errorMessage := fmt.Sprintf("Failed to add template: %v", err)
-		escapedErrorMessage, _ := json.Marshal(errorMessage) // Ensure message is JSON-safe.
-		triggerEvent := fmt.Sprintf(`{"showMessage": {"message": %s, "type": "error"}}`, string(escapedErrorMessage))
-		w.Header().Set("HX-Trigger", triggerEvent)
-		w.Header().Set("HX-Reswap", "none")
-		w.WriteHeader(http.StatusOK)
+		app.triggerHXManagerError(w, err, fmt.Sprintf("Failed to add template: %v", err))
 		return
 	}
 
@@ -772,7 +932,8 @@ func (app *adminApplication) moduleAddTemplateHandler(w http.ResponseWriter, r *
 	}
 
 	successMessage := fmt.Sprintf("Template '%s' added successfully.", newTemplateName)
-	triggerEvent := fmt.Sprintf(`{"showMessage": {"message": "%s", "type": "success"}}`, successMessage)
+	// moduleRevision lets the editor send the new revision with its next save.
+	triggerEvent := fmt.Sprintf(`{"showMessage": {"message": "%s", "type": "success"}, "moduleRevision": {"revision": %d}}`, successMessage, addedModule.Revision)
 	w.Header().Set("HX-Trigger", triggerEvent)
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -831,15 +992,10 @@ func (app *adminApplication) moduleRemoveTemplateHandler(w http.ResponseWriter,
 		return
 	}
 
-	err := app.moduleManager.RemoveTemplateFromModule(moduleID, templateFilename)
+	err := app.manager(r).RemoveTemplateFromModule(moduleID, templateFilename)
 	if err != nil {
 		app.logger.Error("moduleRemoveTemplateHandler: Error removing template via manager", "error", err, "moduleID", moduleID, "templateFilename", templateFilename)
-		errorMessage := fmt.Sprintf("Failed to remove template '%s': %v", templateFilename, err)
-		escapedErrorMessage, _ := json.Marshal(errorMessage) // Ensure message is JSON-safe.
-		triggerEvent := fmt.Sprintf(`{"showMessage": {"message": %s, "type": "error"}}`, string(escapedErrorMessage))
-		w.Header().Set("HX-Trigger", triggerEvent)
-		w.Header().Set("HX-Reswap", "none")
-		w.WriteHeader(http.StatusOK)
+		app.triggerHXManagerError(w, err, fmt.Sprintf("Failed to remove template '%s': %v", templateFilename, err))
 		return
 	}
 
@@ -889,7 +1045,7 @@ func (app *adminApplication) moduleRemoveTemplateHandler(w http.ResponseWriter,
 	}
 
 	successMessage := fmt.Sprintf("Template '%s' removed successfully.", templateFilename)
-	triggerEvent := fmt.Sprintf(`{"showMessage": {"message": "%s", "type": "success"}}`, successMessage)
+	triggerEvent := fmt.Sprintf(`{"showMessage": {"message": "%s", "type": "success"}, "moduleRevision": {"revision": %d}}`, successMessage, updatedModule.Revision)
 	w.Header().Set("HX-Trigger", triggerEvent)
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -899,3 +1055,397 @@ func (app *adminApplication) moduleRemoveTemplateHandler(w http.ResponseWriter,
 		app.logger.Error("moduleRemoveTemplateHandler: Error executing template list partial", "error", err)
 	}
 }
+
+// moduleToggleTemplateHandler enables or disables a template via HTMX. Disabled
+// templates stay in the module but are neither parsed nor rendered.
+func (app *adminApplication) moduleToggleTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	moduleID := chi.URLParam(r, "moduleID")
+	templateFilename := chi.URLParam(r, "templateFilename")
+	if moduleID == "" || templateFilename == "" {
+		app.logger.Error("moduleToggleTemplateHandler: Module ID or Template Filename missing from URL")
+		app.triggerHXError(w, "Bad Request - Missing Module ID or Template Filename")
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		app.logger.Error("moduleToggleTemplateHandler: Error parsing form data", "error", err, "moduleID", moduleID)
+		app.triggerHXError(w, "Bad Request - Could not parse form")
+		return
+	}
+	active := r.PostForm.Get("active") == "true"
+
+	if app.moduleManager == nil {
+		app.logger.Error("moduleToggleTemplateHandler: ModuleManager not initialized")
+		app.triggerHXError(w, "Internal Server Error - Configuration Error")
+		return
+	}
+
+	updatedModule, err := app.manager(r).SetTemplateActive(moduleID, templateFilename, active)
+	if err != nil {
+		app.logger.Error("moduleToggleTemplateHandler: Error setting template state via manager", "error", err, "moduleID", moduleID, "templateFilename", templateFilename)
+		app.triggerHXManagerError(w, err, fmt.Sprintf("Failed to update template '%s': %v", templateFilename, err))
+		return
+	}
+
+	state := "disabled"
+	if active {
+		state = "enabled"
+	}
+	app.logger.Info("moduleToggleTemplateHandler: Template state updated", "moduleID", moduleID, "templateFilename", templateFilename, "active", active)
+	app.writeTemplateListPartial(w, r, updatedModule, fmt.Sprintf("Template '%s' %s.", templateFilename, state))
+}
+
+// moduleReorderTemplatesHandler sets the render order of a module's templates via HTMX.
+// The form repeats "order" once per template filename, in the new order.
+func (app *adminApplication) moduleReorderTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	moduleID := chi.URLParam(r, "moduleID")
+	if moduleID == "" {
+		app.logger.Error("moduleReorderTemplatesHandler: Module ID missing from URL")
+		app.triggerHXError(w, "Bad Request - Missing Module ID")
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		app.logger.Error("moduleReorderTemplatesHandler: Error parsing form data", "error", err, "moduleID", moduleID)
+		app.triggerHXError(w, "Bad Request - Could not parse form")
+		return
+	}
+	order := r.PostForm["order"]
+
+	if app.moduleManager == nil {
+		app.logger.Error("moduleReorderTemplatesHandler: ModuleManager not initialized")
+		app.triggerHXError(w, "Internal Server Error - Configuration Error")
+		return
+	}
+
+	updatedModule, err := app.manager(r).ReorderTemplates(moduleID, order)
+	if err != nil {
+		app.logger.Error("moduleReorderTemplatesHandler: Error reordering templates via manager", "error", err, "moduleID", moduleID, "order", order)
+		app.triggerHXManagerError(w, err, fmt.Sprintf("Failed to reorder templates: %v", err))
+		return
+	}
+
+	app.logger.Info("moduleReorderTemplatesHandler: Templates reordered", "moduleID", moduleID, "order", order)
+	app.writeTemplateListPartial(w, r, updatedModule, "Template order saved.")
+}
+
+// moduleLayoutHandler sets the layout a module is rendered in via HTMX. An empty
+// layout selects the default layout.html.
+func (app *adminApplication) moduleLayoutHandler(w http.ResponseWriter, r *http.Request) {
+	moduleID := chi.URLParam(r, "moduleID")
+	if moduleID == "" {
+		app.logger.Error("moduleLayoutHandler: Module ID missing from URL")
+		app.triggerHXError(w, "Bad Request - Missing Module ID")
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		app.logger.Error("moduleLayoutHandler: Error parsing form data", "error", err, "moduleID", moduleID)
+		app.triggerHXError(w, "Bad Request - Could not parse form")
+		return
+	}
+	layout := r.PostForm.Get("layout")
+	if layout == "" {
+		layout = templating.DefaultLayout
+	}
+
+	if app.moduleManager == nil {
+		app.logger.Error("moduleLayoutHandler: ModuleManager not initialized")
+		app.triggerHXError(w, "Internal Server Error - Configuration Error")
+		return
+	}
+
+	if err := app.manager(r).UpdateModule(moduleID, "", "", "", layout, ""); err != nil {
+		app.logger.Error("moduleLayoutHandler: Error updating layout via manager", "error", err, "moduleID", moduleID, "layout", layout)
+		app.triggerHXManagerError(w, err, fmt.Sprintf("Failed to set layout: %v", err))
+		return
+	}
+	updatedModule, err := app.moduleManager.GetStore().LoadModule(moduleID)
+	if err != nil {
+		app.logger.Error("moduleLayoutHandler: Failed to reload module after layout change", "error", err, "moduleID", moduleID)
+		app.triggerHXError(w, "Layout saved, but reloading the module failed. Reload the page before saving templates.")
+		return
+	}
+
+	app.logger.Info("moduleLayoutHandler: Module layout updated", "moduleID", moduleID, "layout", layout)
+	successMessage, _ := json.Marshal(fmt.Sprintf("Layout set to %s.", layout))
+	// moduleRevision lets the editor send the new revision with its next save.
+	w.Header().Set("HX-Trigger", fmt.Sprintf(`{"showMessage": {"message": %s, "type": "success"}, "moduleRevision": {"revision": %d}}`, successMessage, updatedModule.Revision))
+	w.WriteHeader(http.StatusOK)
+}
+
+// moduleScheduleHandler sets when a published module goes live and when it stops
+// being served via HTMX. An empty field removes that bound.
+func (app *adminApplication) moduleScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	moduleID := chi.URLParam(r, "moduleID")
+	if moduleID == "" {
+		app.logger.Error("moduleScheduleHandler: Module ID missing from URL")
+		app.triggerHXError(w, "Bad Request - Missing Module ID")
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		app.logger.Error("moduleScheduleHandler: Error parsing form data", "error", err, "moduleID", moduleID)
+		app.triggerHXError(w, "Bad Request - Could not parse form")
+		return
+	}
+	publishAt, err := modulemanager.ParseScheduleTime(r.PostForm.Get("publishAt"))
+	if err != nil {
+		app.triggerHXError(w, fmt.Sprintf("Invalid publish time: %v", err))
+		return
+	}
+	unpublishAt, err := modulemanager.ParseScheduleTime(r.PostForm.Get("unpublishAt"))
+	if err != nil {
+		app.triggerHXError(w, fmt.Sprintf("Invalid unpublish time: %v", err))
+		return
+	}
+
+	if app.moduleManager == nil {
+		app.logger.Error("moduleScheduleHandler: ModuleManager not initialized")
+		app.triggerHXError(w, "Internal Server Error - Configuration Error")
+		return
+	}
+
+	updatedModule, err := app.manager(r).SetModuleSchedule(moduleID, publishAt, unpublishAt)
+	if err != nil {
+		app.logger.Error("moduleScheduleHandler: Error updating schedule via manager", "error", err, "moduleID", moduleID)
+		app.triggerHXManagerError(w, err, fmt.Sprintf("Failed to set schedule: %v", err))
+		return
+	}
+
+	app.logger.Info("moduleScheduleHandler: Module schedule updated", "moduleID", moduleID, "publishAt", publishAt, "unpublishAt", unpublishAt)
+	successMessage, _ := json.Marshal("Schedule saved.")
+	// moduleRevision lets the editor send the new revision with its next save.
+	w.Header().Set("HX-Trigger", fmt.Sprintf(`{"showMessage": {"message": %s, "type": "success"}, "moduleRevision": {"revision": %d}}`, successMessage, updatedModule.Revision))
+	w.WriteHeader(http.StatusOK)
+}
+
+// scheduleInputValue formats a schedule time for a datetime-local input, in the
+// server's local time zone. No time gives an empty input.
+func scheduleInputValue(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.In(time.Local).Format("2006-01-02T15:04")
+}
+
+// layoutNames lists the named layouts for the layout pickers. A failure is logged
+// and leaves only the default layout to choose.
+func (app *adminApplication) layoutNames() []string {
+	if app.moduleManager == nil {
+		return nil
+	}
+	names, err := templating.LayoutNames(app.moduleManager.LayoutsDir())
+	if err != nil {
+		app.logger.Error("Failed to list named layouts", "error", err)
+		return nil
+	}
+	return names
+}
+
+// triggerHXError reports a failed HTMX action as an error message, without
+// swapping in any content.
+func (app *adminApplication) triggerHXError(w http.ResponseWriter, message string) {
+	app.triggerHXErrorStatus(w, message, http.StatusOK) // Respond 200 OK for HX-Trigger processing.
+}
+
+// triggerHXErrorStatus is triggerHXError with another status. htmx still shows the
+// message, but doesn't swap in error responses.
+func (app *adminApplication) triggerHXErrorStatus(w http.ResponseWriter, message string, status int) {
+	escapedMessage, _ := json.Marshal(message) // Ensure message is JSON-safe.
+	w.Header().Set("HX-Trigger", fmt.Sprintf(`{"showMessage": {"message": %s, "type": "error"}}`, escapedMessage))
+	w.Header().Set("HX-Reswap", "none")
+	w.WriteHeader(status)
+}
+
+// triggerHXManagerError reports a failed manager call from an HTMX action. A
+// concurrent modification is answered with 409 and asks the user to reload, as the
+// page they acted on is out of date; anything else shows message.
+func (app *adminApplication) triggerHXManagerError(w http.ResponseWriter, err error, message string) {
+	if storage.IsConflict(err) {
+		app.triggerHXErrorStatus(w, "The module was changed by someone else. Reload the page and try again.", http.StatusConflict)
+		return
+	}
+	app.triggerHXError(w, message)
+}
+
+// writeTemplateListPartial responds with the module's template list, in render order,
+// along with a success message and the module's new revision.
+func (app *adminApplication) writeTemplateListPartial(w http.ResponseWriter, r *http.Request, module *model.Module, successMessage string) {
+	tmpl, ok := app.templateCache["template_list_items.html"]
+	if !ok {
+		app.logger.Error("Partial template 'template_list_items.html' not found in cache")
+		app.triggerHXError(w, "Internal Server Error - UI component missing")
+		return
+	}
+
+	sort.SliceStable(module.Templates, func(i, j int) bool {
+		if module.Templates[i].Order != module.Templates[j].Order {
+			return module.Templates[i].Order < module.Templates[j].Order
+		}
+		return module.Templates[i].Name < module.Templates[j].Name
+	})
+	partialData := map[string]any{
+		"Templates": module.Templates,
+		"ModuleID":  module.ID,
+		"CSRFToken": nosurf.Token(r),
+	}
+
+	escapedMessage, _ := json.Marshal(successMessage)
+	// moduleRevision lets the editor send the new revision with its next save.
+	w.Header().Set("HX-Trigger", fmt.Sprintf(`{"showMessage": {"message": %s, "type": "success"}, "moduleRevision": {"revision": %d}}`, escapedMessage, module.Revision))
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if err := tmpl.Execute(w, partialData); err != nil {
+		app.logger.Error("Error executing template list partial", "error", err, "moduleID", module.ID)
+	}
+}
+
+// writeJSON encodes data as the JSON response body.
+func (app *adminApplication) writeJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		app.logger.Error("Error writing JSON response", "error", err)
+	}
+}
+
+// revisionETag formats a module revision as an HTTP entity tag.
+func revisionETag(revision int) string {
+	return fmt.Sprintf(`"%d"`, revision)
+}
+
+// historyErrorStatus maps a manager error to an HTTP status: missing modules or
+// snapshots become 404, concurrent modifications 409, anything else 500.
+func historyErrorStatus(err error) int {
+	if errors.Is(err, os.ErrNotExist) {
+		return http.StatusNotFound
+	}
+	if storage.IsConflict(err) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// moduleSnapshotsHandler returns the module's recorded snapshots as JSON, newest first.
+func (app *adminApplication) moduleSnapshotsHandler(w http.ResponseWriter, r *http.Request) {
+	moduleID := chi.URLParam(r, "moduleID")
+	if app.moduleManager == nil {
+		app.logger.Error("moduleSnapshotsHandler: ModuleManager not initialized")
+		http.Error(w, "Internal Server Error - Configuration Error", http.StatusInternalServerError)
+		return
+	}
+
+	snapshots, err := app.moduleManager.ListSnapshots(moduleID)
+	if err != nil {
+		app.logger.Error("moduleSnapshotsHandler: Failed to list snapshots", "moduleID", moduleID, "error", err)
+		http.Error(w, "Failed to load module history", historyErrorStatus(err))
+		return
+	}
+	app.writeJSON(w, http.StatusOK, snapshots)
+}
+
+// moduleSnapshotDiffHandler returns the differences between a snapshot and the module's current state as JSON.
+func (app *adminApplication) moduleSnapshotDiffHandler(w http.ResponseWriter, r *http.Request) {
+	moduleID := chi.URLParam(r, "moduleID")
+	snapshotID := chi.URLParam(r, "snapshotID")
+	if app.moduleManager == nil {
+		app.logger.Error("moduleSnapshotDiffHandler: ModuleManager not initialized")
+		http.Error(w, "Internal Server Error - Configuration Error", http.StatusInternalServerError)
+		return
+	}
+
+	diff, err := app.moduleManager.DiffSnapshot(moduleID, snapshotID)
+	if err != nil {
+		app.logger.Error("moduleSnapshotDiffHandler: Failed to diff snapshot", "moduleID", moduleID, "snapshotID", snapshotID, "error", err)
+		http.Error(w, "Failed to compare snapshot", historyErrorStatus(err))
+		return
+	}
+	app.writeJSON(w, http.StatusOK, diff)
+}
+
+// moduleSnapshotRestoreHandler restores a module to a snapshot. The CSRF token is sent in the X-CSRF-Token header.
+func (app *adminApplication) moduleSnapshotRestoreHandler(w http.ResponseWriter, r *http.Request) {
+	moduleID := chi.URLParam(r, "moduleID")
+	snapshotID := chi.URLParam(r, "snapshotID")
+	if app.moduleManager == nil {
+		app.logger.Error("moduleSnapshotRestoreHandler: ModuleManager not initialized")
+		http.Error(w, "Internal Server Error - Configuration Error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := app.manager(r).RestoreSnapshot(moduleID, snapshotID); err != nil {
+		app.logger.Error("moduleSnapshotRestoreHandler: Failed to restore snapshot", "moduleID", moduleID, "snapshotID", snapshotID, "error", err)
+		http.Error(w, fmt.Sprintf("Failed to restore snapshot: %v", err), historyErrorStatus(err))
+		return
+	}
+
+	app.logger.Info("Restored module snapshot", "moduleID", moduleID, "snapshotID", snapshotID)
+	fmt.Fprintf(w, "Module restored to snapshot %s.", snapshotID)
+}
+
+// auditLogLimit is the most audit entries the audit page shows at once.
+const auditLogLimit = 200
+
+// auditHandler displays the audit log, newest first, filtered by the module, user and
+// time range in the query string.
+func (app *adminApplication) auditHandler(w http.ResponseWriter, r *http.Request) {
+	if app.moduleManager == nil {
+		app.logger.Error("ModuleManager not initialized in admin application")
+		http.Error(w, "Internal Server Error - Configuration Error", http.StatusInternalServerError)
+		return
+	}
+	data := app.newTemplateData(r, "audit")
+	query := r.URL.Query()
+	filter := storage.AuditFilter{
+		ModuleID: strings.TrimSpace(query.Get("moduleId")),
+		Actor:    strings.TrimSpace(query.Get("user")),
+		Limit:    auditLogLimit,
+	}
+	data["ModuleID"] = filter.ModuleID
+	data["User"] = filter.Actor
+	data["Since"] = query.Get("since")
+	data["Until"] = query.Get("until")
+	data["Limit"] = auditLogLimit
+
+	since, sinceErr := modulemanager.ParseScheduleTime(query.Get("since"))
+	until, untilErr := modulemanager.ParseScheduleTime(query.Get("until"))
+	auditLog := app.moduleManager.AuditLog()
+	switch {
+	case sinceErr != nil:
+		data["Error"] = "Invalid 'since' time: " + sinceErr.Error()
+	case untilErr != nil:
+		data["Error"] = "Invalid 'until' time: " + untilErr.Error()
+	case auditLog == nil:
+		app.logger.Warn("Audit log is not initialized in audit handler")
+		data["Error"] = "Audit log not available."
+	default:
+		if since != nil {
+			filter.Since = *since
+		}
+		if until != nil {
+			filter.Until = *until
+		}
+		entries, err := auditLog.QueryAudit(filter)
+		if corrupt, ok := storage.AsCorruptAudit(err); ok {
+			app.logger.Warn("auditHandler: Skipped undecodable audit log lines", "error", corrupt)
+			data["SkippedLines"] = corrupt.LineNumbers()
+			err = nil
+		}
+		if err != nil {
+			app.logger.Error("auditHandler: Failed to query audit log", "error", err)
+			data["Error"] = "Failed to load the audit log."
+		}
+		data["Entries"] = entries
+	}
+
+	ts, ok := app.templateCache["audit.html"]
+	if !ok {
+		app.logger.Error("Template audit.html not found in cache")
+		http.Error(w, "Internal Server Error - Template not found", http.StatusInternalServerError)
+		return
+	}
+	if err := ts.ExecuteTemplate(w, "layout.html", data); err != nil {
+		app.logger.Error("Error executing admin layout template", "error", err)
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-module-builder/internal/model"
+	"go-module-builder/internal/modulemanager"
+	"go-module-builder/internal/storage"
+)
+
+// conflictStore fails every SaveModule as if another save had won the race.
+type conflictStore struct {
+	storage.DataStore
+}
+
+func (s conflictStore) SaveModule(module *model.Module) error {
+	return &storage.ConflictError{ModuleID: module.ID, Expected: module.Revision, Actual: module.Revision + 1}
+}
+
+func TestSaveTemplate_ConflictRecordsNoSnapshot(t *testing.T) {
+	srv, app := newTestServer(t, &model.User{Username: "ed", Role: model.RoleEditor})
+	mod, err := app.moduleManager.CreateModule("Hero", "hero")
+	if err != nil {
+		t.Fatalf("CreateModule failed: %v", err)
+	}
+	app.moduleManager = modulemanager.NewManager(conflictStore{app.moduleStore}, app.logger, app.projectRoot, filepath.Join(app.projectRoot, "modules"))
+	client, token := signIn(t, srv, "ed")
+
+	req, _ := http.NewRequest(http.MethodPut, srv.URL+"/api/admin/modules/"+mod.ID+"/templates/base.html", strings.NewReader("<p>lost</p>"))
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("X-CSRF-Token", token)
+	req.Header.Set("If-Match", revisionETag(mod.Revision)) // Current, but another save wins the race
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("Status = %d, want %d", resp.StatusCode, http.StatusConflict)
+	}
+
+	snapshots, err := app.moduleManager.ListSnapshots(mod.ID)
+	if err != nil {
+		t.Fatalf("ListSnapshots failed: %v", err)
+	}
+	if len(snapshots) != 0 {
+		t.Errorf("A rejected save recorded %d snapshots, want none", len(snapshots))
+	}
+}
+
+func TestSaveTemplate_RequiresCurrentRevision(t *testing.T) {
+	srv, app := newTestServer(t, &model.User{Username: "ed", Role: model.RoleEditor})
+	mod, err := app.moduleManager.CreateModule("Hero", "hero")
+	if err != nil {
+		t.Fatalf("CreateModule failed: %v", err)
+	}
+	templatePath := filepath.Join(mod.Directory, "templates", "base.html")
+	oldContent, err := os.ReadFile(templatePath)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	client, token := signIn(t, srv, "ed")
+
+	tests := []struct {
+		name       string
+		ifMatch    string
+		wantStatus int
+	}{
+		{"without If-Match", "", http.StatusPreconditionRequired},
+		{"stale revision", revisionETag(mod.Revision - 1), http.StatusConflict},
+		{"invalid revision", `"latest"`, http.StatusConflict},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPut, srv.URL+"/api/admin/modules/"+mod.ID+"/templates/base.html", strings.NewReader("<p>lost</p>"))
+			req.Header.Set("Content-Type", "text/plain")
+			req.Header.Set("X-CSRF-Token", token)
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tc.wantStatus {
+				t.Errorf("Status = %d, want %d", resp.StatusCode, tc.wantStatus)
+			}
+		})
+	}
+
+	if content, err := os.ReadFile(templatePath); err != nil || string(content) != string(oldContent) {
+		t.Errorf("base.html = %q, %v after refused saves, want it unchanged", content, err)
+	}
+	if infos, err := app.moduleManager.ListSnapshots(mod.ID); err != nil || len(infos) != 0 {
+		t.Errorf("ListSnapshots = %d snapshots, %v after refused saves, want none", len(infos), err)
+	}
+}
+
+func TestSaveTemplate_SnapshotsPreviousVersion(t *testing.T) {
+	srv, app := newTestServer(t, &model.User{Username: "ed", Role: model.RoleEditor})
+	mod, err := app.moduleManager.CreateModule("Hero", "hero")
+	if err != nil {
+		t.Fatalf("CreateModule failed: %v", err)
+	}
+	oldContent, err := os.ReadFile(filepath.Join(mod.Directory, "templates", "base.html"))
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	client, token := signIn(t, srv, "ed")
+
+	req, _ := http.NewRequest(http.MethodPut, srv.URL+"/api/admin/modules/"+mod.ID+"/templates/base.html", strings.NewReader("<p>new</p>"))
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("X-CSRF-Token", token)
+	req.Header.Set("If-Match", revisionETag(mod.Revision))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	infos, err := app.moduleManager.ListSnapshots(mod.ID)
+	if err != nil || len(infos) != 1 {
+		t.Fatalf("ListSnapshots = %v, %v; want one snapshot", infos, err)
+	}
+	snap, err := app.moduleStore.LoadSnapshot(mod.ID, infos[0].ID)
+	if err != nil {
+		t.Fatalf("LoadSnapshot failed: %v", err)
+	}
+	// The snapshot pairs the old content with the revision that had it
+	if snap.Module.Revision != mod.Revision || snap.Files["base.html"] != string(oldContent) {
+		t.Errorf("Snapshot has revision %d and base.html %q, want revision %d with %q", snap.Module.Revision, snap.Files["base.html"], mod.Revision, oldContent)
+	}
+}
+
+func TestTemplateActions_ConflictAsksForReload(t *testing.T) {
+	srv, app := newTestServer(t, &model.User{Username: "ed", Role: model.RoleEditor})
+	mod, err := app.moduleManager.CreateModule("Hero", "hero")
+	if err != nil {
+		t.Fatalf("CreateModule failed: %v", err)
+	}
+	layoutsDir := filepath.Join(app.projectRoot, "web", "templates", "layouts")
+	if err := os.MkdirAll(layoutsDir, 0755); err != nil {
+		t.Fatalf("MkdirAll failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(layoutsDir, "layout.html"), []byte(`{{ template "page" . }}`), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	var order []string
+	for _, tmpl := range mod.Templates {
+		order = append(order, tmpl.Name)
+	}
+	app.moduleManager = modulemanager.NewManager(conflictStore{app.moduleStore}, app.logger, app.projectRoot, filepath.Join(app.projectRoot, "modules"))
+	client, token := signIn(t, srv, "ed")
+
+	editPath := "/admin/modules/edit/" + mod.ID
+	tests := []struct {
+		name string
+		path string
+		form url.Values
+	}{
+		{"add", editPath + "/add-template", url.Values{"new_template_name": {"card.html"}}},
+		{"remove", editPath + "/remove-template/base.html", url.Values{}},
+		{"toggle", editPath + "/toggle-template/base.html", url.Values{"active": {"false"}}},
+		{"reorder", editPath + "/reorder-templates", url.Values{"order": order}},
+		{"layout", editPath + "/layout", url.Values{"layout": {"layout.html"}}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.form.Set("csrf_token", token)
+			req, _ := http.NewRequest(http.MethodPost, srv.URL+tc.path, strings.NewReader(tc.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("HX-Request", "true")
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusConflict {
+				t.Errorf("Status = %d, want %d", resp.StatusCode, http.StatusConflict)
+			}
+			if trigger := resp.Header.Get("HX-Trigger"); !strings.Contains(trigger, "Reload the page") {
+				t.Errorf("HX-Trigger = %s, want a message asking to reload", trigger)
+			}
+		})
+	}
+}
+
+func TestAuditPage_WithoutModuleManager(t *testing.T) {
+	srv, app := newTestServer(t, &model.User{Username: "root", Role: model.RoleAdmin})
+	client, _ := signIn(t, srv, "root")
+	app.moduleManager = nil
+
+	resp, err := client.Get(srv.URL + "/admin/audit")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	// The recovered panic would also be a 500, but without the message
+	if resp.StatusCode != http.StatusInternalServerError || !strings.Contains(string(body), "Configuration Error") {
+		t.Errorf("GET /admin/audit = %d %q, want %d and a configuration error", resp.StatusCode, body, http.StatusInternalServerError)
+	}
+}
+
+func TestFlashMessagesStayInTheirSession(t *testing.T) {
+	srv, app := newTestServer(t,
+		&model.User{Username: "ed", Role: model.RoleEditor},
+		&model.User{Username: "vera", Role: model.RoleViewer},
+	)
+	ed, token := signIn(t, srv, "ed")
+	ed.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	vera, _ := signIn(t, srv, "vera")
+
+	resp, err := ed.PostForm(srv.URL+"/admin/modules/new", url.Values{"csrf_token": {token}, "moduleName": {""}})
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	// templateData returns the template data of a page loaded by client
+	templateData := func(client *http.Client) map[string]any {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		serverURL, _ := url.Parse(srv.URL)
+		for _, cookie := range client.Jar.Cookies(serverURL) {
+			req.AddCookie(cookie)
+		}
+		return app.newTemplateData(req, "dashboard")
+	}
+	if data := templateData(vera); data["FlashError"] != nil {
+		t.Errorf("vera's page shows ed's message %q", data["FlashError"])
+	}
+	if data := templateData(ed); data["FlashError"] != "Module Name is required." {
+		t.Errorf("ed's page shows FlashError %q, want the form error", data["FlashError"])
+	}
+	if data := templateData(ed); data["FlashError"] != nil {
+		t.Errorf("ed's next page still shows %q", data["FlashError"])
+	}
+}
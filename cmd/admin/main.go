@@ -9,8 +9,13 @@ import (
 	"path/filepath" // Added for joining paths
 
 	// Added for module type
+	"go-module-builder/internal/auth"
+	"go-module-builder/internal/dataprovider"
+	"go-module-builder/internal/logging"
 	"go-module-builder/internal/modulemanager"
 	"go-module-builder/internal/storage" // Added for storage interface
+	"go-module-builder/internal/templating"
+	"go-module-builder/pkg/fsutils"
 
 	"github.com/justinas/nosurf" // Added for CSRF token in template data
 	"github.com/spf13/viper"     // Added for configuration management
@@ -23,28 +28,33 @@ type adminApplication struct {
 	projectRoot   string                        // Added project root
 	moduleManager *modulemanager.ModuleManager  // Added module manager field
 	templateCache map[string]*template.Template // Added for template caching
-	// Fields for simulated flash messages
-	FlashSuccessMessage string
-	FlashErrorMessage   string
+	dataResolver  *dataprovider.Resolver        // Loads declared module data for previews
+	renderer      *templating.Renderer          // Renders module previews like the main server
+	users         storage.UserStore             // Accounts that can sign in
+	sessions      *auth.Sessions                // Login sessions
+	secureCookies bool                          // Send the session and CSRF cookies over HTTPS only
+	sameSite      http.SameSite                 // SameSite mode of the session and CSRF cookies
 }
 
 // newTemplateData creates a map of data to pass to templates, including CSRF token and active nav item.
 func (app *adminApplication) newTemplateData(r *http.Request, activeNav string) map[string]any {
 	// Create a base map.
 	data := map[string]any{
-		"CSRFToken": nosurf.Token(r),
-		"ActiveNav": activeNav, // Identifier for the current active navigation tab
+		"CSRFToken":   nosurf.Token(r),
+		"ActiveNav":   activeNav, // Identifier for the current active navigation tab
+		"CurrentUser": app.currentUser(r),
 		// "CurrentYear": time.Now().Year(), // Could be added here if not page-specific
 	}
 
-	// Add flash messages to template data if they exist
-	if app.FlashSuccessMessage != "" {
-		data["FlashSuccess"] = app.FlashSuccessMessage
-		app.FlashSuccessMessage = "" // Clear after adding to data
-	}
-	if app.FlashErrorMessage != "" {
-		data["FlashError"] = app.FlashErrorMessage
-		app.FlashErrorMessage = "" // Clear after adding to data
+	// Add the session's flash messages to template data if they exist; they are shown once
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		flash := app.sessions.PopFlash(cookie.Value)
+		if flash.Success != "" {
+			data["FlashSuccess"] = flash.Success
+		}
+		if flash.Error != "" {
+			data["FlashError"] = flash.Error
+		}
 	}
 
 	return data
@@ -59,6 +69,7 @@ func newTemplateCache(projectRoot string) (map[string]*template.Template, error)
 		"dashboard.html",
 		"module_form.html",
 		"module_editor.html",
+		"audit.html",
 	}
 
 	// Path to the admin templates directory
@@ -92,6 +103,13 @@ func newTemplateCache(projectRoot string) (map[string]*template.Template, error)
 		cache[name] = ts
 	}
 
+	// The login page stands alone, without the sidebar
+	loginTemplate, err := template.ParseFiles(filepath.Join(adminTemplatesDir, "login.html"))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing login template: %w", err)
+	}
+	cache["login.html"] = loginTemplate
+
 	// Additionally, cache partials individually so they can be executed directly by handlers.
 	// This is important for HTMX partial responses that are not part of a full page render.
 	for _, partialFile := range partialFiles {
@@ -113,7 +131,9 @@ func newTemplateCache(projectRoot string) (map[string]*template.Template, error)
 
 func main() {
 	// --- Initialize Logger ---
-	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	// Until config.yaml has been read, log with the default level and format; the
+	// defaults are always valid.
+	logger, _ := logging.New(os.Stdout, logging.DefaultLevel, logging.DefaultFormat)
 
 	// --- Initialize Application Struct ---
 	// Get working directory (assuming run from project root)
@@ -126,8 +146,47 @@ func main() {
 	metadataDir := filepath.Join(projRoot, ".module_metadata")
 	logger.Info("Using metadata directory", "path", metadataDir)
 
+	// --- Configuration ---
+	viper.SetConfigName("config")    // name of config file (without extension)
+	viper.SetConfigType("yaml")      // REQUIRED if the config file does not have the extension in the name
+	viper.AddConfigPath(".")         // look for config in the working directory
+	viper.SetEnvPrefix("GOWS_ADMIN") // Prefix for environment variables for admin server
+	viper.AutomaticEnv()             // Read in environment variables that match
+
+	// Set default values
+	viper.SetDefault("admin_server.port", "8081")
+	viper.SetDefault("admin_server.sessionTTL", auth.DefaultSessionTTL)
+	viper.SetDefault("admin_server.sameSite", "lax")
+	viper.SetDefault("storage.usersDir", storage.DefaultUsersDir)
+	viper.SetDefault("storage.auditLog", storage.DefaultAuditLogFile)
+	viper.SetDefault("storage.backend", storage.BackendJSON)
+	viper.SetDefault("storage.sqlitePath", filepath.Join(".module_metadata", storage.DefaultSQLiteFile))
+	viper.SetDefault("logging.level", logging.DefaultLevel)
+	viper.SetDefault("logging.format", logging.DefaultFormat)
+
+	// Read the config file
+	if err := viper.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
+			logger.Warn("config.yaml not found, using default admin server port.")
+		} else {
+			logger.Error("Fatal error reading config file for admin server", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	// Replace the startup logger with the one configured in the logging section
+	logger, err = logging.New(os.Stdout, viper.GetString("logging.level"), viper.GetString("logging.format"))
+	if err != nil {
+		slog.Error("Invalid logging configuration", "error", err)
+		os.Exit(1)
+	}
+	fsutils.SetLogger(logger)
+
 	// --- Initialize Storage ---
-	store, err := storage.NewJSONStore(metadataDir)
+	storageBackend := viper.GetString("storage.backend")
+	storagePath := storage.ResolvePath(projRoot, storageBackend, metadataDir, viper.GetString("storage.sqlitePath"))
+	logger.Info("Using storage backend", "backend", storageBackend, "path", storagePath)
+	store, err := storage.Open(storageBackend, storagePath, logger)
 	if err != nil {
 		// Log non-fatal error if dir doesn't exist, fatal otherwise
 		if os.IsNotExist(err) {
@@ -141,11 +200,43 @@ func main() {
 		}
 	}
 
+	// Accounts live next to the module metadata: in their own directory for the JSON
+	// backend, in the database for SQLite.
+	usersPath := storage.ResolvePath(projRoot, storageBackend, viper.GetString("storage.usersDir"), viper.GetString("storage.sqlitePath"))
+	users, err := storage.OpenUsers(storageBackend, usersPath, store)
+	if err != nil {
+		logger.Error("Failed to initialize user store", "error", err)
+		os.Exit(1)
+	}
+	if existing, err := users.ReadAllUsers(); err != nil {
+		logger.Error("Failed to read admin users", "error", err)
+		os.Exit(1)
+	} else if len(existing) == 0 {
+		logger.Warn("No admin users exist, so nobody can sign in. Create one with: builder-cli user add -username <name> -role admin")
+	}
+
 	// Initialize Module Manager
 	// Note: Using the same modules directory as the CLI/Server for now.
 	modulesDir := filepath.Join(projRoot, "modules")                         // Define modules dir path
 	manager := modulemanager.NewManager(store, logger, projRoot, modulesDir) // Use same logger for now
 
+	// Module changes are recorded in the audit log, kept like the accounts
+	auditPath := storage.ResolvePath(projRoot, storageBackend, viper.GetString("storage.auditLog"), viper.GetString("storage.sqlitePath"))
+	audit, err := storage.OpenAudit(storageBackend, auditPath, store)
+	if err != nil {
+		logger.Error("Failed to initialize audit log", "error", err)
+		os.Exit(1)
+	}
+	manager.SetAuditLog(audit)
+
+	// Pages are served before modules, so their slugs are off limits to modules
+	pageStore, err := storage.NewJSONPageStore(filepath.Join(projRoot, ".page_metadata"))
+	if err != nil {
+		logger.Error("Failed to initialize page store", "error", err)
+		os.Exit(1)
+	}
+	manager.SetPageStore(pageStore)
+
 	// --- Initialize Application Struct ---
 	// Initialize Template Cache
 	templateCache, err := newTemplateCache(projRoot)
@@ -155,46 +246,70 @@ func main() {
 	}
 	logger.Info("Admin UI templates cached successfully")
 
+	// Previews render modules inside the main site's layouts
+	siteLayouts, err := templating.ParseLayouts(filepath.Join(projRoot, "web", "templates"))
+	if err != nil {
+		logger.Error("Failed to parse site layout templates", "error", err)
+		os.Exit(1)
+	}
+	renderer, err := templating.NewRenderer(modulesDir, siteLayouts, logger)
+	if err != nil {
+		logger.Error("Failed to create template renderer", "error", err)
+		os.Exit(1)
+	}
+	if err := renderer.LoadNamedLayouts(filepath.Join(projRoot, "web", "templates", templating.LayoutsDir)); err != nil {
+		logger.Error("Failed to parse named layouts", "error", err)
+		os.Exit(1)
+	}
+
 	app := &adminApplication{
 		logger:        logger,
 		moduleStore:   store, // Assign the initialized store
 		projectRoot:   projRoot,
 		moduleManager: manager,       // Assign the initialized manager
 		templateCache: templateCache, // Assign the initialized cache
-	}
-
-	// --- Configuration ---
-	viper.SetConfigName("config")    // name of config file (without extension)
-	viper.SetConfigType("yaml")      // REQUIRED if the config file does not have the extension in the name
-	viper.AddConfigPath(".")         // look for config in the working directory
-	viper.SetEnvPrefix("GOWS_ADMIN") // Prefix for environment variables for admin server
-	viper.AutomaticEnv()             // Read in environment variables that match
-
-	// Set default values
-	viper.SetDefault("admin_server.port", "8081")
-
-	// Read the config file
-	if err := viper.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-			logger.Warn("config.yaml not found, using default admin server port.")
-		} else {
-			logger.Error("Fatal error reading config file for admin server", "error", err)
-			os.Exit(1)
-		}
+		dataResolver:  dataprovider.NewResolver(projRoot, nil),
+		renderer:      renderer,
+		users:         users,
+		sessions:      auth.NewSessions(viper.GetDuration("admin_server.sessionTTL")),
 	}
 
 	adminPort := viper.GetString("admin_server.port")
+	certFile := viper.GetString("admin_server.certFile")
+	keyFile := viper.GetString("admin_server.keyFile")
+	useTLS := certFile != "" && keyFile != ""
+	// Behind an HTTPS reverse proxy the server itself sees plain HTTP
+	app.secureCookies = useTLS || viper.GetBool("admin_server.secureCookies")
+	app.sameSite, err = parseSameSite(viper.GetString("admin_server.sameSite"))
+	if err != nil {
+		logger.Error("Invalid admin server cookie settings", "error", err)
+		os.Exit(1)
+	}
 
 	// --- Start Server ---
 	addr := ":" + adminPort
-	logger.Info("Starting admin server", "address", fmt.Sprintf("http://localhost%s", addr))
 
 	// Get the router from the routes method
 	router := app.routes() // This now uses the app variable
 
-	err = http.ListenAndServe(addr, router) // Corrected assignment from := to =
+	if useTLS {
+		logger.Info("Starting admin server", "address", fmt.Sprintf("https://localhost%s", addr))
+		err = http.ListenAndServeTLS(addr, resolvePath(projRoot, certFile), resolvePath(projRoot, keyFile), router)
+	} else {
+		logger.Warn("Admin server is using plain HTTP; set admin_server.certFile and keyFile, or use an HTTPS reverse proxy, outside local development")
+		logger.Info("Starting admin server", "address", fmt.Sprintf("http://localhost%s", addr))
+		err = http.ListenAndServe(addr, router)
+	}
 	if err != nil {
 		logger.Error("Admin server failed to start", "error", err)
 		os.Exit(1) // Keep os.Exit(1)
 	}
 }
+
+// resolvePath resolves a configured path relative to the project root.
+func resolvePath(projectRoot, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(projectRoot, path)
+}
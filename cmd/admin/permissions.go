@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"go-module-builder/internal/model"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// requireRole allows an action that is not tied to one module, such as creating or
+// importing modules, to users with at least role on every module.
+func (app *adminApplication) requireRole(role model.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := app.currentUser(r)
+			if user == nil || !user.Can(role, nil) {
+				app.forbidden(w, r, user, role, nil)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requireModuleRole allows an action on the module in the {moduleID} route parameter
+// to users with at least role on that module's group.
+func (app *adminApplication) requireModuleRole(role model.Role) func(http.Handler) http.Handler {
+	return app.requireModuleRoleFor(func(*http.Request) model.Role { return role })
+}
+
+// requireModuleRoleFor is requireModuleRole for routes whose required role depends on
+// the request, such as deleteRole.
+func (app *adminApplication) requireModuleRoleFor(roleFor func(*http.Request) model.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role := roleFor(r)
+			user := app.currentUser(r)
+			if user == nil {
+				app.forbidden(w, r, user, role, nil)
+				return
+			}
+			var module *model.Module
+			if app.moduleStore != nil {
+				module, _ = app.moduleStore.LoadModule(chi.URLParam(r, "moduleID"))
+			}
+			if module == nil {
+				// Let the handler report the missing module to those who may see every module
+				if !user.Can(role, nil) {
+					app.forbidden(w, r, user, role, nil)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !user.Can(role, module) {
+				app.forbidden(w, r, user, role, module)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// deleteRole is the role needed to delete a module: archiving stops serving it, so it
+// takes a publisher, while a force delete cannot be undone and takes an admin.
+func deleteRole(r *http.Request) model.Role {
+	if r.PostFormValue("force") == "true" {
+		return model.RoleAdmin
+	}
+	return model.RolePublisher
+}
+
+// forbidden answers a request the user's role does not allow with an HTMX error
+// message, or a plain 403 for pages and the JSON API.
+func (app *adminApplication) forbidden(w http.ResponseWriter, r *http.Request, user *model.User, role model.Role, module *model.Module) {
+	username, moduleID := "", ""
+	if user != nil {
+		username = user.Username
+	}
+	if module != nil {
+		moduleID = module.ID
+	}
+	app.logger.Warn("Permission denied", "username", username, "required_role", role, "moduleID", moduleID, "method", r.Method, "path", r.URL.Path)
+
+	message := fmt.Sprintf("Forbidden - This action needs the %s role", role)
+	if user != nil && len(user.Groups) > 0 {
+		message += " on the module's group" // Group-limited users only have roles within their groups
+	}
+	if r.Header.Get("HX-Request") == "true" {
+		app.triggerHXError(w, message+".")
+		return
+	}
+	http.Error(w, message, http.StatusForbidden)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"go-module-builder/internal/model"
+)
+
+func TestRoutesEnforceRoles(t *testing.T) {
+	srv, app := newTestServer(t,
+		&model.User{Username: "vera", Role: model.RoleViewer},
+		&model.User{Username: "ed", Role: model.RoleEditor},
+		&model.User{Username: "mia", Role: model.RoleEditor, Groups: []string{"marketing"}},
+	)
+	marketing, err := app.moduleManager.CreateModule("Campaign", "campaign")
+	if err != nil {
+		t.Fatalf("CreateModule failed: %v", err)
+	}
+	blog, err := app.moduleManager.CreateModule("Blog", "blog")
+	if err != nil {
+		t.Fatalf("CreateModule failed: %v", err)
+	}
+	for id, group := range map[string]string{marketing.ID: "marketing", blog.ID: "blog"} {
+		if err := app.moduleManager.UpdateModule(id, "", "", group, "", ""); err != nil {
+			t.Fatalf("UpdateModule failed: %v", err)
+		}
+	}
+
+	templatePath := func(moduleID string) string {
+		return "/api/admin/modules/" + moduleID + "/templates/base.html"
+	}
+	const newContent = `{{ define "page" }}changed{{ end }}`
+
+	tests := []struct {
+		name       string
+		user       string
+		method     string
+		path       string
+		form       url.Values // Sent as a form with the CSRF token; otherwise body is sent as text
+		body       string
+		wantStatus int
+	}{
+		{"viewer cannot save a template", "vera", http.MethodPut, templatePath(blog.ID), nil, newContent, http.StatusForbidden},
+		{"viewer can read a template", "vera", http.MethodGet, templatePath(blog.ID), nil, "", http.StatusOK},
+		{"editor can save a template", "ed", http.MethodPut, templatePath(blog.ID), nil, newContent, http.StatusOK},
+		{"editor cannot publish", "ed", http.MethodPost, "/admin/modules/status/" + blog.ID, url.Values{"status": {"published"}}, "", http.StatusForbidden},
+		{"editor cannot archive", "ed", http.MethodPost, "/admin/modules/delete/" + blog.ID, url.Values{}, "", http.StatusForbidden},
+		{"editor cannot force delete", "ed", http.MethodPost, "/admin/modules/delete/" + blog.ID, url.Values{"force": {"true"}}, "", http.StatusForbidden},
+		{"group editor can save in their group", "mia", http.MethodPut, templatePath(marketing.ID), nil, newContent, http.StatusOK},
+		{"group editor cannot save in another group", "mia", http.MethodPut, templatePath(blog.ID), nil, newContent, http.StatusForbidden},
+		{"group editor cannot read another group", "mia", http.MethodGet, templatePath(blog.ID), nil, "", http.StatusForbidden},
+		{"group editor cannot create modules", "mia", http.MethodPost, "/admin/modules/new", url.Values{"moduleName": {"New"}}, "", http.StatusForbidden},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client, token := signIn(t, srv, tc.user)
+			client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+
+			body, contentType := tc.body, "text/plain"
+			if tc.form != nil {
+				tc.form.Set("csrf_token", token)
+				body, contentType = tc.form.Encode(), "application/x-www-form-urlencoded"
+			}
+			req, _ := http.NewRequest(tc.method, srv.URL+tc.path, strings.NewReader(body))
+			req.Header.Set("Content-Type", contentType)
+			req.Header.Set("X-CSRF-Token", token)
+			if tc.method == http.MethodPut {
+				moduleID := strings.Split(tc.path, "/")[4]
+				if mod, err := app.moduleStore.LoadModule(moduleID); err == nil {
+					req.Header.Set("If-Match", revisionETag(mod.Revision))
+				}
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tc.wantStatus {
+				t.Errorf("%s %s = %d, want %d", tc.method, tc.path, resp.StatusCode, tc.wantStatus)
+			}
+		})
+	}
+
+	// None of the refused requests changed the blog module
+	mod, err := app.moduleStore.LoadModule(blog.ID)
+	if err != nil {
+		t.Fatalf("LoadModule failed: %v", err)
+	}
+	if mod.Status != model.StatusDraft || mod.IsArchived() {
+		t.Errorf("Blog module is %s after refused status changes, want draft", mod.Status)
+	}
+}
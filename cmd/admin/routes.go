@@ -3,32 +3,62 @@ package main
 import (
 	"net/http"
 	"path/filepath"
+	"strings"
 	"time" // Keep time for middleware.Timeout
 
+	"go-module-builder/internal/model"
+
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware" // Import middleware
 	"github.com/justinas/nosurf"          // Added for CSRF protection
 )
 
-func noSurfMiddleware(next http.Handler) http.Handler {
+// noSurfMiddleware adds CSRF protection. Its cookie covers the whole site, so the
+// token stays the same whether the login page or the dashboard is visited first.
+// Forms send the token in their csrf_token field and scripts in the X-CSRF-Token header.
+func (app *adminApplication) noSurfMiddleware(next http.Handler) http.Handler {
 	csrfHandler := nosurf.New(next)
-	// TODO: Set HttpOnly, Secure, and Path attributes for the cookie in a production environment.
-	// For example, for production:
-	// csrfHandler.SetBaseCookie(http.Cookie{
-	// 	HttpOnly: true,
-	// 	Path:     "/",
-	// 	Secure:   true, // Assuming HTTPS in production
-	// 	SameSite: http.SameSiteLaxMode,
-	// })
-
-	// Exempt the API preview path as it's an AJAX JSON request not a traditional form post
-	// and doesn't need CSRF protection in the same way.
-	// Alternatively, ensure frontend sends X-CSRF-Token header for JSON APIs if not exempting.
-	csrfHandler.ExemptGlob("/api/admin/preview/*")
+	csrfHandler.SetBaseCookie(http.Cookie{
+		HttpOnly: true,
+		Path:     "/",
+		MaxAge:   nosurf.MaxAge,
+		Secure:   app.secureCookies,
+		SameSite: app.sameSite,
+	})
+	csrfHandler.SetFailureHandler(http.HandlerFunc(app.csrfFailureHandler))
 
 	return csrfHandler
 }
 
+// requireCSRFHeader makes the admin JSON APIs take their CSRF token from the
+// X-CSRF-Token header only. A form on another site cannot set headers, so its requests
+// are refused here even before the token is checked.
+func (app *adminApplication) requireCSRFHeader(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/") && !isSafeMethod(r.Method) && r.Header.Get(nosurf.HeaderName) == "" {
+			app.logger.Warn("Rejected API request without a CSRF header", "method", r.Method, "path", r.URL.Path, "remote_addr", r.RemoteAddr)
+			http.Error(w, "Forbidden - The "+nosurf.HeaderName+" header is required", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// csrfFailureHandler answers requests whose CSRF token is missing or wrong.
+func (app *adminApplication) csrfFailureHandler(w http.ResponseWriter, r *http.Request) {
+	app.logger.Warn("CSRF check failed", "method", r.Method, "path", r.URL.Path, "reason", nosurf.Reason(r), "remote_addr", r.RemoteAddr)
+	http.Error(w, "Forbidden - CSRF token missing or invalid, reload the page and try again", http.StatusForbidden)
+}
+
+// isSafeMethod reports whether method only reads, so it needs no CSRF token.
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
 // routes sets up the HTTP router for the admin application.
 func (app *adminApplication) routes() http.Handler {
 	r := chi.NewRouter()
@@ -39,7 +69,8 @@ func (app *adminApplication) routes() http.Handler {
 	r.Use(middleware.Logger) // Chi's built-in logger
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Timeout(60 * time.Second)) // Keep timeout
-	r.Use(noSurfMiddleware)                     // Add CSRF protection middleware
+	r.Use(app.noSurfMiddleware)                 // Add CSRF protection middleware
+	r.Use(app.requireCSRFHeader)                // JSON APIs need the token in a header
 
 	// --- Static file server ---
 	staticPath := filepath.Join(app.projectRoot, "web", "admin", "static")
@@ -52,30 +83,70 @@ func (app *adminApplication) routes() http.Handler {
 		r.Handle("/static/*", http.StripPrefix("/static/", fs))
 	})
 
+	// --- Login ---
+	r.Get("/admin/login", app.loginFormHandler) // Display the login form
+	r.Post("/admin/login", app.loginHandler)    // Check credentials and start a session
+
 	// --- Handlers ---
-	// These handlers are now defined in handlers.go
-	r.Get("/", app.dashboardHandler)
+	// These handlers are now defined in handlers.go. Every one of them needs a signed-in user,
+	// and each route names the least role it needs (see model.Role); module routes check the
+	// role against the module's group.
+	r.Group(func(r chi.Router) {
+		r.Use(app.requireLogin)
+
+		r.Post("/admin/logout", app.logoutHandler) // End the session
+		r.Get("/", app.dashboardHandler)           // Lists the modules the user may view
+
+		viewer := app.requireModuleRole(model.RoleViewer)
+		editor := app.requireModuleRole(model.RoleEditor)
+		publisher := app.requireModuleRole(model.RolePublisher)
+
+		// Module Creation Routes
+		r.With(app.requireRole(model.RoleEditor)).Get("/admin/modules/new", app.moduleCreateFormHandler) // Display the form
+		r.With(app.requireRole(model.RoleEditor)).Post("/admin/modules/new", app.moduleCreateHandler)    // Handle form submission
+
+		// Module Archive Routes
+		r.With(viewer).Get("/admin/modules/{moduleID}/export", app.moduleExportHandler)                  // Download a module archive
+		r.With(app.requireRole(model.RoleEditor)).Post("/admin/modules/import", app.moduleImportHandler) // Upload a module archive
 
-	// Module Creation Routes
-	r.Get("/admin/modules/new", app.moduleCreateFormHandler) // Display the form
-	r.Post("/admin/modules/new", app.moduleCreateHandler)    // Handle form submission
+		// Module Deletion Route (archiving needs a publisher, force delete an admin)
+		r.With(app.requireModuleRoleFor(deleteRole)).Post("/admin/modules/delete/{moduleID}", app.moduleDeleteHandler) // Handle delete submission
 
-	// Module Deletion Route
-	r.Post("/admin/modules/delete/{moduleID}", app.moduleDeleteHandler) // Handle delete submission
+		// Module Status Route (publish, disable, back to draft)
+		r.With(publisher).Post("/admin/modules/status/{moduleID}", app.moduleStatusHandler)
 
-	// Module Editing Route
-	r.Get("/admin/modules/edit/{moduleID}", app.moduleEditFormHandler)                                           // Display edit form/placeholder
-	r.Post("/admin/modules/edit/{moduleID}/add-template", app.moduleAddTemplateHandler)                          // Handle adding a new template
-	r.Post("/admin/modules/edit/{moduleID}/remove-template/{templateFilename}", app.moduleRemoveTemplateHandler) // Handle removing a template
+		// Module Restore Route (soft-deleted modules)
+		r.With(publisher).Post("/admin/modules/restore/{moduleID}", app.moduleRestoreHandler) // Undo a soft delete
 
-	// API Route to get template content
-	r.Get("/api/admin/modules/{moduleID}/templates/{filename}", app.getModuleTemplateContentHandler)
+		// Module Duplication Route
+		r.With(editor).Post("/admin/modules/duplicate/{moduleID}", app.moduleDuplicateHandler) // Clone a module
 
-	// API Route for Live Preview
-	r.Post("/api/admin/preview/{moduleID}", app.modulePreviewHandler)
+		// Module Editing Route (viewers get the editor read-only)
+		r.With(viewer).Get("/admin/modules/edit/{moduleID}", app.moduleEditFormHandler)                                           // Display edit form/placeholder
+		r.With(editor).Post("/admin/modules/edit/{moduleID}/add-template", app.moduleAddTemplateHandler)                          // Handle adding a new template
+		r.With(editor).Post("/admin/modules/edit/{moduleID}/remove-template/{templateFilename}", app.moduleRemoveTemplateHandler) // Handle removing a template
+		r.With(editor).Post("/admin/modules/edit/{moduleID}/toggle-template/{templateFilename}", app.moduleToggleTemplateHandler) // Enable or disable a template
+		r.With(editor).Post("/admin/modules/edit/{moduleID}/reorder-templates", app.moduleReorderTemplatesHandler)                // Set the template render order
+		r.With(editor).Post("/admin/modules/edit/{moduleID}/layout", app.moduleLayoutHandler)                                     // Select the layout the module renders in
+		r.With(publisher).Post("/admin/modules/edit/{moduleID}/schedule", app.moduleScheduleHandler)                              // Set when the module is published and unpublished
 
-	// API Route to save template content
-	r.Put("/api/admin/modules/{moduleID}/templates/{filename}", app.saveModuleTemplateContentHandler)
+		// API Route to get template content
+		r.With(viewer).Get("/api/admin/modules/{moduleID}/templates/{filename}", app.getModuleTemplateContentHandler)
+
+		// API Route for Live Preview (renders without saving, so viewers may use it; still needs the CSRF header)
+		r.With(viewer).Post("/api/admin/preview/{moduleID}", app.modulePreviewHandler)
+
+		// API Route to save template content
+		r.With(editor).Put("/api/admin/modules/{moduleID}/templates/{filename}", app.saveModuleTemplateContentHandler)
+
+		// API Routes for module version history
+		r.With(viewer).Get("/api/admin/modules/{moduleID}/snapshots", app.moduleSnapshotsHandler)
+		r.With(viewer).Get("/api/admin/modules/{moduleID}/snapshots/{snapshotID}/diff", app.moduleSnapshotDiffHandler)
+		r.With(editor).Post("/api/admin/modules/{moduleID}/snapshots/{snapshotID}/restore", app.moduleSnapshotRestoreHandler)
+
+		// Audit Log Route (changes to every module, so admins only)
+		r.With(app.requireRole(model.RoleAdmin)).Get("/admin/audit", app.auditHandler)
+	})
 
 	return r
 }
@@ -2,8 +2,16 @@ package main
 
 import (
 	"bufio" // Added for reading user input
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"go-module-builder/internal/auth"
+	"go-module-builder/internal/dataprovider"
+	"go-module-builder/internal/export"
+	"go-module-builder/internal/generator"
+	"go-module-builder/internal/logging"
+	"go-module-builder/internal/model"
 	"go-module-builder/internal/modulemanager" // Import the new manager package
 	"go-module-builder/internal/storage"
 	"go-module-builder/internal/templating"
@@ -12,14 +20,22 @@ import (
 	"log/slog" // Import slog for the manager
 	"os"
 	"os/exec" // Added for opening browser
+	"os/user"
 	"path/filepath"
 	"runtime" // Added for OS detection
+	"slices"
 	"strings" // Added for trimming user input
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
 )
 
 const (
-	metadataDir    = ".module_metadata" // Directory to store JSON metadata files
-	modulesBaseDir = "modules"          // Default directory to store actual module content
+	metadataDir     = ".module_metadata" // Directory to store JSON metadata files
+	pageMetadataDir = ".page_metadata"   // Directory to store JSON page metadata files
+	modulesBaseDir  = "modules"          // Default directory to store actual module content
 )
 
 func main() {
@@ -32,41 +48,99 @@ func main() {
 	}
 	fmt.Printf("Operating in: %s\n", projectRoot)
 
-	storagePath := filepath.Join(projectRoot, metadataDir)
+	// --- Configuration ---
+	// The storage and logging settings are shared with the servers.
+	viper.SetConfigName("config")
+	viper.SetConfigType("yaml")
+	viper.AddConfigPath(projectRoot)
+	viper.SetEnvPrefix("GOWS")
+	viper.AutomaticEnv()
+	viper.SetDefault("storage.backend", storage.BackendJSON)
+	viper.SetDefault("storage.sqlitePath", filepath.Join(metadataDir, storage.DefaultSQLiteFile))
+	viper.SetDefault("storage.usersDir", storage.DefaultUsersDir)
+	viper.SetDefault("storage.auditLog", storage.DefaultAuditLogFile)
+	viper.SetDefault("logging.level", logging.DefaultLevel)
+	viper.SetDefault("logging.format", logging.DefaultFormat)
+	if err := viper.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+			log.Fatalf("Error reading config file: %v", err)
+		}
+	}
+
+	// Log to stderr so structured records never mix with command output on stdout
+	cliLogger, err := logging.New(os.Stderr, viper.GetString("logging.level"), viper.GetString("logging.format"))
+	if err != nil {
+		log.Fatalf("Error configuring logging: %v", err)
+	}
+	fsutils.SetLogger(cliLogger)
+
+	storageBackend := viper.GetString("storage.backend")
+	storagePath := storage.ResolvePath(projectRoot, storageBackend, metadataDir, viper.GetString("storage.sqlitePath"))
 	moduleStorageDir := filepath.Join(projectRoot, modulesBaseDir)
 
-	store, err := storage.NewJSONStore(storagePath)
+	store, err := storage.Open(storageBackend, storagePath, cliLogger)
 	if err != nil {
 		log.Fatalf("Error initializing storage: %v", err)
 	}
-	// Initialize the templating engine
-	templateEngine := templating.NewEngine(store)
-
+	pageStore, err := storage.NewJSONPageStore(filepath.Join(projectRoot, pageMetadataDir))
+	if err != nil {
+		log.Fatalf("Error initializing page storage: %v", err)
+	}
 	// Initialize Module Manager
-	// Use standard log for CLI output, but maybe a structured logger for the manager itself?
-	// For now, let's pass nil to use the manager's default discard logger for internal operations.
-	// Or create a simple slog logger. Let's create one.
-	cliLogger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo})) // Log manager errors to stderr
 	manager := modulemanager.NewManager(store, cliLogger, projectRoot, moduleStorageDir)
+	// Changes made here are recorded in the same audit log as the admin server's
+	auditPath := storage.ResolvePath(projectRoot, storageBackend, viper.GetString("storage.auditLog"), viper.GetString("storage.sqlitePath"))
+	auditLog, err := storage.OpenAudit(storageBackend, auditPath, store)
+	if err != nil {
+		log.Fatalf("Error initializing audit log: %v", err)
+	}
+	manager.SetAuditLog(auditLog)
+	manager.SetPageStore(pageStore)
+	manager = manager.As(cliActor())
 
-	fmt.Printf("Using storage path: %s\n", storagePath)
+	fmt.Printf("Using storage path: %s (%s)\n", storagePath, storageBackend)
 	fmt.Printf("Using modules base path: %s\n", moduleStorageDir)
 
 	// --- Command Parsing using 'flag' package ---
 	// Define subcommands
 	listCmd := flag.NewFlagSet("list", flag.ExitOnError)
 	createCmd := flag.NewFlagSet("create", flag.ExitOnError)
+	cloneCmd := flag.NewFlagSet("clone", flag.ExitOnError)
+	setStatusCmd := flag.NewFlagSet("set-status", flag.ExitOnError)
 	deleteCmd := flag.NewFlagSet("delete", flag.ExitOnError)
 	previewCmd := flag.NewFlagSet("preview", flag.ExitOnError)
+	exportCmd := flag.NewFlagSet("export", flag.ExitOnError)
+	exportModuleCmd := flag.NewFlagSet("export-module", flag.ExitOnError)
+	importModuleCmd := flag.NewFlagSet("import-module", flag.ExitOnError)
 	addTemplateCmd := flag.NewFlagSet("add-template", flag.ExitOnError)
 	purgeRemovedCmd := flag.NewFlagSet("purge-removed", flag.ExitOnError)
 	// --- New: Define update subcommand ---
 	updateCmd := flag.NewFlagSet("update", flag.ExitOnError)
+	createPageCmd := flag.NewFlagSet("create-page", flag.ExitOnError)
+	pageAddModuleCmd := flag.NewFlagSet("page-add-module", flag.ExitOnError)
+	migrateStoreCmd := flag.NewFlagSet("migrate-store", flag.ExitOnError)
+	historyCmd := flag.NewFlagSet("history", flag.ExitOnError)
+	restoreCmd := flag.NewFlagSet("restore", flag.ExitOnError)
+	generateHandlersCmd := flag.NewFlagSet("generate-handlers", flag.ExitOnError)
+	setDataCmd := flag.NewFlagSet("set-data", flag.ExitOnError)
+	userCmd := flag.NewFlagSet("user", flag.ExitOnError)
+	auditCmd := flag.NewFlagSet("audit", flag.ExitOnError)
+	toggleTemplateCmd := flag.NewFlagSet("toggle-template", flag.ExitOnError)
+	reorderTemplatesCmd := flag.NewFlagSet("reorder-templates", flag.ExitOnError)
 
 	// Flags for create command
 	createName := createCmd.String("name", "", "Name of the module to create (required)")
 	createSlug := createCmd.String("slug", "", "Optional custom URL slug (default: module UUID)") // NEW FLAG
 
+	// Flags for clone command
+	cloneID := cloneCmd.String("id", "", "ID of the module to clone (required)")
+	cloneName := cloneCmd.String("name", "", "Name of the new module (default: \"<name> (copy)\")")
+	cloneSlug := cloneCmd.String("slug", "", "URL slug of the new module (default: its UUID)")
+
+	// Flags for set-status command
+	setStatusID := setStatusCmd.String("id", "", "ID of the module (required)")
+	setStatusValue := setStatusCmd.String("status", "", "New status: published, disabled or draft (required)")
+
 	// Flags for delete command
 	deleteID := deleteCmd.String("id", "", "ID(s) of the module(s) to delete (comma-separated)")
 	deleteForce := deleteCmd.Bool("force", false, "Force delete files and metadata immediately (optional)")
@@ -75,6 +149,14 @@ func main() {
 	// Flags for preview command
 	previewID := previewCmd.String("id", "", "ID of the module to preview (required)")
 
+	// Flags for export command
+	exportOut := exportCmd.String("out", "dist", "Directory to write the static site to")
+	exportClean := exportCmd.Bool("clean", false, "Replace the output directory, removing files left from earlier exports")
+
+	// Flags for export-module command (the module ID may also be given before the flags)
+	exportModuleID := exportModuleCmd.String("id", "", "ID of the module to export (required)")
+	exportModuleOut := exportModuleCmd.String("o", "", "Archive file to write (default <slug>.zip)")
+
 	// Flags for add-template command
 	addTemplateName := addTemplateCmd.String("name", "", "Filename for the new template (e.g., card.html) (required)")
 	addTemplateModuleID := addTemplateCmd.String("moduleId", "", "ID of the module to add the template to (required)")
@@ -84,9 +166,64 @@ func main() {
 	updateName := updateCmd.String("name", "", "New name for the module (optional)") // Make optional
 	updateSlug := updateCmd.String("slug", "", "New URL slug for the module (optional)")
 	updateGroup := updateCmd.String("group", "", "New group for the module (optional)")
-	updateLayout := updateCmd.String("layout", "", "New layout file override (optional)")
+	updateLayout := updateCmd.String("layout", "", "Layout from web/templates/layouts (e.g. landing.html), or \"default\" for layout.html (optional)")
 	updateDesc := updateCmd.String("desc", "", "New description for the module (optional)")
-	// Note: IsActive and Assets might need different handling (e.g., separate commands or flags)
+	updatePublishAt := updateCmd.String("publish-at", "", "Time the published module goes live, e.g. \"2025-06-01 09:00\" or RFC 3339, or \"none\" to clear (optional)")
+	updateUnpublishAt := updateCmd.String("unpublish-at", "", "Time the module stops being served, or \"none\" to clear (optional)")
+	// Note: Status is changed with set-status; Assets might need different handling (e.g., separate commands or flags)
+
+	// Flags for create-page command
+	createPageName := createPageCmd.String("name", "", "Name of the page to create (required)")
+	createPageSlug := createPageCmd.String("slug", "", "URL slug the page is served under (required)")
+	createPageLayout := createPageCmd.String("layout", "", "File name of a layout in web/templates/layouts to render the page in (optional)")
+
+	// Flags for page-add-module command
+	pageAddPageID := pageAddModuleCmd.String("page", "", "ID of the page to add the module to (required)")
+	pageAddModuleID := pageAddModuleCmd.String("module", "", "ID of the module to place on the page (required)")
+	pageAddOrder := pageAddModuleCmd.Int("order", -1, "Render order of the instance (default: after existing instances)")
+	pageAddConfig := pageAddModuleCmd.String("config", "", "Instance configuration as a JSON object (optional)")
+
+	// Flags for migrate-store command
+	migrateFrom := migrateStoreCmd.String("from", "", "Source store as <backend>[:<path>], e.g. json:.module_metadata (required)")
+	migrateTo := migrateStoreCmd.String("to", "", "Destination store as <backend>[:<path>], e.g. sqlite:.module_metadata/modules.db (required)")
+	migrateDryRun := migrateStoreCmd.Bool("dry-run", false, "Report what would be copied without writing to the destination")
+	migrateOverwrite := migrateStoreCmd.Bool("overwrite", false, "Replace modules that already exist in the destination")
+
+	// Flags for history command
+	historyID := historyCmd.String("id", "", "ID of the module whose history to show (required)")
+	historyDiff := historyCmd.String("diff", "", "Snapshot ID to compare against the current version (optional)")
+
+	// Flags for restore command
+	restoreID := restoreCmd.String("id", "", "ID of the module to restore (required)")
+	restoreSnapshot := restoreCmd.String("snapshot", "", "ID of the snapshot to restore (omit to restore a soft-deleted module)")
+
+	// Flags for set-data command
+	setDataID := setDataCmd.String("id", "", "ID of the module (required)")
+	setDataFile := setDataCmd.String("file", "", "JSON or YAML data file, relative to the module directory")
+	setDataURL := setDataCmd.String("url", "", "Local HTTP endpoint returning JSON (localhost or loopback only)")
+	setDataClear := setDataCmd.Bool("clear", false, "Remove the module's data source")
+
+	// Flags for user command (the action, add/remove/passwd/role/list, comes first)
+	userName := userCmd.String("username", "", "Username of the admin account (required for add, remove, passwd and role)")
+	userRole := userCmd.String("role", "", "Role of the account: viewer, editor, publisher or admin (add defaults to viewer; required for role)")
+	userGroups := userCmd.String("groups", "", "Comma-separated module groups the account is limited to ('none' to lift the limit)")
+
+	// Flags for audit command
+	auditID := auditCmd.String("id", "", "Only show changes to this module (optional)")
+	auditUser := auditCmd.String("user", "", "Only show changes made by this user, e.g. alice or cli:bob (optional)")
+	auditSince := auditCmd.String("since", "", "Only show changes at or after this time, e.g. \"2025-06-01 09:00\" or RFC 3339 (optional)")
+	auditUntil := auditCmd.String("until", "", "Only show changes before this time (optional)")
+	auditLimit := auditCmd.Int("limit", 50, "Most entries to show, newest first (0 for all)")
+
+	// Flags for toggle-template command
+	toggleTemplateModuleID := toggleTemplateCmd.String("moduleId", "", "ID of the module (required)")
+	toggleTemplateName := toggleTemplateCmd.String("name", "", "Filename of the template (required)")
+	toggleTemplateEnable := toggleTemplateCmd.Bool("enable", false, "Parse and render the template")
+	toggleTemplateDisable := toggleTemplateCmd.Bool("disable", false, "Keep the template file but skip it when rendering")
+
+	// Flags for reorder-templates command
+	reorderTemplatesModuleID := reorderTemplatesCmd.String("moduleId", "", "ID of the module (required)")
+	reorderTemplatesOrder := reorderTemplatesCmd.String("order", "", "Comma-separated filenames of all the module's templates, in render order (required)")
 
 	if len(os.Args) < 2 {
 		printUsage()
@@ -111,6 +248,30 @@ func main() {
 			log.Fatalf("Error creating module via manager: %v", err)
 		}
 		// Success message is now handled within the manager method's logging
+	case "clone":
+		cloneCmd.Parse(os.Args[2:])
+		if *cloneID == "" {
+			fmt.Println("Error: -id flag is required for clone command")
+			cloneCmd.Usage()
+			return
+		}
+		clone, err := manager.CloneModule(*cloneID, *cloneName, *cloneSlug)
+		if err != nil {
+			log.Fatalf("Error cloning module via manager: %v", err)
+		}
+		fmt.Printf("Cloned module %s into %s (ID: %s, slug: %s)\n", *cloneID, clone.Name, clone.ID, clone.Slug)
+	case "set-status":
+		setStatusCmd.Parse(os.Args[2:])
+		if *setStatusID == "" || *setStatusValue == "" {
+			fmt.Println("Error: -id and -status flags are required for set-status command")
+			setStatusCmd.Usage()
+			return
+		}
+		module, err := manager.SetModuleStatus(*setStatusID, model.ModuleStatus(*setStatusValue))
+		if err != nil {
+			log.Fatalf("Error changing module status via manager: %v", err)
+		}
+		fmt.Printf("Module %s (ID: %s) is now %s.\n", module.Name, module.ID, module.Status)
 	case "delete":
 		deleteCmd.Parse(os.Args[2:])
 		// Check flags *after* parsing
@@ -129,7 +290,39 @@ func main() {
 			previewCmd.Usage()
 			return
 		}
-		handlePreviewModule(templateEngine, *previewID)
+		handlePreviewModule(newTemplateEngine(store, projectRoot, moduleStorageDir, cliLogger), *previewID)
+	case "export":
+		exportCmd.Parse(os.Args[2:])
+		// The export must stay clear of everything the project keeps its state in
+		protected := []string{
+			moduleStorageDir, filepath.Join(projectRoot, "modules_removed"),
+			storagePath, filepath.Join(projectRoot, pageMetadataDir), auditPath,
+			storage.ResolvePath(projectRoot, storageBackend, viper.GetString("storage.usersDir"), viper.GetString("storage.sqlitePath")),
+			filepath.Join(projectRoot, "web"), filepath.Join(projectRoot, "cmd"), filepath.Join(projectRoot, "internal"),
+			filepath.Join(projectRoot, "pkg"), filepath.Join(projectRoot, ".git"),
+			filepath.Join(projectRoot, "config.yaml"), filepath.Join(projectRoot, "go.mod"),
+		}
+		handleExport(store, pageStore, newTemplateEngine(store, projectRoot, moduleStorageDir, cliLogger), projectRoot, moduleStorageDir, *exportOut, *exportClean, protected, cliLogger)
+	case "export-module":
+		args := os.Args[2:]
+		if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+			*exportModuleID, args = args[0], args[1:]
+		}
+		exportModuleCmd.Parse(args)
+		if *exportModuleID == "" {
+			fmt.Println("Error: a module ID is required for export-module command")
+			exportModuleCmd.Usage()
+			return
+		}
+		handleExportModule(manager, *exportModuleID, *exportModuleOut)
+	case "import-module":
+		importModuleCmd.Parse(os.Args[2:])
+		if importModuleCmd.NArg() != 1 {
+			fmt.Println("Error: import-module takes the archive file to import")
+			importModuleCmd.Usage()
+			return
+		}
+		handleImportModule(manager, importModuleCmd.Arg(0))
 	case "add-template":
 		addTemplateCmd.Parse(os.Args[2:])
 		if *addTemplateName == "" || *addTemplateModuleID == "" {
@@ -158,8 +351,9 @@ func main() {
 			return
 		}
 		// Check if at least one update flag was provided
-		if *updateName == "" && *updateSlug == "" && *updateGroup == "" && *updateLayout == "" && *updateDesc == "" {
-			fmt.Println("Error: At least one update flag (-name, -slug, -group, -layout, -desc) must be provided")
+		scheduleUpdate := *updatePublishAt != "" || *updateUnpublishAt != ""
+		if *updateName == "" && *updateSlug == "" && *updateGroup == "" && *updateLayout == "" && *updateDesc == "" && !scheduleUpdate {
+			fmt.Println("Error: At least one update flag (-name, -slug, -group, -layout, -desc, -publish-at, -unpublish-at) must be provided")
 			updateCmd.Usage()
 			return
 		}
@@ -169,8 +363,164 @@ func main() {
 		if err != nil {
 			log.Fatalf("Error updating module via manager: %v", err)
 		}
+		if scheduleUpdate {
+			handleUpdateSchedule(manager, *updateID, *updatePublishAt, *updateUnpublishAt)
+		}
 		// Success message is handled by manager logging
 
+	case "create-page":
+		createPageCmd.Parse(os.Args[2:])
+		if *createPageName == "" || *createPageSlug == "" {
+			fmt.Println("Error: -name and -slug flags are required for create-page command")
+			createPageCmd.Usage()
+			return
+		}
+		handleCreatePage(pageStore, manager.LayoutsDir(), *createPageName, *createPageSlug, *createPageLayout)
+	case "page-add-module":
+		pageAddModuleCmd.Parse(os.Args[2:])
+		if *pageAddPageID == "" || *pageAddModuleID == "" {
+			fmt.Println("Error: -page and -module flags are required for page-add-module command")
+			pageAddModuleCmd.Usage()
+			return
+		}
+		handlePageAddModule(pageStore, store, *pageAddPageID, *pageAddModuleID, *pageAddOrder, *pageAddConfig)
+	case "migrate-store":
+		migrateStoreCmd.Parse(os.Args[2:])
+		if *migrateFrom == "" || *migrateTo == "" {
+			fmt.Println("Error: -from and -to flags are required for migrate-store command")
+			migrateStoreCmd.Usage()
+			return
+		}
+		handleMigrateStore(projectRoot, *migrateFrom, *migrateTo, *migrateDryRun, *migrateOverwrite, cliLogger)
+	case "history":
+		historyCmd.Parse(os.Args[2:])
+		if *historyID == "" {
+			fmt.Println("Error: -id flag is required for history command")
+			historyCmd.Usage()
+			return
+		}
+		handleHistory(manager, *historyID, *historyDiff)
+	case "restore":
+		restoreCmd.Parse(os.Args[2:])
+		if *restoreID == "" {
+			fmt.Println("Error: -id flag is required for restore command")
+			restoreCmd.Usage()
+			return
+		}
+		if *restoreSnapshot == "" {
+			// Without a snapshot, bring back a soft-deleted module
+			module, err := manager.RestoreModule(*restoreID)
+			if err != nil {
+				log.Fatalf("Error restoring module via manager: %v", err)
+			}
+			fmt.Printf("Module %s (ID: %s) restored to %s as disabled; publish it with set-status to serve it again.\n", module.Name, module.ID, module.Directory)
+			break
+		}
+		if !askForConfirmation(fmt.Sprintf("Restore module %s to snapshot %s?", *restoreID, *restoreSnapshot)) {
+			fmt.Println("Restore cancelled.")
+			return
+		}
+		if err := manager.RestoreSnapshot(*restoreID, *restoreSnapshot); err != nil {
+			log.Fatalf("Error restoring snapshot via manager: %v", err)
+		}
+		fmt.Printf("Module %s restored to snapshot %s (previous version kept in history).\n", *restoreID, *restoreSnapshot)
+
+	case "set-data":
+		setDataCmd.Parse(os.Args[2:])
+		if *setDataID == "" {
+			fmt.Println("Error: -id flag is required for set-data command")
+			setDataCmd.Usage()
+			return
+		}
+		if *setDataClear != (*setDataFile == "" && *setDataURL == "") {
+			fmt.Println("Error: provide -file or -url, or -clear to remove the data source")
+			setDataCmd.Usage()
+			return
+		}
+		var src *model.DataSource
+		if !*setDataClear {
+			src = &model.DataSource{File: *setDataFile, URL: *setDataURL}
+		}
+		if err := manager.SetDataSource(*setDataID, src); err != nil {
+			log.Fatalf("Error setting data source via manager: %v", err)
+		}
+		if src == nil {
+			fmt.Printf("Data source removed from module %s.\n", *setDataID)
+		} else {
+			fmt.Printf("Data source set for module %s; templates receive it as .Data.\n", *setDataID)
+		}
+
+	case "toggle-template":
+		toggleTemplateCmd.Parse(os.Args[2:])
+		if *toggleTemplateModuleID == "" || *toggleTemplateName == "" {
+			fmt.Println("Error: -moduleId and -name flags are required for toggle-template command")
+			toggleTemplateCmd.Usage()
+			return
+		}
+		if *toggleTemplateEnable == *toggleTemplateDisable {
+			fmt.Println("Error: provide exactly one of -enable or -disable")
+			toggleTemplateCmd.Usage()
+			return
+		}
+		module, err := manager.SetTemplateActive(*toggleTemplateModuleID, *toggleTemplateName, *toggleTemplateEnable)
+		if err != nil {
+			log.Fatalf("Error setting template state via manager: %v", err)
+		}
+		printTemplates(module)
+
+	case "reorder-templates":
+		reorderTemplatesCmd.Parse(os.Args[2:])
+		if *reorderTemplatesModuleID == "" || *reorderTemplatesOrder == "" {
+			fmt.Println("Error: -moduleId and -order flags are required for reorder-templates command")
+			reorderTemplatesCmd.Usage()
+			return
+		}
+		var names []string
+		for _, name := range strings.Split(*reorderTemplatesOrder, ",") {
+			names = append(names, strings.TrimSpace(name))
+		}
+		module, err := manager.ReorderTemplates(*reorderTemplatesModuleID, names)
+		if err != nil {
+			log.Fatalf("Error reordering templates via manager: %v", err)
+		}
+		printTemplates(module)
+
+	case "generate-handlers":
+		generateHandlersCmd.Parse(os.Args[2:])
+		handlers, err := manager.RegenerateHandlerRegistry()
+		if err != nil {
+			log.Fatalf("Error generating handler registry via manager: %v", err)
+		}
+		fmt.Printf("Wrote %s with %d module handler(s):\n", generator.HandlerRegistryFile, len(handlers))
+		for _, h := range handlers {
+			fmt.Printf("- %s (%s)\n", h.ModuleID, h.ImportPath)
+		}
+		fmt.Println("Rebuild the main server to serve the updated handlers.")
+
+	case "user":
+		if len(os.Args) < 3 {
+			fmt.Println("Error: user takes an action: add, remove, passwd, role or list")
+			userCmd.Usage()
+			return
+		}
+		action := os.Args[2]
+		userCmd.Parse(os.Args[3:])
+		if action != "list" && *userName == "" {
+			fmt.Printf("Error: -username flag is required for user %s\n", action)
+			userCmd.Usage()
+			return
+		}
+		usersPath := storage.ResolvePath(projectRoot, storageBackend, viper.GetString("storage.usersDir"), viper.GetString("storage.sqlitePath"))
+		users, err := storage.OpenUsers(storageBackend, usersPath, store)
+		if err != nil {
+			log.Fatalf("Error initializing user storage: %v", err)
+		}
+		handleUser(users, action, *userName, *userRole, *userGroups)
+
+	case "audit":
+		auditCmd.Parse(os.Args[2:])
+		handleAudit(auditLog, *auditID, *auditUser, *auditSince, *auditUntil, *auditLimit)
+
 	default:
 		fmt.Printf("Unknown command: %s\n", os.Args[1])
 		printUsage()
@@ -185,15 +535,47 @@ func printUsage() {
 	fmt.Println("  list          List all known modules")
 	fmt.Println("  create -name <module-name> [-slug <custom-slug>]")
 	fmt.Println("                Create a new module (slug defaults to UUID if not provided)")
-	fmt.Println("  update -id <module-id> [-name <new-name>] [-slug <new-slug>] [-group <group>] [-layout <layout>] [-desc <desc>]")
+	fmt.Println("  clone -id <module-id> [-name <new-name>] [-slug <new-slug>]")
+	fmt.Println("                Create a new module from a copy of an existing one")
+	fmt.Println("  set-status -id <module-id> -status (published | disabled | draft)")
+	fmt.Println("                Publish a module so the server serves it, or take it offline")
+	fmt.Println("  update -id <module-id> [-name <new-name>] [-slug <new-slug>] [-group <group>] [-layout <layout>] [-desc <desc>] [-publish-at <time|none>] [-unpublish-at <time|none>]")
 	fmt.Println("                Update module metadata (provide at least one optional flag)")
 	fmt.Println("  delete -id <module-id,...> [--force] | --nuke-all")
 	fmt.Println("                Delete modules by ID, or use --nuke-all to delete everything")
 	fmt.Println("  preview -id <module-id>")
 	fmt.Println("                Combine and print module templates to console")
+	fmt.Println("  export [-out dist] [-clean]")
+	fmt.Println("                Render all published modules and pages and their assets to a static site")
+	fmt.Println("  export-module <module-id> [-o <file.zip>]")
+	fmt.Println("                Bundle a module's metadata and files into a zip archive")
+	fmt.Println("  import-module <file.zip>")
+	fmt.Println("                Add a module from an archive made by export-module")
 	fmt.Println("  add-template -name <filename> -moduleId <module-id>")
 	fmt.Println("                Add a new template file to a module")
-	fmt.Println("  purge-removed Permanently delete all modules marked as 'removed'")
+	fmt.Println("  toggle-template -moduleId <module-id> -name <filename> (-enable | -disable)")
+	fmt.Println("                Enable or disable a template; disabled templates are not rendered")
+	fmt.Println("  reorder-templates -moduleId <module-id> -order <file1,file2,...>")
+	fmt.Println("                Set the order in which a module's templates are rendered")
+	fmt.Println("  purge-removed Permanently delete all archived (soft-deleted) modules")
+	fmt.Println("  create-page -name <page-name> -slug <slug> [-layout <layout>]")
+	fmt.Println("                Create a new page composed of module instances")
+	fmt.Println("  page-add-module -page <page-id> -module <module-id> [-order <n>] [-config <json>]")
+	fmt.Println("                Place a module instance on a page")
+	fmt.Println("  migrate-store -from <backend>[:<path>] -to <backend>[:<path>] [-dry-run] [-overwrite]")
+	fmt.Println("                Copy all module metadata between storage backends (json, sqlite) and verify it")
+	fmt.Println("  history -id <module-id> [-diff <snapshot-id>]")
+	fmt.Println("                List a module's recorded versions, or diff one against the current version")
+	fmt.Println("  restore -id <module-id> [-snapshot <snapshot-id>]")
+	fmt.Println("                Bring back a soft-deleted module, or restore a module's metadata and template files to a recorded version")
+	fmt.Println("  set-data -id <module-id> (-file <data.json|data.yaml> | -url <http://localhost/...> | -clear)")
+	fmt.Println("                Declare the data passed to a module's templates as .Data")
+	fmt.Println("  generate-handlers")
+	fmt.Println("                Regenerate the registry that compiles module handler.go files into the server")
+	fmt.Println("  user (add | remove | passwd) -username <name> [-role <role>] [-groups <g1,g2>] | user role -username <name> -role <role> [-groups <g1,g2|none>] | user list")
+	fmt.Println("                Manage the accounts that sign in to the admin server; passwords are prompted for")
+	fmt.Println("  audit [-id <module-id>] [-user <name>] [-since <time>] [-until <time>] [-limit <n>]")
+	fmt.Println("                Show who changed which module and when, newest first")
 	// Add more commands as they are implemented
 }
 
@@ -201,6 +583,12 @@ func handleListModules(store storage.DataStore) {
 	fmt.Println("\nListing modules...")
 	// Load all module details instead of just IDs
 	modules, err := store.ReadAll()
+	if corrupt, ok := storage.AsCorruptModules(err); ok {
+		for _, id := range corrupt.IDs() {
+			fmt.Printf("Warning: skipping module %s: %v\n", id, corrupt.Modules[id])
+		}
+		err = nil
+	}
 	if err != nil {
 		log.Fatalf("Error listing modules: %v", err)
 	}
@@ -217,22 +605,31 @@ func handleListModules(store storage.DataStore) {
 				}
 			}
 
-			// Determine status string based on IsActive
-			statusStr := "Inactive"
-			if module.IsActive {
-				statusStr = "Active"
-			}
 			// Print details including the non-base template count and status
 			fmt.Printf("- ID: %s\n  Name: %s\n  Status: %s\n  Path: %s\n  Additional Templates: %d\n\n",
 				module.ID,
 				module.Name,
-				statusStr, // Use the derived status string
+				module.Status,
 				module.Directory,
 				nonBaseTemplateCount)
 		}
 	}
 }
 
+// printTemplates prints a module's templates in render order with their state.
+func printTemplates(module *model.Module) {
+	templates := slices.Clone(module.Templates)
+	slices.SortStableFunc(templates, func(a, b model.Template) int { return a.Order - b.Order })
+	fmt.Printf("Templates of module %s (%s), in render order:\n", module.Name, module.ID)
+	for _, t := range templates {
+		state := "enabled"
+		if !t.IsActive {
+			state = "disabled"
+		}
+		fmt.Printf("  %d. %s (%s)\n", t.Order, t.Name, state)
+	}
+}
+
 // func handleCreateModule(store storage.DataStore, moduleBaseDir, moduleName, customSlug string) {
 // 	// --- This logic is now moved to internal/modulemanager/manager.go ---
 // }
@@ -282,10 +679,24 @@ func handleDeleteModule(manager *modulemanager.ModuleManager, moduleIDs string,
 		removedModulesDir := filepath.Join(manager.GetProjectRoot(), "modules_removed")
 		storagePath := manager.GetStoreBasePath() // Need to add GetStoreBasePath() to manager
 
-		pathsToDelete := []string{moduleBaseDir, removedModulesDir, storagePath}
+		pathsToDelete := []string{moduleBaseDir, removedModulesDir}
 		failedDeletes := 0
 		failedCreates := 0
 
+		// The SQLite backend stores metadata in a single database file rather than a
+		// directory; remove it (and its WAL files) and let the next run recreate the schema.
+		if info, err := os.Stat(storagePath); err == nil && !info.IsDir() {
+			for _, f := range []string{storagePath, storagePath + "-wal", storagePath + "-shm"} {
+				fmt.Printf("Attempting to remove database file: %s\n", f)
+				if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+					log.Printf("Error removing database file %s: %v", f, err)
+					failedDeletes++
+				}
+			}
+		} else {
+			pathsToDelete = append(pathsToDelete, storagePath)
+		}
+
 		for _, p := range pathsToDelete {
 			fmt.Printf("Attempting to remove directory: %s\n", p)
 			if _, err := os.Stat(p); err == nil {
@@ -379,6 +790,24 @@ func handleDeleteModule(manager *modulemanager.ModuleManager, moduleIDs string,
 	fmt.Printf("Total IDs provided: %d\n", len(ids))
 }
 
+// newTemplateEngine creates the engine used by preview. It renders modules the way
+// the main server does, inside the layouts from web/templates.
+func newTemplateEngine(store storage.DataStore, projectRoot, modulesDir string, logger *slog.Logger) *templating.Engine {
+	templatesDir := filepath.Join(projectRoot, "web", "templates")
+	layouts, err := templating.ParseLayouts(templatesDir)
+	if err != nil {
+		log.Fatalf("Error parsing layout templates: %v", err)
+	}
+	renderer, err := templating.NewRenderer(modulesDir, layouts, logger)
+	if err != nil {
+		log.Fatalf("Error preparing template renderer: %v", err)
+	}
+	if err := renderer.LoadNamedLayouts(filepath.Join(templatesDir, templating.LayoutsDir)); err != nil {
+		log.Fatalf("Error parsing named layouts: %v", err)
+	}
+	return templating.NewEngine(store, renderer, dataprovider.NewResolver(projectRoot, nil))
+}
+
 func handlePreviewModule(engine *templating.Engine, moduleID string) {
 	fmt.Printf("\nGenerating preview for module ID: %s\n", moduleID)
 
@@ -441,22 +870,26 @@ func handlePurgeRemovedModules(manager *modulemanager.ModuleManager) {
 	// We need to know if there *are* any modules to purge first.
 	// Let's peek using the store via the manager.
 	modules, err := manager.GetStore().ReadAll()
+	if corrupt, ok := storage.AsCorruptModules(err); ok {
+		fmt.Printf("Warning: %d module(s) with unreadable metadata will not be purged: %s\n", len(corrupt.Modules), strings.Join(corrupt.IDs(), ", "))
+		err = nil
+	}
 	if err != nil {
 		log.Fatalf("Error reading module metadata before purge confirmation: %v", err)
 	}
 	removedCount := 0
 	for _, mod := range modules {
-		if !mod.IsActive {
+		if mod.IsArchived() {
 			removedCount++
 		}
 	}
 
 	if removedCount == 0 {
-		fmt.Println("No inactive modules found. Nothing to purge.")
+		fmt.Println("No archived modules found. Nothing to purge.")
 		return
 	}
 
-	fmt.Printf("WARNING: You are about to permanently delete the files and metadata for %d inactive module(s).\n", removedCount)
+	fmt.Printf("WARNING: You are about to permanently delete the files and metadata for %d archived module(s).\n", removedCount)
 	// We could list them here again if desired, but the manager logs details during the actual purge.
 	if !askForConfirmation("Are you sure you want to proceed?") {
 		fmt.Println("Operation cancelled.")
@@ -481,3 +914,493 @@ func handlePurgeRemovedModules(manager *modulemanager.ModuleManager) {
 // func handleUpdateModule(store storage.DataStore, moduleID, newName, newSlug, newGroup, newLayout, newDesc string) {
 // 	// --- This logic is now moved to internal/modulemanager/manager.go ---
 // }
+
+// handleCreatePage creates an empty, active page served under the given slug. layout
+// must be a named layout in layoutsDir; empty or "default" selects layout.html.
+func handleCreatePage(pageStore storage.PageStore, layoutsDir, name, slug, layout string) {
+	if layout == templating.DefaultLayout {
+		layout = ""
+	} else if layout != "" {
+		if err := templating.ValidateLayout(layoutsDir, layout); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	}
+
+	pages, err := pageStore.ReadAllPages()
+	if corrupt, ok := storage.AsCorruptPages(err); ok {
+		for _, id := range corrupt.IDs() {
+			fmt.Printf("Warning: skipping page %s: %v\n", id, corrupt.Pages[id])
+		}
+		err = nil
+	}
+	if err != nil {
+		log.Fatalf("Error reading page metadata: %v", err)
+	}
+	for _, p := range pages {
+		if p.Slug == slug {
+			log.Fatalf("Error: slug '%s' is already used by page '%s' (ID: %s)", slug, p.Name, p.ID)
+		}
+	}
+
+	now := time.Now()
+	page := &model.Page{
+		ID:          uuid.New().String(),
+		Name:        name,
+		Slug:        slug,
+		Layout:      layout,
+		Modules:     make([]model.ModuleInstance, 0),
+		CreatedAt:   now,
+		LastUpdated: now,
+		IsActive:    true,
+	}
+	if err := pageStore.SavePage(page); err != nil {
+		log.Fatalf("Error saving page: %v", err)
+	}
+	fmt.Printf("Created page '%s' (ID: %s) at /%s\n", page.Name, page.ID, page.Slug)
+}
+
+// handlePageAddModule appends a module instance to a page.
+// A negative order places the instance after all existing instances.
+func handlePageAddModule(pageStore storage.PageStore, moduleStore storage.DataStore, pageID, moduleID string, order int, configJSON string) {
+	page, err := pageStore.LoadPage(pageID)
+	if err != nil {
+		log.Fatalf("Error loading page: %v", err)
+	}
+	if _, err := moduleStore.LoadModule(moduleID); err != nil {
+		log.Fatalf("Error loading module to place on page: %v", err)
+	}
+
+	var config map[string]any
+	if configJSON != "" {
+		if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+			log.Fatalf("Error: -config must be a JSON object: %v", err)
+		}
+	}
+
+	if order < 0 {
+		order = 0
+		for _, inst := range page.Modules {
+			if inst.Order >= order {
+				order = inst.Order + 1
+			}
+		}
+	}
+
+	page.Modules = append(page.Modules, model.ModuleInstance{
+		ModuleID: moduleID,
+		Order:    order,
+		Config:   config,
+	})
+	page.LastUpdated = time.Now()
+	if err := pageStore.SavePage(page); err != nil {
+		log.Fatalf("Error saving page: %v", err)
+	}
+	fmt.Printf("Added module %s to page '%s' at order %d\n", moduleID, page.Name, order)
+}
+
+// handleHistory lists a module's snapshots, or prints the differences between
+// one snapshot and the module's current version when diffID is set.
+func handleHistory(manager *modulemanager.ModuleManager, moduleID, diffID string) {
+	if diffID == "" {
+		snapshots, err := manager.ListSnapshots(moduleID)
+		if err != nil {
+			log.Fatalf("Error listing snapshots: %v", err)
+		}
+		if len(snapshots) == 0 {
+			fmt.Println("No snapshots recorded for this module.")
+			return
+		}
+		fmt.Printf("Snapshots for module %s (newest first):\n", moduleID)
+		for _, s := range snapshots {
+			fmt.Printf("- %s  %s  %s\n", s.ID, s.CreatedAt.Local().Format(time.DateTime), s.Reason)
+		}
+		return
+	}
+
+	diff, err := manager.DiffSnapshot(moduleID, diffID)
+	if err != nil {
+		log.Fatalf("Error comparing snapshot: %v", err)
+	}
+	if len(diff.Fields) == 0 && len(diff.Files) == 0 {
+		fmt.Println("Snapshot is identical to the current version.")
+		return
+	}
+	fmt.Printf("Changes from snapshot %s to the current version:\n", diffID)
+	for _, f := range diff.Fields {
+		fmt.Printf("  %s: %q -> %q\n", f.Field, f.Snapshot, f.Current)
+	}
+	for _, f := range diff.Files {
+		fmt.Printf("\n--- %s (%s)\n", f.Name, f.Status)
+		for _, line := range f.Lines {
+			fmt.Println(line)
+		}
+	}
+}
+
+// parseStoreSpec splits a "<backend>[:<path>]" store spec. A missing path falls back to
+// the configured location for that backend; relative paths are resolved against projectRoot.
+func parseStoreSpec(projectRoot, spec string) (backend, path string) {
+	backend, path, _ = strings.Cut(spec, ":")
+	if path == "" {
+		return backend, storage.ResolvePath(projectRoot, backend, metadataDir, viper.GetString("storage.sqlitePath"))
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(projectRoot, path)
+	}
+	return backend, path
+}
+
+// handleExport renders the site into outDir (relative to the project root) as static
+// HTML. Any template error aborts the export. outDir must stay clear of the protected
+// paths, and with clean it is only replaced once the new export has been written.
+func handleExport(store storage.DataStore, pageStore storage.PageStore, engine *templating.Engine, projectRoot, modulesDir, outDir string, clean bool, protected []string, logger *slog.Logger) {
+	if !filepath.IsAbs(outDir) {
+		outDir = filepath.Join(projectRoot, outDir)
+	}
+	outDir = filepath.Clean(outDir)
+	if rel, err := filepath.Rel(outDir, projectRoot); err == nil && !strings.HasPrefix(rel, "..") {
+		log.Fatalf("Error: output directory %s must not contain the project", outDir)
+	}
+
+	if err := export.CheckOutDir(outDir, protected); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	fmt.Printf("\nExporting site to %s\n", outDir)
+	result, err := export.Site(store, engine, export.Options{
+		OutDir:     outDir,
+		StaticDir:  filepath.Join(projectRoot, "web", "static"),
+		ModulesDir: modulesDir,
+		Pages:      pageStore,
+		Clean:      clean,
+		Protected:  protected,
+		Logger:     logger,
+	})
+	if err != nil {
+		log.Fatalf("Error exporting site: %v", err)
+	}
+	for _, page := range result.Pages {
+		fmt.Printf("  %s\n", page)
+	}
+	fmt.Printf("Exported %d pages and %d assets.\n", len(result.Pages), result.Assets)
+}
+
+// handleUpdateSchedule changes the module's publish and unpublish times. An empty
+// value keeps the current time and "none" removes it.
+func handleUpdateSchedule(manager *modulemanager.ModuleManager, moduleID, publishAt, unpublishAt string) {
+	module, err := manager.GetStore().LoadModule(moduleID)
+	if err != nil {
+		log.Fatalf("Error loading module %s: %v", moduleID, err)
+	}
+	scheduleTime := func(flagName, value string, current *time.Time) *time.Time {
+		switch value {
+		case "":
+			return current
+		case "none":
+			return nil
+		}
+		t, err := modulemanager.ParseScheduleTime(value)
+		if err != nil {
+			log.Fatalf("Error in -%s: %v", flagName, err)
+		}
+		return t
+	}
+	start := scheduleTime("publish-at", publishAt, module.PublishAt)
+	end := scheduleTime("unpublish-at", unpublishAt, module.UnpublishAt)
+	if _, err := manager.SetModuleSchedule(moduleID, start, end); err != nil {
+		log.Fatalf("Error updating module schedule via manager: %v", err)
+	}
+}
+
+// handleUser adds, removes or lists admin accounts, or changes an account's password or role.
+// roleName and groups are used by add and role; empty groups keep the account's groups
+// and "none" removes them.
+func handleUser(users storage.UserStore, action, username, roleName, groups string) {
+	switch action {
+	case "add":
+		if roleName == "" {
+			roleName = string(model.RoleViewer)
+		}
+		role, err := model.ParseRole(roleName)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		if _, err := users.LoadUser(username); err == nil {
+			log.Fatalf("Error: user %s already exists; use user passwd to change the password", username)
+		} else if !errors.Is(err, storage.ErrUserNotFound) {
+			log.Fatalf("Error loading user %s: %v", username, err)
+		}
+		user, err := auth.NewUser(username, readNewPassword())
+		if err != nil {
+			log.Fatalf("Error creating user: %v", err)
+		}
+		user.Role = role
+		user.Groups = parseUserGroups(groups)
+		if err := users.SaveUser(user); err != nil {
+			log.Fatalf("Error saving user: %v", err)
+		}
+		fmt.Printf("User %s added as %s.\n", username, describeUserRole(user))
+	case "passwd":
+		user, err := users.LoadUser(username)
+		if err != nil {
+			log.Fatalf("Error loading user %s: %v", username, err)
+		}
+		if err := auth.SetPassword(user, readNewPassword()); err != nil {
+			log.Fatalf("Error changing password: %v", err)
+		}
+		if err := users.SaveUser(user); err != nil {
+			log.Fatalf("Error saving user: %v", err)
+		}
+		fmt.Printf("Password of %s changed; the user's admin sessions have ended.\n", username)
+	case "role":
+		if roleName == "" {
+			log.Fatal("Error: -role flag is required for user role")
+		}
+		role, err := model.ParseRole(roleName)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		user, err := users.LoadUser(username)
+		if err != nil {
+			log.Fatalf("Error loading user %s: %v", username, err)
+		}
+		user.Role = role
+		if groups != "" {
+			user.Groups = parseUserGroups(groups)
+		}
+		if err := users.SaveUser(user); err != nil {
+			log.Fatalf("Error saving user: %v", err)
+		}
+		fmt.Printf("User %s is now %s.\n", username, describeUserRole(user))
+	case "remove":
+		if err := users.DeleteUser(username); err != nil {
+			log.Fatalf("Error removing user %s: %v", username, err)
+		}
+		fmt.Printf("User %s removed.\n", username)
+	case "list":
+		list, err := users.ReadAllUsers()
+		if err != nil {
+			log.Fatalf("Error listing users: %v", err)
+		}
+		if len(list) == 0 {
+			fmt.Println("No users found. Add one with: builder-cli user add -username <name> -role admin")
+			return
+		}
+		for _, user := range list {
+			fmt.Printf("- %s: %s (created %s)\n", user.Username, describeUserRole(user), user.CreatedAt.Format("2006-01-02 15:04"))
+		}
+	default:
+		log.Fatalf("Unknown user action %q (expected add, remove, passwd, role or list)", action)
+	}
+}
+
+// parseUserGroups splits the -groups flag; "none" or an empty flag means no groups.
+func parseUserGroups(groups string) []string {
+	if groups == "" || groups == "none" {
+		return nil
+	}
+	var list []string
+	for _, group := range strings.Split(groups, ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			list = append(list, group)
+		}
+	}
+	return list
+}
+
+// describeUserRole formats the account's role and, if it has any, its groups.
+func describeUserRole(user *model.User) string {
+	if len(user.Groups) == 0 {
+		return string(user.EffectiveRole())
+	}
+	return fmt.Sprintf("%s of groups %s", user.EffectiveRole(), strings.Join(user.Groups, ", "))
+}
+
+// readNewPassword prompts for a password twice without echoing it. When standard input
+// is not a terminal (e.g. a script), the first line of input is the password.
+func readNewPassword() string {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			log.Fatalf("Error reading password from standard input: %v", err)
+		}
+		return strings.TrimRight(line, "\r\n")
+	}
+	fmt.Print("Password: ")
+	password, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	if err != nil {
+		log.Fatalf("Error reading password: %v", err)
+	}
+	fmt.Print("Repeat password: ")
+	repeated, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	if err != nil {
+		log.Fatalf("Error reading password: %v", err)
+	}
+	if string(password) != string(repeated) {
+		log.Fatal("Error: the passwords do not match")
+	}
+	return string(password)
+}
+
+// handleExportModule writes the module's archive to outPath, by default <slug>.zip
+// in the current directory. A failed export leaves no file behind.
+func handleExportModule(manager *modulemanager.ModuleManager, moduleID, outPath string) {
+	if outPath == "" {
+		module, err := manager.GetStore().LoadModule(moduleID)
+		if err != nil {
+			log.Fatalf("Error loading module %s: %v", moduleID, err)
+		}
+		outPath = module.Slug + ".zip"
+	}
+
+	f, err := os.Create(outPath)
+	if err != nil {
+		log.Fatalf("Error creating archive file: %v", err)
+	}
+	if err := manager.ExportModule(moduleID, f); err != nil {
+		f.Close()
+		os.Remove(outPath)
+		log.Fatalf("Error exporting module via manager: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(outPath)
+		log.Fatalf("Error writing archive file: %v", err)
+	}
+	fmt.Printf("Module %s exported to %s\n", moduleID, outPath)
+}
+
+// handleImportModule adds the module in the archive at archivePath.
+func handleImportModule(manager *modulemanager.ModuleManager, archivePath string) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		log.Fatalf("Error opening archive: %v", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		log.Fatalf("Error reading archive: %v", err)
+	}
+
+	result, err := manager.ImportModule(f, info.Size())
+	if err != nil {
+		log.Fatalf("Error importing module via manager: %v", err)
+	}
+	module := result.Module
+	fmt.Printf("Imported module %s (ID: %s, slug: %s)\n", module.Name, module.ID, module.Slug)
+	if module.ID != result.OriginalID {
+		fmt.Printf("  ID %s was already in use; the module was given a new ID.\n", result.OriginalID)
+	}
+	if module.Slug != result.OriginalSlug {
+		fmt.Printf("  Slug %q was already in use; the module is served at /%s.\n", result.OriginalSlug, module.Slug)
+	}
+	if module.Layout != result.OriginalLayout {
+		fmt.Printf("  Layout %q does not exist here; the module uses the default layout.\n", result.OriginalLayout)
+	}
+	for _, name := range result.DroppedFiles {
+		fmt.Printf("  Left out %s: Go files are not imported. Add module handlers to the project by hand.\n", name)
+	}
+}
+
+// handleMigrateStore copies all module metadata from one storage backend to another.
+func handleMigrateStore(projectRoot, fromSpec, toSpec string, dryRun, overwrite bool, logger *slog.Logger) {
+	fromBackend, fromPath := parseStoreSpec(projectRoot, fromSpec)
+	toBackend, toPath := parseStoreSpec(projectRoot, toSpec)
+	if fromBackend == toBackend && fromPath == toPath {
+		log.Fatalf("Error: source and destination are the same store (%s:%s)", fromBackend, fromPath)
+	}
+
+	fmt.Printf("Migrating module metadata from %s:%s to %s:%s\n", fromBackend, fromPath, toBackend, toPath)
+	if dryRun {
+		fmt.Println("Dry run: nothing will be written.")
+	}
+
+	if _, err := os.Stat(fromPath); err != nil {
+		log.Fatalf("Error: source store %s not found: %v", fromPath, err)
+	}
+	src, err := storage.Open(fromBackend, fromPath, logger)
+	if err != nil {
+		log.Fatalf("Error opening source store: %v", err)
+	}
+
+	// Opening a store creates it, so a dry run only opens a destination that already exists.
+	var dst storage.DataStore
+	if _, statErr := os.Stat(toPath); !dryRun || statErr == nil {
+		dst, err = storage.Open(toBackend, toPath, logger)
+		if err != nil {
+			log.Fatalf("Error opening destination store: %v", err)
+		}
+	}
+
+	report, err := storage.Migrate(src, dst, storage.MigrateOptions{DryRun: dryRun, Overwrite: overwrite})
+	if report != nil {
+		fmt.Printf("Modules in source: %d\n", report.Total)
+		for _, id := range report.Copied {
+			action := "copy"
+			if slices.Contains(report.Overwritten, id) {
+				action = "overwrite"
+			}
+			fmt.Printf("  - %s: %s\n", action, id)
+		}
+	}
+	if err != nil {
+		log.Fatalf("Error migrating store: %v", err)
+	}
+
+	if dryRun {
+		fmt.Printf("Dry run complete: %d module(s) would be copied (%d overwritten).\n", len(report.Copied), len(report.Overwritten))
+		return
+	}
+	fmt.Printf("Migration complete: %d module(s) copied and verified.\n", report.Verified)
+}
+
+// cliActor names the user running the CLI in the audit log, so CLI changes can be told
+// apart from those made in the admin server.
+func cliActor() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return "cli:" + u.Username
+	}
+	return "cli"
+}
+
+// handleAudit prints the audit log entries matching the filters, newest first. Empty
+// filters match every entry.
+func handleAudit(auditLog storage.AuditLog, moduleID, actor, since, until string, limit int) {
+	filter := storage.AuditFilter{ModuleID: moduleID, Actor: actor, Limit: limit}
+	if t, err := modulemanager.ParseScheduleTime(since); err != nil {
+		log.Fatalf("Error in -since: %v", err)
+	} else if t != nil {
+		filter.Since = *t
+	}
+	if t, err := modulemanager.ParseScheduleTime(until); err != nil {
+		log.Fatalf("Error in -until: %v", err)
+	} else if t != nil {
+		filter.Until = *t
+	}
+
+	entries, err := auditLog.QueryAudit(filter)
+	if corrupt, ok := storage.AsCorruptAudit(err); ok {
+		for _, line := range corrupt.LineNumbers() {
+			fmt.Printf("Warning: skipping line %d of the audit log: %v\n", line, corrupt.Lines[line])
+		}
+		err = nil
+	}
+	if err != nil {
+		log.Fatalf("Error reading audit log: %v", err)
+	}
+	if len(entries) == 0 {
+		fmt.Println("No audit entries found.")
+		return
+	}
+	fmt.Println("Audit log (newest first):")
+	for _, e := range entries {
+		fmt.Printf("- %s  %s  %s  %s\n", e.Time.Local().Format(time.DateTime), e.Actor, e.Action, e.ModuleID)
+		if e.Before != "" {
+			fmt.Printf("    before: %s\n", e.Before)
+		}
+		if e.After != "" {
+			fmt.Printf("    after:  %s\n", e.After)
+		}
+	}
+}
@@ -10,8 +10,8 @@ import (
 	"flag"
 	"fmt"
 	"html/template"
-	"log"      // Keep standard log for initial setup/fatal errors
-	"log/slog" // Import slog
+	"log" // Only for errors before the logger is configured
+	"log/slog"
 	"math/big"
 	"net"
 	"net/http"
@@ -19,8 +19,12 @@ import (
 	"path/filepath" // Keep for app struct initialization
 	"time"
 
+	"go-module-builder/internal/dataprovider"
+	"go-module-builder/internal/logging"
 	"go-module-builder/internal/model"
 	"go-module-builder/internal/storage"
+	"go-module-builder/internal/templating"
+	"go-module-builder/pkg/fsutils"
 
 	"github.com/spf13/viper"
 )
@@ -41,12 +45,18 @@ func main() {
 	viper.SetDefault("server.port", "8443")
 	viper.SetDefault("server.certFile", "cert.pem")
 	viper.SetDefault("server.keyFile", "key.pem")
+	viper.SetDefault("server.hotReload", true)
+	viper.SetDefault("storage.backend", storage.BackendJSON)
+	viper.SetDefault("storage.sqlitePath", filepath.Join(".module_metadata", storage.DefaultSQLiteFile))
+	viper.SetDefault("logging.level", logging.DefaultLevel)
+	viper.SetDefault("logging.format", logging.DefaultFormat)
 
 	// Read the config file
+	configMissing := false
 	if err := viper.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-			// Config file not found; ignore error if desired
-			log.Println("Warning: config.yaml not found, using defaults/flags/env vars.")
+			// Config file not found; reported once the logger is configured
+			configMissing = true
 		} else {
 			// Config file was found but another error was produced
 			log.Fatalf("Fatal error reading config file: %v", err)
@@ -59,6 +69,7 @@ func main() {
 	certFileFlag := flag.String("cert-file", viper.GetString("server.certFile"), "Path to TLS certificate file")
 	keyFileFlag := flag.String("key-file", viper.GetString("server.keyFile"), "Path to TLS key file")
 	toggleModuleList := flag.Bool("toggle-module-list", false, "Toggle the /modules/list page (default: disabled)")
+	hotReloadFlag := flag.Bool("hot-reload", viper.GetBool("server.hotReload"), "Watch modules and metadata and reload changed module templates without a restart")
 	flag.Parse()
 
 	// Bind flags to Viper AFTER parsing, so flags take precedence
@@ -69,150 +80,162 @@ func main() {
 	finalCertFile := *certFileFlag
 	finalKeyFile := *keyFileFlag
 
+	// --- Initialize Logger ---
+	logger, err := logging.New(os.Stdout, viper.GetString("logging.level"), viper.GetString("logging.format"))
+	if err != nil {
+		log.Fatalf("Error configuring logging: %v", err)
+	}
+	fsutils.SetLogger(logger)
+	slog.SetDefault(logger) // Also routes the standard log package, used by libraries, through it
+	if configMissing {
+		logger.Warn("config.yaml not found, using defaults, flags and environment variables")
+	}
+
 	// Log module list page status
 	if *toggleModuleList {
-		log.Println("Module list page enabled at /modules/list")
+		logger.Info("Module list page enabled", "path", "/modules/list")
 	} else {
-		log.Println("Module list page is disabled. Use -toggle-module-list to enable it.")
+		logger.Info("Module list page is disabled. Use -toggle-module-list to enable it.")
 	}
 
 	// Get working directory
 	wd, err := os.Getwd()
 	if err != nil {
-		log.Fatalf("Error getting working directory: %v", err)
+		logger.Error("Failed to get working directory", "error", err)
+		os.Exit(1)
 	}
 	projRoot := wd
 
 	metadataDir := filepath.Join(projRoot, ".module_metadata")
+	pageMetadataDir := filepath.Join(projRoot, ".page_metadata")
 	templatesDir := filepath.Join(projRoot, "web", "templates")
 	modulesDir := filepath.Join(projRoot, "modules")
-	log.Printf("Loading module metadata from: %s", metadataDir)
-	log.Printf("Loading layout templates from: %s", templatesDir)
-	log.Printf("Using modules directory: %s", modulesDir)
+	logger.Info("Using project directories", "module_metadata", metadataDir, "page_metadata", pageMetadataDir, "layouts", templatesDir, "modules", modulesDir)
 
 	// --- Module Discovery ---
 	var modules []*model.Module
-	store, err := storage.NewJSONStore(metadataDir)
+	var store storage.DataStore
+	storageBackend := viper.GetString("storage.backend")
+	storagePath := storage.ResolvePath(projRoot, storageBackend, metadataDir, viper.GetString("storage.sqlitePath"))
+	logger.Info("Opening module storage", "backend", storageBackend, "path", storagePath)
+	openedStore, err := storage.Open(storageBackend, storagePath, logger)
 	if err != nil {
 		if os.IsNotExist(err) {
-			log.Printf("Metadata directory not found at %s. No modules loaded.", metadataDir)
+			logger.Warn("Metadata directory not found. No modules loaded.", "path", metadataDir)
 			modules = make([]*model.Module, 0)
 		} else {
-			log.Fatalf("Error initializing storage: %v", err)
+			logger.Error("Failed to initialize storage", "error", err)
+			os.Exit(1)
 		}
 	} else {
+		store = openedStore
 		modules, err = store.ReadAll()
-		if err != nil {
-			log.Printf("Warning: Error reading module metadata: %v", err)
+		if corrupt, ok := storage.AsCorruptModules(err); ok {
+			// Serve the modules that loaded; only the corrupt ones are unavailable
+			logger.Warn("Skipping modules with unreadable metadata", "module_ids", corrupt.IDs(), "error", err)
+		} else if err != nil {
+			logger.Warn("Failed to read module metadata", "error", err)
 			modules = make([]*model.Module, 0)
 		}
 	}
 
-	log.Printf("Discovered %d modules:", len(modules))
+	logger.Info("Discovered modules", "count", len(modules))
 	for _, mod := range modules {
-		statusStr := "Inactive"
-		if mod.IsActive {
-			statusStr = "Active"
-		}
-		log.Printf("  - ID: %s, Name: %s, Status: %s", mod.ID, mod.Name, statusStr)
+		logger.Info("Discovered module", "id", mod.ID, "name", mod.Name, "status", mod.Status)
 	}
 	// --- End Module Discovery ---
 
+	// --- Page Discovery ---
+	var pages []*model.Page
+	pageStore, err := storage.NewJSONPageStore(pageMetadataDir)
+	if err != nil {
+		logger.Error("Failed to initialize page storage", "error", err)
+		os.Exit(1)
+	}
+	pages, err = pageStore.ReadAllPages()
+	if corrupt, ok := storage.AsCorruptPages(err); ok {
+		// Serve the pages that loaded; only the corrupt ones are unavailable
+		logger.Warn("Skipping pages with unreadable metadata", "page_ids", corrupt.IDs(), "error", err)
+	} else if err != nil {
+		logger.Warn("Failed to read page metadata", "error", err)
+		pages = make([]*model.Page, 0)
+	}
+	logger.Info("Discovered pages", "count", len(pages))
+	for _, page := range pages {
+		logger.Info("Discovered page", "id", page.ID, "name", page.Name, "slug", page.Slug, "modules", len(page.Modules))
+	}
+	// --- End Page Discovery ---
+
 	// --- Template Parsing ---
-	log.Println("Parsing templates...")
 	modTemplates := make(map[string]*template.Template)
 
 	// 1. Parse base/layout templates first
-	layoutPattern := filepath.Join(templatesDir, "*.html")
-	layoutFiles, err := filepath.Glob(layoutPattern)
-	if err != nil || len(layoutFiles) == 0 {
-		log.Fatalf("Error finding or no layout templates found matching %s: %v", layoutPattern, err)
+	logger.Info("Parsing base layout templates", "path", templatesDir)
+	baseTmpl, err := templating.ParseLayouts(templatesDir)
+	if err != nil {
+		logger.Error("Failed to parse base layout templates", "error", err)
+		os.Exit(1)
 	}
-	log.Printf("Parsing base layout templates: %v", layoutFiles)
-	baseTmpl, err := template.ParseFiles(layoutFiles...)
+	renderer, err := templating.NewRenderer(modulesDir, baseTmpl, logger)
 	if err != nil {
-		log.Fatalf("Error parsing base layout templates: %v", err)
+		logger.Error("Failed to prepare template renderer", "error", err)
+		os.Exit(1)
+	}
+	// Modules can select one of these instead of layout.html
+	if err := renderer.LoadNamedLayouts(filepath.Join(templatesDir, templating.LayoutsDir)); err != nil {
+		logger.Error("Failed to parse named layouts", "error", err)
+		os.Exit(1)
 	}
 
-	// 2. For each active module, clone base templates and parse module templates into the clone
+	// 2. For each published module, parse its templates into a copy of the layouts
 	for _, mod := range modules {
-		if mod.IsActive {
-			moduleTemplatesDir := filepath.Join(modulesDir, mod.ID, "templates")
-			htmlPattern := filepath.Join(moduleTemplatesDir, "*.[th][mt][lm]l")
-			cssPattern := filepath.Join(moduleTemplatesDir, "*.css")
-			htmlFiles, errHtml := filepath.Glob(htmlPattern)
-			cssFiles, errCss := filepath.Glob(cssPattern)
-
-			if errHtml != nil {
-				log.Printf("Warning: Error finding html/tmpl templates for module %s (%s): %v", mod.Name, mod.ID, errHtml)
-			}
-			if errCss != nil {
-				log.Printf("Warning: Error finding css templates for module %s (%s): %v", mod.Name, mod.ID, errCss)
-			}
-
-			moduleFiles := append(htmlFiles, cssFiles...)
-
-			if len(moduleFiles) > 0 {
-				log.Printf("Preparing templates for module %s from files: %v", mod.ID, moduleFiles)
-
-				moduleSet := template.New(mod.ID)
-				moduleSet, err := moduleSet.ParseFiles(moduleFiles...)
-				if err != nil {
-					log.Printf("ERROR: Failed to parse module templates for %s: %v", mod.ID, err)
-					continue
-				}
-
-				clonedTemplates, err := baseTmpl.Clone()
-				if err != nil {
-					log.Printf("ERROR: Failed to clone base templates for module %s: %v", mod.ID, err)
-					continue
-				}
-
-				for _, tmpl := range moduleSet.Templates() {
-					if tmpl.Name() == mod.ID {
-						continue
-					}
-					addTmpl, err := clonedTemplates.AddParseTree(tmpl.Name(), tmpl.Tree)
-					if err != nil {
-						log.Printf("ERROR: Failed to add template '%s' from module %s to cloned set: %v", tmpl.Name(), mod.ID, err)
-						continue
-					}
-					clonedTemplates = addTmpl
-				}
-
-				clonedTemplates, err = clonedTemplates.ParseFiles(moduleFiles...)
-				if err != nil {
-					log.Printf("CRITICAL: Failed to parse templates for module %s (%s). The specific error was: %v", mod.Name, mod.ID, err)
-					log.Printf("CRITICAL: Module %s will NOT be available.", mod.ID)
-					continue
-				}
-
-				modTemplates[mod.ID] = clonedTemplates
-				log.Printf("Successfully prepared templates for module %s", mod.ID)
-
-			} else {
-				log.Printf("No template files (.html, .tmpl, .css) found for active module %s in %s", mod.ID, moduleTemplatesDir)
+		if mod.IsPublished() {
+			clonedTemplates, err := renderer.ParseModule(mod, nil)
+			if err != nil {
+				logger.Error("Failed to prepare templates; the module will NOT be available", "name", mod.Name, "id", mod.ID, "error", err)
+				continue
 			}
+			modTemplates[mod.ID] = clonedTemplates
+			logger.Debug("Prepared templates for module", "id", mod.ID)
 		}
 	}
-	log.Println("Finished template preparation.")
+	logger.Info("Finished template preparation", "modules", len(modTemplates))
 	// --- End Template Parsing ---
 
-	// --- Initialize Logger ---
-	logLevel := new(slog.LevelVar)                                                            // Create a variable to hold the level
-	logLevel.Set(slog.LevelDebug)                                                             // Set the level to DEBUG
-	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: logLevel})) // Pass options
-
 	// --- Initialize Application Struct ---
 	app := &application{ // application struct is defined in routes.go
 		logger:              logger, // Pass logger
 		projectRoot:         projRoot,
 		isModuleListEnabled: *toggleModuleList,
+		modulesDir:          modulesDir,
+		metadataDir:         metadataDir,
+		storageFile:         sqliteFile(storageBackend, storagePath),
+		pageMetadataDir:     pageMetadataDir,
+		moduleStore:         store,
+		pageStore:           pageStore,
 		loadedModules:       modules,
+		loadedPages:         pages,
 		baseTemplates:       baseTmpl,
+		renderer:            renderer,
 		moduleTemplates:     modTemplates,
+		moduleHandlers:      make(map[string]http.Handler, len(moduleHandlers)),
 		// Mutex is zero-value ready
 	}
+	for id, handler := range moduleHandlers {
+		app.moduleHandlers[id] = handler
+	}
+	app.dataResolver = dataprovider.NewResolver(projRoot, app.dataProviders(moduleDataProviders))
+	logger.Info("Registered module handlers", "count", len(app.moduleHandlers), "data_providers", len(moduleDataProviders))
+
+	// --- Hot Reload ---
+	if *hotReloadFlag && store != nil {
+		stopWatcher, err := app.startTemplateWatcher()
+		if err != nil {
+			logger.Warn("Hot reload disabled", "error", err)
+		} else {
+			defer stopWatcher()
+		}
+	}
 
 	// --- Create Router ---
 	router := app.routes() // routes method is defined in routes.go
@@ -233,32 +256,31 @@ func main() {
 	_, keyErr := os.Stat(keyPath)
 
 	if os.IsNotExist(certErr) || os.IsNotExist(keyErr) {
-		log.Println("Certificate or key file not found.")
-		err = generateSelfSignedCert(certPath, keyPath) // Use local function
+		logger.Info("Certificate or key file not found")
+		err = generateSelfSignedCert(certPath, keyPath, logger) // Use local function
 		if err != nil {
-			log.Fatalf("Failed to generate self-signed certificate/key: %v", err)
+			logger.Error("Failed to generate self-signed certificate and key", "error", err)
+			os.Exit(1)
 		}
 	} else if certErr != nil || keyErr != nil {
-		log.Fatalf("Error checking certificate/key files: certErr=%v, keyErr=%v", certErr, keyErr)
+		logger.Error("Failed to check certificate and key files", "cert_error", certErr, "key_error", keyErr)
+		os.Exit(1)
 	} else {
-		log.Printf("Using existing certificate and key files: %s, %s", certPath, keyPath)
+		logger.Info("Using existing certificate and key files", "cert", certPath, "key", keyPath)
 	}
 
 	// --- Start Server ---
-	log.Println("--------------------------------------------------------------------")
-	log.Println("WARNING: Starting server with a self-signed certificate.")
-	log.Println("Your browser will likely show security warnings (e.g., NET::ERR_CERT_AUTHORITY_INVALID).")
-	log.Println("This is expected. You may need to click 'Advanced' and 'Proceed' to access the site.")
-	log.Println("For production use, configure a proper reverse proxy (like Caddy) with valid certificates.")
-	log.Println("--------------------------------------------------------------------")
+	logger.Warn("Starting server with a self-signed certificate. Browsers will show security warnings " +
+		"(e.g., NET::ERR_CERT_AUTHORITY_INVALID); this is expected, click 'Advanced' and 'Proceed' to access the site. " +
+		"For production use, configure a proper reverse proxy (like Caddy) with valid certificates.")
 
-	addr := ":" + finalPort // Use final port value
-	fmt.Printf("Starting HTTPS server on https://localhost%s\n", addr)
-	log.Printf("Listening on port %s...", finalPort) // Use final port value
+	addr := ":" + finalPort                     // Use final port value
+	logger.Info("Listening", "port", finalPort) // Use final port value
 
 	err = http.ListenAndServeTLS(addr, certPath, keyPath, router) // Pass the router
 	if err != nil {
-		log.Fatalf("Server failed to start: %v", err)
+		logger.Error("Server failed to start", "error", err)
+		os.Exit(1)
 	}
 }
 
@@ -269,8 +291,8 @@ func main() {
 
 // generateSelfSignedCert creates a self-signed certificate and key file.
 // This function remains here as it doesn't depend on application state.
-func generateSelfSignedCert(certPath, keyPath string) error {
-	log.Printf("Generating self-signed certificate and key: %s, %s", certPath, keyPath)
+func generateSelfSignedCert(certPath, keyPath string, logger *slog.Logger) error {
+	logger.Info("Generating self-signed certificate and key", "cert", certPath, "key", keyPath)
 
 	priv, err := rsa.GenerateKey(rand.Reader, 2048)
 	if err != nil {
@@ -319,7 +341,7 @@ func generateSelfSignedCert(certPath, keyPath string) error {
 	// if err := certOut.Close(); err != nil { // No need to close explicitly due to defer
 	// 	return fmt.Errorf("failed to close %s: %w", certPath, err)
 	// }
-	log.Printf("Successfully generated %s", certPath)
+	logger.Info("Generated certificate", "path", certPath)
 
 	keyOut, err := os.OpenFile(keyPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
@@ -338,7 +360,15 @@ func generateSelfSignedCert(certPath, keyPath string) error {
 	// if err := keyOut.Close(); err != nil { // No need to close explicitly due to defer
 	// 	return fmt.Errorf("failed to close %s: %w", keyPath, err)
 	// }
-	log.Printf("Successfully generated %s", keyPath)
+	logger.Info("Generated key", "path", keyPath)
 
 	return nil
 }
+
+// sqliteFile returns the database file for the SQLite backend, or "" for other backends.
+func sqliteFile(backend, path string) string {
+	if backend == storage.BackendSQLite {
+		return path
+	}
+	return ""
+}
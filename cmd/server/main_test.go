@@ -1,7 +1,9 @@
 package main
 
 import (
+	"go-module-builder/internal/dataprovider"
 	"go-module-builder/internal/model"
+	"go-module-builder/internal/templating"
 	"html/template"
 	"io"       // For io.Discard
 	"log/slog" // For slog
@@ -36,14 +38,21 @@ func newTestApplication(t *testing.T) *application {
 		t.Fatalf("Failed to parse base layout template for test setup: %v", err)
 	}
 
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	renderer, err := templating.NewRenderer(filepath.Join(projRoot, "modules"), baseTmpl, logger)
+	if err != nil {
+		t.Fatalf("Failed to create renderer for test setup: %v", err)
+	}
+
 	return &application{
 		projectRoot:          projRoot,
 		isModuleListEnabled:  false, // Default, override in specific tests if needed
 		loadedModules:        make([]*model.Module, 0),
 		baseTemplates:        baseTmpl,
+		renderer:             renderer,
 		moduleTemplates:      make(map[string]*template.Template),
-		moduleTemplatesMutex: sync.RWMutex{},                                 // Initialize mutex
-		logger:               slog.New(slog.NewTextHandler(io.Discard, nil)), // Add discard logger for tests
+		moduleTemplatesMutex: sync.RWMutex{}, // Initialize mutex
+		logger:               logger,         // Add discard logger for tests
 	}
 }
 
@@ -164,14 +173,14 @@ func TestHandleModulePageRequest(t *testing.T) {
 		ID:          "test-module-123",
 		Name:        "Test Module",
 		Slug:        "test-module-slug", // Add slug for testing
-		IsActive:    true,
+		Status:      model.StatusPublished,
 		CreatedAt:   time.Now(),
 		LastUpdated: time.Now(),
 		Templates: []model.Template{
 			// Mock template definitions needed for rendering logic
-			{Name: "base.html", Path: "templates/base.html", IsBase: true, Order: 0},
-			{Name: "content.html", Path: "templates/content.html", IsBase: false, Order: 1},
-			{Name: "widget.tmpl", Path: "templates/widget.tmpl", IsBase: false, Order: 2},
+			{Name: "base.html", Path: "templates/base.html", IsBase: true, Order: 0, IsActive: true},
+			{Name: "content.html", Path: "templates/content.html", IsBase: false, Order: 1, IsActive: true},
+			{Name: "widget.tmpl", Path: "templates/widget.tmpl", IsBase: false, Order: 2, IsActive: true},
 		},
 	}
 
@@ -339,14 +348,14 @@ func TestHandleModuleListRequest(t *testing.T) {
 
 	// Create test modules
 	activeModule := &model.Module{
-		ID:       "active-test-module",
-		Name:     "Active Module",
-		IsActive: true,
+		ID:     "active-test-module",
+		Name:   "Active Module",
+		Status: model.StatusPublished,
 	}
 	removedModule := &model.Module{
-		ID:       "removed-test-module",
-		Name:     "Removed Module",
-		IsActive: false,
+		ID:     "removed-test-module",
+		Name:   "Removed Module",
+		Status: model.StatusArchived,
 	}
 
 	// Set loadedModules on the app instance
@@ -387,3 +396,253 @@ func TestHandleModuleListRequest(t *testing.T) {
 		t.Errorf("Module list response should not contain removed module name")
 	}
 }
+
+func TestHandleModulePageRequest_Schedule(t *testing.T) {
+	app := newTestApplication(t)
+	app.isModuleListEnabled = true
+	publishAt := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	unpublishAt := publishAt.Add(7 * 24 * time.Hour)
+	campaign := &model.Module{
+		ID:          "campaign-module",
+		Name:        "Summer Campaign",
+		Slug:        "summer",
+		Status:      model.StatusPublished,
+		PublishAt:   &publishAt,
+		UnpublishAt: &unpublishAt,
+	}
+	app.loadedModules = []*model.Module{campaign}
+	set, err := app.baseTemplates.Clone()
+	if err != nil {
+		t.Fatalf("Failed to clone base templates: %v", err)
+	}
+	app.moduleTemplates[campaign.ID] = template.Must(set.Parse(`{{ define "page" }}Campaign{{ end }}`))
+	router := app.routes()
+
+	for _, tc := range []struct {
+		name     string
+		now      time.Time
+		wantCode int
+	}{
+		{"before publishAt", publishAt.Add(-time.Minute), http.StatusForbidden},
+		{"at publishAt", publishAt, http.StatusOK},
+		{"during the campaign", publishAt.Add(48 * time.Hour), http.StatusOK},
+		{"at unpublishAt", unpublishAt, http.StatusForbidden},
+	} {
+		app.now = func() time.Time { return tc.now }
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", "/summer", nil))
+		if rr.Code != tc.wantCode {
+			t.Errorf("%s: GET /summer returned %d, want %d", tc.name, rr.Code, tc.wantCode)
+		}
+
+		rrList := httptest.NewRecorder()
+		router.ServeHTTP(rrList, httptest.NewRequest("GET", "/modules/list", nil))
+		if listed := strings.Contains(rrList.Body.String(), "Summer Campaign"); listed != (tc.wantCode == http.StatusOK) {
+			t.Errorf("%s: module list contains the module = %v, want %v", tc.name, listed, !listed)
+		}
+	}
+}
+
+func TestHandleModulePageRequest_OldSlugRedirect(t *testing.T) {
+	app := newTestApplication(t)
+	app.loadedModules = []*model.Module{
+		{ID: "about-module", Name: "About", Slug: "about-us", OldSlugs: []string{"about", "team"}, Status: model.StatusPublished},
+		{ID: "archived-module", Name: "Old", Slug: "old", OldSlugs: []string{"legacy"}, Status: model.StatusArchived},
+	}
+	router := app.routes()
+
+	for _, tc := range []struct {
+		method, target string
+		wantCode       int
+		wantLocation   string
+	}{
+		{"GET", "/about", http.StatusMovedPermanently, "/about-us"},
+		{"GET", "/team?tab=1", http.StatusMovedPermanently, "/about-us?tab=1"},
+		{"GET", "/about/contact", http.StatusMovedPermanently, "/about-us/contact"},
+		{"POST", "/about/contact", http.StatusPermanentRedirect, "/about-us/contact"},
+		{"GET", "/legacy", http.StatusNotFound, ""}, // Archived modules are not redirected to
+	} {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(tc.method, tc.target, nil))
+		if rr.Code != tc.wantCode || rr.Header().Get("Location") != tc.wantLocation {
+			t.Errorf("%s %s = %d to %q, want %d to %q", tc.method, tc.target, rr.Code, rr.Header().Get("Location"), tc.wantCode, tc.wantLocation)
+		}
+	}
+}
+
+func TestHandleModulePageRequest_ArchivedSlugTaken(t *testing.T) {
+	app := newTestApplication(t)
+	set, err := app.baseTemplates.Clone()
+	if err != nil {
+		t.Fatalf("Failed to clone base templates: %v", err)
+	}
+	// The archived module comes first, as it would if it was created first
+	app.loadedModules = []*model.Module{
+		{ID: "old-news", Name: "Old News", Slug: "news", Status: model.StatusArchived},
+		{ID: "new-news", Name: "News", Slug: "news", Status: model.StatusPublished},
+	}
+	app.moduleTemplates["new-news"] = template.Must(set.Parse(`{{ define "page" }}Current news{{ end }}`))
+	app.moduleHandlers = map[string]http.Handler{
+		"old-news": http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "old handler") }),
+		"new-news": http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "new handler") }),
+	}
+	router := app.routes()
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/news", nil))
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "Current news") {
+		t.Errorf("GET /news = %d %q, want the live module", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("POST", "/news/subscribe", nil))
+	if rr.Code != http.StatusOK || rr.Body.String() != "new handler" {
+		t.Errorf("POST /news/subscribe = %d %q, want the live module's handler", rr.Code, rr.Body.String())
+	}
+}
+
+func TestHandlePageRequest(t *testing.T) {
+	// --- Setup ---
+	app := newTestApplication(t)
+
+	hero := &model.Module{
+		ID:     "hero-module",
+		Name:   "Hero",
+		Slug:   "hero",
+		Status: model.StatusPublished,
+		Templates: []model.Template{
+			{Name: "base.html", Path: "templates/base.html", IsBase: true, Order: 0, IsActive: true},
+			{Name: "content.html", Path: "templates/content.html", IsBase: false, Order: 1, IsActive: true},
+		},
+	}
+	card := &model.Module{
+		ID:     "card-module",
+		Name:   "Card",
+		Slug:   "card",
+		Status: model.StatusPublished,
+		Templates: []model.Template{
+			{Name: "base.html", Path: "templates/base.html", IsBase: true, Order: 0, IsActive: true},
+			{Name: "content.html", Path: "templates/content.html", IsBase: false, Order: 1, IsActive: true},
+		},
+	}
+	app.loadedModules = []*model.Module{hero, card}
+
+	// Each module gets its own set, like the server builds at startup
+	for _, mod := range app.loadedModules {
+		clonedTemplates, err := app.baseTemplates.Clone()
+		if err != nil {
+			t.Fatalf("Failed to clone base templates: %v", err)
+		}
+		_, err = clonedTemplates.Parse(`{{define "page"}}<div class="` + mod.Slug + `">{{ .RenderedContent }}</div>{{end}}`)
+		if err != nil {
+			t.Fatalf("Failed to parse mock page template: %v", err)
+		}
+		_, err = clonedTemplates.Parse(`{{define "content"}}<p>{{ .Name }}: {{ index .Config "title" }}</p>{{end}}`)
+		if err != nil {
+			t.Fatalf("Failed to parse mock content template: %v", err)
+		}
+		app.moduleTemplates[mod.ID] = clonedTemplates
+	}
+
+	// Card is listed first but ordered after hero
+	app.loadedPages = []*model.Page{{
+		ID:       "home-page",
+		Name:     "Home",
+		Slug:     "home",
+		IsActive: true,
+		Modules: []model.ModuleInstance{
+			{ModuleID: "card-module", Order: 2, Config: map[string]any{"title": "Card Title"}},
+			{ModuleID: "hero-module", Order: 1, Config: map[string]any{"title": "Hero Title"}},
+			{ModuleID: "missing-module", Order: 3},
+		},
+	}}
+
+	router := app.routes()
+
+	// --- Test Standard Page Request ---
+	req := httptest.NewRequest("GET", "/home", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("Page handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	bodyStr := rr.Body.String()
+	heroIdx := strings.Index(bodyStr, "Hero: Hero Title")
+	cardIdx := strings.Index(bodyStr, "Card: Card Title")
+	if heroIdx == -1 || cardIdx == -1 {
+		t.Fatalf("Page response missing rendered module instances with config. Got: %s", bodyStr)
+	}
+	if heroIdx > cardIdx {
+		t.Errorf("Page response rendered modules out of order (hero at %d, card at %d)", heroIdx, cardIdx)
+	}
+	if !strings.Contains(bodyStr, "Page: Home") {
+		t.Errorf("Page response header missing page name")
+	}
+	if !strings.Contains(bodyStr, "<html") {
+		t.Errorf("Page response should be rendered inside the layout")
+	}
+
+	// --- Test HTMX Page Request ---
+	reqHtmx := httptest.NewRequest("GET", "/home", nil)
+	reqHtmx.Header.Add("HX-Request", "true")
+	rrHtmx := httptest.NewRecorder()
+	router.ServeHTTP(rrHtmx, reqHtmx)
+
+	htmxBodyStr := rrHtmx.Body.String()
+	if strings.Contains(htmxBodyStr, "<html") {
+		t.Errorf("HTMX page response shouldn't contain full HTML structure")
+	}
+	if !strings.Contains(htmxBodyStr, `hx-swap-oob="innerHTML">Page: Home`) || !strings.Contains(htmxBodyStr, "Hero: Hero Title") {
+		t.Errorf("HTMX page response missing OOB header or content. Got: %s", htmxBodyStr)
+	}
+
+	// --- Test Inactive Page ---
+	app.loadedPages[0].IsActive = false
+	rrInactive := httptest.NewRecorder()
+	router.ServeHTTP(rrInactive, httptest.NewRequest("GET", "/home", nil))
+	if status := rrInactive.Code; status != http.StatusForbidden {
+		t.Errorf("Inactive page returned wrong status code: got %v want %v", status, http.StatusForbidden)
+	}
+}
+
+func TestHandleModulePageRequest_ModuleData(t *testing.T) {
+	app := newTestApplication(t)
+	moduleDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(moduleDir, "data.json"), []byte(`{"items": ["Alpha", "Beta"]}`), 0644); err != nil {
+		t.Fatalf("Failed to write data file: %v", err)
+	}
+	testModule := &model.Module{
+		ID:         "data-module",
+		Name:       "Data Module",
+		Slug:       "data-module",
+		Directory:  moduleDir,
+		Status:     model.StatusPublished,
+		DataSource: &model.DataSource{File: "data.json"},
+		Templates: []model.Template{
+			{Name: "base.html", Path: "templates/base.html", IsBase: true, Order: 0, IsActive: true},
+			{Name: "list.html", Path: "templates/list.html", Order: 1, IsActive: true},
+		},
+	}
+	app.loadedModules = []*model.Module{testModule}
+	app.dataResolver = dataprovider.NewResolver(app.projectRoot, nil)
+
+	tmplSet, err := app.baseTemplates.Clone()
+	if err != nil {
+		t.Fatalf("Failed to clone base templates: %v", err)
+	}
+	template.Must(tmplSet.Parse(`{{define "list"}}<ul>{{range .Data.items}}<li>{{.}}</li>{{end}}</ul><p>{{ .Name }}</p>{{end}}`))
+	app.moduleTemplates[testModule.ID] = tmplSet
+
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, httptest.NewRequest("GET", "/data-module", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Module page returned status %d, want %d", rr.Code, http.StatusOK)
+	}
+	body := rr.Body.String()
+	if !strings.Contains(body, "<li>Alpha</li><li>Beta</li>") || !strings.Contains(body, "<p>Data Module</p>") {
+		t.Errorf("Module page did not render the module data. Got: %s", body)
+	}
+}
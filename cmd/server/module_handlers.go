@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+
+	"go-module-builder/internal/dataprovider"
+	"go-module-builder/internal/model"
+	"go-module-builder/pkg/modulehttp"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// handleModuleHandlerRequest routes GET /{moduleSlug}/* and POST /{moduleSlug}[/*] to the
+// Handle function of the module's handler.go, as compiled in via moduleHandlers
+// (module_handlers_gen.go). The handler sees the path relative to the slug and can get the
+// module, including its parsed templates, from modulehttp.FromRequest.
+func (app *application) handleModuleHandlerRequest(w http.ResponseWriter, r *http.Request) {
+	moduleSlug := chi.URLParam(r, "moduleSlug")
+	loadedModules, _ := app.snapshot()
+
+	targetModule := moduleBySlug(loadedModules, moduleSlug)
+	if targetModule == nil && app.redirectOldSlug(w, r, loadedModules, moduleSlug) {
+		return
+	}
+	if targetModule == nil || !app.isLive(targetModule) {
+		app.logger.Debug("No published module for handler request", "slug", moduleSlug, "method", r.Method, "uri", r.RequestURI)
+		app.notFound(w)
+		return
+	}
+
+	handler, ok := app.moduleHandlers[targetModule.ID]
+	if !ok {
+		app.logger.Debug("Module has no compiled handler", "module_id", targetModule.ID, "slug", moduleSlug)
+		app.notFound(w)
+		return
+	}
+
+	req := r.WithContext(modulehttp.NewContext(r.Context(), app.moduleHTTP(targetModule)))
+	u := *r.URL
+	u.Path = "/" + chi.URLParam(r, "*")
+	u.RawPath = ""
+	req.URL = &u
+
+	app.logger.Debug("Dispatching to module handler", "module_id", targetModule.ID, "method", r.Method, "path", u.Path)
+	handler.ServeHTTP(w, req)
+}
+
+// moduleHTTP describes a loaded module, with its current template set, for module handler code.
+func (app *application) moduleHTTP(mod *model.Module) *modulehttp.Module {
+	app.moduleTemplatesMutex.RLock()
+	templates := app.moduleTemplates[mod.ID]
+	app.moduleTemplatesMutex.RUnlock()
+	return modulehttp.NewModule(mod.ID, mod.Slug, mod.Name, templates)
+}
+
+// dataProviders adapts the Data functions compiled in from module handler.go files
+// (moduleDataProviders) to dataprovider.Provider. The request passed to a Data
+// function carries the module, as for Handle.
+func (app *application) dataProviders(funcs map[string]modulehttp.DataFunc) map[string]dataprovider.Provider {
+	providers := make(map[string]dataprovider.Provider, len(funcs))
+	for id, fn := range funcs {
+		providers[id] = dataprovider.ProviderFunc(func(r *http.Request, module *model.Module) (any, error) {
+			return fn(r.WithContext(modulehttp.NewContext(r.Context(), app.moduleHTTP(module))))
+		})
+	}
+	return providers
+}
+
+// moduleData loads the data passed to the module's templates as .Data. A failing
+// provider is logged and yields nil, so the module still renders without its data.
+func (app *application) moduleData(r *http.Request, mod *model.Module) any {
+	if app.dataResolver == nil {
+		return nil
+	}
+	data, err := app.dataResolver.Load(r, mod)
+	if err != nil {
+		app.logger.Error("Failed to load module data", "module_id", mod.ID, "module_slug", mod.Slug, "error", err)
+		return nil
+	}
+	return data
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"go-module-builder/internal/model"
+	"go-module-builder/pkg/modulehttp"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandleModuleHandlerRequest(t *testing.T) {
+	app := newTestApplication(t)
+	app.loadedModules = []*model.Module{
+		{ID: "mod-1", Name: "Contact", Slug: "contact", Status: model.StatusPublished},
+		{ID: "mod-2", Name: "Archived", Slug: "archived", Status: model.StatusArchived},
+		{ID: "mod-3", Name: "Static", Slug: "static-only", Status: model.StatusPublished},
+	}
+	tmpl := template.Must(template.New("mod-1").Parse(`{{define "content"}}<p>Fragment for {{ .Name }}</p>{{end}}`))
+	app.moduleTemplates["mod-1"] = tmpl
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		mod, ok := modulehttp.FromRequest(r)
+		if !ok {
+			http.Error(w, "no module in context", http.StatusInternalServerError)
+			return
+		}
+		if modulehttp.IsHTMX(r) {
+			if err := mod.Render(w, "content", mod); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+		fmt.Fprintf(w, "%s %s %s", r.Method, mod.ID, r.URL.Path)
+	}
+	app.moduleHandlers = map[string]http.Handler{
+		"mod-1": http.HandlerFunc(handler),
+		"mod-2": http.HandlerFunc(handler),
+	}
+	router := app.routes()
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		htmx       bool
+		wantStatus int
+		wantBody   string
+	}{
+		{"GET sub path", http.MethodGet, "/contact/form", false, http.StatusOK, "GET mod-1 /form"},
+		{"POST slug root", http.MethodPost, "/contact", false, http.StatusOK, "POST mod-1 /"},
+		{"POST nested path", http.MethodPost, "/contact/api/submit", false, http.StatusOK, "POST mod-1 /api/submit"},
+		{"HTMX fragment", http.MethodGet, "/contact/fragment", true, http.StatusOK, "<p>Fragment for Contact</p>"},
+		{"inactive module", http.MethodPost, "/archived", false, http.StatusNotFound, ""},
+		{"module without handler", http.MethodGet, "/static-only/x", false, http.StatusNotFound, ""},
+		{"unknown slug", http.MethodPost, "/missing/x", false, http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			if tt.htmx {
+				req.Header.Set("HX-Request", "true")
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("%s %s returned status %d, want %d (body %q)", tt.method, tt.target, rr.Code, tt.wantStatus, rr.Body.String())
+			}
+			if tt.wantBody != "" && !strings.Contains(rr.Body.String(), tt.wantBody) {
+				t.Errorf("%s %s body = %q, want it to contain %q", tt.method, tt.target, rr.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
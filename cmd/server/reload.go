@@ -0,0 +1,252 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go-module-builder/internal/model"
+	"go-module-builder/internal/storage"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDebounce is how long the watcher waits after the last event for a module
+// before re-parsing it. Editors and the JSON store usually emit several events per save.
+const reloadDebounce = 150 * time.Millisecond
+
+// reloadModule re-reads a single module's metadata and templates and swaps them
+// into the application under moduleTemplatesMutex. If the new templates fail to
+// parse, the last good metadata and template set keep being served.
+func (app *application) reloadModule(moduleID string) {
+	logger := app.logger.With("module_id", moduleID)
+
+	mod, err := app.moduleStore.LoadModule(moduleID)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Error("Hot reload: failed to load module metadata, keeping current version", "error", err)
+		return
+	}
+
+	var newSet *template.Template
+	if mod != nil && mod.IsPublished() {
+		newSet, err = app.renderer.ParseModule(mod, nil)
+		if err != nil {
+			logger.Error("Hot reload: template parse failed, keeping last good template set", "error", err)
+			return
+		}
+		app.watchModuleDir(moduleID)
+	}
+
+	app.moduleTemplatesMutex.Lock()
+	defer app.moduleTemplatesMutex.Unlock()
+
+	// Copy-on-write so handlers holding the previous slice are unaffected.
+	modules := make([]*model.Module, 0, len(app.loadedModules)+1)
+	replaced := false
+	for _, existing := range app.loadedModules {
+		if existing.ID == moduleID {
+			if mod != nil {
+				modules = append(modules, mod)
+			}
+			replaced = true
+			continue
+		}
+		modules = append(modules, existing)
+	}
+	if !replaced && mod != nil {
+		modules = append(modules, mod)
+	}
+	app.loadedModules = modules
+
+	if newSet != nil {
+		app.moduleTemplates[moduleID] = newSet
+		logger.Info("Hot reload: module templates reloaded")
+	} else {
+		delete(app.moduleTemplates, moduleID)
+		logger.Info("Hot reload: module removed or unpublished, templates unloaded")
+	}
+}
+
+// reloadChangedModules re-reads all module metadata and reloads the modules that
+// were added, removed or updated since they were loaded. It is used for the SQLite
+// backend, where a write touches the shared database file rather than a per-module file.
+func (app *application) reloadChangedModules() {
+	stored, err := app.moduleStore.ReadAll()
+	corrupt, partial := storage.AsCorruptModules(err)
+	if err != nil && !partial {
+		app.logger.Error("Hot reload: failed to read module metadata, keeping current modules", "error", err)
+		return
+	}
+	loaded, _ := app.snapshot()
+
+	loadedByID := make(map[string]*model.Module, len(loaded))
+	for _, mod := range loaded {
+		loadedByID[mod.ID] = mod
+	}
+	if partial {
+		// Keep serving the last good version of modules whose metadata is unreadable
+		app.logger.Error("Hot reload: skipping modules with unreadable metadata", "moduleIDs", corrupt.IDs(), "error", err)
+		for _, id := range corrupt.IDs() {
+			delete(loadedByID, id)
+		}
+	}
+	for _, mod := range stored {
+		current, ok := loadedByID[mod.ID]
+		delete(loadedByID, mod.ID)
+		if ok && current.LastUpdated.Equal(mod.LastUpdated) && current.Status == mod.Status {
+			continue
+		}
+		app.reloadModule(mod.ID)
+	}
+	// Whatever is left was deleted from the store
+	for id := range loadedByID {
+		app.reloadModule(id)
+	}
+}
+
+// reloadPages re-reads all page metadata. On failure the current pages are kept.
+func (app *application) reloadPages() {
+	if app.pageStore == nil {
+		return
+	}
+	pages, err := app.pageStore.ReadAllPages()
+	if corrupt, ok := storage.AsCorruptPages(err); ok {
+		app.logger.Warn("Hot reload: skipping pages with unreadable metadata", "page_ids", corrupt.IDs(), "error", err)
+	} else if err != nil {
+		app.logger.Error("Hot reload: failed to read page metadata, keeping current pages", "error", err)
+		return
+	}
+	app.moduleTemplatesMutex.Lock()
+	app.loadedPages = pages
+	app.moduleTemplatesMutex.Unlock()
+	app.logger.Info("Hot reload: pages reloaded", "count", len(pages))
+}
+
+// watchModuleDir adds a module's directory and its templates directory to the watcher.
+// Adding an already watched path is a no-op for fsnotify.
+func (app *application) watchModuleDir(moduleID string) {
+	if app.watcher == nil {
+		return
+	}
+	moduleDir := filepath.Join(app.modulesDir, moduleID)
+	for _, dir := range []string{moduleDir, filepath.Join(moduleDir, "templates")} {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			if err := app.watcher.Add(dir); err != nil {
+				app.logger.Warn("Hot reload: failed to watch directory", "path", dir, "error", err)
+			}
+		}
+	}
+}
+
+// moduleIDForPath maps a changed file to the module it belongs to, returning
+// ok=false for paths that don't affect a module (e.g. temp files).
+func (app *application) moduleIDForPath(path string) (string, bool) {
+	if filepath.Dir(path) == app.metadataDir {
+		name := filepath.Base(path)
+		if !strings.HasSuffix(name, ".json") {
+			return "", false
+		}
+		return strings.TrimSuffix(name, ".json"), true
+	}
+	rel, err := filepath.Rel(app.modulesDir, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", false
+	}
+	return strings.Split(rel, string(filepath.Separator))[0], true
+}
+
+// startTemplateWatcher watches modules/ and the metadata directories and reloads
+// only the module (or the page list) affected by each change.
+// It returns a function that stops the watcher.
+func (app *application) startTemplateWatcher() (func() error, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	app.watcher = watcher
+
+	watchDirs := []string{app.modulesDir, app.metadataDir, app.pageMetadataDir}
+	if app.storageFile != "" {
+		watchDirs = append(watchDirs, filepath.Dir(app.storageFile))
+	}
+	for _, dir := range watchDirs {
+		if dir == "" {
+			continue
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			watcher.Close()
+			return nil, fmt.Errorf("failed to ensure watched directory %s: %w", dir, err)
+		}
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return nil, fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+	}
+	entries, err := os.ReadDir(app.modulesDir)
+	if err == nil {
+		for _, entry := range entries {
+			if entry.IsDir() {
+				app.watchModuleDir(entry.Name())
+			}
+		}
+	}
+
+	var (
+		pendingMu sync.Mutex
+		pending   = make(map[string]*time.Timer)
+	)
+	schedule := func(key string, fn func()) {
+		pendingMu.Lock()
+		defer pendingMu.Unlock()
+		if t, ok := pending[key]; ok {
+			t.Stop()
+		}
+		pending[key] = time.AfterFunc(reloadDebounce, func() {
+			pendingMu.Lock()
+			delete(pending, key)
+			pendingMu.Unlock()
+			fn()
+		})
+	}
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Has(fsnotify.Chmod) && !event.Has(fsnotify.Write) {
+					continue
+				}
+				if app.storageFile != "" && (event.Name == app.storageFile || event.Name == app.storageFile+"-wal") {
+					schedule("modules", app.reloadChangedModules)
+					continue
+				}
+				if filepath.Dir(event.Name) == app.pageMetadataDir {
+					schedule("pages", app.reloadPages)
+					continue
+				}
+				moduleID, ok := app.moduleIDForPath(event.Name)
+				if !ok {
+					continue
+				}
+				app.logger.Debug("Hot reload: change detected", "path", event.Name, "op", event.Op.String(), "module_id", moduleID)
+				schedule("module:"+moduleID, func() { app.reloadModule(moduleID) })
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				app.logger.Error("Hot reload: watcher error", "error", err)
+			}
+		}
+	}()
+
+	app.logger.Info("Hot reload enabled", "modules_dir", app.modulesDir, "metadata_dir", app.metadataDir)
+	return watcher.Close, nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"go-module-builder/internal/model"
+	"go-module-builder/internal/storage"
+	"go-module-builder/internal/templating"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newReloadTestApplication creates an application backed by a temporary
+// modules directory and JSON store, with a single active module "reload-mod".
+func newReloadTestApplication(t *testing.T) (*application, string) {
+	app := newTestApplication(t)
+	tempDir := t.TempDir()
+
+	app.modulesDir = filepath.Join(tempDir, "modules")
+	renderer, err := templating.NewRenderer(app.modulesDir, app.baseTemplates, nil)
+	if err != nil {
+		t.Fatalf("NewRenderer() failed: %v", err)
+	}
+	app.renderer = renderer
+	app.metadataDir = filepath.Join(tempDir, ".module_metadata")
+	store, err := storage.NewJSONStore(app.metadataDir, nil)
+	if err != nil {
+		t.Fatalf("NewJSONStore() failed: %v", err)
+	}
+	app.moduleStore = store
+
+	templatesDir := filepath.Join(app.modulesDir, "reload-mod", "templates")
+	if err := os.MkdirAll(templatesDir, 0755); err != nil {
+		t.Fatalf("Failed to create module templates dir: %v", err)
+	}
+	writeTemplate(t, templatesDir, "content.html", `{{ define "content" }}version one{{ end }}`)
+
+	mod := &model.Module{
+		ID:     "reload-mod",
+		Name:   "Reload Module",
+		Slug:   "reload",
+		Status: model.StatusPublished,
+		Templates: []model.Template{
+			{Name: "content.html", Path: "templates/content.html", Order: 1, IsActive: true},
+		},
+	}
+	if err := store.SaveModule(mod); err != nil {
+		t.Fatalf("SaveModule() failed: %v", err)
+	}
+	return app, templatesDir
+}
+
+func writeTemplate(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write template %s: %v", name, err)
+	}
+}
+
+// renderContent executes the module's "content" template from the currently loaded set.
+func renderContent(t *testing.T, app *application, moduleID string) string {
+	t.Helper()
+	app.moduleTemplatesMutex.RLock()
+	set, ok := app.moduleTemplates[moduleID]
+	app.moduleTemplatesMutex.RUnlock()
+	if !ok {
+		return ""
+	}
+	var buf bytes.Buffer
+	if err := set.ExecuteTemplate(&buf, "content", nil); err != nil {
+		t.Fatalf("Failed to execute content template: %v", err)
+	}
+	return buf.String()
+}
+
+func TestReloadModule(t *testing.T) {
+	app, templatesDir := newReloadTestApplication(t)
+
+	// Initial load
+	app.reloadModule("reload-mod")
+	if got := renderContent(t, app, "reload-mod"); got != "version one" {
+		t.Fatalf("Initial reload rendered %q, want %q", got, "version one")
+	}
+	modules, _ := app.snapshot()
+	if len(modules) != 1 || modules[0].Slug != "reload" {
+		t.Fatalf("Initial reload loaded modules %+v, want the reload module", modules)
+	}
+
+	// Edited template is picked up
+	writeTemplate(t, templatesDir, "content.html", `{{ define "content" }}version two{{ end }}`)
+	app.reloadModule("reload-mod")
+	if got := renderContent(t, app, "reload-mod"); got != "version two" {
+		t.Errorf("Reload after edit rendered %q, want %q", got, "version two")
+	}
+
+	// A broken template keeps the last good set
+	writeTemplate(t, templatesDir, "content.html", `{{ define "content" }}broken{{ end`)
+	app.reloadModule("reload-mod")
+	if got := renderContent(t, app, "reload-mod"); got != "version two" {
+		t.Errorf("Reload after parse error rendered %q, want last good %q", got, "version two")
+	}
+
+	// Deleting the metadata unloads the module
+	if err := app.moduleStore.DeleteModule("reload-mod"); err != nil {
+		t.Fatalf("DeleteModule() failed: %v", err)
+	}
+	app.reloadModule("reload-mod")
+	if got := renderContent(t, app, "reload-mod"); got != "" {
+		t.Errorf("Deleted module still has a template set rendering %q", got)
+	}
+	if modules, _ := app.snapshot(); len(modules) != 0 {
+		t.Errorf("Deleted module still listed in loaded modules: %+v", modules)
+	}
+}
+
+func TestTemplateWatcherReloadsChangedModule(t *testing.T) {
+	app, templatesDir := newReloadTestApplication(t)
+	app.reloadModule("reload-mod")
+
+	stop, err := app.startTemplateWatcher()
+	if err != nil {
+		t.Fatalf("startTemplateWatcher() failed: %v", err)
+	}
+	defer stop()
+
+	writeTemplate(t, templatesDir, "content.html", `{{ define "content" }}watched edit{{ end }}`)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if strings.Contains(renderContent(t, app, "reload-mod"), "watched edit") {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("Watcher did not reload the edited template in time; still rendering %q", renderContent(t, app, "reload-mod"))
+}
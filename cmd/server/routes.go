@@ -3,18 +3,23 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"go-module-builder/internal/dataprovider"
 	"go-module-builder/internal/model"
+	"go-module-builder/internal/storage"
+	"go-module-builder/internal/templating"
 	"html/template"
 	"net/http"
 	"os"
 	"path/filepath"
 	"runtime/debug" // Add debug import
-	"sort"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 
 	"log/slog" // Import slog
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/go-chi/chi/v5" // Import chi
 )
 
@@ -45,28 +50,47 @@ type application struct {
 	// Configuration
 	projectRoot         string
 	isModuleListEnabled bool
+	modulesDir          string // Directory holding module folders (modules/{id}/templates)
+	metadataDir         string // Directory watched for module metadata changes
+	storageFile         string // SQLite database file watched for metadata changes (empty for the JSON backend)
+	pageMetadataDir     string // Directory watched for page metadata changes
 	// Data
-	loadedModules []*model.Module
+	moduleStore   storage.DataStore // Used by hot reload to re-read changed modules
+	pageStore     storage.PageStore // Used by hot reload to re-read pages
+	loadedModules []*model.Module   // Guarded by moduleTemplatesMutex; replaced, never mutated in place
+	loadedPages   []*model.Page     // Guarded by moduleTemplatesMutex; replaced, never mutated in place
 	// Templates
-	baseTemplates        *template.Template
-	moduleTemplates      map[string]*template.Template
+	baseTemplates        *template.Template            // Layouts for pages without a module (root, module list, Pages)
+	renderer             *templating.Renderer          // Parses and renders module template sets
+	moduleTemplates      map[string]*template.Template // Module ID -> template set parsed by renderer
 	moduleTemplatesMutex sync.RWMutex
-	// Potentially add logger, etc. here later
+	watcher              *fsnotify.Watcher // nil when hot reload is disabled
+	// Module handlers compiled in from modules/{id}/handler.go, keyed by module ID
+	moduleHandlers map[string]http.Handler
+	dataResolver   *dataprovider.Resolver // Loads each module's template data (.Data); nil means no data
+	now            func() time.Time       // Clock for module PublishAt/UnpublishAt; nil means time.Now
 }
 
-// PageData holds the data passed to the main layout and page templates
-// for a specific module page
-type PageData struct {
-	Module          *model.Module
-	RenderedContent template.HTML // Pre-rendered HTML of sorted sub-templates
+// isLive reports whether a module is served right now: published, and within its
+// publish schedule by the server's clock.
+func (app *application) isLive(mod *model.Module) bool {
+	now := time.Now
+	if app.now != nil {
+		now = app.now
+	}
+	return mod.IsLive(now())
 }
 
-// LayoutData holds the data passed to the main layout template
-type LayoutData struct {
-	IsModuleListEnabled bool
-	PageContent         any // Can be nil, []*model.Module, or PageData
+// snapshot returns the currently loaded modules and pages.
+// The slices are replaced wholesale on reload, so callers may range over them freely.
+func (app *application) snapshot() ([]*model.Module, []*model.Page) {
+	app.moduleTemplatesMutex.RLock()
+	defer app.moduleTemplatesMutex.RUnlock()
+	return app.loadedModules, app.loadedPages
 }
 
+// Template data types (PageData, ModuleInstanceData, LayoutData) are defined in internal/templating
+
 // --- Router Setup ---
 
 // routes sets up the HTTP router using chi and application methods.
@@ -101,6 +125,12 @@ func (app *application) routes() http.Handler { // Changed return type
 	app.logger.Info("Enabling module page route", "pattern", "/{moduleSlug}") // Use slog
 	r.Get("/{moduleSlug}", app.handleModulePageRequest)                       // Use slug in pattern
 
+	// Dynamic endpoints served by the module's own handler.go
+	app.logger.Info("Enabling module handler routes", "pattern", "/{moduleSlug}/*", "handlers", len(app.moduleHandlers))
+	r.Post("/{moduleSlug}", app.handleModuleHandlerRequest)
+	r.Get("/{moduleSlug}/*", app.handleModuleHandlerRequest)
+	r.Post("/{moduleSlug}/*", app.handleModuleHandlerRequest)
+
 	return r // Return the chi router (which implements http.Handler)
 }
 
@@ -118,7 +148,7 @@ func (app *application) handleRootRequest(w http.ResponseWriter, r *http.Request
 	isHTMX := r.Header.Get("HX-Request") == "true"
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 
-	layoutData := LayoutData{
+	layoutData := templating.LayoutData{
 		IsModuleListEnabled: app.isModuleListEnabled,
 		PageContent:         nil,
 	}
@@ -130,7 +160,7 @@ func (app *application) handleRootRequest(w http.ResponseWriter, r *http.Request
 		if err != nil {
 			app.logger.Error("Error writing OOB header clear for root", "error", err) // Use slog Error
 		}
-		err = app.baseTemplates.ExecuteTemplate(w, "page", layoutData)
+		err = app.baseTemplates.ExecuteTemplate(w, templating.PageTemplate, layoutData)
 		if err != nil {
 			app.logger.Error("Error executing page template for root (HTMX)", "error", err) // Use slog Error
 			if !strings.Contains(err.Error(), "multiple response.WriteHeader calls") {
@@ -139,7 +169,7 @@ func (app *application) handleRootRequest(w http.ResponseWriter, r *http.Request
 		}
 	} else {
 		app.logger.Debug("Standard request for root") // Use Debug level
-		err := app.baseTemplates.ExecuteTemplate(w, "layout.html", layoutData)
+		err := app.baseTemplates.ExecuteTemplate(w, templating.LayoutTemplate, layoutData)
 		if err != nil {
 			app.logger.Error("Error executing layout template for root", "error", err) // Use slog Error
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -154,21 +184,22 @@ func (app *application) handleModuleListRequest(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	loadedModules, _ := app.snapshot()
 	activeModules := make([]*model.Module, 0)
-	for _, mod := range app.loadedModules {
-		if mod.IsActive {
+	for _, mod := range loadedModules {
+		if app.isLive(mod) {
 			activeModules = append(activeModules, mod)
 		}
 	}
 
-	layoutData := LayoutData{
+	layoutData := templating.LayoutData{
 		IsModuleListEnabled: app.isModuleListEnabled,
 		PageContent:         activeModules,
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	app.logger.Info("Rendering module list page") // Use Info level
-	err := app.baseTemplates.ExecuteTemplate(w, "layout.html", layoutData)
+	err := app.baseTemplates.ExecuteTemplate(w, templating.LayoutTemplate, layoutData)
 	if err != nil {
 		app.logger.Error("Error executing layout template for module list", "error", err) // Use slog Error
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -186,28 +217,35 @@ func (app *application) handleModulePageRequest(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	// 2. Find the module by Slug
-	var targetModule *model.Module
-	for _, mod := range app.loadedModules {
-		if mod.Slug == moduleSlug { // Match by Slug field
-			targetModule = mod
-			break
+	loadedModules, loadedPages := app.snapshot()
+
+	// 2. A Page registered under this slug takes precedence over a module page
+	for _, page := range loadedPages {
+		if page.Slug == moduleSlug {
+			app.handlePageRequest(w, r, page)
+			return
 		}
 	}
 
-	// 3. Handle not found or inactive module
+	// 3. Find the module by Slug
+	targetModule := moduleBySlug(loadedModules, moduleSlug)
+
+	// 4. Handle not found or inactive module
 	if targetModule == nil {
+		if app.redirectOldSlug(w, r, loadedModules, moduleSlug) {
+			return
+		}
 		app.logger.Warn("Module not found for slug", "slug", moduleSlug) // Use Warn level with context
 		http.NotFound(w, r)                                              // Treat as 404 if slug doesn't match any loaded module
 		return
 	}
-	if !targetModule.IsActive {
-		app.logger.Warn("Attempted to access inactive module", "slug", moduleSlug, "name", targetModule.Name, "id", targetModule.ID) // Use Warn level with context
+	if !app.isLive(targetModule) {
+		app.logger.Warn("Attempted to access unpublished or unscheduled module", "status", targetModule.Status, "slug", moduleSlug, "name", targetModule.Name, "id", targetModule.ID) // Use Warn level with context
 		http.Error(w, "Module not available", http.StatusForbidden)
 		return
 	}
 
-	// 4. Get the specific template set for this module (using its ID, not slug)
+	// 5. Get the specific template set for this module (using its ID, not slug)
 	app.moduleTemplatesMutex.RLock()
 	moduleSpecificTemplates, ok := app.moduleTemplates[targetModule.ID] // Still use ID to lookup templates
 	app.moduleTemplatesMutex.RUnlock()
@@ -218,39 +256,10 @@ func (app *application) handleModulePageRequest(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	// 5. Prepare data: Filter, sort, and pre-render sub-templates
-	var renderableTemplates []model.Template
-	for _, t := range targetModule.Templates {
-		if !t.IsBase && (strings.HasSuffix(t.Name, ".html") || strings.HasSuffix(t.Name, ".tmpl")) {
-			renderableTemplates = append(renderableTemplates, t)
-		}
-	}
-	sort.SliceStable(renderableTemplates, func(i, j int) bool {
-		return renderableTemplates[i].Order < renderableTemplates[j].Order
-	})
-
-	var renderedContentBuf bytes.Buffer
-	for _, tmplToRender := range renderableTemplates {
-		definedName := strings.TrimSuffix(tmplToRender.Name, filepath.Ext(tmplToRender.Name))
-		app.logger.Debug("Rendering sub-template", "template_name", definedName, "module_name", targetModule.Name, "module_slug", moduleSlug) // Use Debug level
-		err := moduleSpecificTemplates.ExecuteTemplate(&renderedContentBuf, definedName, targetModule)
-		if err != nil {
-			app.logger.Error("Error rendering sub-template", "template_name", definedName, "module_name", targetModule.Name, "module_slug", moduleSlug, "error", err) // Use Error level
-		}
-	}
+	// 6. Prepare data for the sub-templates
+	instanceData := templating.ModuleInstanceData{Module: targetModule, Data: app.moduleData(r, targetModule)}
 
-	pageData := PageData{
-		Module:          targetModule,
-		RenderedContent: template.HTML(renderedContentBuf.String()),
-	}
-	app.logger.Debug("Prepared templates for module page", "count", len(renderableTemplates), "module_name", targetModule.Name, "module_slug", moduleSlug) // Use Debug level
-
-	layoutData := LayoutData{
-		IsModuleListEnabled: app.isModuleListEnabled,
-		PageContent:         pageData,
-	}
-
-	// 6. Determine if it's an HTMX request and render
+	// 7. Determine if it's an HTMX request and render
 	isHTMX := r.Header.Get("HX-Request") == "true"
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 
@@ -262,7 +271,7 @@ func (app *application) handleModulePageRequest(w http.ResponseWriter, r *http.R
 			app.logger.Error("Error writing OOB header swap", "module_name", targetModule.Name, "module_slug", moduleSlug, "error", err) // Use Error level
 			return
 		}
-		err = moduleSpecificTemplates.ExecuteTemplate(w, "page", layoutData.PageContent)
+		err = app.renderer.RenderModulePage(w, moduleSpecificTemplates, instanceData, nil)
 		if err != nil {
 			app.logger.Error("Error executing page template (HTMX)", "module_name", targetModule.Name, "module_slug", moduleSlug, "error", err) // Use Error level
 			return
@@ -270,7 +279,8 @@ func (app *application) handleModulePageRequest(w http.ResponseWriter, r *http.R
 		app.logger.Debug("Successfully rendered OOB header and page fragment", "module_name", targetModule.Name, "module_slug", moduleSlug) // Use Debug level
 	} else {
 		app.logger.Debug("Standard request for module", "module_name", targetModule.Name, "module_slug", moduleSlug) // Use Debug level
-		err := moduleSpecificTemplates.ExecuteTemplate(w, "layout.html", layoutData)
+		layoutData := templating.LayoutData{IsModuleListEnabled: app.isModuleListEnabled}
+		err := app.renderer.RenderModulePage(w, moduleSpecificTemplates, instanceData, &layoutData)
 		if err != nil {
 			app.logger.Error("Error executing layout template", "module_name", targetModule.Name, "module_slug", moduleSlug, "error", err) // Use Error level
 			if strings.Contains(err.Error(), "template\" is undefined") {
@@ -282,6 +292,86 @@ func (app *application) handleModulePageRequest(w http.ResponseWriter, r *http.R
 	}
 }
 
+// handlePageRequest serves a composed Page: every referenced module instance is
+// rendered in order (through the module's own "page" template, so its styles are
+// included) and the combined output is placed into the layout.
+func (app *application) handlePageRequest(w http.ResponseWriter, r *http.Request, page *model.Page) {
+	if !page.IsActive {
+		app.logger.Warn("Attempted to access inactive page", "slug", page.Slug, "name", page.Name, "id", page.ID)
+		http.Error(w, "Page not available", http.StatusForbidden)
+		return
+	}
+	if app.baseTemplates == nil {
+		http.Error(w, "Internal Server Error - Base templates not loaded", http.StatusInternalServerError)
+		return
+	}
+
+	loadedModules, _ := app.snapshot()
+
+	var pageContentBuf bytes.Buffer
+	for _, instance := range page.SortedModules() {
+		var module *model.Module
+		for _, mod := range loadedModules {
+			if mod.ID == instance.ModuleID {
+				module = mod
+				break
+			}
+		}
+		if module == nil || !app.isLive(module) {
+			app.logger.Warn("Skipping missing or unpublished module on page", "page_slug", page.Slug, "module_id", instance.ModuleID)
+			continue
+		}
+
+		app.moduleTemplatesMutex.RLock()
+		moduleSpecificTemplates, ok := app.moduleTemplates[module.ID]
+		app.moduleTemplatesMutex.RUnlock()
+		if !ok {
+			app.logger.Error("Template set not found for module on page", "page_slug", page.Slug, "module_id", module.ID)
+			continue
+		}
+
+		instanceData := templating.ModuleInstanceData{Module: module, Config: instance.Config, Data: app.moduleData(r, module)}
+
+		fmt.Fprintf(&pageContentBuf, `<section class="gws-module-instance" data-module-id="%s">`, template.HTMLEscapeString(module.ID))
+		err := app.renderer.RenderModulePage(&pageContentBuf, moduleSpecificTemplates, instanceData, nil)
+		if err != nil {
+			app.logger.Error("Error executing module page template for page", "page_slug", page.Slug, "module_id", module.ID, "error", err)
+		}
+		pageContentBuf.WriteString("</section>")
+	}
+
+	pageData := templating.PageData{
+		Page:            page,
+		RenderedContent: template.HTML(pageContentBuf.String()),
+	}
+	layoutData := templating.LayoutData{
+		IsModuleListEnabled: app.isModuleListEnabled,
+		PageContent:         pageData,
+	}
+
+	isHTMX := r.Header.Get("HX-Request") == "true"
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	if isHTMX {
+		app.logger.Debug("HTMX request detected for page", "page_name", page.Name, "page_slug", page.Slug)
+		headerSwapHTML := fmt.Sprintf(`<span id="module-header-info" hx-swap-oob="innerHTML">Page: %s</span>`, template.HTMLEscapeString(page.Name))
+		if _, err := w.Write([]byte(headerSwapHTML)); err != nil {
+			app.logger.Error("Error writing OOB header swap for page", "page_slug", page.Slug, "error", err)
+			return
+		}
+		if err := app.baseTemplates.ExecuteTemplate(w, templating.PageTemplate, pageData); err != nil {
+			app.logger.Error("Error executing page template for page (HTMX)", "page_slug", page.Slug, "error", err)
+		}
+		return
+	}
+
+	app.logger.Debug("Standard request for page", "page_name", page.Name, "page_slug", page.Slug, "layout", page.Layout)
+	if err := app.renderer.RenderPageLayout(w, page, layoutData); err != nil {
+		app.logger.Error("Error executing layout template for page", "page_slug", page.Slug, "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
 // handleModuleStaticRequest serves static files from a module's directory using chi URL params
 func (app *application) handleModuleStaticRequest(w http.ResponseWriter, r *http.Request) {
 	moduleID := chi.URLParam(r, "moduleID")
@@ -321,3 +411,44 @@ func (app *application) handleModuleStaticRequest(w http.ResponseWriter, r *http
 	app.logger.Debug("Serving module static file", "module_id", moduleID, "path", filePath) // Use Debug level
 	http.ServeFile(w, r, filePath)
 }
+
+// moduleBySlug returns the module served at slug. An archived module only keeps its
+// slug until another module takes it, so a module that is not archived wins.
+func moduleBySlug(modules []*model.Module, slug string) *model.Module {
+	var archived *model.Module
+	for _, mod := range modules {
+		if mod.Slug != slug {
+			continue
+		}
+		if !mod.IsArchived() {
+			return mod
+		}
+		if archived == nil {
+			archived = mod
+		}
+	}
+	return archived
+}
+
+// redirectOldSlug redirects a request under a slug a module had before (Module.OldSlugs)
+// to the same path under the module's current slug, and reports whether it did. GET and
+// HEAD requests get a 301; other methods a 308, so module handlers see the same request.
+func (app *application) redirectOldSlug(w http.ResponseWriter, r *http.Request, modules []*model.Module, slug string) bool {
+	for _, mod := range modules {
+		if mod.IsArchived() || !slices.Contains(mod.OldSlugs, slug) {
+			continue
+		}
+		target := "/" + mod.Slug + strings.TrimPrefix(r.URL.Path, "/"+slug)
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		code := http.StatusMovedPermanently
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			code = http.StatusPermanentRedirect
+		}
+		app.logger.Debug("Redirecting old module slug", "slug", slug, "module_id", mod.ID, "target", target)
+		http.Redirect(w, r, target, code)
+		return true
+	}
+	return false
+}
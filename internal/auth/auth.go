@@ -0,0 +1,77 @@
+// Package auth manages the accounts that sign in to the admin server: bcrypt password
+// hashing, credential checks against a storage.UserStore, and login sessions.
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"go-module-builder/internal/model"
+	"go-module-builder/internal/storage"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MinPasswordLength is the shortest password accepted for an account.
+const MinPasswordLength = 8
+
+// ErrInvalidCredentials is returned by Authenticate for an unknown user or a wrong
+// password; the two are not told apart so usernames cannot be probed.
+var ErrInvalidCredentials = errors.New("invalid username or password")
+
+// NewUser validates the username and password and returns an account with the
+// password hashed. It is not saved.
+func NewUser(username, password string) (*model.User, error) {
+	if err := model.ValidateUsername(username); err != nil {
+		return nil, err
+	}
+	user := &model.User{Username: username, CreatedAt: time.Now()}
+	if err := SetPassword(user, password); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// SetPassword replaces the user's password hash. Sessions the user started before
+// are no longer valid (see Sessions.Get).
+func SetPassword(user *model.User, password string) error {
+	if len(password) < MinPasswordLength {
+		return fmt.Errorf("password must be at least %d characters long", MinPasswordLength)
+	}
+	if len(password) > 72 {
+		return errors.New("password must be at most 72 bytes long") // bcrypt ignores the rest
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("hashing password failed: %w", err)
+	}
+	user.PasswordHash = string(hash)
+	user.PasswordChangedAt = time.Now()
+	return nil
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// Authenticate returns the account if password matches its hash, or ErrInvalidCredentials.
+// An unknown user takes as long to reject as a wrong password.
+func Authenticate(users storage.UserStore, username, password string) (*model.User, error) {
+	user, err := users.LoadUser(username)
+	if err != nil {
+		if !errors.Is(err, storage.ErrUserNotFound) && model.ValidateUsername(username) == nil {
+			return nil, fmt.Errorf("loading user failed: %w", err)
+		}
+		dummyHashOnce.Do(func() {
+			dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not a password"), bcrypt.DefaultCost)
+		})
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return user, nil
+}
@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"errors"
+	"go-module-builder/internal/storage"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuthenticate(t *testing.T) {
+	users, err := storage.NewJSONUserStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewJSONUserStore failed: %v", err)
+	}
+	if _, err := NewUser("alice", "short"); err == nil {
+		t.Error("NewUser with a short password did not return an error")
+	}
+	if _, err := NewUser("../alice", "long enough"); err == nil {
+		t.Error("NewUser with an invalid username did not return an error")
+	}
+	alice, err := NewUser("alice", "correct horse")
+	if err != nil {
+		t.Fatalf("NewUser failed: %v", err)
+	}
+	if strings.Contains(alice.PasswordHash, "correct horse") {
+		t.Fatal("The password is stored in plain text")
+	}
+	if err := users.SaveUser(alice); err != nil {
+		t.Fatalf("SaveUser failed: %v", err)
+	}
+
+	if user, err := Authenticate(users, "alice", "correct horse"); err != nil || user.Username != "alice" {
+		t.Errorf("Authenticate with the right password = %v, %v", user, err)
+	}
+	for _, tc := range []struct{ username, password string }{
+		{"alice", "wrong horse"},
+		{"bob", "correct horse"},
+		{"../alice", "correct horse"},
+	} {
+		if _, err := Authenticate(users, tc.username, tc.password); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("Authenticate(%q, %q) = %v, want ErrInvalidCredentials", tc.username, tc.password, err)
+		}
+	}
+}
+
+func TestSessions(t *testing.T) {
+	sessions := NewSessions(time.Hour)
+	now := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	sessions.now = func() time.Time { return now }
+
+	token, err := sessions.Create("alice")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	other, _ := sessions.Create("alice")
+	if token == other {
+		t.Fatal("Create returned the same token twice")
+	}
+	session, ok := sessions.Get(token)
+	if !ok || session.Username != "alice" {
+		t.Fatalf("Get = %+v, %v; want alice's session", session, ok)
+	}
+
+	alice, err := NewUser("alice", "correct horse")
+	if err != nil {
+		t.Fatalf("NewUser failed: %v", err)
+	}
+	alice.PasswordChangedAt = now.Add(-time.Minute)
+	if !session.ValidFor(alice) {
+		t.Error("Session should be valid for its user")
+	}
+	alice.PasswordChangedAt = now.Add(time.Minute)
+	if session.ValidFor(alice) {
+		t.Error("Session should not be valid after a password change")
+	}
+
+	sessions.Delete(other)
+	if _, ok := sessions.Get(other); ok {
+		t.Error("Deleted session is still valid")
+	}
+	now = now.Add(time.Hour)
+	if _, ok := sessions.Get(token); ok {
+		t.Error("Expired session is still valid")
+	}
+}
+
+func TestSessionsFlash(t *testing.T) {
+	sessions := NewSessions(time.Hour)
+	alice, _ := sessions.Create("alice")
+	bob, _ := sessions.Create("bob")
+
+	sessions.AddFlash(alice, Flash{Error: "Failed to import 'a.zip'"})
+	sessions.AddFlash(alice, Flash{Success: "Module 'A' created."}) // Keeps the error
+	sessions.AddFlash("no-such-session", Flash{Error: "lost"})
+
+	if flash := sessions.PopFlash(bob); flash != (Flash{}) {
+		t.Errorf("PopFlash(bob) = %+v, want no messages from alice's session", flash)
+	}
+	want := Flash{Success: "Module 'A' created.", Error: "Failed to import 'a.zip'"}
+	if flash := sessions.PopFlash(alice); flash != want {
+		t.Errorf("PopFlash(alice) = %+v, want %+v", flash, want)
+	}
+	if flash := sessions.PopFlash(alice); flash != (Flash{}) {
+		t.Errorf("Second PopFlash(alice) = %+v, want the messages shown only once", flash)
+	}
+}
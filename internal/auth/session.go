@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"go-module-builder/internal/model"
+	"sync"
+	"time"
+)
+
+// DefaultSessionTTL is how long a login lasts when admin_server.sessionTTL is not set.
+const DefaultSessionTTL = 12 * time.Hour
+
+// Session is a signed-in admin user.
+type Session struct {
+	Username  string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+
+	flash Flash // Shown on the session's next page; see AddFlash
+}
+
+// Flash holds one-time messages for the next page a session loads, such as the outcome
+// of a form that redirects.
+type Flash struct {
+	Success string
+	Error   string
+}
+
+// ValidFor reports whether the session still belongs to user: the account exists
+// under the same name and its password has not changed since the login.
+func (s Session) ValidFor(user *model.User) bool {
+	return user != nil && user.Username == s.Username && !s.CreatedAt.Before(user.PasswordChangedAt)
+}
+
+// Sessions keeps login sessions in memory, keyed by a random token the browser
+// holds in a cookie. Restarting the admin server signs everyone out.
+type Sessions struct {
+	ttl time.Duration
+	now func() time.Time // Replaced in tests
+
+	mu       sync.Mutex
+	sessions map[string]Session
+}
+
+// NewSessions creates an empty session store whose sessions last ttl.
+func NewSessions(ttl time.Duration) *Sessions {
+	return &Sessions{ttl: ttl, now: time.Now, sessions: make(map[string]Session)}
+}
+
+// TTL returns how long a session lasts.
+func (s *Sessions) TTL() time.Duration {
+	return s.ttl
+}
+
+// Create starts a session for username and returns its token.
+func (s *Sessions) Create(username string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating session token failed: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for t, session := range s.sessions { // Drop expired sessions so the map doesn't grow
+		if !now.Before(session.ExpiresAt) {
+			delete(s.sessions, t)
+		}
+	}
+	s.sessions[token] = Session{Username: username, CreatedAt: now, ExpiresAt: now.Add(s.ttl)}
+	return token, nil
+}
+
+// Get returns the session for token, if there is one and it has not expired.
+func (s *Sessions) Get(token string) (Session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[token]
+	if !ok {
+		return Session{}, false
+	}
+	if !s.now().Before(session.ExpiresAt) {
+		delete(s.sessions, token)
+		return Session{}, false
+	}
+	return session, true
+}
+
+// Delete ends the session for token (logout).
+func (s *Sessions) Delete(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, token)
+}
+
+// AddFlash stores messages for the next page the session for token loads. Empty fields
+// keep the messages already stored. Nothing is stored without a valid session.
+func (s *Sessions) AddFlash(token string, flash Flash) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[token]
+	if !ok {
+		return
+	}
+	if flash.Success != "" {
+		session.flash.Success = flash.Success
+	}
+	if flash.Error != "" {
+		session.flash.Error = flash.Error
+	}
+	s.sessions[token] = session
+}
+
+// PopFlash returns the messages stored for the session for token and clears them.
+func (s *Sessions) PopFlash(token string) Flash {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[token]
+	if !ok {
+		return Flash{}
+	}
+	flash := session.flash
+	session.flash = Flash{}
+	s.sessions[token] = session
+	return flash
+}
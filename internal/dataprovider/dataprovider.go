@@ -0,0 +1,191 @@
+// Package dataprovider loads the data a module's templates receive as .Data,
+// either from a Go provider compiled into the server or from the data source
+// declared in the module's metadata (a JSON/YAML file or a local HTTP endpoint).
+package dataprovider
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-module-builder/internal/model"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxDataSize limits how much data a file or endpoint may return.
+const maxDataSize = 4 << 20
+
+// DefaultTimeout bounds requests to a module's data endpoint.
+const DefaultTimeout = 2 * time.Second
+
+// Provider supplies the data passed to a module's templates.
+// r is the request being rendered (a page view or an admin preview).
+type Provider interface {
+	ModuleData(r *http.Request, module *model.Module) (any, error)
+}
+
+// ProviderFunc adapts a function to the Provider interface.
+type ProviderFunc func(r *http.Request, module *model.Module) (any, error)
+
+// ModuleData calls f(r, module).
+func (f ProviderFunc) ModuleData(r *http.Request, module *model.Module) (any, error) {
+	return f(r, module)
+}
+
+// Resolver finds the data for a module: a registered Provider takes precedence
+// over the module's declared DataSource. It is safe for concurrent use.
+type Resolver struct {
+	projectRoot string
+	providers   map[string]Provider // Module ID -> provider; never modified after NewResolver
+	client      *http.Client
+}
+
+// NewResolver creates a Resolver. Relative module directories are resolved against
+// projectRoot. providers may be nil.
+func NewResolver(projectRoot string, providers map[string]Provider) *Resolver {
+	registered := make(map[string]Provider, len(providers))
+	for id, p := range providers {
+		registered[id] = p
+	}
+	return &Resolver{
+		projectRoot: projectRoot,
+		providers:   registered,
+		client: &http.Client{
+			Timeout: DefaultTimeout,
+			// A local endpoint could otherwise redirect the server to any other host
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return fmt.Errorf("data endpoint redirected to %s; redirects are not followed", req.URL.Redacted())
+			},
+		},
+	}
+}
+
+// Load returns the module's data, or nil if the module has no provider and declares no data source.
+func (res *Resolver) Load(r *http.Request, module *model.Module) (any, error) {
+	if p, ok := res.providers[module.ID]; ok {
+		data, err := p.ModuleData(r, module)
+		if err != nil {
+			return nil, fmt.Errorf("data provider for module %s failed: %w", module.ID, err)
+		}
+		return data, nil
+	}
+
+	src := module.DataSource
+	if src == nil || (src.File == "" && src.URL == "") {
+		return nil, nil
+	}
+	if err := Validate(src); err != nil {
+		return nil, err
+	}
+	if src.File != "" {
+		return res.loadFile(module, src.File)
+	}
+	return res.loadURL(r, src.URL)
+}
+
+// Validate checks that a data source names either a JSON/YAML file inside the module
+// directory or a loopback http(s) endpoint, but not both.
+func Validate(src *model.DataSource) error {
+	if src.File != "" && src.URL != "" {
+		return fmt.Errorf("data source cannot declare both a file and a URL")
+	}
+	if src.File != "" {
+		clean := filepath.Clean(filepath.FromSlash(src.File))
+		if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("data file %q must be a path inside the module directory", src.File)
+		}
+		switch strings.ToLower(filepath.Ext(clean)) {
+		case ".json", ".yaml", ".yml":
+		default:
+			return fmt.Errorf("data file %q must be .json, .yaml or .yml", src.File)
+		}
+	}
+	if src.URL != "" {
+		u, err := url.Parse(src.URL)
+		if err != nil {
+			return fmt.Errorf("invalid data URL %q: %w", src.URL, err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("data URL %q must use http or https", src.URL)
+		}
+		if !isLoopback(u.Hostname()) {
+			return fmt.Errorf("data URL %q must point to a local endpoint (localhost or a loopback address)", src.URL)
+		}
+	}
+	return nil
+}
+
+func isLoopback(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// moduleDir returns the absolute path of the module's root directory.
+func (res *Resolver) moduleDir(module *model.Module) string {
+	if filepath.IsAbs(module.Directory) {
+		return module.Directory
+	}
+	return filepath.Join(res.projectRoot, module.Directory)
+}
+
+// loadFile decodes a JSON or YAML data file from the module directory.
+func (res *Resolver) loadFile(module *model.Module, name string) (any, error) {
+	path := filepath.Join(res.moduleDir(module), filepath.Clean(filepath.FromSlash(name)))
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open data file for module %s: %w", module.ID, err)
+	}
+	defer f.Close()
+	content, err := io.ReadAll(io.LimitReader(f, maxDataSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data file %s: %w", path, err)
+	}
+	if len(content) > maxDataSize {
+		return nil, fmt.Errorf("data file %s exceeds %d bytes", path, maxDataSize)
+	}
+
+	var data any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(content, &data)
+	default:
+		err = yaml.Unmarshal(content, &data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode data file %s: %w", path, err)
+	}
+	return data, nil
+}
+
+// loadURL fetches JSON data from a local endpoint, bounded by the request's context and DefaultTimeout.
+func (res *Resolver) loadURL(r *http.Request, rawURL string) (any, error) {
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data URL %q: %w", rawURL, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := res.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch data from %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("data endpoint %s returned %s", rawURL, resp.Status)
+	}
+
+	var data any
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxDataSize)).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to decode data from %s: %w", rawURL, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,166 @@
+package dataprovider
+
+import (
+	"errors"
+	"fmt"
+	"go-module-builder/internal/model"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeDataFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+}
+
+func TestResolverLoad_Files(t *testing.T) {
+	projectRoot := t.TempDir()
+	moduleDir := filepath.Join(projectRoot, "modules", "mod-1")
+	if err := os.MkdirAll(moduleDir, 0755); err != nil {
+		t.Fatalf("Failed to create module directory: %v", err)
+	}
+	writeDataFile(t, moduleDir, "data.json", `{"items": [{"title": "A"}, {"title": "B"}]}`)
+	writeDataFile(t, moduleDir, "data.yaml", "items:\n  - title: A\n  - title: B\n")
+
+	want := map[string]any{"items": []any{map[string]any{"title": "A"}, map[string]any{"title": "B"}}}
+	res := NewResolver(projectRoot, nil)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	for _, file := range []string{"data.json", "data.yaml"} {
+		// Relative directory, resolved against the project root
+		module := &model.Module{ID: "mod-1", Directory: filepath.Join("modules", "mod-1"), DataSource: &model.DataSource{File: file}}
+		got, err := res.Load(req, module)
+		if err != nil {
+			t.Fatalf("Load(%s) failed: %v", file, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Load(%s) = %#v, want %#v", file, got, want)
+		}
+	}
+
+	// No data source means no data
+	got, err := res.Load(req, &model.Module{ID: "mod-1", Directory: moduleDir})
+	if err != nil || got != nil {
+		t.Errorf("Load() without a data source = %v, %v; want nil, nil", got, err)
+	}
+
+	// Missing and malformed files are errors
+	writeDataFile(t, moduleDir, "broken.json", `{"items": [`)
+	for _, file := range []string{"missing.json", "broken.json"} {
+		module := &model.Module{ID: "mod-1", Directory: moduleDir, DataSource: &model.DataSource{File: file}}
+		if _, err := res.Load(req, module); err == nil {
+			t.Errorf("Load(%s) did not return an error", file)
+		}
+	}
+}
+
+func TestResolverLoad_URL(t *testing.T) {
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"count": 3}`)
+	}))
+	defer endpoint.Close()
+
+	res := NewResolver(t.TempDir(), nil)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	got, err := res.Load(req, &model.Module{ID: "mod-1", DataSource: &model.DataSource{URL: endpoint.URL + "/data"}})
+	if err != nil {
+		t.Fatalf("Load() from endpoint failed: %v", err)
+	}
+	if want := map[string]any{"count": float64(3)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Load() from endpoint = %#v, want %#v", got, want)
+	}
+
+	if _, err := res.Load(req, &model.Module{ID: "mod-1", DataSource: &model.DataSource{URL: endpoint.URL + "/fail"}}); err == nil {
+		t.Error("Load() did not return an error for a failing endpoint")
+	}
+}
+
+func TestResolverLoad_URLRedirectIsRefused(t *testing.T) {
+	remoteHit := false
+	remote := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remoteHit = true
+		fmt.Fprint(w, `{"secret": true}`)
+	}))
+	defer remote.Close()
+	// Stands in for a host that is not local: the check only applies to the first URL
+	endpoint := httptest.NewServer(http.RedirectHandler(remote.URL+"/latest/meta-data", http.StatusFound))
+	defer endpoint.Close()
+
+	res := NewResolver(t.TempDir(), nil)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if _, err := res.Load(req, &model.Module{ID: "mod-1", DataSource: &model.DataSource{URL: endpoint.URL + "/data"}}); err == nil {
+		t.Error("Load() followed a redirect from the data endpoint")
+	}
+	if remoteHit {
+		t.Error("The redirect target was requested")
+	}
+}
+
+func TestResolverLoad_ProviderTakesPrecedence(t *testing.T) {
+	called := false
+	providers := map[string]Provider{
+		"mod-1": ProviderFunc(func(r *http.Request, module *model.Module) (any, error) {
+			called = true
+			return []string{module.ID}, nil
+		}),
+		"mod-2": ProviderFunc(func(r *http.Request, module *model.Module) (any, error) {
+			return nil, errors.New("database down")
+		}),
+	}
+	res := NewResolver(t.TempDir(), providers)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	got, err := res.Load(req, &model.Module{ID: "mod-1", DataSource: &model.DataSource{File: "missing.json"}})
+	if err != nil {
+		t.Fatalf("Load() with a provider failed: %v", err)
+	}
+	if !called || !reflect.DeepEqual(got, []string{"mod-1"}) {
+		t.Errorf("Provider not used: called=%v, got %#v", called, got)
+	}
+
+	if _, err := res.Load(req, &model.Module{ID: "mod-2"}); err == nil {
+		t.Error("Load() did not return the provider's error")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := []model.DataSource{
+		{File: "data.json"},
+		{File: "data/items.yml"},
+		{URL: "http://localhost:9000/items"},
+		{URL: "https://127.0.0.1/items"},
+		{URL: "http://[::1]:8080/"},
+	}
+	for _, src := range valid {
+		if err := Validate(&src); err != nil {
+			t.Errorf("Validate(%+v) failed: %v", src, err)
+		}
+	}
+
+	invalid := []model.DataSource{
+		{File: "data.json", URL: "http://localhost/"},
+		{File: "../other/data.json"},
+		{File: "/etc/data.json"},
+		{File: "data.txt"},
+		{URL: "http://example.com/items"},
+		{URL: "ftp://localhost/items"},
+		{URL: "http://10.0.0.1/items"},
+	}
+	for _, src := range invalid {
+		if err := Validate(&src); err == nil {
+			t.Errorf("Validate(%+v) did not return an error", src)
+		}
+	}
+}
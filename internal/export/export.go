@@ -0,0 +1,305 @@
+// Package export builds a static copy of the site: every active Page and published
+// module rendered to plain HTML, as the main server would serve it, plus the static
+// assets the pages use.
+package export
+
+import (
+	"fmt"
+	"go-module-builder/internal/model"
+	"go-module-builder/internal/storage"
+	"go-module-builder/internal/templating"
+	"go-module-builder/pkg/fsutils"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Options configure an export.
+type Options struct {
+	OutDir     string            // Directory the site is written to
+	StaticDir  string            // Global static assets (web/static), exported under static/
+	ModulesDir string            // Module folders; a module's assets are exported under modules/{id}/static/
+	Pages      storage.PageStore // Composed Pages to export; nil exports modules only
+	Clean      bool              // Replace OutDir with the new export instead of writing into it
+	Protected  []string          // Project files and directories OutDir must not be, contain or be inside
+	Logger     *slog.Logger      // nil discards log records
+}
+
+// Result describes an export.
+type Result struct {
+	Pages  []string // Written HTML files, relative to the output directory
+	Assets int      // Number of copied asset files
+}
+
+// page is a rendered page waiting to be written.
+type page struct {
+	urlPath string // Path the main server serves it at, e.g. "/" or "/about"
+	file    string // Output file relative to the output directory, e.g. "about/index.html"
+	html    string
+}
+
+// Site renders the site root, every active Page and every module live at the time of
+// the export (published and within its schedule) through engine and writes them, with
+// their assets, to opts.OutDir. Pages and module pages are written to {slug}/index.html;
+// as on the main server, a Page hides the module with the same slug. Every page is
+// rendered before anything is written, so a template error leaves the output directory
+// untouched.
+// With opts.Clean the site is written to a new directory next to opts.OutDir, which
+// replaces it only once the whole export has been written.
+func Site(store storage.DataStore, engine *templating.Engine, opts Options) (*Result, error) {
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	if err := CheckOutDir(opts.OutDir, opts.Protected); err != nil {
+		return nil, err
+	}
+
+	modules, err := store.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read modules: %w", err)
+	}
+	sort.Slice(modules, func(i, j int) bool { return modules[i].Slug < modules[j].Slug })
+	var sitePages []*model.Page
+	if opts.Pages != nil {
+		sitePages, err = opts.Pages.ReadAllPages()
+		if corrupt, ok := storage.AsCorruptPages(err); ok {
+			for _, id := range corrupt.IDs() {
+				logger.Warn("Skipping page that could not be loaded", "page_id", id, "error", corrupt.Pages[id])
+			}
+			err = nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read pages: %w", err)
+		}
+		sort.Slice(sitePages, func(i, j int) bool { return sitePages[i].Slug < sitePages[j].Slug })
+	}
+
+	// 1. Render every page, with the modules live at the time of the export
+	now := time.Now()
+	root, err := engine.RenderRoot()
+	if err != nil {
+		return nil, err
+	}
+	pages := []page{{urlPath: "/", file: "index.html", html: root}}
+	live := make(map[string]*model.Module) // Module ID -> module, for the modules live now
+	for _, mod := range modules {
+		if mod.IsLive(now) {
+			live[mod.ID] = mod
+		}
+	}
+	var exported []*model.Module // Modules whose assets the pages use
+	exportedIDs := make(map[string]bool)
+	addExported := func(mod *model.Module) {
+		if !exportedIDs[mod.ID] {
+			exportedIDs[mod.ID] = true
+			exported = append(exported, mod)
+		}
+	}
+	pageSlugs := make(map[string]bool) // Taken by a Page, whether it is served or not
+	for _, p := range sitePages {
+		pageSlugs[p.Slug] = true
+		if !p.IsActive {
+			logger.Debug("Skipping inactive page", "page_id", p.ID, "slug", p.Slug)
+			continue
+		}
+		if !exportableSlug(p.Slug) {
+			return nil, fmt.Errorf("page %s has slug %q, which cannot be exported as a directory", p.ID, p.Slug)
+		}
+		html, err := engine.RenderPage(p, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render page %s (%s): %w", p.Name, p.ID, err)
+		}
+		pages = append(pages, page{urlPath: "/" + p.Slug, file: path.Join(p.Slug, "index.html"), html: html})
+		for _, instance := range p.Modules {
+			if mod, ok := live[instance.ModuleID]; ok {
+				addExported(mod)
+			}
+		}
+	}
+	for _, mod := range modules {
+		if live[mod.ID] == nil {
+			logger.Debug("Skipping module that is not live", "module_id", mod.ID, "slug", mod.Slug, "status", mod.Status)
+			continue
+		}
+		if pageSlugs[mod.Slug] {
+			logger.Warn("Skipping module whose slug a page is served under", "module_id", mod.ID, "slug", mod.Slug)
+			continue
+		}
+		if !exportableSlug(mod.Slug) {
+			return nil, fmt.Errorf("module %s has slug %q, which cannot be exported as a directory", mod.ID, mod.Slug)
+		}
+		html, err := engine.CombineTemplates(mod.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render module %s (%s): %w", mod.Name, mod.ID, err)
+		}
+		pages = append(pages, page{urlPath: "/" + mod.Slug, file: path.Join(mod.Slug, "index.html"), html: html})
+		addExported(mod)
+	}
+
+	// 2. Copy the assets, so links to them can be rewritten
+	outDir := opts.OutDir
+	if opts.Clean {
+		if err := fsutils.CreateDir(filepath.Dir(opts.OutDir)); err != nil {
+			return nil, fmt.Errorf("failed to create parent of output directory: %w", err)
+		}
+		tmpDir, err := os.MkdirTemp(filepath.Dir(opts.OutDir), "."+filepath.Base(opts.OutDir)+"-*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create export directory: %w", err)
+		}
+		defer os.RemoveAll(tmpDir) // Only left over if the export failed
+		if err := os.Chmod(tmpDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to set permissions of export directory: %w", err)
+		}
+		outDir = tmpDir
+	}
+	result := &Result{}
+	assets := make(map[string]bool) // URL paths of the copied assets
+	if opts.StaticDir != "" {
+		if err := copyAssets(opts.StaticDir, filepath.Join(outDir, "static"), "/static", nil, assets); err != nil {
+			return nil, err
+		}
+	}
+	for _, mod := range exported {
+		// The main server serves /modules/{id}/static/* from the module's templates
+		// directory; the template sources themselves are left out.
+		src := filepath.Join(opts.ModulesDir, mod.ID, "templates")
+		dst := filepath.Join(outDir, "modules", mod.ID, "static")
+		if err := copyAssets(src, dst, "/modules/"+mod.ID+"/static", isTemplateSource, assets); err != nil {
+			return nil, err
+		}
+	}
+	result.Assets = len(assets)
+
+	// 3. Write the pages with their links made relative
+	urls := make(map[string]string, len(pages)) // URL path -> output file
+	for _, p := range pages {
+		urls[p.urlPath] = p.file
+	}
+	for _, p := range pages {
+		html := rewriteLinks(p.html, p.file, urls, assets, logger)
+		outPath := filepath.Join(outDir, filepath.FromSlash(p.file))
+		if err := fsutils.CreateDir(filepath.Dir(outPath)); err != nil {
+			return nil, fmt.Errorf("failed to create directory for %s: %w", p.file, err)
+		}
+		if err := fsutils.WriteToFile(outPath, []byte(html)); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", p.file, err)
+		}
+		result.Pages = append(result.Pages, p.file)
+		logger.Info("Exported page", "url", p.urlPath, "file", p.file)
+	}
+	// 4. Swap the new export in for the old one
+	if opts.Clean {
+		if err := os.RemoveAll(opts.OutDir); err != nil {
+			return nil, fmt.Errorf("failed to remove previous export %s: %w", opts.OutDir, err)
+		}
+		if err := os.Rename(outDir, opts.OutDir); err != nil {
+			return nil, fmt.Errorf("failed to move export to %s: %w", opts.OutDir, err)
+		}
+		logger.Info("Replaced previous export", "dir", opts.OutDir)
+	}
+	return result, nil
+}
+
+// exportableSlug reports whether a slug can be written as a single directory name.
+func exportableSlug(slug string) bool {
+	return slug != "" && !strings.ContainsAny(slug, `/\`) && slug != "." && slug != ".."
+}
+
+// CheckOutDir returns an error if outDir is one of the protected paths, contains one,
+// or is inside one, so an export (and its Clean) cannot overwrite project files.
+func CheckOutDir(outDir string, protected []string) error {
+	out, err := filepath.Abs(outDir)
+	if err != nil {
+		return fmt.Errorf("invalid output directory %s: %w", outDir, err)
+	}
+	for _, p := range protected {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return fmt.Errorf("invalid protected path %s: %w", p, err)
+		}
+		if isWithin(out, abs) || isWithin(abs, out) {
+			return fmt.Errorf("output directory %s must not be, contain or be inside %s", outDir, p)
+		}
+	}
+	return nil
+}
+
+// isWithin reports whether path is dir or inside it. Both must be absolute.
+func isWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && filepath.IsLocal(rel)
+}
+
+// isTemplateSource reports whether a module file is a page template rather than an asset.
+func isTemplateSource(name string) bool {
+	ext := filepath.Ext(name)
+	return ext == ".html" || ext == ".tmpl"
+}
+
+// copyAssets copies the files under src to dst, skipping those excluded, and records
+// the URL path (under urlPrefix) of each copied file. A missing src has no assets.
+func copyAssets(src, dst, urlPrefix string, exclude func(name string) bool, assets map[string]bool) error {
+	if _, err := os.Stat(src); os.IsNotExist(err) {
+		return nil
+	}
+	return filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || (exclude != nil && exclude(d.Name())) {
+			return nil
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return fmt.Errorf("failed to read asset %s: %w", p, err)
+		}
+		target := filepath.Join(dst, rel)
+		if err := fsutils.CreateDir(filepath.Dir(target)); err != nil {
+			return fmt.Errorf("failed to create directory for asset %s: %w", target, err)
+		}
+		if err := fsutils.WriteToFile(target, content); err != nil {
+			return fmt.Errorf("failed to copy asset %s: %w", p, err)
+		}
+		assets[urlPrefix+"/"+filepath.ToSlash(rel)] = true
+		return nil
+	})
+}
+
+// linkPattern matches root-relative URLs in link attributes and CSS url(), capturing
+// the part before the URL, the URL, and its query or fragment.
+var linkPattern = regexp.MustCompile(`((?:\s(?:href|src|action|hx-get)\s*=\s*["'])|(?:url\(\s*["']?))(/(?:[^/"'()\s?#][^"'()\s?#]*)?)([?#][^"'()\s]*)?`)
+
+// rewriteLinks makes the root-relative links in a page written to file relative to
+// it, so the export works from any directory: links to exported pages point to their
+// index.html, links to copied assets to the copy. Other links, such as module
+// handler endpoints, only exist on the main server; they are kept and logged.
+func rewriteLinks(html, file string, pages map[string]string, assets map[string]bool, logger *slog.Logger) string {
+	prefix := strings.Repeat("../", strings.Count(file, "/"))
+	return linkPattern.ReplaceAllStringFunc(html, func(match string) string {
+		parts := linkPattern.FindStringSubmatch(match)
+		before, urlPath, suffix := parts[1], parts[2], parts[3]
+		target, ok := pages[strings.TrimSuffix(urlPath, "/")]
+		if urlPath == "/" {
+			target, ok = pages["/"]
+		}
+		if !ok && assets[urlPath] {
+			target, ok = strings.TrimPrefix(urlPath, "/"), true
+		}
+		if !ok {
+			logger.Warn("Link is not part of the export, keeping it", "page", file, "url", urlPath)
+			return match
+		}
+		return before + prefix + target + suffix
+	})
+}
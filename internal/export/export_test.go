@@ -0,0 +1,277 @@
+package export
+
+import (
+	"go-module-builder/internal/model"
+	"go-module-builder/internal/storage"
+	"go-module-builder/internal/templating"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory for %s: %v", path, err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+// newSiteFixture creates a published module served at /about, a draft, and a
+// global stylesheet, and returns the store and engine to export them with.
+func newSiteFixture(t *testing.T) (storage.DataStore, *templating.Engine, Options) {
+	t.Helper()
+	root := t.TempDir()
+	opts := Options{
+		OutDir:     filepath.Join(root, "dist"),
+		StaticDir:  filepath.Join(root, "web", "static"),
+		ModulesDir: filepath.Join(root, "modules"),
+	}
+	writeFile(t, filepath.Join(opts.StaticDir, "site.css"), "body {}")
+
+	aboutDir := filepath.Join(opts.ModulesDir, "mod-about", "templates")
+	writeFile(t, filepath.Join(aboutDir, "base.html"), `{{ define "page" }}<img src="/modules/mod-about/static/logo.png"><a href="/about#team">About</a><form action="/about/contact"></form>{{ .RenderedContent }}{{ end }}`)
+	writeFile(t, filepath.Join(aboutDir, "content.html"), `{{ define "content" }}<p>{{ .Name }}</p>{{ end }}`)
+	writeFile(t, filepath.Join(aboutDir, "logo.png"), "png")
+	writeFile(t, filepath.Join(opts.ModulesDir, "mod-draft", "templates", "base.html"), `{{ define "page" }}draft{{ end }}`)
+
+	store, err := storage.Open("json", filepath.Join(root, ".module_metadata"), nil)
+	if err != nil {
+		t.Fatalf("storage.Open failed: %v", err)
+	}
+	for _, mod := range []*model.Module{
+		{ID: "mod-about", Name: "About Us", Slug: "about", Status: model.StatusPublished, Templates: []model.Template{
+			{Name: "base.html", IsBase: true, Order: 0, IsActive: true},
+			{Name: "content.html", Order: 1, IsActive: true},
+		}},
+		{ID: "mod-draft", Name: "Draft", Slug: "draft", Status: model.StatusDraft, Templates: []model.Template{
+			{Name: "base.html", IsBase: true, IsActive: true},
+		}},
+	} {
+		if err := store.SaveModule(mod); err != nil {
+			t.Fatalf("SaveModule failed: %v", err)
+		}
+	}
+
+	layouts := template.Must(template.New(templating.LayoutTemplate).Parse(
+		`<html><head><link href="/static/site.css" rel="stylesheet"></head><body><a href="/">Home</a>{{ block "page" .PageContent }}{{ with . }}{{ .RenderedContent }}{{ else }}Welcome{{ end }}{{ end }}</body></html>`))
+	renderer, err := templating.NewRenderer(opts.ModulesDir, layouts, nil)
+	if err != nil {
+		t.Fatalf("NewRenderer failed: %v", err)
+	}
+	return store, templating.NewEngine(store, renderer, nil), opts
+}
+
+func TestSite(t *testing.T) {
+	store, engine, opts := newSiteFixture(t)
+
+	result, err := Site(store, engine, opts)
+	if err != nil {
+		t.Fatalf("Site failed: %v", err)
+	}
+	if len(result.Pages) != 2 || result.Pages[0] != "index.html" || result.Pages[1] != "about/index.html" {
+		t.Errorf("Pages = %v, want the root and the published module", result.Pages)
+	}
+	if result.Assets != 2 {
+		t.Errorf("Assets = %d, want site.css and logo.png", result.Assets)
+	}
+
+	read := func(rel string) string {
+		t.Helper()
+		b, err := os.ReadFile(filepath.Join(opts.OutDir, rel))
+		if err != nil {
+			t.Fatalf("Expected %s in the export: %v", rel, err)
+		}
+		return string(b)
+	}
+
+	if got := read("index.html"); !strings.Contains(got, `href="static/site.css"`) || !strings.Contains(got, `href="index.html"`) || !strings.Contains(got, "Welcome") {
+		t.Errorf("index.html = %q, want relative links and the welcome content", got)
+	}
+	about := read("about/index.html")
+	for _, want := range []string{
+		`href="../static/site.css"`,
+		`href="../index.html"`,
+		`src="../modules/mod-about/static/logo.png"`,
+		`href="../about/index.html#team"`,
+		`action="/about/contact"`, // Module handler endpoints need the main server
+		`<p>About Us</p>`,
+	} {
+		if !strings.Contains(about, want) {
+			t.Errorf("about/index.html missing %q:\n%s", want, about)
+		}
+	}
+	if read("modules/mod-about/static/logo.png") != "png" || read("static/site.css") != "body {}" {
+		t.Error("Assets were not copied")
+	}
+	for _, rel := range []string{"draft/index.html", "modules/mod-about/static/base.html"} {
+		if _, err := os.Stat(filepath.Join(opts.OutDir, rel)); !os.IsNotExist(err) {
+			t.Errorf("%s should not be exported", rel)
+		}
+	}
+}
+
+// savePages stores the pages in a page store for opts.
+func savePages(t *testing.T, opts *Options, pages ...*model.Page) {
+	t.Helper()
+	pageStore, err := storage.NewJSONPageStore(filepath.Join(filepath.Dir(opts.OutDir), ".page_metadata"))
+	if err != nil {
+		t.Fatalf("NewJSONPageStore failed: %v", err)
+	}
+	for _, page := range pages {
+		if err := pageStore.SavePage(page); err != nil {
+			t.Fatalf("SavePage failed: %v", err)
+		}
+	}
+	opts.Pages = pageStore
+}
+
+func TestSite_Pages(t *testing.T) {
+	store, engine, opts := newSiteFixture(t)
+	writeFile(t, filepath.Join(opts.ModulesDir, "mod-about", "templates", "content.html"), `{{ define "content" }}<p>{{ .Name }}</p><a href="/team">Team</a>{{ end }}`)
+	savePages(t, &opts,
+		&model.Page{ID: "page-team", Name: "Team", Slug: "team", IsActive: true, Modules: []model.ModuleInstance{
+			{ModuleID: "mod-about", Order: 1},
+			{ModuleID: "mod-draft", Order: 0}, // Not live, left out as on the server
+		}},
+		&model.Page{ID: "page-hidden", Name: "Hidden", Slug: "hidden", IsActive: false},
+	)
+
+	result, err := Site(store, engine, opts)
+	if err != nil {
+		t.Fatalf("Site failed: %v", err)
+	}
+	if strings.Join(result.Pages, ",") != "index.html,team/index.html,about/index.html" {
+		t.Errorf("Pages = %v, want the root, the active page and the published module", result.Pages)
+	}
+
+	team, err := os.ReadFile(filepath.Join(opts.OutDir, "team", "index.html"))
+	if err != nil {
+		t.Fatalf("Expected team/index.html in the export: %v", err)
+	}
+	for _, want := range []string{
+		`<section class="gws-module-instance" data-module-id="mod-about">`,
+		`<p>About Us</p>`,
+		`href="../static/site.css"`,
+		`src="../modules/mod-about/static/logo.png"`,
+	} {
+		if !strings.Contains(string(team), want) {
+			t.Errorf("team/index.html missing %q:\n%s", want, team)
+		}
+	}
+	if strings.Contains(string(team), "mod-draft") {
+		t.Errorf("team/index.html should leave out the draft module:\n%s", team)
+	}
+	about, err := os.ReadFile(filepath.Join(opts.OutDir, "about", "index.html"))
+	if err != nil {
+		t.Fatalf("Expected about/index.html in the export: %v", err)
+	}
+	if !strings.Contains(string(about), `href="../team/index.html"`) {
+		t.Errorf("Links to the page should be made relative:\n%s", about)
+	}
+	if _, err := os.Stat(filepath.Join(opts.OutDir, "hidden")); !os.IsNotExist(err) {
+		t.Error("Inactive pages should not be exported")
+	}
+}
+
+func TestSite_PageHidesModuleWithItsSlug(t *testing.T) {
+	store, engine, opts := newSiteFixture(t)
+	// The server resolves pages first, so even an inactive page takes the slug
+	savePages(t, &opts, &model.Page{ID: "page-about", Name: "About", Slug: "about", IsActive: false})
+
+	result, err := Site(store, engine, opts)
+	if err != nil {
+		t.Fatalf("Site failed: %v", err)
+	}
+	if len(result.Pages) != 1 || result.Pages[0] != "index.html" {
+		t.Errorf("Pages = %v, want only the root", result.Pages)
+	}
+	if _, err := os.Stat(filepath.Join(opts.OutDir, "about")); !os.IsNotExist(err) {
+		t.Error("The module whose slug a page takes should not be exported")
+	}
+}
+
+func TestSite_TemplateError(t *testing.T) {
+	store, engine, opts := newSiteFixture(t)
+	writeFile(t, filepath.Join(opts.ModulesDir, "mod-about", "templates", "content.html"), `{{ define "content" }}{{ .Missing }}{{ end }}`)
+
+	if _, err := Site(store, engine, opts); err == nil || !strings.Contains(err.Error(), "mod-about") {
+		t.Fatalf("Site error = %v, want an error naming the broken module", err)
+	}
+	if _, err := os.Stat(opts.OutDir); !os.IsNotExist(err) {
+		t.Error("Nothing should be written when a page fails to render")
+	}
+}
+
+func TestSite_Clean(t *testing.T) {
+	store, engine, opts := newSiteFixture(t)
+	writeFile(t, filepath.Join(opts.OutDir, "stale.html"), "old")
+	opts.Clean = true
+
+	if _, err := Site(store, engine, opts); err != nil {
+		t.Fatalf("Site failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(opts.OutDir, "stale.html")); !os.IsNotExist(err) {
+		t.Error("A clean export should remove files from the previous export")
+	}
+	if _, err := os.Stat(filepath.Join(opts.OutDir, "about", "index.html")); err != nil {
+		t.Errorf("about/index.html missing: %v", err)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(opts.OutDir))
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".dist-") {
+			t.Errorf("Temporary export directory %s was left behind", e.Name())
+		}
+	}
+}
+
+func TestSite_CleanTemplateErrorKeepsPreviousExport(t *testing.T) {
+	store, engine, opts := newSiteFixture(t)
+	writeFile(t, filepath.Join(opts.OutDir, "index.html"), "previous")
+	writeFile(t, filepath.Join(opts.ModulesDir, "mod-about", "templates", "content.html"), `{{ define "content" }}{{ .Missing }}{{ end }}`)
+	opts.Clean = true
+
+	if _, err := Site(store, engine, opts); err == nil {
+		t.Fatal("Site should fail on the broken template")
+	}
+	if data, err := os.ReadFile(filepath.Join(opts.OutDir, "index.html")); err != nil || string(data) != "previous" {
+		t.Errorf("index.html = %q, %v; the previous export should be kept", data, err)
+	}
+}
+
+func TestCheckOutDir(t *testing.T) {
+	root := t.TempDir()
+	protected := []string{filepath.Join(root, "modules"), filepath.Join(root, "web"), filepath.Join(root, ".module_metadata")}
+
+	for _, dir := range []string{
+		filepath.Join(root, "modules"),
+		filepath.Join(root, "web", "static"),
+		filepath.Join(root, ".module_metadata", "out"),
+		root,
+		filepath.Dir(root),
+	} {
+		if err := CheckOutDir(dir, protected); err == nil {
+			t.Errorf("CheckOutDir(%s) accepted a directory overlapping the project", dir)
+		}
+	}
+	for _, dir := range []string{filepath.Join(root, "dist"), filepath.Join(root, "modules-export"), filepath.Join(root, "out", "web")} {
+		if err := CheckOutDir(dir, protected); err != nil {
+			t.Errorf("CheckOutDir(%s) = %v, want nil", dir, err)
+		}
+	}
+
+	store, engine, opts := newSiteFixture(t)
+	opts.OutDir = opts.ModulesDir
+	opts.Clean = true
+	opts.Protected = []string{opts.ModulesDir}
+	if _, err := Site(store, engine, opts); err == nil {
+		t.Fatal("Site should refuse to export into a protected directory")
+	}
+	if _, err := os.Stat(filepath.Join(opts.ModulesDir, "mod-about", "templates", "base.html")); err != nil {
+		t.Errorf("Module templates were removed: %v", err)
+	}
+}
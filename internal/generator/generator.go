@@ -4,7 +4,7 @@ import (
 	"fmt"
 	"go-module-builder/internal/model"
 	"go-module-builder/pkg/fsutils"
-	"log"
+	"log/slog"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -16,6 +16,7 @@ type Config struct {
 	BaseDir      string                 // Base directory where module folders are created (e.g., "modules")
 	SubDirs      []string               // Subdirectories to create within each module folder
 	DefaultFiles map[string]FileContent // Map of filename to its content and target subdir
+	Logger       *slog.Logger           // Receives debug records for created files; nil discards them
 }
 
 // FileContent defines the content and target subdirectory for a default file.
@@ -86,6 +87,8 @@ func DefaultGeneratorConfig(baseDir string) Config {
 import (
 	"fmt"
 	"net/http"
+
+	"` + GoModulePath + `/pkg/modulehttp"
 )
 
 // ModuleData might hold data passed from the handler to the template
@@ -94,22 +97,31 @@ type ModuleData struct {
 	// Add other fields needed by the template(s)
 }
 
-// Handle is the main entry point for this module's HTTP requests.
-// The main server needs a mechanism to discover and route to this handler.
+// Handle serves this module's dynamic endpoints: GET /{slug}/... and POST /{slug}[/...].
+// r.URL.Path is relative to the module's slug. The server picks the handler up once the
+// registry is regenerated (builder-cli generate-handlers) and the server is rebuilt.
 func Handle(w http.ResponseWriter, r *http.Request) {
 	data := ModuleData{
 		ModuleName: "{{ .ModuleName }}", // Use the actual module name
 	}
 
-	isHTMX := r.Header.Get("HX-Request") == "true"
-	isTargetMain := r.Header.Get("HX-Target") == "main"
-
-	if isHTMX && isTargetMain {
-		fmt.Fprintf(w, "<div>HTMX Fragment Response for %s (Render page block here)</div>", data.ModuleName)
-	} else {
-		fmt.Fprintf(w, "<html><body>Full Page Response for %s (Render layout + page block here)</body></html>", data.ModuleName)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	mod, ok := modulehttp.FromRequest(r)
+	if ok && modulehttp.IsHTMX(r) {
+		// Answer HTMX requests with a fragment rendered from this module's templates
+		if err := mod.Render(w, "content", mod); err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
 	}
-}`
+	fmt.Fprintf(w, "<html><body>%s response from %s</body></html>", r.Method, data.ModuleName)
+}
+
+// To pass data to this module's templates as .Data, declare:
+//
+//	func Data(r *http.Request) (any, error)
+//
+// and regenerate the handler registry. It takes precedence over a data source set with builder-cli set-data.`
 
 	return Config{
 		BaseDir: baseDir,
@@ -145,19 +157,23 @@ func GenerateModuleBoilerplate(cfg Config, moduleName, moduleID, customSlug stri
 		return nil, fmt.Errorf("module name and ID cannot be empty")
 	}
 
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
 	moduleDir := filepath.Join(cfg.BaseDir, moduleID)
 
 	if err := fsutils.CreateDir(moduleDir); err != nil {
 		return nil, fmt.Errorf("failed to create module directory %s: %w", moduleDir, err)
 	}
-	fmt.Printf("Created directory: %s\n", moduleDir)
+	logger.Debug("Created directory", "path", moduleDir)
 
 	for _, subDir := range cfg.SubDirs {
 		fullSubDirPath := filepath.Join(moduleDir, subDir)
 		if err := fsutils.CreateDir(fullSubDirPath); err != nil {
 			return nil, fmt.Errorf("failed to create subdirectory %s: %w", fullSubDirPath, err)
 		}
-		fmt.Printf("Created directory: %s\n", fullSubDirPath)
+		logger.Debug("Created directory", "path", fullSubDirPath)
 	}
 
 	now := time.Now()
@@ -167,7 +183,7 @@ func GenerateModuleBoilerplate(cfg Config, moduleName, moduleID, customSlug stri
 		Directory:   moduleDir,
 		CreatedAt:   now,
 		LastUpdated: now,
-		IsActive:    true, // Default new modules to active
+		Status:      model.StatusDraft, // New modules are served once published
 		Group:       "",
 		Layout:      "",
 		Assets:      nil,
@@ -199,7 +215,7 @@ func GenerateModuleBoilerplate(cfg Config, moduleName, moduleID, customSlug stri
 		if err := fsutils.WriteToFile(filePath, []byte(content)); err != nil {
 			return nil, fmt.Errorf("failed to create default file %s: %w", filePath, err)
 		}
-		fmt.Printf("Created file: %s\n", filePath)
+		logger.Debug("Created file", "path", filePath)
 
 		// --- UPDATED: Metadata creation logic ---
 		if fileInfo.SubDir == "templates" {
@@ -236,7 +252,11 @@ func GenerateModuleBoilerplate(cfg Config, moduleName, moduleID, customSlug stri
 // AddTemplateToModule adds a new template file.
 // It no longer modifies base.html or style.css.
 // The CLI command is responsible for updating the module's JSON metadata.
-func AddTemplateToModule(moduleID, templateName, modulesDir string) error {
+// A nil logger discards the debug record for the created file.
+func AddTemplateToModule(moduleID, templateName, modulesDir string, logger *slog.Logger) error {
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
 	moduleTemplatesDir := filepath.Join(modulesDir, moduleID, "templates")
 
 	templateFileName := strings.TrimSuffix(templateName, filepath.Ext(templateName))
@@ -251,8 +271,6 @@ func AddTemplateToModule(moduleID, templateName, modulesDir string) error {
 	if err := fsutils.WriteToFile(newTemplateFilePath, []byte(newTemplateContent)); err != nil {
 		return fmt.Errorf("failed to create template file %s: %w", newTemplateFilePath, err)
 	}
-	fmt.Printf("Created template file: %s\n", newTemplateFilePath)
-
-	log.Printf("Successfully created template file '%s' for module %s. Remember to update module metadata.", templateName, moduleID)
+	logger.Debug("Created template file", "moduleID", moduleID, "path", newTemplateFilePath)
 	return nil
 }
@@ -40,8 +40,8 @@ func TestGenerateModuleBoilerplate(t *testing.T) {
 		t.Errorf("Module Name mismatch: got %q, want %q", module.Name, moduleName)
 	}
 	// Check new default fields
-	if !module.IsActive {
-		t.Errorf("Expected module.IsActive to be true by default, got false")
+	if module.Status != model.StatusDraft {
+		t.Errorf("Expected module.Status to be %q by default, got %q", model.StatusDraft, module.Status)
 	}
 	if module.Group != "" {
 		t.Errorf("Expected module.Group to be empty string by default, got %q", module.Group)
@@ -193,7 +193,7 @@ func TestAddTemplateToModule(t *testing.T) {
 	templateName := "card.html"
 
 	// --- Execute ---
-	err := AddTemplateToModule(moduleID, templateName, tempDir)
+	err := AddTemplateToModule(moduleID, templateName, tempDir, nil)
 	if err != nil {
 		t.Fatalf("AddTemplateToModule failed: %v", err)
 	}
@@ -227,14 +227,14 @@ func TestAddTemplateToModule(t *testing.T) {
 
 	// --- Test Case: Error on non-existent module ---
 	nonExistentID := "non-existent-module"
-	err = AddTemplateToModule(nonExistentID, templateName, tempDir)
+	err = AddTemplateToModule(nonExistentID, templateName, tempDir, nil)
 	if err == nil {
 		t.Errorf("Expected error for non-existent module, but got nil")
 	}
 
 	// --- Test Case: Different template name/extension ---
 	cssTemplateName := "custom.css"
-	err = AddTemplateToModule(moduleID, cssTemplateName, tempDir)
+	err = AddTemplateToModule(moduleID, cssTemplateName, tempDir, nil)
 	if err != nil {
 		t.Fatalf("AddTemplateToModule with CSS template failed: %v", err)
 	}
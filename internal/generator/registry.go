@@ -0,0 +1,148 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"go-module-builder/pkg/fsutils"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// GoModulePath is the Go module path of this project, used to import module handler packages.
+const GoModulePath = "go-module-builder"
+
+// HandlerRegistryFile is the generated file, relative to the project root, that compiles
+// module handlers into the main server.
+const HandlerRegistryFile = "cmd/server/module_handlers_gen.go"
+
+// RegisteredHandler is a module whose handler.go is compiled into the server.
+type RegisteredHandler struct {
+	ModuleID   string
+	ImportPath string
+	Alias      string // Import name used in the generated file
+	HasData    bool   // handler.go also declares func Data(*http.Request) (any, error)
+}
+
+var registryTemplate = template.Must(template.New("registry").Parse(`// Code generated by builder-cli generate-handlers; DO NOT EDIT.
+
+package main
+
+import (
+	"net/http"
+
+	"go-module-builder/pkg/modulehttp"
+{{ range . }}
+	{{ .Alias }} "{{ .ImportPath }}"
+{{- end }}
+)
+
+// moduleHandlers maps module IDs to the Handle function declared in modules/{id}/handler.go.
+var moduleHandlers = map[string]http.HandlerFunc{
+{{- range . }}
+	"{{ .ModuleID }}": {{ .Alias }}.Handle,
+{{- end }}
+}
+
+// moduleDataProviders maps module IDs to the optional Data function declared in modules/{id}/handler.go.
+var moduleDataProviders = map[string]modulehttp.DataFunc{
+{{- range . }}{{ if .HasData }}
+	"{{ .ModuleID }}": {{ .Alias }}.Data,
+{{- end }}{{ end }}
+}
+`))
+
+// GenerateHandlerRegistry scans modulesDir for module directories containing a handler.go
+// that declares func Handle(http.ResponseWriter, *http.Request) and writes HandlerRegistryFile
+// under projectRoot importing each of them, along with their optional Data functions. modulesDir must be inside projectRoot.
+// The file is only rewritten when its content changes. A nil logger discards skipped-module warnings.
+func GenerateHandlerRegistry(projectRoot, modulesDir string, logger *slog.Logger) ([]RegisteredHandler, error) {
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	rel, err := filepath.Rel(projectRoot, modulesDir)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return nil, fmt.Errorf("modules directory %s is not inside project root %s", modulesDir, projectRoot)
+	}
+
+	entries, err := os.ReadDir(modulesDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read modules directory %s: %w", modulesDir, err)
+	}
+
+	handlers := make([]RegisteredHandler, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		handlerPath := filepath.Join(modulesDir, entry.Name(), "handler.go")
+		if _, err := os.Stat(handlerPath); err != nil {
+			continue // Module without a handler
+		}
+		ok, hasData, err := inspectHandler(handlerPath)
+		if err != nil || !ok {
+			logger.Warn("Skipping module handler without a valid Handle function", "moduleID", entry.Name(), "path", handlerPath, "error", err)
+			continue
+		}
+		handlers = append(handlers, RegisteredHandler{
+			ModuleID:   entry.Name(),
+			ImportPath: GoModulePath + "/" + filepath.ToSlash(filepath.Join(rel, entry.Name())),
+			Alias:      "mod_" + nonAlphanumericPkg.ReplaceAllString(entry.Name(), "_"),
+			HasData:    hasData,
+		})
+	}
+	sort.Slice(handlers, func(i, j int) bool { return handlers[i].ModuleID < handlers[j].ModuleID })
+
+	var buf bytes.Buffer
+	if err := registryTemplate.Execute(&buf, handlers); err != nil {
+		return nil, fmt.Errorf("failed to render handler registry: %w", err)
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format handler registry: %w", err)
+	}
+
+	outPath := filepath.Join(projectRoot, HandlerRegistryFile)
+	if existing, err := os.ReadFile(outPath); err == nil && bytes.Equal(existing, src) {
+		logger.Debug("Handler registry unchanged", "path", outPath, "handlers", len(handlers))
+		return handlers, nil
+	}
+	if err := fsutils.WriteFileAtomic(outPath, src, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write handler registry: %w", err)
+	}
+	logger.Info("Wrote module handler registry", "path", outPath, "handlers", len(handlers))
+	return handlers, nil
+}
+
+// inspectHandler reports whether the Go file declares a package-level
+// func Handle(w http.ResponseWriter, r *http.Request), and whether it also declares
+// func Data(r *http.Request) (any, error). Only the number of parameters and results is checked.
+func inspectHandler(path string) (hasHandle, hasData bool, err error) {
+	file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.SkipObjectResolution)
+	if err != nil {
+		return false, false, err
+	}
+	if file.Name.Name == "main" {
+		return false, false, fmt.Errorf("package main cannot be imported by the server")
+	}
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil {
+			continue
+		}
+		switch fn.Name.Name {
+		case "Handle":
+			hasHandle = fn.Type.Results == nil && fn.Type.Params.NumFields() == 2
+		case "Data":
+			hasData = fn.Type.Params.NumFields() == 1 && fn.Type.Results.NumFields() == 2
+		}
+	}
+	return hasHandle, hasData, nil
+}
@@ -0,0 +1,103 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateHandlerRegistry(t *testing.T) {
+	projectRoot := t.TempDir()
+	modulesDir := filepath.Join(projectRoot, "modules")
+	if err := os.MkdirAll(filepath.Join(projectRoot, "cmd", "server"), 0755); err != nil {
+		t.Fatalf("Failed to create server directory: %v", err)
+	}
+
+	// A module generated with the default boilerplate
+	if _, err := GenerateModuleBoilerplate(DefaultGeneratorConfig(modulesDir), "Contact Form", "b-module", ""); err != nil {
+		t.Fatalf("GenerateModuleBoilerplate failed: %v", err)
+	}
+	// A module that also provides template data
+	writeFile(t, filepath.Join(modulesDir, "d-module", "handler.go"), `package dmodule
+
+import "net/http"
+
+func Handle(w http.ResponseWriter, r *http.Request) {}
+
+func Data(r *http.Request) (any, error) { return nil, nil }
+`)
+	// A module whose handler.go lacks a Handle function, and one without handler.go
+	writeFile(t, filepath.Join(modulesDir, "a-module", "handler.go"), "package amodule\n\nfunc Serve() {}\n")
+	if err := os.MkdirAll(filepath.Join(modulesDir, "c-module", "templates"), 0755); err != nil {
+		t.Fatalf("Failed to create module directory: %v", err)
+	}
+
+	handlers, err := GenerateHandlerRegistry(projectRoot, modulesDir, nil)
+	if err != nil {
+		t.Fatalf("GenerateHandlerRegistry failed: %v", err)
+	}
+	if len(handlers) != 2 || handlers[0].ModuleID != "b-module" || handlers[1].ModuleID != "d-module" {
+		t.Fatalf("Expected b-module and d-module to be registered, got %+v", handlers)
+	}
+	if handlers[0].HasData || !handlers[1].HasData {
+		t.Errorf("Expected only d-module to have a Data function, got %+v", handlers)
+	}
+	if want := GoModulePath + "/modules/b-module"; handlers[0].ImportPath != want {
+		t.Errorf("ImportPath = %q, want %q", handlers[0].ImportPath, want)
+	}
+
+	outPath := filepath.Join(projectRoot, HandlerRegistryFile)
+	content, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("Registry file was not written: %v", err)
+	}
+	for _, want := range []string{
+		"// Code generated by builder-cli generate-handlers; DO NOT EDIT.",
+		`mod_b_module "go-module-builder/modules/b-module"`,
+		`"b-module": mod_b_module.Handle,`,
+		`"d-module": mod_d_module.Data,`,
+	} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("Registry file missing %q:\n%s", want, content)
+		}
+	}
+	if strings.Contains(string(content), "mod_b_module.Data") {
+		t.Errorf("Registry file registers a Data function b-module does not declare:\n%s", content)
+	}
+	if strings.Contains(string(content), "a-module") || strings.Contains(string(content), "c-module") {
+		t.Errorf("Registry file includes modules without a valid handler:\n%s", content)
+	}
+
+	// Regenerating without changes must not rewrite the file
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(outPath, old, old); err != nil {
+		t.Fatalf("Chtimes failed: %v", err)
+	}
+	if _, err := GenerateHandlerRegistry(projectRoot, modulesDir, nil); err != nil {
+		t.Fatalf("Second GenerateHandlerRegistry failed: %v", err)
+	}
+	info, err := os.Stat(outPath)
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if !info.ModTime().Equal(old) {
+		t.Error("Unchanged registry file was rewritten")
+	}
+
+	// modulesDir outside the project cannot be imported
+	if _, err := GenerateHandlerRegistry(projectRoot, t.TempDir(), nil); err == nil {
+		t.Error("Expected an error for a modules directory outside the project root")
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory for %s: %v", path, err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
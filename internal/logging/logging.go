@@ -0,0 +1,52 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Supported values for the logging.format config key.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Defaults used when the logging section of config.yaml is missing.
+const (
+	DefaultLevel  = "info"
+	DefaultFormat = FormatText
+)
+
+// New builds a logger writing to w from the logging.level ("debug", "info", "warn",
+// "error") and logging.format ("text" or "json") config values. Empty values select
+// DefaultLevel and DefaultFormat.
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", FormatText:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case FormatJSON:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q (expected %q or %q)", format, FormatText, FormatJSON)
+	}
+}
+
+// ParseLevel converts a config level name (case-insensitive) to a slog.Level.
+func ParseLevel(level string) (slog.Level, error) {
+	if strings.TrimSpace(level) == "" {
+		level = DefaultLevel
+	}
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(strings.TrimSpace(level))); err != nil {
+		return 0, fmt.Errorf("unknown log level %q (expected debug, info, warn or error)", level)
+	}
+	return lvl, nil
+}
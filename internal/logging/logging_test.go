@@ -0,0 +1,76 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNew_Formats(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "info", "json")
+	if err != nil {
+		t.Fatalf("New(json) failed: %v", err)
+	}
+	logger.Info("hello", "moduleID", "abc")
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("JSON format produced invalid JSON %q: %v", buf.String(), err)
+	}
+	if record["msg"] != "hello" || record["moduleID"] != "abc" {
+		t.Errorf("Unexpected JSON record: %v", record)
+	}
+
+	buf.Reset()
+	logger, err = New(&buf, "", "")
+	if err != nil {
+		t.Fatalf("New() with defaults failed: %v", err)
+	}
+	logger.Info("hello")
+	if !strings.Contains(buf.String(), "msg=hello") {
+		t.Errorf("Default format is not text: %q", buf.String())
+	}
+
+	if _, err := New(&buf, "info", "xml"); err == nil {
+		t.Error("New() with an unknown format did not return an error")
+	}
+}
+
+func TestNew_Level(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "WARN", "text")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	logger.Info("dropped")
+	logger.Warn("kept")
+	if strings.Contains(buf.String(), "dropped") || !strings.Contains(buf.String(), "kept") {
+		t.Errorf("Level warn not applied, output: %q", buf.String())
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		in   string
+		want slog.Level
+	}{
+		{"", slog.LevelInfo},
+		{"debug", slog.LevelDebug},
+		{" Error ", slog.LevelError},
+	}
+	for _, tt := range tests {
+		got, err := ParseLevel(tt.in)
+		if err != nil {
+			t.Errorf("ParseLevel(%q) failed: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseLevel(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("ParseLevel() with an unknown level did not return an error")
+	}
+}
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// AuditEntry records one change to a module: who made it, what it was, and a short
+// summary of the module before and after.
+type AuditEntry struct {
+	Time     time.Time `json:"time"`
+	Actor    string    `json:"actor"`  // Admin username, or "cli:<os user>" for builder-cli
+	Action   string    `json:"action"` // e.g. "create", "delete", "save-template"
+	ModuleID string    `json:"moduleId"`
+	Before   string    `json:"before,omitempty"` // Summary of what changed, as it was
+	After    string    `json:"after,omitempty"`  // ...and as it is now
+}
@@ -1,6 +1,29 @@
 package model
 
-import "time"
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// ModuleStatus is the lifecycle state of a module.
+type ModuleStatus string
+
+const (
+	StatusDraft     ModuleStatus = "draft"     // Being built; never served yet
+	StatusPublished ModuleStatus = "published" // Served by the main server
+	StatusDisabled  ModuleStatus = "disabled"  // Taken offline, kept in place
+	StatusArchived  ModuleStatus = "archived"  // Soft-deleted; files moved to modules_removed/
+)
+
+// Valid reports whether s is one of the known statuses.
+func (s ModuleStatus) Valid() bool {
+	switch s {
+	case StatusDraft, StatusPublished, StatusDisabled, StatusArchived:
+		return true
+	}
+	return false
+}
 
 // Template represents a single template file (HTML, CSS, etc.) within a module.
 type Template struct {
@@ -17,19 +40,78 @@ type Template struct {
 // Module represents a self-contained component or website section,
 // including its metadata stored in the corresponding JSON file.
 type Module struct {
-	ID        string `json:"id"`        // Unique identifier for the module
-	Name      string `json:"name"`      // User-friendly name (e.g., "Product Card", "Header")
-	Directory string `json:"directory"` // Path to the module's root directory
-	// Status      string     `json:"status"`    // Status of the module (e.g., "active", "removed") - Consider if IsActive replaces this need - REMOVED, use IsActive
-	Order       int        `json:"order"` // Order for display/processing
-	CreatedAt   time.Time  `json:"createdAt"`
-	LastUpdated time.Time  `json:"lastUpdated"`
-	IsActive    bool       `json:"is_active"`             // Whether the module is enabled (true) or disabled (false)
-	Slug        string     `json:"slug,omitempty"`        // URL-friendly identifier (e.g., "my-module-name")
-	Group       string     `json:"group,omitempty"`       // Group this module belongs to
-	Layout      string     `json:"layout,omitempty"`      // Specific layout file override (relative path from web/templates?)
-	Assets      []string   `json:"assets,omitempty"`      // List of global asset identifiers associated
-	Templates   []Template `json:"templates"`             // List of templates belonging to this module (Loaded dynamically, might not be saved in meta JSON)
-	Description string     `json:"description,omitempty"` // Optional description (Moved from ModuleMeta)
+	ID          string       `json:"id"`        // Unique identifier for the module
+	Name        string       `json:"name"`      // User-friendly name (e.g., "Product Card", "Header")
+	Directory   string       `json:"directory"` // Path to the module's root directory
+	Order       int          `json:"order"`     // Order for display/processing
+	CreatedAt   time.Time    `json:"createdAt"`
+	LastUpdated time.Time    `json:"lastUpdated"`
+	Status      ModuleStatus `json:"status"`                // Lifecycle state; only published modules are served
+	Slug        string       `json:"slug,omitempty"`        // URL-friendly identifier (e.g., "my-module-name")
+	OldSlugs    []string     `json:"oldSlugs,omitempty"`    // Slugs the module had before; the server redirects them to Slug
+	Group       string       `json:"group,omitempty"`       // Group this module belongs to
+	Layout      string       `json:"layout,omitempty"`      // Specific layout file override (relative path from web/templates?)
+	Assets      []string     `json:"assets,omitempty"`      // List of global asset identifiers associated
+	Templates   []Template   `json:"templates"`             // List of templates belonging to this module (Loaded dynamically, might not be saved in meta JSON)
+	Description string       `json:"description,omitempty"` // Optional description (Moved from ModuleMeta)
+	Revision    int          `json:"revision"`              // Incremented by every save; used to detect concurrent edits
+	DataSource  *DataSource  `json:"dataSource,omitempty"`  // Where the data passed to the templates as .Data comes from
+	PublishAt   *time.Time   `json:"publishAt,omitempty"`   // A published module is served from this time on (nil: immediately)
+	UnpublishAt *time.Time   `json:"unpublishAt,omitempty"` // A published module is no longer served from this time on (nil: never)
 	// Add other metadata as needed, e.g., version, author, tags
 }
+
+// IsPublished reports whether the module is served by the main server.
+func (m *Module) IsPublished() bool {
+	return m.Status == StatusPublished
+}
+
+// IsLive reports whether the module is served at now: it is published and now falls
+// within its PublishAt/UnpublishAt schedule.
+func (m *Module) IsLive(now time.Time) bool {
+	if !m.IsPublished() {
+		return false
+	}
+	if m.PublishAt != nil && now.Before(*m.PublishAt) {
+		return false
+	}
+	return m.UnpublishAt == nil || now.Before(*m.UnpublishAt)
+}
+
+// IsArchived reports whether the module is soft-deleted.
+func (m *Module) IsArchived() bool {
+	return m.Status == StatusArchived
+}
+
+// UnmarshalJSON decodes module metadata, deriving the Status of metadata written
+// before it existed from the legacy is_active flag: active modules are published,
+// inactive ones in modules_removed/ archived, and other inactive ones disabled.
+// The status is stored with the module's next save.
+func (m *Module) UnmarshalJSON(data []byte) error {
+	type plain Module // Without this method, to avoid recursion
+	aux := struct {
+		*plain
+		LegacyIsActive *bool `json:"is_active"`
+	}{plain: (*plain)(m)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if m.Status == "" {
+		switch {
+		case aux.LegacyIsActive != nil && *aux.LegacyIsActive:
+			m.Status = StatusPublished
+		case strings.Contains(m.Directory, "modules_removed"):
+			m.Status = StatusArchived
+		default:
+			m.Status = StatusDisabled
+		}
+	}
+	return nil
+}
+
+// DataSource declares the data a module's templates receive as .Data.
+// At most one of File and URL is set.
+type DataSource struct {
+	File string `json:"file,omitempty"` // JSON or YAML file relative to the module directory (e.g. "data.json")
+	URL  string `json:"url,omitempty"`  // Local (loopback) HTTP endpoint returning JSON
+}
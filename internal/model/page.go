@@ -0,0 +1,40 @@
+package model
+
+import (
+	"sort"
+	"time"
+)
+
+// ModuleInstance places a reusable Module on a Page.
+// The same Module may appear on many Pages (or several times on one Page),
+// each instance carrying its own configuration.
+type ModuleInstance struct {
+	ModuleID string         `json:"moduleId"`         // ID of the Module to render
+	Order    int            `json:"order"`            // Render order within the Page (ascending)
+	Config   map[string]any `json:"config,omitempty"` // Instance-specific configuration passed to the Module's templates
+}
+
+// Page represents a URL-addressable page composed of Module instances
+// rendered in order inside a layout. Its metadata is stored alongside,
+// but separately from, module metadata.
+type Page struct {
+	ID          string           `json:"id"`               // Unique identifier for the page
+	Name        string           `json:"name"`             // User-friendly name (e.g., "Homepage")
+	Slug        string           `json:"slug"`             // URL-friendly identifier the page is served under
+	Layout      string           `json:"layout,omitempty"` // Layout override (empty means the default layout)
+	Modules     []ModuleInstance `json:"modules"`          // Module instances making up the page
+	CreatedAt   time.Time        `json:"createdAt"`
+	LastUpdated time.Time        `json:"lastUpdated"`
+	IsActive    bool             `json:"is_active"` // Whether the page is served (true) or hidden (false)
+}
+
+// SortedModules returns a copy of the page's module instances sorted by Order.
+// Instances sharing the same Order keep their stored relative position.
+func (p *Page) SortedModules() []ModuleInstance {
+	instances := make([]ModuleInstance, len(p.Modules))
+	copy(instances, p.Modules)
+	sort.SliceStable(instances, func(i, j int) bool {
+		return instances[i].Order < instances[j].Order
+	})
+	return instances
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// SnapshotInfo describes a recorded version of a module without its contents.
+type SnapshotInfo struct {
+	ID        string    `json:"id"`       // Sortable, time-based identifier (unique per module)
+	ModuleID  string    `json:"moduleId"` // Module the snapshot belongs to
+	CreatedAt time.Time `json:"createdAt"`
+	Reason    string    `json:"reason,omitempty"` // What triggered it (e.g., "update metadata", "save content.html")
+}
+
+// Snapshot is a point-in-time copy of a module's metadata and template file contents.
+type Snapshot struct {
+	SnapshotInfo
+	Module Module            `json:"module"` // Metadata as it was when the snapshot was taken
+	Files  map[string]string `json:"files"`  // Template filename -> file content
+}
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+)
+
+// Role decides what an admin account may do. Each role includes the ones before it.
+type Role string
+
+const (
+	RoleViewer    Role = "viewer"    // Open modules and their templates read-only
+	RoleEditor    Role = "editor"    // Create modules and change their templates and layout
+	RolePublisher Role = "publisher" // Publish, disable, schedule, archive and restore modules
+	RoleAdmin     Role = "admin"     // Force delete (purge) archived modules
+)
+
+// Roles lists every role from least to most privileged.
+var Roles = []Role{RoleViewer, RoleEditor, RolePublisher, RoleAdmin}
+
+// ParseRole returns the role named s.
+func ParseRole(s string) (Role, error) {
+	if !slices.Contains(Roles, Role(s)) {
+		return "", fmt.Errorf("invalid role %q: use one of %v", s, Roles)
+	}
+	return Role(s), nil
+}
+
+// Includes reports whether r grants everything required does.
+func (r Role) Includes(required Role) bool {
+	return slices.Index(Roles, r) >= slices.Index(Roles, required)
+}
+
+// User is an account that can sign in to the admin server.
+type User struct {
+	Username          string    `json:"username"`     // Unique login name
+	PasswordHash      string    `json:"passwordHash"` // bcrypt hash; the password itself is never stored
+	CreatedAt         time.Time `json:"createdAt"`
+	PasswordChangedAt time.Time `json:"passwordChangedAt"` // Sessions started before this are no longer valid
+	Role              Role      `json:"role,omitempty"`    // Empty for accounts created before roles existed, which act as admins
+	Groups            []string  `json:"groups,omitempty"`  // If set, the account may only work on modules in these groups
+}
+
+// EffectiveRole returns the user's role, treating accounts without one as admins.
+func (u *User) EffectiveRole() Role {
+	if u.Role == "" {
+		return RoleAdmin
+	}
+	return u.Role
+}
+
+// Can reports whether the user has role on module. A nil module stands for an action
+// that is not tied to one module, such as creating or importing modules, which users
+// limited to groups may not perform.
+func (u *User) Can(role Role, module *Module) bool {
+	if !u.EffectiveRole().Includes(role) {
+		return false
+	}
+	if len(u.Groups) == 0 {
+		return true
+	}
+	return module != nil && slices.Contains(u.Groups, module.Group)
+}
+
+// usernamePattern keeps usernames safe to use as file names.
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]{0,63}$`)
+
+// ValidateUsername checks that username is 1-64 letters, digits, dots, underscores
+// or hyphens, starting with a letter or digit.
+func ValidateUsername(username string) error {
+	if !usernamePattern.MatchString(username) {
+		return fmt.Errorf("invalid username %q: use 1-64 letters, digits, '.', '_' or '-', starting with a letter or digit", username)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package model
+
+import "testing"
+
+func TestUserCan(t *testing.T) {
+	marketing := &Module{ID: "m1", Group: "marketing"}
+	docs := &Module{ID: "m2", Group: "docs"}
+
+	tests := []struct {
+		name   string
+		user   User
+		role   Role
+		module *Module
+		want   bool
+	}{
+		{"viewer views", User{Role: RoleViewer}, RoleViewer, marketing, true},
+		{"viewer cannot edit", User{Role: RoleViewer}, RoleEditor, marketing, false},
+		{"editor edits", User{Role: RoleEditor}, RoleEditor, marketing, true},
+		{"editor cannot publish", User{Role: RoleEditor}, RolePublisher, marketing, false},
+		{"publisher cannot purge", User{Role: RolePublisher}, RoleAdmin, marketing, false},
+		{"admin purges", User{Role: RoleAdmin}, RoleAdmin, marketing, true},
+		{"account without role acts as admin", User{}, RoleAdmin, marketing, true},
+		{"editor creates modules", User{Role: RoleEditor}, RoleEditor, nil, true},
+		{"group editor edits own group", User{Role: RoleEditor, Groups: []string{"marketing"}}, RoleEditor, marketing, true},
+		{"group editor cannot view other group", User{Role: RoleEditor, Groups: []string{"marketing"}}, RoleViewer, docs, false},
+		{"group editor cannot create modules", User{Role: RoleEditor, Groups: []string{"marketing"}}, RoleEditor, nil, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.user.Can(tc.role, tc.module); got != tc.want {
+				t.Errorf("Can(%s, %v) = %v, want %v", tc.role, tc.module, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseRole(t *testing.T) {
+	if role, err := ParseRole("publisher"); err != nil || role != RolePublisher {
+		t.Errorf("ParseRole(publisher) = %q, %v", role, err)
+	}
+	if _, err := ParseRole("owner"); err == nil {
+		t.Error("ParseRole(owner) did not return an error")
+	}
+}
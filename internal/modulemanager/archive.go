@@ -0,0 +1,390 @@
+package modulemanager
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-module-builder/internal/dataprovider"
+	"go-module-builder/internal/model"
+	"go-module-builder/internal/storage"
+	"go-module-builder/internal/templating"
+	"go-module-builder/pkg/fsutils"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// ArchiveFormat is the version of the module archive layout written by ExportModule.
+	ArchiveFormat = 1
+	// MaxArchiveSize limits the total uncompressed size of an imported archive.
+	MaxArchiveSize = 64 << 20
+
+	archiveManifest = "manifest.json" // ArchiveManifest, not itself checksummed
+	archiveModule   = "module.json"   // The module's metadata
+	archiveFilesDir = "files/"        // Prefix of the contents of modules/{id}/
+)
+
+// ArchiveManifest describes the contents of a module archive.
+type ArchiveManifest struct {
+	Format     int           `json:"format"`
+	ModuleID   string        `json:"moduleId"`
+	ModuleName string        `json:"moduleName"`
+	ExportedAt time.Time     `json:"exportedAt"`
+	Files      []ArchiveFile `json:"files"` // Every entry other than the manifest, sorted by path
+}
+
+// ArchiveFile is an archive entry with its checksum.
+type ArchiveFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// ImportResult describes an imported module and what had to change to fit this project.
+type ImportResult struct {
+	Module         *model.Module
+	OriginalID     string   // ID in the archive; differs from Module.ID if it was taken
+	OriginalSlug   string   // Slug in the archive; differs from Module.Slug if it was taken
+	OriginalLayout string   // Layout in the archive; differs from Module.Layout if this project lacks it
+	DroppedFiles   []string // Go files in the archive, which are never imported (see isGoFile)
+}
+
+// ExportModule writes a zip archive of the module to w: its metadata, every file in
+// its directory, and a manifest with their SHA-256 checksums.
+func (m *ModuleManager) ExportModule(moduleID string, w io.Writer) error {
+	m.logger.Info("Exporting module", "moduleID", moduleID)
+
+	// 1. Load the module metadata
+	module, err := m.store.LoadModule(moduleID)
+	if err != nil {
+		m.logger.Error("Error loading module metadata for export", "moduleID", moduleID, "error", err)
+		return fmt.Errorf("loading module metadata failed for ID %s: %w", moduleID, err)
+	}
+	metadata, err := json.MarshalIndent(module, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding module metadata failed for ID %s: %w", moduleID, err)
+	}
+
+	// 2. Collect the archive entries
+	entries := map[string][]byte{archiveModule: metadata}
+	dir := m.moduleDir(module)
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		entries[archiveFilesDir+filepath.ToSlash(rel)] = content
+		return nil
+	})
+	if err != nil {
+		m.logger.Error("Error reading module directory for export", "moduleID", moduleID, "path", dir, "error", err)
+		return fmt.Errorf("reading module directory %s failed: %w", dir, err)
+	}
+
+	// 3. Write the manifest, then the entries in its order
+	manifest := ArchiveManifest{Format: ArchiveFormat, ModuleID: module.ID, ModuleName: module.Name, ExportedAt: time.Now().UTC()}
+	for p, content := range entries {
+		sum := sha256.Sum256(content)
+		manifest.Files = append(manifest.Files, ArchiveFile{Path: p, Size: int64(len(content)), SHA256: hex.EncodeToString(sum[:])})
+	}
+	sort.Slice(manifest.Files, func(i, j int) bool { return manifest.Files[i].Path < manifest.Files[j].Path })
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding archive manifest failed: %w", err)
+	}
+
+	zw := zip.NewWriter(w)
+	if err := writeZipEntry(zw, archiveManifest, manifestJSON, manifest.ExportedAt); err != nil {
+		return err
+	}
+	for _, f := range manifest.Files {
+		if err := writeZipEntry(zw, f.Path, entries[f.Path], manifest.ExportedAt); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("finishing module archive failed: %w", err)
+	}
+
+	m.logger.Info("Successfully exported module", "moduleID", moduleID, "files", len(manifest.Files))
+	return nil
+}
+
+func writeZipEntry(zw *zip.Writer, name string, content []byte, modified time.Time) error {
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return fmt.Errorf("adding %s to module archive failed: %w", name, err)
+	}
+	if _, err := f.Write(content); err != nil {
+		return fmt.Errorf("writing %s to module archive failed: %w", name, err)
+	}
+	return nil
+}
+
+// ImportModule adds the module in a zip archive written by ExportModule to this project.
+// Every entry is checked against the manifest, and the metadata validated, before
+// anything is written. The module gets a new ID if its ID (or directory) is taken, and a
+// numbered slug ("about-2") if another module or a page uses its slug or it is reserved. A layout
+// this project lacks is reset to the default. Go files, such as handler.go, are dropped:
+// they would be compiled into the main server, so only code added to the project by its
+// developers is. Imported modules start as drafts, with no history.
+func (m *ModuleManager) ImportModule(r io.ReaderAt, size int64) (*ImportResult, error) {
+	m.logger.Info("Importing module archive", "bytes", size)
+
+	// 1. Read and verify the archive
+	manifest, module, files, err := readModuleArchive(r, size)
+	if err != nil {
+		m.logger.Warn("Rejected module archive", "error", err)
+		return nil, err
+	}
+	result := &ImportResult{Module: module, OriginalID: module.ID, OriginalSlug: module.Slug, OriginalLayout: module.Layout}
+	if err := m.checkImportedModule(module); err != nil {
+		m.logger.Warn("Rejected module archive metadata", "moduleID", module.ID, "error", err)
+		return nil, err
+	}
+	for rel := range files {
+		if isGoFile(rel) {
+			delete(files, rel)
+			result.DroppedFiles = append(result.DroppedFiles, rel)
+		}
+	}
+	sort.Strings(result.DroppedFiles)
+	if len(result.DroppedFiles) > 0 {
+		m.logger.Warn("Dropped Go files from module archive", "moduleID", module.ID, "files", result.DroppedFiles)
+	}
+	m.logger.Debug("Verified module archive", "moduleID", manifest.ModuleID, "files", len(files))
+
+	// 2. Pick an ID and slug that are free in this project
+	existing, err := m.store.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("reading existing modules failed: %w", err)
+	}
+	ids := make(map[string]bool, len(existing))
+	slugs := make(map[string]bool, len(existing)+len(ReservedSlugs))
+	for _, slug := range ReservedSlugs {
+		slugs[slug] = true
+	}
+	for _, mod := range existing {
+		ids[mod.ID] = true
+		slugs[mod.Slug] = true
+	}
+	if m.pages != nil {
+		pages, err := m.pages.ReadAllPages()
+		if corrupt, ok := storage.AsCorruptPages(err); ok {
+			m.logger.Warn("Skipping pages with unreadable metadata during import", "pageIDs", corrupt.IDs(), "error", err)
+			err = nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading existing pages failed: %w", err)
+		}
+		for _, page := range pages {
+			slugs[page.Slug] = true
+		}
+	}
+	if _, err := os.Stat(filepath.Join(m.modulesDir, module.ID)); ids[module.ID] || err == nil {
+		module.ID = uuid.New().String()
+		m.logger.Info("Module ID already in use, assigned a new one", "originalID", result.OriginalID, "moduleID", module.ID)
+		if module.Slug == result.OriginalID {
+			module.Slug = module.ID // Slugs default to the ID
+		}
+	}
+	if slugs[module.Slug] {
+		base := module.Slug
+		for n := 2; slugs[module.Slug]; n++ {
+			module.Slug = fmt.Sprintf("%s-%d", base, n)
+		}
+		m.logger.Info("Module slug already in use, assigned a new one", "originalSlug", base, "slug", module.Slug)
+	}
+
+	// 3. Extract the files next to the modules, then move them in place
+	if err := fsutils.CreateDir(m.modulesDir); err != nil {
+		return nil, fmt.Errorf("creating modules directory failed: %w", err)
+	}
+	tmpDir, err := os.MkdirTemp(m.modulesDir, ".import-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating import directory failed: %w", err)
+	}
+	defer os.RemoveAll(tmpDir) // Only left over if the import failed
+	for rel, content := range files {
+		target := filepath.Join(tmpDir, filepath.FromSlash(rel))
+		if err := fsutils.CreateDir(filepath.Dir(target)); err != nil {
+			return nil, fmt.Errorf("creating directory for %s failed: %w", rel, err)
+		}
+		if err := fsutils.WriteToFile(target, content); err != nil {
+			return nil, fmt.Errorf("writing %s failed: %w", rel, err)
+		}
+	}
+	moduleDir := filepath.Join(m.modulesDir, module.ID)
+	if err := os.Rename(tmpDir, moduleDir); err != nil {
+		return nil, fmt.Errorf("moving imported files to %s failed: %w", moduleDir, err)
+	}
+
+	// 4. Save the metadata as a new module
+	module.Directory = moduleDir
+	module.Status = model.StatusDraft
+	module.OldSlugs = nil // Links to the exporting site do not point here
+	module.Revision = 0
+	module.LastUpdated = time.Now()
+	if err := m.store.SaveModule(module); err != nil {
+		m.logger.Error("Error saving imported module metadata", "moduleID", module.ID, "error", err)
+		os.RemoveAll(moduleDir)
+		return nil, fmt.Errorf("saving module metadata failed: %w", err)
+	}
+
+	m.logger.Info("Successfully imported module", "moduleID", module.ID, "name", module.Name, "slug", module.Slug, "originalID", result.OriginalID)
+	m.RecordAudit("import", module.ID, "archive of "+result.OriginalID, moduleSummary(module))
+	return result, nil // No handler.go was imported, so the handler registry is unchanged
+}
+
+// checkImportedModule validates the metadata of an archived module the way the manager
+// validates each field when it is set: template names, slug format, group, data source
+// and layout. Template paths are normalized to templates/<name>, and a well-formed
+// layout missing from this project is reset to the default.
+func (m *ModuleManager) checkImportedModule(module *model.Module) error {
+	for i, t := range module.Templates {
+		if _, err := fsutils.ParseTemplateFilename(t.Name); err != nil {
+			return fmt.Errorf("module archive lists an invalid template: %w", err)
+		}
+		if filepath.ToSlash(t.Path) != "templates/"+t.Name {
+			return fmt.Errorf("module archive lists template %s at invalid path %q", t.Name, t.Path)
+		}
+		module.Templates[i].Path = filepath.Join("templates", t.Name)
+	}
+	if err := checkSlugFormat(module.Slug); err != nil {
+		return fmt.Errorf("module archive has an %w", err)
+	}
+	if err := checkGroup(module.Group); err != nil {
+		return fmt.Errorf("module archive has an %w", err)
+	}
+	if module.DataSource != nil {
+		if err := dataprovider.Validate(module.DataSource); err != nil {
+			return fmt.Errorf("module archive has an invalid data source: %w", err)
+		}
+	}
+	if module.Layout != "" {
+		if err := templating.ValidateLayout(m.LayoutsDir(), module.Layout); err != nil {
+			if filepath.Base(module.Layout) != module.Layout || filepath.Ext(module.Layout) != ".html" {
+				return fmt.Errorf("module archive has an %w", err)
+			}
+			m.logger.Warn("Layout of imported module not found, using the default", "moduleID", module.ID, "layout", module.Layout)
+			module.Layout = ""
+		}
+	}
+	return nil
+}
+
+// isGoFile reports whether the archive file rel is Go source or a Go module file, which
+// an import never writes into the project.
+func isGoFile(rel string) bool {
+	base := path.Base(rel)
+	return strings.HasSuffix(base, ".go") || base == "go.mod" || base == "go.work"
+}
+
+// readModuleArchive reads a module archive, checking that its entries are exactly
+// those in the manifest, with matching checksums and safe paths. It returns the
+// module files keyed by their path relative to the module directory.
+func readModuleArchive(r io.ReaderAt, size int64) (*ArchiveManifest, *model.Module, map[string][]byte, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("not a module archive: %w", err)
+	}
+
+	var total int64
+	entries := make(map[string][]byte, len(zr.File))
+	for _, f := range zr.File {
+		if strings.HasSuffix(f.Name, "/") {
+			continue // Directory entry
+		}
+		if _, dup := entries[f.Name]; dup {
+			return nil, nil, nil, fmt.Errorf("module archive contains %s twice", f.Name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("reading %s from module archive failed: %w", f.Name, err)
+		}
+		content, err := io.ReadAll(io.LimitReader(rc, MaxArchiveSize-total+1))
+		rc.Close()
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("reading %s from module archive failed: %w", f.Name, err)
+		}
+		if total += int64(len(content)); total > MaxArchiveSize {
+			return nil, nil, nil, fmt.Errorf("module archive is larger than %d bytes uncompressed", MaxArchiveSize)
+		}
+		entries[f.Name] = content
+	}
+
+	manifestJSON, ok := entries[archiveManifest]
+	if !ok {
+		return nil, nil, nil, errors.New("module archive has no " + archiveManifest)
+	}
+	delete(entries, archiveManifest)
+	var manifest ArchiveManifest
+	if err := json.Unmarshal(manifestJSON, &manifest); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid %s: %w", archiveManifest, err)
+	}
+	if manifest.Format != ArchiveFormat {
+		return nil, nil, nil, fmt.Errorf("unsupported module archive format %d (expected %d)", manifest.Format, ArchiveFormat)
+	}
+
+	// Every entry must be listed with its checksum, and every listed file present
+	if len(manifest.Files) != len(entries) {
+		return nil, nil, nil, fmt.Errorf("module archive has %d files, manifest lists %d", len(entries), len(manifest.Files))
+	}
+	files := make(map[string][]byte, len(entries))
+	for _, f := range manifest.Files {
+		content, ok := entries[f.Path]
+		if !ok {
+			return nil, nil, nil, fmt.Errorf("module archive is missing %s", f.Path)
+		}
+		sum := sha256.Sum256(content)
+		if hex.EncodeToString(sum[:]) != f.SHA256 || int64(len(content)) != f.Size {
+			return nil, nil, nil, fmt.Errorf("checksum mismatch for %s", f.Path)
+		}
+		if f.Path == archiveModule {
+			continue
+		}
+		rel, ok := strings.CutPrefix(f.Path, archiveFilesDir)
+		if !ok || !isSafeArchivePath(rel) {
+			return nil, nil, nil, fmt.Errorf("invalid path %q in module archive", f.Path)
+		}
+		files[rel] = content
+	}
+
+	metadata, ok := entries[archiveModule]
+	if !ok {
+		return nil, nil, nil, errors.New("module archive has no " + archiveModule)
+	}
+	var module model.Module
+	if err := json.Unmarshal(metadata, &module); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid %s: %w", archiveModule, err)
+	}
+	if module.ID == "" || module.ID != manifest.ModuleID || !isSafeArchivePath(module.ID) || strings.Contains(module.ID, "/") {
+		return nil, nil, nil, fmt.Errorf("invalid module ID %q in module archive", module.ID)
+	}
+	return &manifest, &module, files, nil
+}
+
+// isSafeArchivePath reports whether p is a clean relative path that stays inside the
+// directory it is extracted to.
+func isSafeArchivePath(p string) bool {
+	return p != "" && p != "." && !strings.Contains(p, `\`) && path.Clean(p) == p &&
+		!path.IsAbs(p) && p != ".." && !strings.HasPrefix(p, "../")
+}
@@ -0,0 +1,231 @@
+package modulemanager
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"go-module-builder/internal/model"
+	"go-module-builder/internal/storage"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestManager creates a manager for an empty project with a JSON store.
+func newTestManager(t testing.TB) *ModuleManager {
+	t.Helper()
+	root := t.TempDir()
+	store, err := storage.Open("json", filepath.Join(root, ".module_metadata"), nil)
+	if err != nil {
+		t.Fatalf("storage.Open failed: %v", err)
+	}
+	return NewManager(store, nil, root, filepath.Join(root, "modules"))
+}
+
+func TestExportImportModule(t *testing.T) {
+	src := newTestManager(t)
+	mod, err := src.CreateModule("Contact Card", "contact")
+	if err != nil {
+		t.Fatalf("CreateModule failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(mod.Directory, "data.json"), []byte(`{"email":"a@example.com"}`), 0644); err != nil {
+		t.Fatalf("Failed to write data file: %v", err)
+	}
+	var archive bytes.Buffer
+	if err := src.ExportModule(mod.ID, &archive); err != nil {
+		t.Fatalf("ExportModule failed: %v", err)
+	}
+
+	// Into an empty project, the module keeps its ID and slug
+	dst := newTestManager(t)
+	result, err := dst.ImportModule(bytes.NewReader(archive.Bytes()), int64(archive.Len()))
+	if err != nil {
+		t.Fatalf("ImportModule failed: %v", err)
+	}
+	if result.Module.ID != mod.ID || result.Module.Slug != "contact" || len(result.Module.Templates) != len(mod.Templates) {
+		t.Errorf("Imported module = %+v, want the exported module", result.Module)
+	}
+	got, err := os.ReadFile(filepath.Join(dst.modulesDir, mod.ID, "data.json"))
+	if err != nil || string(got) != `{"email":"a@example.com"}` {
+		t.Errorf("data.json = %q, %v; want the exported content", got, err)
+	}
+
+	// A second import collides with both
+	again, err := dst.ImportModule(bytes.NewReader(archive.Bytes()), int64(archive.Len()))
+	if err != nil {
+		t.Fatalf("Second ImportModule failed: %v", err)
+	}
+	if again.Module.ID == mod.ID || again.OriginalID != mod.ID || again.Module.Slug != "contact-2" {
+		t.Errorf("Second import got ID %q and slug %q, want a new ID and contact-2", again.Module.ID, again.Module.Slug)
+	}
+	if _, err := dst.GetStore().LoadModule(again.Module.ID); err != nil {
+		t.Errorf("Second import was not saved: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dst.modulesDir, again.Module.ID, "templates", "base.html")); err != nil {
+		t.Errorf("Second import's files were not extracted: %v", err)
+	}
+}
+
+// rewriteArchive copies a zip archive, letting edit change each entry's name and content.
+func rewriteArchive(t *testing.T, archive []byte, edit func(name string, content []byte) (string, []byte)) []byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatalf("Failed to read archive: %v", err)
+	}
+	var out bytes.Buffer
+	zw := zip.NewWriter(&out)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", f.Name, err)
+		}
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		name, content := edit(f.Name, content)
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to add %s: %v", name, err)
+		}
+		w.Write(content)
+	}
+	zw.Close()
+	return out.Bytes()
+}
+
+func TestImportModule_Rejected(t *testing.T) {
+	src := newTestManager(t)
+	mod, err := src.CreateModule("Card", "")
+	if err != nil {
+		t.Fatalf("CreateModule failed: %v", err)
+	}
+	var archive bytes.Buffer
+	if err := src.ExportModule(mod.ID, &archive); err != nil {
+		t.Fatalf("ExportModule failed: %v", err)
+	}
+
+	cases := map[string][]byte{
+		"tampered file": rewriteArchive(t, archive.Bytes(), func(name string, content []byte) (string, []byte) {
+			if name == "files/templates/base.html" {
+				return name, append(content, "<script>"...)
+			}
+			return name, content
+		}),
+		"unlisted file": rewriteArchive(t, archive.Bytes(), func(name string, content []byte) (string, []byte) {
+			if name == "files/templates/style.css" {
+				return "files/templates/other.css", content
+			}
+			return name, content
+		}),
+		"path escaping the module": rewriteArchive(t, archive.Bytes(), func(name string, content []byte) (string, []byte) {
+			rename := func(s string) string {
+				return strings.ReplaceAll(s, "files/templates/style.css", "files/../../style.css")
+			}
+			return rename(name), []byte(rename(string(content)))
+		}),
+		"not a zip": []byte("plain text"),
+	}
+	dst := newTestManager(t)
+	for name, data := range cases {
+		if _, err := dst.ImportModule(bytes.NewReader(data), int64(len(data))); err == nil {
+			t.Errorf("%s: ImportModule did not return an error", name)
+		}
+	}
+	if entries, _ := os.ReadDir(dst.modulesDir); len(entries) != 0 {
+		t.Errorf("Rejected imports left files behind: %v", entries)
+	}
+}
+
+// buildArchive writes a module archive with a manifest matching its entries, as a
+// hostile archive would, so that only the import's own checks can reject it.
+func buildArchive(t *testing.T, module *model.Module, files map[string]string) []byte {
+	t.Helper()
+	metadata, err := json.Marshal(module)
+	if err != nil {
+		t.Fatalf("Failed to encode module: %v", err)
+	}
+	entries := map[string][]byte{archiveModule: metadata}
+	for rel, content := range files {
+		entries[archiveFilesDir+rel] = []byte(content)
+	}
+	manifest := ArchiveManifest{Format: ArchiveFormat, ModuleID: module.ID, ModuleName: module.Name}
+	for p, content := range entries {
+		sum := sha256.Sum256(content)
+		manifest.Files = append(manifest.Files, ArchiveFile{Path: p, Size: int64(len(content)), SHA256: hex.EncodeToString(sum[:])})
+	}
+	manifestJSON, _ := json.Marshal(manifest)
+	var out bytes.Buffer
+	zw := zip.NewWriter(&out)
+	writeZipEntry(zw, archiveManifest, manifestJSON, time.Now())
+	for p, content := range entries {
+		writeZipEntry(zw, p, content, time.Now())
+	}
+	zw.Close()
+	return out.Bytes()
+}
+
+func TestImportModule_HostileArchive(t *testing.T) {
+	newModule := func() *model.Module {
+		return &model.Module{
+			ID:        "hostile",
+			Name:      "Hostile",
+			Slug:      "hostile",
+			Templates: []model.Template{{Name: "base.html", Path: "templates/base.html", IsBase: true, IsActive: true}},
+		}
+	}
+	files := map[string]string{"templates/base.html": `{{ define "page" }}hi{{ end }}`}
+
+	cases := map[string]func(*model.Module){
+		"template name leaving the module":  func(mod *model.Module) { mod.Templates[0].Name = "../../../cmd/server/evil.html" },
+		"template path leaving the module":  func(mod *model.Module) { mod.Templates[0].Path = "../../evil.html" },
+		"slug with a path separator":        func(mod *model.Module) { mod.Slug = "a/b" },
+		"empty slug":                        func(mod *model.Module) { mod.Slug = "" },
+		"group with control characters":     func(mod *model.Module) { mod.Group = "docs\nadmin" },
+		"data file outside the module":      func(mod *model.Module) { mod.DataSource = &model.DataSource{File: "../../.user_metadata/root.json"} },
+		"data URL of another host":          func(mod *model.Module) { mod.DataSource = &model.DataSource{URL: "http://169.254.169.254/latest"} },
+		"layout outside the layouts folder": func(mod *model.Module) { mod.Layout = "../layout.html" },
+	}
+	dst := newTestManager(t)
+	for name, edit := range cases {
+		mod := newModule()
+		edit(mod)
+		data := buildArchive(t, mod, files)
+		if _, err := dst.ImportModule(bytes.NewReader(data), int64(len(data))); err == nil {
+			t.Errorf("%s: ImportModule did not return an error", name)
+		}
+	}
+	if entries, _ := os.ReadDir(dst.modulesDir); len(entries) != 0 {
+		t.Errorf("Rejected imports left files behind: %v", entries)
+	}
+
+	// Go code is left out, so it never reaches the server build
+	mod := newModule()
+	mod.Layout = "missing.html"
+	hostile := map[string]string{
+		"templates/base.html": files["templates/base.html"],
+		"handler.go":          "package hostile\n\nfunc init() { panic(\"pwned\") }\n",
+		"internal/x/x.go":     "package x\n",
+		"go.mod":              "module hostile\n",
+	}
+	data := buildArchive(t, mod, hostile)
+	result, err := dst.ImportModule(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("ImportModule failed: %v", err)
+	}
+	if want := []string{"go.mod", "handler.go", "internal/x/x.go"}; strings.Join(result.DroppedFiles, ",") != strings.Join(want, ",") {
+		t.Errorf("DroppedFiles = %v, want %v", result.DroppedFiles, want)
+	}
+	for _, rel := range []string{"handler.go", "internal/x/x.go", "go.mod"} {
+		if _, err := os.Stat(filepath.Join(dst.modulesDir, result.Module.ID, rel)); !os.IsNotExist(err) {
+			t.Errorf("%s was imported (stat error %v)", rel, err)
+		}
+	}
+	if result.Module.Layout != "" || result.OriginalLayout != "missing.html" {
+		t.Errorf("Layout = %q (was %q), want the missing layout reset to the default", result.Module.Layout, result.OriginalLayout)
+	}
+}
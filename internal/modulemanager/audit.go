@@ -0,0 +1,90 @@
+package modulemanager
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"go-module-builder/internal/model"
+	"go-module-builder/internal/storage"
+	"strings"
+	"time"
+)
+
+// DefaultActor is recorded in the audit log for changes made through a manager that
+// was not given an actor with As.
+const DefaultActor = "system"
+
+// SetAuditLog makes the manager record every module change in audit. Without one,
+// changes are only logged.
+func (m *ModuleManager) SetAuditLog(audit storage.AuditLog) {
+	m.audit = audit
+}
+
+// AuditLog returns the audit log the manager records to, or nil.
+func (m *ModuleManager) AuditLog() storage.AuditLog {
+	return m.audit
+}
+
+// As returns a manager that records its changes in the audit log as made by actor.
+// It shares m's store and audit log, so it is cheap to create per request.
+func (m *ModuleManager) As(actor string) *ModuleManager {
+	scoped := *m
+	scoped.actor = actor
+	return &scoped
+}
+
+// RecordAudit appends an entry for a change made outside the manager, such as the
+// admin server saving a template file. Failures are logged, not returned: the change
+// has already been made.
+func (m *ModuleManager) RecordAudit(action, moduleID, before, after string) {
+	if m.audit == nil {
+		return
+	}
+	actor := m.actor
+	if actor == "" {
+		actor = DefaultActor
+	}
+	entry := &model.AuditEntry{
+		Time:     time.Now(),
+		Actor:    actor,
+		Action:   action,
+		ModuleID: moduleID,
+		Before:   before,
+		After:    after,
+	}
+	if err := m.audit.AppendAudit(entry); err != nil {
+		m.logger.Error("Error recording audit entry", "action", action, "moduleID", moduleID, "actor", actor, "error", err)
+	}
+}
+
+// moduleSummary describes a module's metadata in one line for the audit log. Unset
+// optional fields are left out.
+func moduleSummary(module *model.Module) string {
+	if module == nil {
+		return ""
+	}
+	parts := []string{
+		fmt.Sprintf("name=%q", module.Name),
+		"slug=" + module.Slug,
+		"status=" + string(module.Status),
+	}
+	for _, field := range []struct{ name, value string }{
+		{"group", module.Group},
+		{"layout", module.Layout},
+		{"publishAt", timeString(module.PublishAt)},
+		{"unpublishAt", timeString(module.UnpublishAt)},
+		{"data", dataSourceString(module.DataSource)},
+	} {
+		if field.value != "" {
+			parts = append(parts, fmt.Sprintf("%s=%q", field.name, field.value))
+		}
+	}
+	parts = append(parts, fmt.Sprintf("templates=[%s]", templateNames(module.Templates)))
+	return strings.Join(parts, " ")
+}
+
+// TemplateSummary describes a template file's content for the audit log by its size
+// and a short hash, so saves that changed the file can be told apart.
+func TemplateSummary(filename string, content []byte) string {
+	sum := sha256.Sum256(content)
+	return fmt.Sprintf("%s (%d bytes, sha256 %x)", filename, len(content), sum[:6])
+}
@@ -0,0 +1,59 @@
+package modulemanager
+
+import (
+	"go-module-builder/internal/storage"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestModuleChangesAreAudited(t *testing.T) {
+	m := newTestManager(t)
+	audit, err := storage.NewJSONAuditLog(filepath.Join(m.GetProjectRoot(), storage.DefaultAuditLogFile))
+	if err != nil {
+		t.Fatalf("NewJSONAuditLog failed: %v", err)
+	}
+	m.SetAuditLog(audit)
+
+	alice := m.As("alice")
+	mod, err := alice.CreateModule("About", "about")
+	if err != nil {
+		t.Fatalf("CreateModule failed: %v", err)
+	}
+	if _, err := m.As("bob").AddTemplate(mod.ID, "card.html"); err != nil {
+		t.Fatalf("AddTemplate failed: %v", err)
+	}
+	if err := alice.UpdateModule(mod.ID, "About Us", "", "", "", ""); err != nil {
+		t.Fatalf("UpdateModule failed: %v", err)
+	}
+	if err := m.DeleteModule(mod.ID, false); err != nil {
+		t.Fatalf("DeleteModule failed: %v", err)
+	}
+	if _, err := alice.CreateModule("Reserved", "static"); err == nil {
+		t.Fatal("CreateModule with a reserved slug did not return an error")
+	}
+
+	entries, err := audit.QueryAudit(storage.AuditFilter{ModuleID: mod.ID})
+	if err != nil {
+		t.Fatalf("QueryAudit failed: %v", err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Actor+" "+e.Action)
+	}
+	want := []string{"system delete", "alice update", "bob add-template", "alice create"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Fatalf("Audit entries = %v, want %v", got, want)
+	}
+
+	update := entries[1]
+	if !strings.Contains(update.Before, `name="About"`) || !strings.Contains(update.After, `name="About Us"`) {
+		t.Errorf("Update entry before %q, after %q; want the old and new name", update.Before, update.After)
+	}
+	if add := entries[2]; strings.Contains(add.Before, "card.html") || !strings.Contains(add.After, "card.html") {
+		t.Errorf("Add-template entry before %q, after %q; want card.html added", add.Before, add.After)
+	}
+	if all, _ := audit.QueryAudit(storage.AuditFilter{}); len(all) != len(entries) {
+		t.Errorf("Audit log has %d entries, want %d (failed changes are not recorded)", len(all), len(entries))
+	}
+}
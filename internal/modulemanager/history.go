@@ -0,0 +1,413 @@
+package modulemanager
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"go-module-builder/internal/model"
+	"go-module-builder/pkg/fsutils"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// snapshotIDLayout produces IDs that sort in creation order and are safe as file names.
+const snapshotIDLayout = "20060102T150405.000000000Z"
+
+// FieldChange describes a metadata field that differs between a snapshot and the current module.
+type FieldChange struct {
+	Field    string `json:"field"`
+	Snapshot string `json:"snapshot"`
+	Current  string `json:"current"`
+}
+
+// FileDiff describes how a template file differs between a snapshot and the current module.
+type FileDiff struct {
+	Name   string   `json:"name"`
+	Status string   `json:"status"` // "added" (only current), "removed" (only snapshot) or "modified"
+	Lines  []string `json:"lines"`  // Line diff prefixed with " ", "-" (snapshot) or "+" (current)
+}
+
+// SnapshotDiff lists the differences between a snapshot and the module's current state.
+type SnapshotDiff struct {
+	ModuleID   string        `json:"moduleId"`
+	SnapshotID string        `json:"snapshotId"`
+	Fields     []FieldChange `json:"fields"`
+	Files      []FileDiff    `json:"files"`
+}
+
+// moduleDir returns the absolute path of the module's root directory.
+func (m *ModuleManager) moduleDir(module *model.Module) string {
+	if filepath.IsAbs(module.Directory) {
+		return module.Directory
+	}
+	return filepath.Join(m.projectRoot, module.Directory)
+}
+
+// readTemplateFiles loads the content of every template listed in the module's metadata.
+// Templates whose file is missing on disk, or whose name is not a valid template
+// filename, are skipped.
+func (m *ModuleManager) readTemplateFiles(module *model.Module) (map[string]string, error) {
+	files := make(map[string]string, len(module.Templates))
+	templatesDir := filepath.Join(m.moduleDir(module), "templates")
+	for _, t := range module.Templates {
+		file, err := fsutils.ParseTemplateFilename(t.Name)
+		if err != nil {
+			m.logger.Warn("Invalid template filename in module metadata, leaving it out of snapshot", "moduleID", module.ID, "name", t.Name, "error", err)
+			continue
+		}
+		path := file.Path(templatesDir)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				m.logger.Warn("Template file missing, leaving it out of snapshot", "moduleID", module.ID, "path", path)
+				continue
+			}
+			return nil, fmt.Errorf("failed to read template file '%s': %w", path, err)
+		}
+		files[t.Name] = string(data)
+	}
+	return files, nil
+}
+
+// SnapshotModule records the module's current metadata and template contents.
+// The reason is stored with the snapshot to describe what triggered it.
+func (m *ModuleManager) SnapshotModule(moduleID, reason string) (*model.SnapshotInfo, error) {
+	module, err := m.store.LoadModule(moduleID)
+	if err != nil {
+		m.logger.Error("Error loading module metadata for snapshot", "moduleID", moduleID, "error", err)
+		return nil, fmt.Errorf("loading module metadata failed for ID %s: %w", moduleID, err)
+	}
+	return m.snapshot(module, reason)
+}
+
+// SaveWithSnapshot saves module, which was loaded as previous, and then records
+// previous as a snapshot so the change can be reverted. The snapshot is only taken once
+// SaveModule has passed its revision check, so a save rejected with a
+// *storage.ConflictError leaves no history entry. Template files are read when the
+// snapshot is taken, so they must not have been changed yet.
+func (m *ModuleManager) SaveWithSnapshot(previous, module *model.Module, reason string) error {
+	if err := m.store.SaveModule(module); err != nil {
+		return err
+	}
+	if _, err := m.snapshot(previous, reason); err != nil {
+		return fmt.Errorf("recording snapshot of the previous version failed: %w", err)
+	}
+	return nil
+}
+
+// snapshot records the given module state.
+func (m *ModuleManager) snapshot(module *model.Module, reason string) (*model.SnapshotInfo, error) {
+	files, err := m.readTemplateFiles(module)
+	if err != nil {
+		m.logger.Error("Error reading template files for snapshot", "moduleID", module.ID, "error", err)
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	snap := &model.Snapshot{
+		SnapshotInfo: model.SnapshotInfo{
+			ID:        now.Format(snapshotIDLayout),
+			ModuleID:  module.ID,
+			CreatedAt: now,
+			Reason:    reason,
+		},
+		Module: *module,
+		Files:  files,
+	}
+	if err := m.store.SaveSnapshot(snap); err != nil {
+		m.logger.Error("Error saving module snapshot", "moduleID", module.ID, "error", err)
+		return nil, fmt.Errorf("saving snapshot failed for module %s: %w", module.ID, err)
+	}
+
+	m.logger.Info("Recorded module snapshot", "moduleID", module.ID, "snapshotID", snap.ID, "reason", reason)
+	return &snap.SnapshotInfo, nil
+}
+
+// ListSnapshots returns the snapshots recorded for a module, newest first.
+func (m *ModuleManager) ListSnapshots(moduleID string) ([]model.SnapshotInfo, error) {
+	if _, err := m.store.LoadModule(moduleID); err != nil {
+		return nil, fmt.Errorf("loading module metadata failed for ID %s: %w", moduleID, err)
+	}
+	infos, err := m.store.ListSnapshots(moduleID)
+	if err != nil {
+		m.logger.Error("Error listing module snapshots", "moduleID", moduleID, "error", err)
+		return nil, fmt.Errorf("listing snapshots failed for module %s: %w", moduleID, err)
+	}
+	return infos, nil
+}
+
+// DiffSnapshot compares a snapshot against the module's current metadata and template files.
+func (m *ModuleManager) DiffSnapshot(moduleID, snapshotID string) (*SnapshotDiff, error) {
+	module, err := m.store.LoadModule(moduleID)
+	if err != nil {
+		return nil, fmt.Errorf("loading module metadata failed for ID %s: %w", moduleID, err)
+	}
+	snap, err := m.store.LoadSnapshot(moduleID, snapshotID)
+	if err != nil {
+		return nil, fmt.Errorf("loading snapshot failed: %w", err)
+	}
+	files, err := m.readTemplateFiles(module)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &SnapshotDiff{ModuleID: moduleID, SnapshotID: snapshotID, Fields: []FieldChange{}, Files: []FileDiff{}}
+	fields := []struct {
+		name              string
+		snapshot, current string
+	}{
+		{"name", snap.Module.Name, module.Name},
+		{"slug", snap.Module.Slug, module.Slug},
+		{"group", snap.Module.Group, module.Group},
+		{"layout", snap.Module.Layout, module.Layout},
+		{"description", snap.Module.Description, module.Description},
+		{"templates", templateNames(snap.Module.Templates), templateNames(module.Templates)},
+		{"dataSource", dataSourceString(snap.Module.DataSource), dataSourceString(module.DataSource)},
+		{"publishAt", timeString(snap.Module.PublishAt), timeString(module.PublishAt)},
+		{"unpublishAt", timeString(snap.Module.UnpublishAt), timeString(module.UnpublishAt)},
+	}
+	for _, f := range fields {
+		if f.snapshot != f.current {
+			diff.Fields = append(diff.Fields, FieldChange{Field: f.name, Snapshot: f.snapshot, Current: f.current})
+		}
+	}
+
+	// Walk snapshot templates first, then templates only present now, to keep a stable order.
+	seen := make(map[string]bool)
+	for _, name := range append(orderedNames(snap.Module.Templates), orderedNames(module.Templates)...) {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		old, inSnapshot := snap.Files[name]
+		cur, inCurrent := files[name]
+		switch {
+		case inSnapshot && !inCurrent:
+			diff.Files = append(diff.Files, FileDiff{Name: name, Status: "removed", Lines: diffLines(old, "")})
+		case !inSnapshot && inCurrent:
+			diff.Files = append(diff.Files, FileDiff{Name: name, Status: "added", Lines: diffLines("", cur)})
+		case inSnapshot && inCurrent && old != cur:
+			diff.Files = append(diff.Files, FileDiff{Name: name, Status: "modified", Lines: diffLines(old, cur)})
+		}
+	}
+	return diff, nil
+}
+
+// RestoreSnapshot brings a module's metadata and template files back to a recorded snapshot.
+// The current state is snapshotted first so a restore can itself be undone. The module keeps
+// its current directory, status, schedule and group, which only publishers may change;
+// template files not in the snapshot are removed. A restored slug must be free, and the
+// current one then redirects to it (see UpdateModule).
+func (m *ModuleManager) RestoreSnapshot(moduleID, snapshotID string) error {
+	m.logger.Info("Restoring module snapshot", "moduleID", moduleID, "snapshotID", snapshotID)
+
+	module, err := m.store.LoadModule(moduleID)
+	if err != nil {
+		m.logger.Error("Error loading module metadata for restore", "moduleID", moduleID, "error", err)
+		return fmt.Errorf("loading module metadata failed for ID %s: %w", moduleID, err)
+	}
+	snap, err := m.store.LoadSnapshot(moduleID, snapshotID)
+	if err != nil {
+		m.logger.Error("Error loading snapshot for restore", "moduleID", moduleID, "snapshotID", snapshotID, "error", err)
+		return fmt.Errorf("loading snapshot failed: %w", err)
+	}
+	for _, t := range snap.Module.Templates {
+		if _, err := fsutils.ParseTemplateFilename(t.Name); err != nil || t.Path != filepath.Join("templates", t.Name) {
+			return fmt.Errorf("snapshot %s contains invalid template path '%s'", snapshotID, t.Path)
+		}
+	}
+
+	if snap.Module.Slug != module.Slug {
+		if err := m.checkSlug(snap.Module.Slug, moduleID); err != nil {
+			return fmt.Errorf("cannot restore snapshot %s: %w", snapshotID, err)
+		}
+	}
+
+	if _, err := m.snapshot(module, "before restore "+snapshotID); err != nil {
+		return fmt.Errorf("recording pre-restore snapshot failed: %w", err)
+	}
+
+	dir := m.moduleDir(module)
+	keep := make(map[string]bool, len(snap.Module.Templates))
+	for _, t := range snap.Module.Templates {
+		keep[t.Name] = true
+		content, ok := snap.Files[t.Name]
+		if !ok {
+			continue // File was already missing when the snapshot was taken
+		}
+		path := filepath.Join(dir, t.Path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create template directory for '%s': %w", path, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			m.logger.Error("Failed to restore template file", "moduleID", moduleID, "path", path, "error", err)
+			return fmt.Errorf("failed to restore template file '%s': %w", path, err)
+		}
+	}
+	for _, t := range module.Templates {
+		if keep[t.Name] {
+			continue
+		}
+		file, err := fsutils.ParseTemplateFilename(t.Name)
+		if err != nil {
+			m.logger.Warn("Not removing template with an invalid filename", "moduleID", moduleID, "name", t.Name, "error", err)
+			continue
+		}
+		path := file.Path(filepath.Join(dir, "templates"))
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			m.logger.Error("Failed to remove template file not present in snapshot", "moduleID", moduleID, "path", path, "error", err)
+			return fmt.Errorf("failed to remove template file '%s': %w", path, err)
+		}
+	}
+
+	restored := snap.Module
+	restored.ID = module.ID
+	restored.Directory = module.Directory
+	restored.Status = module.Status
+	restored.PublishAt = module.PublishAt
+	restored.UnpublishAt = module.UnpublishAt
+	restored.Group = module.Group
+	restored.Slug = module.Slug
+	restored.OldSlugs = module.OldSlugs
+	changeSlug(&restored, snap.Module.Slug)
+	restored.CreatedAt = module.CreatedAt
+	restored.Revision = module.Revision
+	restored.LastUpdated = time.Now()
+	if err := m.store.SaveModule(&restored); err != nil {
+		m.logger.Error("Error saving restored module metadata", "moduleID", moduleID, "error", err)
+		return fmt.Errorf("saving restored module metadata failed for ID %s: %w", moduleID, err)
+	}
+
+	m.logger.Info("Successfully restored module snapshot", "moduleID", moduleID, "snapshotID", snapshotID)
+	m.RecordAudit("restore-snapshot", moduleID, moduleSummary(module), moduleSummary(&restored)+" snapshot="+snapshotID)
+	return nil
+}
+
+// dataSourceString describes a module's data source for diffs and logs ("" when none).
+func dataSourceString(src *model.DataSource) string {
+	switch {
+	case src == nil:
+		return ""
+	case src.File != "":
+		return "file:" + src.File
+	default:
+		return "url:" + src.URL
+	}
+}
+
+// timeString formats an optional schedule time for diffs ("" when unset).
+func timeString(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// templateNames returns the module's template names as a comma-separated list, in render
+// order, marking disabled templates so enabling, disabling and reordering show in a diff.
+func templateNames(templates []model.Template) string {
+	sorted := slices.Clone(templates)
+	slices.SortStableFunc(sorted, func(a, b model.Template) int { return cmp.Compare(a.Order, b.Order) })
+	names := make([]string, len(sorted))
+	for i, t := range sorted {
+		names[i] = t.Name
+		if !t.IsActive {
+			names[i] += " (disabled)"
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+func orderedNames(templates []model.Template) []string {
+	names := make([]string, len(templates))
+	for i, t := range templates {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// maxDiffCells caps the size of the table diffLines compares the changed lines in, as
+// lines in a times lines in b, so that diffing two large files cannot use up memory.
+// It allows e.g. 2000 changed lines on each side (about 32 MB).
+const maxDiffCells = 4_000_000
+
+// diffLines returns a line diff from a to b based on their longest common subsequence.
+// Unchanged lines are prefixed with " ", removed lines with "-" and added lines with "+".
+// Lines both have at the start and end are matched up first; if what lies between is
+// larger than maxDiffCells, only a note that the files differ is returned.
+func diffLines(a, b string) []string {
+	var x, y []string
+	if a != "" {
+		x = strings.Split(a, "\n")
+	}
+	if b != "" {
+		y = strings.Split(b, "\n")
+	}
+
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(x)-prefix && suffix < len(y)-prefix && x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+	lines := make([]string, 0, len(x)+len(y))
+	for _, line := range x[:prefix] {
+		lines = append(lines, " "+line)
+	}
+	lines = append(lines, diffMiddle(x[prefix:len(x)-suffix], y[prefix:len(y)-suffix])...)
+	for _, line := range x[len(x)-suffix:] {
+		lines = append(lines, " "+line)
+	}
+	return lines
+}
+
+// diffMiddle diffs the lines between the common start and end found by diffLines.
+func diffMiddle(x, y []string) []string {
+	if len(x) > 0 && len(y) > 0 && len(x)*len(y) > maxDiffCells {
+		return []string{fmt.Sprintf(" (files differ in %d lines of the snapshot and %d current lines, too many to compare line by line)", len(x), len(y))}
+	}
+
+	// lcs[i][j] is the LCS length of x[i:] and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	lines := make([]string, 0, len(x)+len(y))
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			lines = append(lines, " "+x[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, "-"+x[i])
+			i++
+		default:
+			lines = append(lines, "+"+y[j])
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		lines = append(lines, "-"+x[i])
+	}
+	for ; j < len(y); j++ {
+		lines = append(lines, "+"+y[j])
+	}
+	return lines
+}
@@ -2,14 +2,18 @@ package modulemanager
 
 import (
 	"fmt"
+	"go-module-builder/internal/dataprovider"
 	"go-module-builder/internal/generator"
 	"go-module-builder/internal/model"
 	"go-module-builder/internal/storage"
+	"go-module-builder/internal/templating"
 	"go-module-builder/pkg/fsutils" // Added for CreateDir
 	"io"
 	"log/slog"      // Using slog for consistency
 	"os"            // Added for file operations
 	"path/filepath" // Added for path joining
+	"slices"        // Added for copying template lists
+	"sync"          // Added for module locks
 	"time"          // Added for LastUpdated timestamp
 
 	"github.com/google/uuid"
@@ -22,6 +26,31 @@ type ModuleManager struct {
 	logger      *slog.Logger
 	modulesDir  string // Base directory where module files are stored (e.g., "modules")
 	projectRoot string // Project root directory
+
+	audit storage.AuditLog  // Records module changes; optional, see SetAuditLog
+	actor string            // Who changes are recorded as made by; see As
+	pages storage.PageStore // Pages whose slugs modules cannot take; optional, see SetPageStore
+	locks *moduleLocks      // Shared by the managers As returns
+}
+
+// moduleLocks serializes changes that check a module's revision and then write its
+// files, so they land in the order of their revisions. It only covers this process.
+type moduleLocks struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex // Module ID -> lock
+}
+
+// lock takes the lock for moduleID and returns the function that releases it.
+func (l *moduleLocks) lock(moduleID string) (unlock func()) {
+	l.mu.Lock()
+	moduleLock, ok := l.locks[moduleID]
+	if !ok {
+		moduleLock = &sync.Mutex{}
+		l.locks[moduleID] = moduleLock
+	}
+	l.mu.Unlock()
+	moduleLock.Lock()
+	return moduleLock.Unlock
 }
 
 // NewManager creates a new ModuleManager instance.
@@ -35,12 +64,18 @@ func NewManager(store storage.DataStore, logger *slog.Logger, projectRoot string
 		logger:      logger,
 		projectRoot: projectRoot,
 		modulesDir:  modulesDir, // Store the base modules directory path
+		locks:       &moduleLocks{locks: make(map[string]*sync.Mutex)},
 	}
 }
 
+// LayoutsDir returns the directory holding the named layouts modules can select.
+func (m *ModuleManager) LayoutsDir() string {
+	return filepath.Join(m.projectRoot, "web", "templates", templating.LayoutsDir)
+}
+
 // CreateModule handles the creation of a new module's boilerplate and metadata.
-// It takes the desired module name and an optional custom slug.
-// It returns the newly created module's metadata or an error.
+// It takes the desired module name and an optional custom slug, which must be free
+// (see checkSlug). It returns the newly created module's metadata or an error.
 func (m *ModuleManager) CreateModule(moduleName, customSlug string) (*model.Module, error) {
 	m.logger.Info("Creating module", "name", moduleName, "customSlug", customSlug)
 
@@ -50,6 +85,7 @@ func (m *ModuleManager) CreateModule(moduleName, customSlug string) (*model.Modu
 
 	// 2. Get generator config using the manager's modulesDir
 	genConfig := generator.DefaultGeneratorConfig(m.modulesDir)
+	genConfig.Logger = m.logger
 
 	// 3. Generate boilerplate files/dirs, passing the custom slug
 	newModule, err := generator.GenerateModuleBoilerplate(genConfig, moduleName, moduleID, customSlug)
@@ -58,6 +94,10 @@ func (m *ModuleManager) CreateModule(moduleName, customSlug string) (*model.Modu
 		// Consider more graceful error handling / cleanup here? For now, just return error.
 		return nil, fmt.Errorf("generating module boilerplate failed: %w", err)
 	}
+	if err := m.checkSlug(newModule.Slug, moduleID); err != nil {
+		os.RemoveAll(newModule.Directory)
+		return nil, err
+	}
 
 	// 4. Save module metadata using the manager's store
 	err = m.store.SaveModule(newModule)
@@ -68,11 +108,75 @@ func (m *ModuleManager) CreateModule(moduleName, customSlug string) (*model.Modu
 	}
 
 	m.logger.Info("Successfully created module", "name", moduleName, "id", moduleID, "directory", newModule.Directory)
+	m.RecordAudit("create", moduleID, "", moduleSummary(newModule))
+	m.refreshHandlerRegistry()
 	return newModule, nil
 }
 
+// CloneModule creates a new module from an existing one: its directory is copied and
+// its metadata saved under a fresh ID. An empty newName defaults to "<name> (copy)" and
+// an empty newSlug to the new ID, as in CreateModule. The slug must be free (see checkSlug).
+// The clone starts as a draft, without history.
+func (m *ModuleManager) CloneModule(sourceID, newName, newSlug string) (*model.Module, error) {
+	m.logger.Info("Cloning module", "sourceID", sourceID, "name", newName, "slug", newSlug)
+
+	// 1. Load the source module metadata
+	source, err := m.store.LoadModule(sourceID)
+	if err != nil {
+		m.logger.Error("Error loading module metadata for clone", "moduleID", sourceID, "error", err)
+		return nil, fmt.Errorf("loading module metadata failed for ID %s: %w", sourceID, err)
+	}
+
+	// 2. Build the clone's metadata under a new ID
+	clone := *source
+	clone.ID = uuid.New().String()
+	clone.Name = newName
+	if clone.Name == "" {
+		clone.Name = source.Name + " (copy)"
+	}
+	clone.Slug = newSlug
+	if clone.Slug == "" {
+		clone.Slug = clone.ID
+	}
+	clone.OldSlugs = nil // Redirects stay with the source
+	clone.Directory = filepath.Join(m.modulesDir, clone.ID)
+	clone.Status = model.StatusDraft
+	clone.Revision = 0
+	clone.CreatedAt = time.Now()
+	clone.LastUpdated = clone.CreatedAt
+	clone.Templates = append([]model.Template(nil), source.Templates...)
+	clone.Assets = append([]string(nil), source.Assets...)
+	if source.DataSource != nil {
+		dataSource := *source.DataSource
+		clone.DataSource = &dataSource
+	}
+
+	if err := m.checkSlug(clone.Slug, clone.ID); err != nil {
+		return nil, err
+	}
+
+	// 3. Copy the module directory
+	if err := fsutils.CopyDir(m.moduleDir(source), clone.Directory); err != nil {
+		m.logger.Error("Error copying module directory for clone", "sourceID", sourceID, "to", clone.Directory, "error", err)
+		os.RemoveAll(clone.Directory)
+		return nil, fmt.Errorf("copying module directory failed: %w", err)
+	}
+
+	// 4. Save the clone's metadata
+	if err := m.store.SaveModule(&clone); err != nil {
+		m.logger.Error("Error saving cloned module metadata", "moduleID", clone.ID, "error", err)
+		os.RemoveAll(clone.Directory)
+		return nil, fmt.Errorf("saving module metadata failed: %w", err)
+	}
+
+	m.logger.Info("Successfully cloned module", "sourceID", sourceID, "id", clone.ID, "name", clone.Name, "slug", clone.Slug)
+	m.RecordAudit("clone", clone.ID, "copy of "+sourceID, moduleSummary(&clone))
+	m.refreshHandlerRegistry()
+	return &clone, nil
+}
+
 // DeleteModule handles deleting a module by ID.
-// It supports both soft delete (moving files, marking inactive) and hard delete (removing files and metadata).
+// It supports both soft delete (moving files, marking archived) and hard delete (removing files and metadata).
 // Returns an error if the deletion fails.
 // Note: Confirmation logic (askForConfirmation) might need adjustment for non-CLI use cases later.
 func (m *ModuleManager) DeleteModule(moduleID string, force bool) error {
@@ -89,6 +193,7 @@ func (m *ModuleManager) DeleteModule(moduleID string, force bool) error {
 		m.logger.Error("Error loading module metadata for delete", "moduleID", moduleID, "error", err)
 		return fmt.Errorf("loading module metadata failed for ID %s: %w", moduleID, err)
 	}
+	before := moduleSummary(module)
 
 	if force {
 		// --- Force Delete Logic ---
@@ -129,14 +234,16 @@ func (m *ModuleManager) DeleteModule(moduleID string, force bool) error {
 
 		if deleteErr == nil {
 			m.logger.Info("Successfully force deleted module", "moduleID", moduleID, "name", module.Name)
+			m.RecordAudit("force-delete", moduleID, before, "")
+			m.refreshHandlerRegistry()
 			return nil // Success
 		}
 		return deleteErr // Return combined or single error
 
 	} else {
-		// --- Soft Delete Logic (Mark as Inactive) ---
-		if !module.IsActive {
-			m.logger.Info("Module is already inactive, skipping soft delete.", "moduleID", moduleID, "name", module.Name)
+		// --- Soft Delete Logic (Archive) ---
+		if module.IsArchived() {
+			m.logger.Info("Module is already archived, skipping soft delete.", "moduleID", moduleID, "name", module.Name)
 			return nil // Not an error, just nothing to do
 		}
 		m.logger.Info("Performing soft delete", "moduleID", moduleID, "name", module.Name)
@@ -177,8 +284,8 @@ func (m *ModuleManager) DeleteModule(moduleID string, force bool) error {
 			return fmt.Errorf("failed to move module directory for ID %s", moduleID)
 		}
 
-		// Update metadata to mark as inactive
-		module.IsActive = false
+		// Update metadata to mark as archived
+		module.Status = model.StatusArchived
 		module.Directory = newModulePathRelative // Update path to the new relative location
 		module.LastUpdated = time.Now()
 
@@ -190,10 +297,160 @@ func (m *ModuleManager) DeleteModule(moduleID string, force bool) error {
 		}
 
 		m.logger.Info("Successfully marked module as removed", "moduleID", moduleID, "name", module.Name, "newPath", newModulePathRelative)
+		m.RecordAudit("delete", moduleID, before, moduleSummary(module))
+		m.refreshHandlerRegistry()
 		return nil // Success
 	}
 }
 
+// RestoreModule undoes a soft delete: the module's directory is moved back from
+// modules_removed/ to the modules directory and the module is disabled, ready to be
+// published again. It fails if another module now uses the module's slug; change the
+// other module's slug first.
+func (m *ModuleManager) RestoreModule(moduleID string) (*model.Module, error) {
+	m.logger.Info("Restoring soft-deleted module", "moduleID", moduleID)
+
+	// 1. Load the module metadata
+	module, err := m.store.LoadModule(moduleID)
+	if err != nil {
+		m.logger.Error("Error loading module metadata for restore", "moduleID", moduleID, "error", err)
+		return nil, fmt.Errorf("loading module metadata failed for ID %s: %w", moduleID, err)
+	}
+	if !module.IsArchived() {
+		return nil, fmt.Errorf("module %s (%s) is not archived", module.Name, moduleID)
+	}
+
+	// 2. Check that the slug is still free
+	if err := m.checkSlug(module.Slug, module.ID); err != nil {
+		m.logger.Warn("Cannot restore module with its slug", "moduleID", moduleID, "slug", module.Slug, "error", err)
+		return nil, fmt.Errorf("cannot restore module %s: %w; change the other module's slug first", moduleID, err)
+	}
+
+	// 3. Move the directory back
+	removedDir := m.moduleDir(module)
+	restoredDir := filepath.Join(m.modulesDir, moduleID)
+	if _, err := os.Stat(restoredDir); err == nil {
+		return nil, fmt.Errorf("cannot restore module %s: %s already exists", moduleID, restoredDir)
+	}
+	if err := fsutils.CreateDir(m.modulesDir); err != nil {
+		return nil, fmt.Errorf("creating modules directory failed: %w", err)
+	}
+	moved := false
+	if _, err := os.Stat(removedDir); err == nil {
+		m.logger.Info("Moving directory", "from", removedDir, "to", restoredDir)
+		if err := os.Rename(removedDir, restoredDir); err != nil {
+			m.logger.Error("Failed to move module directory back from removed location", "moduleID", moduleID, "error", err)
+			return nil, fmt.Errorf("moving module directory back failed for ID %s: %w", moduleID, err)
+		}
+		moved = true
+	} else if os.IsNotExist(err) {
+		m.logger.Warn("Removed module directory not found, only updating metadata status.", "path", removedDir, "moduleID", moduleID)
+	} else {
+		return nil, fmt.Errorf("failed to check removed module directory '%s': %w", removedDir, err)
+	}
+
+	// 4. Mark the module disabled at its restored location
+	before := moduleSummary(module)
+	module.Status = model.StatusDisabled
+	module.Directory = restoredDir
+	module.LastUpdated = time.Now()
+	if err := m.store.SaveModule(module); err != nil {
+		m.logger.Error("Error saving restored module metadata", "moduleID", moduleID, "error", err)
+		if moved {
+			os.Rename(restoredDir, removedDir) // Keep files and metadata consistent
+		}
+		return nil, fmt.Errorf("saving module metadata failed for ID %s: %w", moduleID, err)
+	}
+
+	m.logger.Info("Successfully restored module", "moduleID", moduleID, "name", module.Name, "directory", restoredDir)
+	m.RecordAudit("restore", moduleID, before, moduleSummary(module))
+	m.refreshHandlerRegistry()
+	return module, nil
+}
+
+// statusTransitions lists the statuses SetModuleStatus may move a module to from each
+// status. Archiving and restoring move files, so they go through DeleteModule and
+// RestoreModule instead.
+var statusTransitions = map[model.ModuleStatus][]model.ModuleStatus{
+	model.StatusDraft:     {model.StatusPublished},
+	model.StatusPublished: {model.StatusDisabled},
+	model.StatusDisabled:  {model.StatusPublished, model.StatusDraft},
+}
+
+// SetModuleStatus moves a module to another lifecycle status: drafts are published,
+// published modules disabled, and disabled modules published again or returned to
+// draft. Other transitions are rejected.
+func (m *ModuleManager) SetModuleStatus(moduleID string, status model.ModuleStatus) (*model.Module, error) {
+	m.logger.Info("Setting module status", "moduleID", moduleID, "status", status)
+
+	// 1. Load the module metadata
+	module, err := m.store.LoadModule(moduleID)
+	if err != nil {
+		m.logger.Error("Error loading module metadata for status change", "moduleID", moduleID, "error", err)
+		return nil, fmt.Errorf("loading module metadata failed for ID %s: %w", moduleID, err)
+	}
+	if module.Status == status {
+		m.logger.Info("Module already has this status, nothing to change.", "moduleID", moduleID, "status", status)
+		return module, nil
+	}
+
+	// 2. Check the transition
+	allowed := false
+	for _, next := range statusTransitions[module.Status] {
+		allowed = allowed || next == status
+	}
+	if !allowed {
+		switch {
+		case !status.Valid():
+			return nil, fmt.Errorf("unknown module status %q", status)
+		case status == model.StatusArchived:
+			return nil, fmt.Errorf("module %s cannot be archived this way; soft-delete it instead", moduleID)
+		case module.IsArchived():
+			return nil, fmt.Errorf("module %s is archived; restore it first", moduleID)
+		}
+		return nil, fmt.Errorf("module %s cannot go from %s to %s", moduleID, module.Status, status)
+	}
+
+	// 3. Save the new status
+	previous := module.Status
+	before := moduleSummary(module)
+	module.Status = status
+	module.LastUpdated = time.Now()
+	if err := m.store.SaveModule(module); err != nil {
+		m.logger.Error("Error saving module status", "moduleID", moduleID, "error", err)
+		return nil, fmt.Errorf("saving module metadata failed for ID %s: %w", moduleID, err)
+	}
+
+	m.logger.Info("Successfully changed module status", "moduleID", moduleID, "from", previous, "to", status)
+	m.RecordAudit("set-status", moduleID, before, moduleSummary(module))
+	return module, nil
+}
+
+// RegenerateHandlerRegistry rewrites the server's module handler registry
+// (generator.HandlerRegistryFile) from the handler.go files under the modules directory.
+// The main server must be rebuilt for the change to take effect.
+func (m *ModuleManager) RegenerateHandlerRegistry() ([]generator.RegisteredHandler, error) {
+	handlers, err := generator.GenerateHandlerRegistry(m.projectRoot, m.modulesDir, m.logger)
+	if err != nil {
+		m.logger.Error("Error generating module handler registry", "error", err)
+		return nil, fmt.Errorf("generating handler registry failed: %w", err)
+	}
+	return handlers, nil
+}
+
+// refreshHandlerRegistry regenerates the handler registry after modules are added or removed.
+// It does nothing outside a project checkout (no cmd/server directory), and failures are
+// only logged since the module operation itself succeeded.
+func (m *ModuleManager) refreshHandlerRegistry() {
+	serverDir := filepath.Dir(filepath.Join(m.projectRoot, generator.HandlerRegistryFile))
+	if info, err := os.Stat(serverDir); err != nil || !info.IsDir() {
+		return
+	}
+	if _, err := m.RegenerateHandlerRegistry(); err != nil {
+		m.logger.Warn("Module handler registry is out of date; run 'builder-cli generate-handlers'", "error", err)
+	}
+}
+
 // --- Getter Methods ---
 
 // GetStore returns the underlying DataStore instance.
@@ -222,6 +479,9 @@ func (m *ModuleManager) GetStoreBasePath() string {
 
 // UpdateModule handles updating the metadata of an existing module.
 // It takes the module ID and optional new values for name, slug, group, layout, and description.
+// The layout must name a file in web/templates/layouts, or be templating.DefaultLayout
+// to go back to layout.html. A new slug must be free (see checkSlug); the old one is kept
+// in OldSlugs so the server redirects it to the new one.
 // Returns an error if the update fails.
 func (m *ModuleManager) UpdateModule(moduleID, newName, newSlug, newGroup, newLayout, newDesc string) error {
 	m.logger.Info("Updating module", "moduleID", moduleID)
@@ -232,6 +492,7 @@ func (m *ModuleManager) UpdateModule(moduleID, newName, newSlug, newGroup, newLa
 		m.logger.Error("Error loading module metadata for update", "moduleID", moduleID, "error", err)
 		return fmt.Errorf("loading module metadata failed for ID %s: %w", moduleID, err)
 	}
+	previous := *module // Kept for the pre-update snapshot
 
 	// 2. Update fields based on provided non-empty values
 	updated := false
@@ -240,18 +501,31 @@ func (m *ModuleManager) UpdateModule(moduleID, newName, newSlug, newGroup, newLa
 		module.Name = newName
 		updated = true
 	}
-	if newSlug != "" {
-		// Consider adding validation/sanitization for user-provided slugs here
+	if newSlug != "" && newSlug != module.Slug {
+		if err := m.checkSlug(newSlug, moduleID); err != nil {
+			return err
+		}
 		m.logger.Debug("Updating Slug", "moduleID", moduleID, "old", module.Slug, "new", newSlug)
-		module.Slug = newSlug
+		changeSlug(module, newSlug) // The old slug redirects to the new one
 		updated = true
 	}
 	if newGroup != "" {
+		if err := checkGroup(newGroup); err != nil {
+			return err
+		}
 		m.logger.Debug("Updating Group", "moduleID", moduleID, "old", module.Group, "new", newGroup)
 		module.Group = newGroup
 		updated = true
 	}
-	if newLayout != "" {
+	if newLayout == templating.DefaultLayout {
+		m.logger.Debug("Resetting Layout to default", "moduleID", moduleID, "old", module.Layout)
+		module.Layout = ""
+		updated = true
+	} else if newLayout != "" {
+		if err := templating.ValidateLayout(m.LayoutsDir(), newLayout); err != nil {
+			m.logger.Warn("Rejected module layout", "moduleID", moduleID, "layout", newLayout, "error", err)
+			return err
+		}
 		m.logger.Debug("Updating Layout", "moduleID", moduleID, "old", module.Layout, "new", newLayout)
 		module.Layout = newLayout
 		updated = true
@@ -269,13 +543,50 @@ func (m *ModuleManager) UpdateModule(moduleID, newName, newSlug, newGroup, newLa
 
 	module.LastUpdated = time.Now()
 
-	// 3. Save updated module metadata
-	if err := m.store.SaveModule(module); err != nil {
+	// 3. Save updated module metadata, recording the previous version so the update can be reverted
+	if err := m.SaveWithSnapshot(&previous, module, "update metadata"); err != nil {
 		m.logger.Error("Error saving updated module metadata", "moduleID", moduleID, "error", err)
 		return fmt.Errorf("saving updated module metadata failed for ID %s: %w", moduleID, err)
 	}
 
 	m.logger.Info("Successfully updated module metadata", "moduleID", moduleID)
+	m.RecordAudit("update", moduleID, moduleSummary(&previous), moduleSummary(module))
+	return nil
+}
+
+// SetDataSource declares where the data passed to the module's templates as .Data comes
+// from: a JSON/YAML file in the module directory or a local HTTP endpoint. A nil src
+// (or one with neither field set) removes the declaration.
+func (m *ModuleManager) SetDataSource(moduleID string, src *model.DataSource) error {
+	m.logger.Info("Setting module data source", "moduleID", moduleID)
+
+	if src != nil && src.File == "" && src.URL == "" {
+		src = nil
+	}
+	if src != nil {
+		if err := dataprovider.Validate(src); err != nil {
+			return fmt.Errorf("invalid data source for module %s: %w", moduleID, err)
+		}
+	}
+
+	// 1. Load the module metadata
+	module, err := m.store.LoadModule(moduleID)
+	if err != nil {
+		m.logger.Error("Error loading module metadata for data source", "moduleID", moduleID, "error", err)
+		return fmt.Errorf("loading module metadata failed for ID %s: %w", moduleID, err)
+	}
+
+	// 2. Save updated module metadata, recording the previous version so the change can be reverted
+	previous := *module
+	module.DataSource = src
+	module.LastUpdated = time.Now()
+	if err := m.SaveWithSnapshot(&previous, module, "set data source"); err != nil {
+		m.logger.Error("Error saving module data source", "moduleID", moduleID, "error", err)
+		return fmt.Errorf("saving module metadata failed for ID %s: %w", moduleID, err)
+	}
+
+	m.logger.Info("Successfully set module data source", "moduleID", moduleID, "dataSource", dataSourceString(src))
+	m.RecordAudit("set-data", moduleID, moduleSummary(&previous), moduleSummary(module))
 	return nil
 }
 
@@ -285,6 +596,12 @@ func (m *ModuleManager) UpdateModule(moduleID, newName, newSlug, newGroup, newLa
 func (m *ModuleManager) AddTemplate(moduleID, templateName string) (*model.Module, error) {
 	m.logger.Info("Adding template to module", "moduleID", moduleID, "templateName", templateName)
 
+	// 0. The name becomes a path under the module's templates directory
+	if _, err := fsutils.ParseTemplateFilename(templateName); err != nil {
+		m.logger.Warn("Invalid template name", "moduleID", moduleID, "templateName", templateName, "error", err)
+		return nil, err
+	}
+
 	// 1. Load the module metadata
 	module, err := m.store.LoadModule(moduleID)
 	if err != nil {
@@ -302,7 +619,7 @@ func (m *ModuleManager) AddTemplate(moduleID, templateName string) (*model.Modul
 
 	// 3. Call the generator to create the physical template file
 	// Use the manager's modulesDir which should be the base "modules" directory
-	err = generator.AddTemplateToModule(moduleID, templateName, m.modulesDir)
+	err = generator.AddTemplateToModule(moduleID, templateName, m.modulesDir, m.logger)
 	if err != nil {
 		m.logger.Error("Error creating template file via generator", "moduleID", moduleID, "templateName", templateName, "error", err)
 		return nil, fmt.Errorf("creating template file failed: %w", err)
@@ -321,13 +638,15 @@ func (m *ModuleManager) AddTemplate(moduleID, templateName string) (*model.Modul
 	templateSubDir := "templates" // Standard subdirectory within a module
 	relativePath := filepath.Join(templateSubDir, templateName)
 	newTemplate := model.Template{
-		Name:   templateName,
-		Path:   relativePath,
-		IsBase: false, // New templates added this way are not base templates
-		Order:  newOrder,
+		Name:     templateName,
+		Path:     relativePath,
+		IsBase:   false, // New templates added this way are not base templates
+		Order:    newOrder,
+		IsActive: true, // Rendered until disabled
 	}
 
 	// 6. Append to module's template list in metadata
+	before := moduleSummary(module)
 	module.Templates = append(module.Templates, newTemplate)
 	module.LastUpdated = time.Now()
 
@@ -339,10 +658,167 @@ func (m *ModuleManager) AddTemplate(moduleID, templateName string) (*model.Modul
 	}
 
 	m.logger.Info("Template added successfully and metadata updated", "moduleID", moduleID, "templateName", templateName, "order", newOrder)
+	m.RecordAudit("add-template", moduleID, before, moduleSummary(module))
 	return module, nil // Return the updated module
 }
 
-// PurgeRemovedModules finds all inactive modules and permanently deletes their files and metadata.
+// SaveTemplate replaces the content of one of the module's template files. revision is
+// the module revision the content was edited at: if the module has been saved since, a
+// *storage.ConflictError is returned and nothing is written. The revision check, the
+// snapshot of the replaced version and the file write happen under the module's lock,
+// so concurrent saves write their files in the order of their revisions.
+// Returns the updated module metadata or an error.
+func (m *ModuleManager) SaveTemplate(moduleID, templateName string, content []byte, revision int) (*model.Module, error) {
+	m.logger.Info("Saving module template", "moduleID", moduleID, "templateName", templateName, "revision", revision)
+	file, err := fsutils.ParseTemplateFilename(templateName)
+	if err != nil {
+		m.logger.Warn("Invalid template name for save", "moduleID", moduleID, "templateName", templateName, "error", err)
+		return nil, err
+	}
+
+	unlock := m.locks.lock(moduleID)
+	defer unlock()
+
+	// 1. Load the module metadata and check the content was edited at the current revision
+	module, err := m.store.LoadModule(moduleID)
+	if err != nil {
+		m.logger.Error("Error loading module metadata for template save", "moduleID", moduleID, "error", err)
+		return nil, fmt.Errorf("loading module metadata failed for ID %s: %w", moduleID, err)
+	}
+	if !slices.ContainsFunc(module.Templates, func(t model.Template) bool { return t.Name == templateName }) {
+		return nil, fmt.Errorf("template '%s' not found in module %s metadata", templateName, moduleID)
+	}
+	if module.Revision != revision {
+		m.logger.Warn("Rejected stale template save", "moduleID", moduleID, "templateName", templateName, "revision", revision, "current", module.Revision)
+		return nil, &storage.ConflictError{ModuleID: moduleID, Expected: revision, Actual: module.Revision}
+	}
+	path := file.Path(filepath.Join(m.moduleDir(module), "templates"))
+	oldContent, _ := os.ReadFile(path) // Summarised in the audit log
+
+	// 2. Save the metadata first: the revision check in SaveModule decides whether this
+	// save wins before the file is touched. The replaced version is recorded so the save
+	// can be reverted.
+	previous := *module
+	module.LastUpdated = time.Now()
+	if err := m.SaveWithSnapshot(&previous, module, "save "+templateName); err != nil {
+		m.logger.Error("Error saving module metadata for template save", "moduleID", moduleID, "templateName", templateName, "error", err)
+		return nil, fmt.Errorf("saving module metadata failed for ID %s: %w", moduleID, err)
+	}
+
+	// 3. Write the template file
+	if err := fsutils.WriteFileAtomic(path, content, 0644); err != nil {
+		m.logger.Error("Failed to write template file", "moduleID", moduleID, "path", path, "error", err)
+		return nil, fmt.Errorf("failed to write template file '%s': %w", path, err)
+	}
+
+	m.logger.Info("Successfully saved module template", "moduleID", moduleID, "templateName", templateName, "revision", module.Revision)
+	m.RecordAudit("save-template", moduleID, TemplateSummary(templateName, oldContent), TemplateSummary(templateName, content))
+	return module, nil
+}
+
+// SetTemplateActive enables or disables one of the module's template files. A disabled
+// template stays on disk but is neither parsed nor rendered.
+// Returns the updated module metadata or an error.
+func (m *ModuleManager) SetTemplateActive(moduleID, templateName string, active bool) (*model.Module, error) {
+	m.logger.Info("Setting template active state", "moduleID", moduleID, "templateName", templateName, "active", active)
+	if _, err := fsutils.ParseTemplateFilename(templateName); err != nil {
+		return nil, err
+	}
+
+	// 1. Load the module metadata
+	module, err := m.store.LoadModule(moduleID)
+	if err != nil {
+		m.logger.Error("Error loading module metadata for template state", "moduleID", moduleID, "error", err)
+		return nil, fmt.Errorf("loading module metadata failed for ID %s: %w", moduleID, err)
+	}
+
+	// 2. Find the template in metadata
+	templateIndex := -1
+	for i, t := range module.Templates {
+		if t.Name == templateName {
+			templateIndex = i
+			break
+		}
+	}
+	if templateIndex == -1 {
+		return nil, fmt.Errorf("template '%s' not found in module %s metadata", templateName, moduleID)
+	}
+	if module.Templates[templateIndex].IsActive == active {
+		m.logger.Info("Template already in requested state, nothing to change", "moduleID", moduleID, "templateName", templateName, "active", active)
+		return module, nil
+	}
+
+	// 3. Save updated module metadata, recording the previous version so the change can be reverted
+	previous := *module
+	previous.Templates = slices.Clone(module.Templates) // Changed in place below
+	module.Templates[templateIndex].IsActive = active
+	module.LastUpdated = time.Now()
+	reason := "disable template " + templateName
+	if active {
+		reason = "enable template " + templateName
+	}
+	if err := m.SaveWithSnapshot(&previous, module, reason); err != nil {
+		m.logger.Error("Error saving template state", "moduleID", moduleID, "templateName", templateName, "error", err)
+		return nil, fmt.Errorf("saving module metadata failed for ID %s: %w", moduleID, err)
+	}
+
+	m.logger.Info("Successfully set template active state", "moduleID", moduleID, "templateName", templateName, "active", active)
+	action := "disable-template"
+	if active {
+		action = "enable-template"
+	}
+	m.RecordAudit(action, moduleID, moduleSummary(&previous), moduleSummary(module))
+	return module, nil
+}
+
+// ReorderTemplates sets the order of the module's templates to the sequence of file
+// names given, which must name every template exactly once. Order values are
+// renumbered from 0 and the metadata list is sorted to match.
+// Returns the updated module metadata or an error.
+func (m *ModuleManager) ReorderTemplates(moduleID string, templateNames []string) (*model.Module, error) {
+	m.logger.Info("Reordering module templates", "moduleID", moduleID, "order", templateNames)
+
+	// 1. Load the module metadata
+	module, err := m.store.LoadModule(moduleID)
+	if err != nil {
+		m.logger.Error("Error loading module metadata for reorder", "moduleID", moduleID, "error", err)
+		return nil, fmt.Errorf("loading module metadata failed for ID %s: %w", moduleID, err)
+	}
+
+	// 2. Check the new order names every template exactly once
+	byName := make(map[string]model.Template, len(module.Templates))
+	for _, t := range module.Templates {
+		byName[t.Name] = t
+	}
+	if len(templateNames) != len(module.Templates) {
+		return nil, fmt.Errorf("new order lists %d templates, module %s has %d", len(templateNames), moduleID, len(module.Templates))
+	}
+	reordered := make([]model.Template, 0, len(templateNames))
+	for i, name := range templateNames {
+		t, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("template '%s' not found in module %s metadata or listed twice", name, moduleID)
+		}
+		delete(byName, name)
+		t.Order = i
+		reordered = append(reordered, t)
+	}
+
+	// 3. Save updated module metadata, recording the previous version so the change can be reverted
+	previous := *module
+	module.Templates = reordered
+	module.LastUpdated = time.Now()
+	if err := m.SaveWithSnapshot(&previous, module, "reorder templates"); err != nil {
+		m.logger.Error("Error saving template order", "moduleID", moduleID, "error", err)
+		return nil, fmt.Errorf("saving module metadata failed for ID %s: %w", moduleID, err)
+	}
+
+	m.logger.Info("Successfully reordered module templates", "moduleID", moduleID)
+	m.RecordAudit("reorder-templates", moduleID, moduleSummary(&previous), moduleSummary(module))
+	return module, nil
+}
+
+// PurgeRemovedModules finds all archived (soft-deleted) modules and permanently deletes their files and metadata.
 // Returns the number of modules successfully purged and a potential error (e.g., if reading metadata fails).
 // Individual deletion errors are logged but don't stop the process.
 // Note: Confirmation should be handled by the caller.
@@ -350,6 +826,11 @@ func (m *ModuleManager) PurgeRemovedModules() (purgedCount int, readErr error) {
 	m.logger.Info("Attempting to purge all removed modules...")
 
 	modules, err := m.store.ReadAll()
+	if corrupt, ok := storage.AsCorruptModules(err); ok {
+		// Unreadable entries can't be checked for their status; leave them alone
+		m.logger.Warn("Skipping modules with unreadable metadata during purge", "moduleIDs", corrupt.IDs(), "error", err)
+		err = nil
+	}
 	if err != nil {
 		m.logger.Error("Error reading module metadata for purge", "error", err)
 		return 0, fmt.Errorf("reading module metadata failed: %w", err) // Return error here
@@ -357,17 +838,17 @@ func (m *ModuleManager) PurgeRemovedModules() (purgedCount int, readErr error) {
 
 	removedModules := make([]*model.Module, 0)
 	for _, mod := range modules {
-		if !mod.IsActive { // Find inactive modules
+		if mod.IsArchived() { // Find soft-deleted modules
 			removedModules = append(removedModules, mod)
 		}
 	}
 
 	if len(removedModules) == 0 {
-		m.logger.Info("No inactive modules found. Nothing to purge.")
+		m.logger.Info("No archived modules found. Nothing to purge.")
 		return 0, nil // No error, just nothing done
 	}
 
-	m.logger.Info("Found inactive modules to purge", "count", len(removedModules))
+	m.logger.Info("Found archived modules to purge", "count", len(removedModules))
 	// NOTE: Confirmation prompt is handled by the caller (CLI)
 
 	purgedCount = 0 // Initialize counter
@@ -404,10 +885,12 @@ func (m *ModuleManager) PurgeRemovedModules() (purgedCount int, readErr error) {
 			failedMetaDelete++
 		} else {
 			purgedCount++ // Only increment if metadata deletion succeeds
+			m.RecordAudit("purge", module.ID, moduleSummary(module), "")
 		}
 	}
 
 	m.logger.Info("Purge complete.", "purgedCount", purgedCount, "dirDeleteFailures", failedDirDelete, "metaDeleteFailures", failedMetaDelete)
+	m.refreshHandlerRegistry()
 	// We don't return an error for individual delete failures, only for the initial ReadAll failure.
 	return purgedCount, nil
 }
@@ -417,6 +900,11 @@ func (m *ModuleManager) PurgeRemovedModules() (purgedCount int, readErr error) {
 // RemoveTemplateFromModule removes a specific template file from a module and updates its metadata.
 func (m *ModuleManager) RemoveTemplateFromModule(moduleID, templateFilename string) error {
 	m.logger.Info("Attempting to remove template from module", "moduleID", moduleID, "templateFilename", templateFilename)
+	file, err := fsutils.ParseTemplateFilename(templateFilename)
+	if err != nil {
+		m.logger.Warn("Invalid template name for removal", "moduleID", moduleID, "templateFilename", templateFilename, "error", err)
+		return err
+	}
 
 	// 1. Load the module metadata
 	module, err := m.store.LoadModule(moduleID)
@@ -427,39 +915,20 @@ func (m *ModuleManager) RemoveTemplateFromModule(moduleID, templateFilename stri
 
 	// 2. Find the template in the metadata
 	templateIndex := -1
-	var templatePath string
 	for i, t := range module.Templates {
 		if t.Name == templateFilename {
 			templateIndex = i
-			// Construct the absolute path to the template file
-			// module.Directory is relative to m.modulesDir, which is relative to m.projectRoot
-			// So, the full path is projectRoot + modulesDir (if modulesDir is not absolute) + moduleID + templates + filename
-			// However, module.Directory is already modulesDir/moduleID.
-			// And m.modulesDir is already projectRoot/modules.
-			// The generator.AddTemplateToModule uses m.modulesDir as the base for moduleID/templates.
-			// So, the path should be: m.modulesDir + moduleID + "templates" + templateFilename
-			// Let's ensure module.Directory is the absolute path to the module's folder.
-			// From GenerateModuleBoilerplate: moduleDir := filepath.Join(cfg.BaseDir, moduleID)
-			// cfg.BaseDir is m.modulesDir. So module.Directory is m.modulesDir/moduleID.
-			// This path is relative to the project root if m.modulesDir is relative, or absolute if m.modulesDir is absolute.
-			// The ModuleManager's modulesDir is initialized in CLI and Admin main as filepath.Join(projectRoot, "modules").
-			// So, module.Directory is effectively projectRoot/modules/moduleID.
-			// And templatePath should be projectRoot/modules/moduleID/templates/templateFilename.
-			// model.Template.Path is "templates/filename.html"
-			templatePath = filepath.Join(module.Directory, t.Path) // t.Path is "templates/filename.html"
-			if !filepath.IsAbs(templatePath) {                     // Ensure it's absolute for os.Remove
-				templatePath = filepath.Join(m.projectRoot, templatePath)
-			}
 			break
 		}
 	}
-
 	if templateIndex == -1 {
 		m.logger.Warn("Template not found in module metadata", "moduleID", moduleID, "templateFilename", templateFilename)
 		return fmt.Errorf("template '%s' not found in metadata for module %s", templateFilename, moduleID)
 	}
 
-	// 3. Delete the physical template file
+	// 3. Delete the physical template file, always <module dir>/templates/<name>
+	// whatever Path the metadata holds
+	templatePath := file.Path(filepath.Join(m.moduleDir(module), "templates"))
 	m.logger.Debug("Attempting to delete template file", "path", templatePath)
 	if _, err := os.Stat(templatePath); err == nil {
 		errRemove := os.Remove(templatePath)
@@ -477,6 +946,7 @@ func (m *ModuleManager) RemoveTemplateFromModule(moduleID, templateFilename stri
 	}
 
 	// 4. Remove the template from the slice in metadata
+	before := moduleSummary(module)
 	module.Templates = append(module.Templates[:templateIndex], module.Templates[templateIndex+1:]...)
 	module.LastUpdated = time.Now()
 
@@ -488,5 +958,6 @@ func (m *ModuleManager) RemoveTemplateFromModule(moduleID, templateFilename stri
 	}
 
 	m.logger.Info("Successfully removed template from module and updated metadata", "moduleID", moduleID, "templateFilename", templateFilename)
+	m.RecordAudit("remove-template", moduleID, before, moduleSummary(module))
 	return nil
 }
@@ -0,0 +1,418 @@
+package modulemanager
+
+import (
+	"fmt"
+	"go-module-builder/internal/model"
+	"go-module-builder/internal/storage"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCloneModule(t *testing.T) {
+	m := newTestManager(t)
+	source, err := m.CreateModule("Hero", "hero")
+	if err != nil {
+		t.Fatalf("CreateModule failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(source.Directory, "templates", "content.html"), []byte(`{{ define "content" }}Big hero{{ end }}`), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+
+	clone, err := m.CloneModule(source.ID, "Hero Dark", "hero-dark")
+	if err != nil {
+		t.Fatalf("CloneModule failed: %v", err)
+	}
+	if clone.ID == source.ID || clone.Name != "Hero Dark" || clone.Slug != "hero-dark" || clone.Directory != filepath.Join(m.modulesDir, clone.ID) {
+		t.Errorf("Clone = %+v, want a new ID, the new name and slug, and its own directory", clone)
+	}
+	if len(clone.Templates) != len(source.Templates) {
+		t.Errorf("Clone has %d templates, want %d", len(clone.Templates), len(source.Templates))
+	}
+	got, err := os.ReadFile(filepath.Join(clone.Directory, "templates", "content.html"))
+	if err != nil || string(got) != `{{ define "content" }}Big hero{{ end }}` {
+		t.Errorf("Cloned content.html = %q, %v; want the source's content", got, err)
+	}
+	if _, err := m.GetStore().LoadModule(clone.ID); err != nil {
+		t.Errorf("Clone was not saved: %v", err)
+	}
+
+	// Defaults, and a slug that is taken
+	copied, err := m.CloneModule(source.ID, "", "")
+	if err != nil {
+		t.Fatalf("CloneModule with defaults failed: %v", err)
+	}
+	if copied.Name != "Hero (copy)" || copied.Slug != copied.ID {
+		t.Errorf("Clone with defaults got name %q and slug %q, want \"Hero (copy)\" and its ID", copied.Name, copied.Slug)
+	}
+	if _, err := m.CloneModule(source.ID, "", "hero"); err == nil {
+		t.Error("CloneModule with a slug in use did not return an error")
+	}
+	if _, err := m.CloneModule("missing", "", ""); err == nil {
+		t.Error("CloneModule of a missing module did not return an error")
+	}
+	entries, err := os.ReadDir(m.modulesDir)
+	if err != nil || len(entries) != 3 {
+		t.Errorf("Modules directory has %d entries, %v; want the source and two clones", len(entries), err)
+	}
+}
+
+func TestRestoreModule(t *testing.T) {
+	m := newTestManager(t)
+	mod, err := m.CreateModule("Hero", "hero")
+	if err != nil {
+		t.Fatalf("CreateModule failed: %v", err)
+	}
+	if _, err := m.RestoreModule(mod.ID); err == nil {
+		t.Error("RestoreModule of an active module did not return an error")
+	}
+	if err := m.DeleteModule(mod.ID, false); err != nil {
+		t.Fatalf("DeleteModule failed: %v", err)
+	}
+
+	// The slug was taken while the module was deleted
+	other, err := m.CreateModule("New Hero", "hero")
+	if err != nil {
+		t.Fatalf("CreateModule failed: %v", err)
+	}
+	if _, err := m.RestoreModule(mod.ID); err == nil {
+		t.Fatal("RestoreModule with its slug in use did not return an error")
+	}
+	if _, err := os.Stat(filepath.Join(m.projectRoot, "modules_removed", mod.ID)); err != nil {
+		t.Errorf("A failed restore should leave the files in modules_removed: %v", err)
+	}
+
+	if err := m.UpdateModule(other.ID, "", "new-hero", "", "", ""); err != nil {
+		t.Fatalf("UpdateModule failed: %v", err)
+	}
+	restored, err := m.RestoreModule(mod.ID)
+	if err != nil {
+		t.Fatalf("RestoreModule failed: %v", err)
+	}
+	if restored.Status != model.StatusDisabled || restored.Directory != mod.Directory {
+		t.Errorf("Restored module is %s in %q, want disabled in %q", restored.Status, restored.Directory, mod.Directory)
+	}
+	if _, err := os.Stat(filepath.Join(mod.Directory, "templates", "base.html")); err != nil {
+		t.Errorf("Restored files are missing: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(m.projectRoot, "modules_removed", mod.ID)); !os.IsNotExist(err) {
+		t.Error("The removed copy should be gone after a restore")
+	}
+}
+
+func TestRestoreSnapshot_KeepsPublishingFields(t *testing.T) {
+	m := newTestManager(t)
+	mod, err := m.CreateModule("Campaign", "campaign")
+	if err != nil {
+		t.Fatalf("CreateModule failed: %v", err)
+	}
+	if err := m.UpdateModule(mod.ID, "", "", "marketing", "", ""); err != nil {
+		t.Fatalf("UpdateModule failed: %v", err)
+	}
+	snap, err := m.SnapshotModule(mod.ID, "test")
+	if err != nil {
+		t.Fatalf("SnapshotModule failed: %v", err)
+	}
+
+	// A publisher moves the module and schedules it after the snapshot
+	publishAt := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	unpublishAt := publishAt.Add(24 * time.Hour)
+	if _, err := m.SetModuleSchedule(mod.ID, &publishAt, &unpublishAt); err != nil {
+		t.Fatalf("SetModuleSchedule failed: %v", err)
+	}
+	if err := m.UpdateModule(mod.ID, "Summer Campaign", "", "blog", "", ""); err != nil {
+		t.Fatalf("UpdateModule failed: %v", err)
+	}
+	if _, err := m.SetModuleStatus(mod.ID, model.StatusPublished); err != nil {
+		t.Fatalf("SetModuleStatus failed: %v", err)
+	}
+
+	if err := m.RestoreSnapshot(mod.ID, snap.ID); err != nil {
+		t.Fatalf("RestoreSnapshot failed: %v", err)
+	}
+	restored, err := m.GetStore().LoadModule(mod.ID)
+	if err != nil {
+		t.Fatalf("LoadModule failed: %v", err)
+	}
+	if restored.Name != "Campaign" {
+		t.Errorf("Name = %q, want the snapshot's", restored.Name)
+	}
+	if restored.Status != model.StatusPublished || restored.Group != "blog" {
+		t.Errorf("Status and group = %s, %q; want the current published, blog", restored.Status, restored.Group)
+	}
+	if restored.PublishAt == nil || !restored.PublishAt.Equal(publishAt) || restored.UnpublishAt == nil || !restored.UnpublishAt.Equal(unpublishAt) {
+		t.Errorf("Schedule = %v to %v, want the current %v to %v", restored.PublishAt, restored.UnpublishAt, publishAt, unpublishAt)
+	}
+}
+
+func TestSnapshotModule_InvalidTemplateNames(t *testing.T) {
+	m := newTestManager(t)
+	mod, err := m.CreateModule("Hero", "hero")
+	if err != nil {
+		t.Fatalf("CreateModule failed: %v", err)
+	}
+	secret := filepath.Join(m.projectRoot, "secret.html")
+	if err := os.WriteFile(secret, []byte("SECRET"), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", secret, err)
+	}
+	// Metadata edited by hand to point outside the module
+	stored, err := m.GetStore().LoadModule(mod.ID)
+	if err != nil {
+		t.Fatalf("LoadModule failed: %v", err)
+	}
+	stored.Templates = append(stored.Templates, model.Template{Name: "../../secret.html", Path: "templates/../../../secret.html", IsActive: true})
+	if err := m.GetStore().SaveModule(stored); err != nil {
+		t.Fatalf("SaveModule failed: %v", err)
+	}
+
+	info, err := m.SnapshotModule(mod.ID, "test")
+	if err != nil {
+		t.Fatalf("SnapshotModule failed: %v", err)
+	}
+	snap, err := m.GetStore().LoadSnapshot(mod.ID, info.ID)
+	if err != nil {
+		t.Fatalf("LoadSnapshot failed: %v", err)
+	}
+	for name, content := range snap.Files {
+		if content == "SECRET" {
+			t.Errorf("Snapshot read %s from outside the module", name)
+		}
+	}
+	if _, ok := snap.Files["base.html"]; !ok {
+		t.Error("Snapshot is missing base.html")
+	}
+}
+
+func TestSetModuleStatus(t *testing.T) {
+	m := newTestManager(t)
+	mod, err := m.CreateModule("Hero", "hero")
+	if err != nil {
+		t.Fatalf("CreateModule failed: %v", err)
+	}
+	if mod.Status != model.StatusDraft {
+		t.Fatalf("New module status = %q, want draft", mod.Status)
+	}
+
+	// Draft -> published -> disabled -> published
+	for _, status := range []model.ModuleStatus{model.StatusPublished, model.StatusDisabled, model.StatusPublished} {
+		updated, err := m.SetModuleStatus(mod.ID, status)
+		if err != nil {
+			t.Fatalf("SetModuleStatus(%s) failed: %v", status, err)
+		}
+		if stored, _ := m.GetStore().LoadModule(mod.ID); updated.Status != status || stored.Status != status {
+			t.Errorf("After SetModuleStatus(%s) the module is %s (stored %s)", status, updated.Status, stored.Status)
+		}
+	}
+
+	// Published modules can't go back to draft, and archiving goes through DeleteModule
+	for _, status := range []model.ModuleStatus{model.StatusDraft, model.StatusArchived, "live"} {
+		if _, err := m.SetModuleStatus(mod.ID, status); err == nil {
+			t.Errorf("SetModuleStatus(%s) of a published module did not return an error", status)
+		}
+	}
+	if err := m.DeleteModule(mod.ID, false); err != nil {
+		t.Fatalf("DeleteModule failed: %v", err)
+	}
+	if _, err := m.SetModuleStatus(mod.ID, model.StatusPublished); err == nil {
+		t.Error("SetModuleStatus of an archived module did not return an error")
+	}
+}
+
+// FuzzTemplateFilenames adds, disables and removes templates by arbitrary names and
+// checks that no file outside modules/{id}/templates is ever created or removed.
+func FuzzTemplateFilenames(f *testing.F) {
+	for _, seed := range []string{
+		"card.html", "base.html", "../card.html", "../../escape.html", "../module.json", `..\card.html`,
+		"/tmp/abs.html", "templates/../../x.css", "..", ".", "", "con.html", "card.html\x00.css",
+	} {
+		f.Add(seed)
+	}
+	m := newTestManager(f)
+	mod, err := m.CreateModule("Fuzz", "fuzz")
+	if err != nil {
+		f.Fatalf("CreateModule failed: %v", err)
+	}
+	templatesDir := filepath.Join(m.moduleDir(mod), "templates")
+	metadataDir := filepath.Join(m.GetProjectRoot(), ".module_metadata") // Holds snapshots of each change
+
+	// files lists the project's files, leaving out the templates directory and the metadata.
+	files := func() map[string]bool {
+		found := map[string]bool{}
+		filepath.WalkDir(m.GetProjectRoot(), func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || filepath.Dir(path) == templatesDir {
+				return nil
+			}
+			if rel, _ := filepath.Rel(metadataDir, path); filepath.IsLocal(rel) {
+				return nil
+			}
+			found[path] = true
+			return nil
+		})
+		return found
+	}
+	want := files()
+
+	f.Fuzz(func(t *testing.T, name string) {
+		if _, err := m.AddTemplate(mod.ID, name); err == nil {
+			if _, err := os.Stat(filepath.Join(templatesDir, name)); err != nil {
+				t.Fatalf("AddTemplate(%q) succeeded without creating the file: %v", name, err)
+			}
+			if _, err := m.SetTemplateActive(mod.ID, name, false); err != nil {
+				t.Fatalf("SetTemplateActive(%q) failed: %v", name, err)
+			}
+		}
+		m.RemoveTemplateFromModule(mod.ID, name)
+
+		got := files()
+		for path := range got {
+			if !want[path] {
+				t.Fatalf("Template name %q created %s outside %s", name, path, templatesDir)
+			}
+		}
+		for path := range want {
+			if !got[path] {
+				t.Fatalf("Template name %q removed %s outside %s", name, path, templatesDir)
+			}
+		}
+	})
+}
+
+// conflictStore fails every SaveModule as if another save had won the race.
+type conflictStore struct {
+	storage.DataStore
+}
+
+func (s conflictStore) SaveModule(module *model.Module) error {
+	return &storage.ConflictError{ModuleID: module.ID, Expected: module.Revision, Actual: module.Revision + 1}
+}
+
+func TestMetadataChanges_SnapshotOnlyAfterSave(t *testing.T) {
+	m := newTestManager(t)
+	mod, err := m.CreateModule("Hero", "hero")
+	if err != nil {
+		t.Fatalf("CreateModule failed: %v", err)
+	}
+	if err := m.UpdateModule(mod.ID, "Big Hero", "", "", "", ""); err != nil {
+		t.Fatalf("UpdateModule failed: %v", err)
+	}
+	infos, err := m.ListSnapshots(mod.ID)
+	if err != nil || len(infos) != 1 {
+		t.Fatalf("ListSnapshots = %v, %v; want one snapshot", infos, err)
+	}
+	snap, err := m.GetStore().LoadSnapshot(mod.ID, infos[0].ID)
+	if err != nil {
+		t.Fatalf("LoadSnapshot failed: %v", err)
+	}
+	if snap.Module.Name != "Hero" || snap.Module.Revision != mod.Revision {
+		t.Errorf("Snapshot has name %q at revision %d, want the previous \"Hero\" at %d", snap.Module.Name, snap.Module.Revision, mod.Revision)
+	}
+
+	conflicting := NewManager(conflictStore{m.GetStore()}, nil, m.projectRoot, m.modulesDir)
+	changes := map[string]func() error{
+		"UpdateModule": func() error { return conflicting.UpdateModule(mod.ID, "Lost", "", "", "", "") },
+		"SetDataSource": func() error {
+			return conflicting.SetDataSource(mod.ID, &model.DataSource{File: "data.json"})
+		},
+		"SetTemplateActive": func() error {
+			_, err := conflicting.SetTemplateActive(mod.ID, "base.html", false)
+			return err
+		},
+		"ReorderTemplates": func() error {
+			var order []string
+			for i := len(mod.Templates) - 1; i >= 0; i-- {
+				order = append(order, mod.Templates[i].Name)
+			}
+			_, err := conflicting.ReorderTemplates(mod.ID, order)
+			return err
+		},
+		"SetModuleSchedule": func() error {
+			publishAt := time.Now().Add(time.Hour)
+			_, err := conflicting.SetModuleSchedule(mod.ID, &publishAt, nil)
+			return err
+		},
+	}
+	for name, change := range changes {
+		if err := change(); !storage.IsConflict(err) {
+			t.Errorf("%s error = %v, want a conflict", name, err)
+		}
+	}
+	if infos, err := m.ListSnapshots(mod.ID); err != nil || len(infos) != 1 {
+		t.Errorf("ListSnapshots after rejected changes = %d snapshots, %v; want still one", len(infos), err)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	got := diffLines("a\nb\nc\nd", "a\nc\nx\nd")
+	want := []string{" a", "-b", " c", "+x", " d"}
+	if !slices.Equal(got, want) {
+		t.Errorf("diffLines = %q, want %q", got, want)
+	}
+
+	// Two large, entirely different files are not compared line by line
+	var x, y []string
+	for i := range 20000 {
+		x = append(x, fmt.Sprintf("old %d", i))
+		y = append(y, fmt.Sprintf("new %d", i))
+	}
+	x[0], y[0] = "same", "same"
+	got = diffLines(strings.Join(x, "\n"), strings.Join(y, "\n"))
+	if len(got) != 2 || got[0] != " same" || !strings.Contains(got[1], "too many to compare") {
+		t.Errorf("diffLines of large files = %d lines starting %q, want the common line and a note", len(got), got[:min(len(got), 2)])
+	}
+
+	// A small change in a large file is still shown
+	y = slices.Clone(x)
+	y[10000] = "changed"
+	got = diffLines(strings.Join(x, "\n"), strings.Join(y, "\n"))
+	if len(got) != 20001 || got[10000] != "-"+x[10000] || got[10001] != "+changed" {
+		t.Errorf("diffLines of a one-line change = %d lines, want the change among the unchanged lines", len(got))
+	}
+}
+
+func TestSaveTemplate_ConcurrentSaves(t *testing.T) {
+	m := newTestManager(t)
+	mod, err := m.CreateModule("Hero", "hero")
+	if err != nil {
+		t.Fatalf("CreateModule failed: %v", err)
+	}
+
+	// Every save is based on the current revision; only one may win
+	const saves = 8
+	var wg sync.WaitGroup
+	results := make([]error, saves)
+	for i := range saves {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, results[i] = m.SaveTemplate(mod.ID, "base.html", []byte(fmt.Sprintf("version %d", i)), mod.Revision)
+		}()
+	}
+	wg.Wait()
+
+	winner := -1
+	for i, err := range results {
+		switch {
+		case err == nil && winner == -1:
+			winner = i
+		case err == nil:
+			t.Errorf("Saves %d and %d both succeeded", winner, i)
+		case !storage.IsConflict(err):
+			t.Errorf("Save %d error = %v, want a conflict", i, err)
+		}
+	}
+	if winner == -1 {
+		t.Fatal("No save succeeded")
+	}
+	content, err := os.ReadFile(filepath.Join(mod.Directory, "templates", "base.html"))
+	if err != nil || string(content) != fmt.Sprintf("version %d", winner) {
+		t.Errorf("base.html = %q, %v; want the winning save's content", content, err)
+	}
+	if infos, err := m.ListSnapshots(mod.ID); err != nil || len(infos) != 1 {
+		t.Errorf("ListSnapshots = %d snapshots, %v; want one for the winning save", len(infos), err)
+	}
+}
@@ -0,0 +1,65 @@
+package modulemanager
+
+import (
+	"fmt"
+	"go-module-builder/internal/model"
+	"strings"
+	"time"
+)
+
+// scheduleLayouts are the time formats ParseScheduleTime accepts. Times without a
+// zone are in the server's local time zone.
+var scheduleLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04", // HTML datetime-local inputs
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// ParseScheduleTime parses a PublishAt/UnpublishAt value given as RFC 3339
+// ("2025-06-01T09:00:00+02:00"), or as a local date and time ("2025-06-01 09:00",
+// "2025-06-01T09:00" or "2025-06-01"). An empty value means no time and returns nil.
+func ParseScheduleTime(value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range scheduleLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid time %q: use RFC 3339 or YYYY-MM-DD[ HH:MM]", value)
+}
+
+// SetModuleSchedule sets when a published module goes live and when it stops being
+// served. A nil time removes that bound. The status is unchanged: a module is only
+// served within its schedule while it is published.
+func (m *ModuleManager) SetModuleSchedule(moduleID string, publishAt, unpublishAt *time.Time) (*model.Module, error) {
+	m.logger.Info("Setting module schedule", "moduleID", moduleID, "publishAt", publishAt, "unpublishAt", unpublishAt)
+
+	if publishAt != nil && unpublishAt != nil && !unpublishAt.After(*publishAt) {
+		return nil, fmt.Errorf("unpublish time %s must be after publish time %s", unpublishAt.Format(time.RFC3339), publishAt.Format(time.RFC3339))
+	}
+
+	// 1. Load the module metadata
+	module, err := m.store.LoadModule(moduleID)
+	if err != nil {
+		m.logger.Error("Error loading module metadata for schedule", "moduleID", moduleID, "error", err)
+		return nil, fmt.Errorf("loading module metadata failed for ID %s: %w", moduleID, err)
+	}
+
+	// 2. Save the new schedule, recording the previous version so the change can be reverted
+	previous := *module
+	module.PublishAt = publishAt
+	module.UnpublishAt = unpublishAt
+	module.LastUpdated = time.Now()
+	if err := m.SaveWithSnapshot(&previous, module, "update schedule"); err != nil {
+		m.logger.Error("Error saving module schedule", "moduleID", moduleID, "error", err)
+		return nil, fmt.Errorf("saving module metadata failed for ID %s: %w", moduleID, err)
+	}
+
+	m.logger.Info("Successfully updated module schedule", "moduleID", moduleID)
+	m.RecordAudit("set-schedule", moduleID, moduleSummary(&previous), moduleSummary(module))
+	return module, nil
+}
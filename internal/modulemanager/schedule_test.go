@@ -0,0 +1,61 @@
+package modulemanager
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseScheduleTime(t *testing.T) {
+	for value, want := range map[string]time.Time{
+		"2025-06-01T09:00:00Z": time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC),
+		"2025-06-01 09:00":     time.Date(2025, 6, 1, 9, 0, 0, 0, time.Local),
+		"2025-06-01T09:00":     time.Date(2025, 6, 1, 9, 0, 0, 0, time.Local),
+		"2025-06-01":           time.Date(2025, 6, 1, 0, 0, 0, 0, time.Local),
+	} {
+		got, err := ParseScheduleTime(value)
+		if err != nil || got == nil || !got.Equal(want) {
+			t.Errorf("ParseScheduleTime(%q) = %v, %v; want %v", value, got, err, want)
+		}
+	}
+	if got, err := ParseScheduleTime(""); got != nil || err != nil {
+		t.Errorf("ParseScheduleTime(\"\") = %v, %v; want no time", got, err)
+	}
+	if _, err := ParseScheduleTime("next tuesday"); err == nil {
+		t.Error("ParseScheduleTime of an invalid time did not return an error")
+	}
+}
+
+func TestSetModuleSchedule(t *testing.T) {
+	m := newTestManager(t)
+	mod, err := m.CreateModule("Campaign", "campaign")
+	if err != nil {
+		t.Fatalf("CreateModule failed: %v", err)
+	}
+	publishAt := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	unpublishAt := publishAt.Add(24 * time.Hour)
+
+	if _, err := m.SetModuleSchedule(mod.ID, &unpublishAt, &publishAt); err == nil {
+		t.Error("SetModuleSchedule with unpublishAt before publishAt did not return an error")
+	}
+	if _, err := m.SetModuleSchedule(mod.ID, &publishAt, &unpublishAt); err != nil {
+		t.Fatalf("SetModuleSchedule failed: %v", err)
+	}
+	stored, err := m.GetStore().LoadModule(mod.ID)
+	if err != nil {
+		t.Fatalf("LoadModule failed: %v", err)
+	}
+	if stored.PublishAt == nil || !stored.PublishAt.Equal(publishAt) || stored.UnpublishAt == nil || !stored.UnpublishAt.Equal(unpublishAt) {
+		t.Errorf("Stored schedule = %v to %v, want %v to %v", stored.PublishAt, stored.UnpublishAt, publishAt, unpublishAt)
+	}
+	if stored.IsLive(publishAt) {
+		t.Error("A draft should not be live within its schedule")
+	}
+
+	// Clearing the schedule
+	if _, err := m.SetModuleSchedule(mod.ID, nil, nil); err != nil {
+		t.Fatalf("SetModuleSchedule(nil, nil) failed: %v", err)
+	}
+	if stored, _ := m.GetStore().LoadModule(mod.ID); stored.PublishAt != nil || stored.UnpublishAt != nil {
+		t.Errorf("Schedule was not cleared: %v to %v", stored.PublishAt, stored.UnpublishAt)
+	}
+}
@@ -0,0 +1,107 @@
+package modulemanager
+
+import (
+	"fmt"
+	"go-module-builder/internal/model"
+	"go-module-builder/internal/storage"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ReservedSlugs are the first path segments the main server routes itself
+// (/static/..., /modules/{id}/static/...); a module served there would be unreachable.
+var ReservedSlugs = []string{"static", "modules"}
+
+// SetPageStore makes slug checks reject the slugs of pages, which the main server
+// serves before any module with the same slug.
+func (m *ModuleManager) SetPageStore(pages storage.PageStore) {
+	m.pages = pages
+}
+
+// checkSlug returns an error if the module moduleID cannot be served at slug: the slug
+// is empty, not a single path segment, reserved, the slug of another module that is
+// not archived, or the slug of a page. Slugs other modules had before (OldSlugs) can be
+// taken; the current slug wins over the redirect.
+func (m *ModuleManager) checkSlug(slug, moduleID string) error {
+	if err := checkSlugFormat(slug); err != nil {
+		return err
+	}
+	if slices.Contains(ReservedSlugs, slug) {
+		return fmt.Errorf("slug %q is reserved for server routes", slug)
+	}
+
+	modules, err := m.store.ReadAll()
+	if corrupt, ok := storage.AsCorruptModules(err); ok {
+		m.logger.Warn("Skipping modules with unreadable metadata during slug check", "moduleIDs", corrupt.IDs(), "error", err)
+		err = nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading existing modules failed: %w", err)
+	}
+	for _, mod := range modules {
+		if mod.ID != moduleID && mod.Slug == slug && !mod.IsArchived() {
+			m.logger.Warn("Rejected slug already in use", "slug", slug, "moduleID", moduleID, "usedBy", mod.ID)
+			return fmt.Errorf("slug %q is already used by module %s (%s)", slug, mod.Name, mod.ID)
+		}
+	}
+
+	if m.pages == nil {
+		return nil
+	}
+	pages, err := m.pages.ReadAllPages()
+	if corrupt, ok := storage.AsCorruptPages(err); ok {
+		m.logger.Warn("Skipping pages with unreadable metadata during slug check", "pageIDs", corrupt.IDs(), "error", err)
+		err = nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading existing pages failed: %w", err)
+	}
+	for _, page := range pages {
+		if page.Slug == slug {
+			m.logger.Warn("Rejected slug used by a page", "slug", slug, "moduleID", moduleID, "pageID", page.ID)
+			return fmt.Errorf("slug %q is already used by page %s (%s)", slug, page.Name, page.ID)
+		}
+	}
+	return nil
+}
+
+// checkSlugFormat returns an error if slug is not a single URL path segment.
+func checkSlugFormat(slug string) error {
+	if slug == "" || slug == "." || slug == ".." || strings.ContainsAny(slug, `/\?#`) {
+		return fmt.Errorf("invalid slug %q: it must be a single URL path segment", slug)
+	}
+	return nil
+}
+
+// MaxGroupLength is the longest module group name accepted, in bytes.
+const MaxGroupLength = 64
+
+// checkGroup returns an error if group cannot be used as a module group: it is too long
+// or contains control characters.
+func checkGroup(group string) error {
+	if len(group) > MaxGroupLength {
+		return fmt.Errorf("invalid group %q: it must be at most %d characters", group, MaxGroupLength)
+	}
+	if strings.ContainsFunc(group, unicode.IsControl) || !utf8.ValidString(group) {
+		return fmt.Errorf("invalid group %q: it must not contain control characters", group)
+	}
+	return nil
+}
+
+// changeSlug moves module to newSlug and records its current slug in OldSlugs, so
+// links to it keep working. Changing back to an old slug removes it from OldSlugs.
+func changeSlug(module *model.Module, newSlug string) {
+	if newSlug == module.Slug {
+		return
+	}
+	oldSlugs := slices.DeleteFunc(slices.Clone(module.OldSlugs), func(s string) bool {
+		return s == newSlug || s == module.Slug
+	})
+	if module.Slug != "" {
+		oldSlugs = append(oldSlugs, module.Slug)
+	}
+	module.OldSlugs = oldSlugs
+	module.Slug = newSlug
+}
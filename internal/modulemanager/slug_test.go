@@ -0,0 +1,86 @@
+package modulemanager
+
+import (
+	"go-module-builder/internal/model"
+	"go-module-builder/internal/storage"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestUpdateModule_Slug(t *testing.T) {
+	m := newTestManager(t)
+	about, err := m.CreateModule("About", "about")
+	if err != nil {
+		t.Fatalf("CreateModule failed: %v", err)
+	}
+	if _, err := m.CreateModule("Contact", "contact"); err != nil {
+		t.Fatalf("CreateModule failed: %v", err)
+	}
+
+	for _, slug := range []string{"contact", "static", "modules", "a/b"} {
+		if err := m.UpdateModule(about.ID, "", slug, "", "", ""); err == nil {
+			t.Errorf("UpdateModule to slug %q did not return an error", slug)
+		}
+	}
+	if _, err := m.CreateModule("Other Contact", "contact"); err == nil {
+		t.Error("CreateModule with a used slug did not return an error")
+	}
+
+	slugs := func() (string, []string) {
+		t.Helper()
+		mod, err := m.GetStore().LoadModule(about.ID)
+		if err != nil {
+			t.Fatalf("LoadModule failed: %v", err)
+		}
+		return mod.Slug, mod.OldSlugs
+	}
+	if err := m.UpdateModule(about.ID, "", "about-us", "", "", ""); err != nil {
+		t.Fatalf("UpdateModule failed: %v", err)
+	}
+	if slug, old := slugs(); slug != "about-us" || !reflect.DeepEqual(old, []string{"about"}) {
+		t.Errorf("After rename: slug %q, old slugs %v; want about-us, [about]", slug, old)
+	}
+	if err := m.UpdateModule(about.ID, "", "team", "", "", ""); err != nil {
+		t.Fatalf("UpdateModule failed: %v", err)
+	}
+	if err := m.UpdateModule(about.ID, "", "about", "", "", ""); err != nil {
+		t.Fatalf("UpdateModule back to an old slug failed: %v", err)
+	}
+	if slug, old := slugs(); slug != "about" || !reflect.DeepEqual(old, []string{"about-us", "team"}) {
+		t.Errorf("After renaming back: slug %q, old slugs %v; want about, [about-us team]", slug, old)
+	}
+
+	// Another module may take an old slug; its current slug wins over the redirect
+	clone, err := m.CloneModule(about.ID, "", "team")
+	if err != nil {
+		t.Fatalf("CloneModule to an old slug of another module failed: %v", err)
+	}
+	if len(clone.OldSlugs) != 0 {
+		t.Errorf("Clone old slugs = %v, want none", clone.OldSlugs)
+	}
+}
+
+func TestCheckSlug_Pages(t *testing.T) {
+	m := newTestManager(t)
+	pages, err := storage.NewJSONPageStore(filepath.Join(m.GetProjectRoot(), ".page_metadata"))
+	if err != nil {
+		t.Fatalf("NewJSONPageStore failed: %v", err)
+	}
+	if err := pages.SavePage(&model.Page{ID: "page-1", Name: "Home", Slug: "home", IsActive: true}); err != nil {
+		t.Fatalf("SavePage failed: %v", err)
+	}
+	m.SetPageStore(pages)
+
+	// Pages are served before modules, so a module at a page's slug would be unreachable
+	if _, err := m.CreateModule("Home Module", "home"); err == nil {
+		t.Error("CreateModule with a page's slug did not return an error")
+	}
+	mod, err := m.CreateModule("About", "about")
+	if err != nil {
+		t.Fatalf("CreateModule failed: %v", err)
+	}
+	if err := m.UpdateModule(mod.ID, "", "home", "", "", ""); err == nil {
+		t.Error("UpdateModule to a page's slug did not return an error")
+	}
+}
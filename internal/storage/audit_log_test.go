@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"go-module-builder/internal/model"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestAuditLogs(t *testing.T) {
+	for _, backend := range []string{BackendJSON, BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "audit", DefaultAuditLogFile)
+			if backend == BackendSQLite {
+				path = filepath.Join(t.TempDir(), "modules.db")
+			}
+			audit, err := OpenAudit(backend, path, nil)
+			if err != nil {
+				t.Fatalf("OpenAudit(%q) failed: %v", backend, err)
+			}
+			if closer, ok := audit.(interface{ Close() error }); ok {
+				t.Cleanup(func() { closer.Close() })
+			}
+
+			if entries, err := audit.QueryAudit(AuditFilter{}); err != nil || len(entries) != 0 {
+				t.Fatalf("QueryAudit on an empty log = %v, %v; want no entries", entries, err)
+			}
+
+			start := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+			for i, e := range []model.AuditEntry{
+				{Actor: "alice", Action: "create", ModuleID: "m1", After: `name="One"`},
+				{Actor: "bob", Action: "update", ModuleID: "m1", Before: `name="One"`, After: `name="Uno"`},
+				{Actor: "alice", Action: "create", ModuleID: "m2"},
+				{Actor: "alice", Action: "delete", ModuleID: "m1"},
+			} {
+				e.Time = start.Add(time.Duration(i) * time.Hour)
+				if err := audit.AppendAudit(&e); err != nil {
+					t.Fatalf("AppendAudit(%d) failed: %v", i, err)
+				}
+			}
+
+			tests := []struct {
+				name    string
+				filter  AuditFilter
+				actions []string
+			}{
+				{"all, newest first", AuditFilter{}, []string{"delete", "create", "update", "create"}},
+				{"module", AuditFilter{ModuleID: "m1"}, []string{"delete", "update", "create"}},
+				{"actor", AuditFilter{Actor: "bob"}, []string{"update"}},
+				{"time range", AuditFilter{Since: start.Add(time.Hour), Until: start.Add(3 * time.Hour)}, []string{"create", "update"}},
+				{"limit", AuditFilter{ModuleID: "m1", Limit: 2}, []string{"delete", "update"}},
+			}
+			for _, tc := range tests {
+				entries, err := audit.QueryAudit(tc.filter)
+				if err != nil {
+					t.Fatalf("%s: QueryAudit failed: %v", tc.name, err)
+				}
+				var actions []string
+				for _, e := range entries {
+					actions = append(actions, e.Action)
+				}
+				if !slices.Equal(actions, tc.actions) {
+					t.Errorf("%s: actions = %v, want %v", tc.name, actions, tc.actions)
+				}
+			}
+
+			entries, _ := audit.QueryAudit(AuditFilter{Actor: "bob"})
+			if len(entries) == 1 && (entries[0].Before != `name="One"` || entries[0].After != `name="Uno"` || !entries[0].Time.Equal(start.Add(time.Hour))) {
+				t.Errorf("QueryAudit returned %+v, want the stored entry unchanged", entries[0])
+			}
+		})
+	}
+}
+
+func TestJSONAuditLog_CorruptLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultAuditLogFile)
+	audit, err := NewJSONAuditLog(path)
+	if err != nil {
+		t.Fatalf("NewJSONAuditLog failed: %v", err)
+	}
+	if err := audit.AppendAudit(&model.AuditEntry{Actor: "alice", Action: "create", ModuleID: "m1"}); err != nil {
+		t.Fatalf("AppendAudit failed: %v", err)
+	}
+	// A crash while appending leaves a partial line behind
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("OpenFile failed: %v", err)
+	}
+	f.WriteString(`{"actor":"bob","act` + "\n")
+	f.Close()
+	if err := audit.AppendAudit(&model.AuditEntry{Actor: "alice", Action: "delete", ModuleID: "m1"}); err != nil {
+		t.Fatalf("AppendAudit failed: %v", err)
+	}
+
+	entries, err := audit.QueryAudit(AuditFilter{})
+	corrupt, ok := AsCorruptAudit(err)
+	if !ok {
+		t.Fatalf("QueryAudit error = %v, want a *CorruptAuditError", err)
+	}
+	if lines := corrupt.LineNumbers(); !slices.Equal(lines, []int{2}) {
+		t.Errorf("Skipped lines = %v, want [2]", lines)
+	}
+	var actions []string
+	for _, e := range entries {
+		actions = append(actions, e.Action)
+	}
+	if !slices.Equal(actions, []string{"delete", "create"}) {
+		t.Errorf("actions = %v, want the readable entries [delete create]", actions)
+	}
+}
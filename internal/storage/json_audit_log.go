@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"go-module-builder/internal/model"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// JSONAuditLog implements the AuditLog interface as a JSON Lines file: one entry per
+// line, only ever appended to.
+type JSONAuditLog struct {
+	// Path is the log file.
+	Path string
+}
+
+// NewJSONAuditLog creates a new JSONAuditLog instance.
+// It ensures the directory of the log file exists; the file is created by the first entry.
+func NewJSONAuditLog(path string) (*JSONAuditLog, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory for '%s': %w", path, err)
+	}
+	return &JSONAuditLog{Path: path}, nil
+}
+
+// AppendAudit writes the entry as a new line at the end of the file. The file is locked
+// while writing so the admin server and the CLI can append at the same time.
+func (al *JSONAuditLog) AppendAudit(entry *model.AuditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+	unlock, err := lockFile(al.Path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	f, err := os.OpenFile(al.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log %s: %w", al.Path, err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write audit log %s: %w", al.Path, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close audit log %s: %w", al.Path, err)
+	}
+	return nil
+}
+
+// QueryAudit reads the whole file and returns the matching entries, newest first.
+// Lines that cannot be decoded are skipped and reported in a *CorruptAuditError.
+func (al *JSONAuditLog) QueryAudit(filter AuditFilter) ([]model.AuditEntry, error) {
+	f, err := os.Open(al.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []model.AuditEntry{}, nil
+		}
+		return nil, fmt.Errorf("failed to open audit log %s: %w", al.Path, err)
+	}
+	defer f.Close()
+
+	entries := []model.AuditEntry{}
+	corrupt := &CorruptAuditError{Path: al.Path}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry model.AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			corrupt.add(line, err)
+			continue
+		}
+		if filter.Matches(&entry) {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log %s: %w", al.Path, err)
+	}
+
+	slices.Reverse(entries) // Appended oldest first
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+	}
+	return entries, corrupt.orNil()
+}
@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-module-builder/internal/model"
+	"go-module-builder/pkg/fsutils"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// JSONPageStore implements the PageStore interface using JSON files.
+// It stores page metadata as individual JSON files, mirroring JSONStore.
+type JSONPageStore struct {
+	// BasePath is the directory where page metadata files (*.json) are stored.
+	BasePath string
+}
+
+// NewJSONPageStore creates a new JSONPageStore instance.
+// It ensures the base storage directory exists.
+func NewJSONPageStore(basePath string) (*JSONPageStore, error) {
+	err := os.MkdirAll(basePath, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create page storage directory '%s': %w", basePath, err)
+	}
+	return &JSONPageStore{BasePath: basePath}, nil
+}
+
+// GetBasePath returns the base path of the JSON page store.
+func (ps *JSONPageStore) GetBasePath() string {
+	return ps.BasePath
+}
+
+// SavePage persists the page's metadata to a JSON file. The file is replaced atomically,
+// so a crash mid-write leaves the previous version.
+func (ps *JSONPageStore) SavePage(page *model.Page) error {
+	if page.ID == "" {
+		return fmt.Errorf("page ID cannot be empty")
+	}
+	filePath := filepath.Join(ps.BasePath, page.ID+".json")
+
+	data, err := json.MarshalIndent(page, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal page %s: %w", page.ID, err)
+	}
+
+	err = fsutils.WriteFileAtomic(filePath, data, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write page file %s: %w", filePath, err)
+	}
+	return nil
+}
+
+// LoadPage retrieves a page's metadata from its JSON file.
+func (ps *JSONPageStore) LoadPage(pageID string) (*model.Page, error) {
+	if pageID == "" {
+		return nil, fmt.Errorf("page ID cannot be empty")
+	}
+	filePath := filepath.Join(ps.BasePath, pageID+".json")
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("page %s not found: %w", pageID, err)
+		}
+		return nil, fmt.Errorf("failed to read page file %s: %w", filePath, err)
+	}
+
+	var page model.Page
+	err = json.Unmarshal(data, &page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal page data from %s: %w", filePath, err)
+	}
+	return &page, nil
+}
+
+// GetAllPageIDs scans the BasePath directory for *.json files and extracts IDs.
+func (ps *JSONPageStore) GetAllPageIDs() ([]string, error) {
+	files, err := os.ReadDir(ps.BasePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, fmt.Errorf("failed to read page storage directory %s: %w", ps.BasePath, err)
+	}
+
+	var ids []string
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), ".json") {
+			ids = append(ids, strings.TrimSuffix(file.Name(), ".json"))
+		}
+	}
+	return ids, nil
+}
+
+// DeletePage removes the page's JSON metadata file.
+// Deleting a page never touches the modules it references.
+func (ps *JSONPageStore) DeletePage(pageID string) error {
+	if pageID == "" {
+		return fmt.Errorf("page ID cannot be empty")
+	}
+	filePath := filepath.Join(ps.BasePath, pageID+".json")
+
+	err := os.Remove(filePath)
+	if err != nil {
+		// Idempotent delete, as in JSONStore.DeleteModule
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to delete page file %s: %w", filePath, err)
+	}
+	return nil
+}
+
+// ReadAllPages retrieves metadata for all pages by loading each one individually.
+// Files that cannot be read or decoded are skipped and reported in a *CorruptPagesError
+// returned alongside the pages that did load.
+func (ps *JSONPageStore) ReadAllPages() ([]*model.Page, error) {
+	ids, err := ps.GetAllPageIDs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get page IDs: %w", err)
+	}
+
+	pages := make([]*model.Page, 0, len(ids))
+	var corrupt CorruptPagesError
+	for _, id := range ids {
+		page, err := ps.LoadPage(id)
+		if err != nil {
+			corrupt.add(id, err)
+			continue
+		}
+		pages = append(pages, page)
+	}
+	return pages, corrupt.orNil()
+}
@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"errors"
+	"go-module-builder/internal/model"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Helper function to create a sample page for testing
+func createSamplePage(id, slug string) *model.Page {
+	now := time.Now().UTC().Truncate(time.Second)
+	return &model.Page{
+		ID:     id,
+		Name:   "Page " + id,
+		Slug:   slug,
+		Layout: "layout.html",
+		Modules: []model.ModuleInstance{
+			{ModuleID: "hero", Order: 0, Config: map[string]any{"title": "Welcome"}},
+			{ModuleID: "card", Order: 1},
+		},
+		CreatedAt:   now,
+		LastUpdated: now,
+		IsActive:    true,
+	}
+}
+
+func TestSaveLoadPage(t *testing.T) {
+	tempDir := t.TempDir()
+	store, err := NewJSONPageStore(filepath.Join(tempDir, ".test_pages"))
+	if err != nil {
+		t.Fatalf("NewJSONPageStore() failed: %v", err)
+	}
+
+	original := createSamplePage("page-1", "home")
+	if err := store.SavePage(original); err != nil {
+		t.Fatalf("SavePage() failed: %v", err)
+	}
+
+	loaded, err := store.LoadPage("page-1")
+	if err != nil {
+		t.Fatalf("LoadPage() failed: %v", err)
+	}
+	if !reflect.DeepEqual(original, loaded) {
+		t.Errorf("LoadPage() loaded page does not match original.\nOriginal: %+v\nLoaded:   %+v", original, loaded)
+	}
+}
+
+func TestLoadPage_NotFound(t *testing.T) {
+	store, err := NewJSONPageStore(filepath.Join(t.TempDir(), ".test_pages"))
+	if err != nil {
+		t.Fatalf("NewJSONPageStore() failed: %v", err)
+	}
+
+	_, err = store.LoadPage("missing")
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("LoadPage() returned error %v, expected an error wrapping os.ErrNotExist", err)
+	}
+}
+
+func TestDeleteAndReadAllPages(t *testing.T) {
+	store, err := NewJSONPageStore(filepath.Join(t.TempDir(), ".test_pages"))
+	if err != nil {
+		t.Fatalf("NewJSONPageStore() failed: %v", err)
+	}
+
+	for _, p := range []*model.Page{createSamplePage("a", "a"), createSamplePage("b", "b")} {
+		if err := store.SavePage(p); err != nil {
+			t.Fatalf("Setup failed: SavePage(%s) failed: %v", p.ID, err)
+		}
+	}
+
+	if err := store.DeletePage("a"); err != nil {
+		t.Fatalf("DeletePage() failed: %v", err)
+	}
+	// Deleting twice is not an error
+	if err := store.DeletePage("a"); err != nil {
+		t.Fatalf("Second DeletePage() failed: %v", err)
+	}
+
+	pages, err := store.ReadAllPages()
+	if err != nil {
+		t.Fatalf("ReadAllPages() failed: %v", err)
+	}
+	if len(pages) != 1 || pages[0].ID != "b" {
+		t.Errorf("ReadAllPages() returned %+v, want only page b", pages)
+	}
+}
+
+func TestReadAllPages_CorruptEntry(t *testing.T) {
+	basePath := filepath.Join(t.TempDir(), ".test_pages")
+	store, err := NewJSONPageStore(basePath)
+	if err != nil {
+		t.Fatalf("NewJSONPageStore() failed: %v", err)
+	}
+
+	if err := store.SavePage(createSamplePage("good", "good")); err != nil {
+		t.Fatalf("SavePage() failed: %v", err)
+	}
+	// Simulate a file truncated by a crash mid-write
+	if err := os.WriteFile(filepath.Join(basePath, "broken.json"), []byte(`{"id": "broken", "sl`), 0644); err != nil {
+		t.Fatalf("Failed to write corrupt page file: %v", err)
+	}
+
+	pages, err := store.ReadAllPages()
+	corrupt, ok := AsCorruptPages(err)
+	if !ok {
+		t.Fatalf("ReadAllPages() error = %v, want a *CorruptPagesError", err)
+	}
+	if ids := corrupt.IDs(); !reflect.DeepEqual(ids, []string{"broken"}) {
+		t.Errorf("CorruptPagesError.IDs() = %v, want [broken]", ids)
+	}
+	if len(pages) != 1 || pages[0].ID != "good" {
+		t.Errorf("ReadAllPages() returned %v, want only good", pages)
+	}
+}
+
+func TestPageSortedModules(t *testing.T) {
+	page := &model.Page{Modules: []model.ModuleInstance{
+		{ModuleID: "third", Order: 5},
+		{ModuleID: "first", Order: 0},
+		{ModuleID: "second", Order: 0},
+	}}
+
+	sorted := page.SortedModules()
+	got := []string{sorted[0].ModuleID, sorted[1].ModuleID, sorted[2].ModuleID}
+	want := []string{"first", "second", "third"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SortedModules() order = %v, want %v", got, want)
+	}
+	if page.Modules[0].ModuleID != "third" {
+		t.Errorf("SortedModules() must not reorder the page's own slice")
+	}
+}
@@ -4,10 +4,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"go-module-builder/internal/model"
+	"go-module-builder/pkg/fsutils"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
-	// "go-module-builder/pkg/fsutils" // Will likely need this later
 )
 
 // JSONStore implements the DataStore interface using JSON files.
@@ -15,17 +16,21 @@ import (
 type JSONStore struct {
 	// BasePath is the directory where module metadata files (*.json) are stored.
 	BasePath string
+	logger   *slog.Logger
 }
 
 // NewJSONStore creates a new JSONStore instance.
-// It ensures the base storage directory exists.
-func NewJSONStore(basePath string) (*JSONStore, error) {
+// It ensures the base storage directory exists. A nil logger discards the store's debug output.
+func NewJSONStore(basePath string, logger *slog.Logger) (*JSONStore, error) {
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
 	// Use os.MkdirAll for robust directory creation
 	err := os.MkdirAll(basePath, 0755) // Use standard permission bits
 	if err != nil {
 		return nil, fmt.Errorf("failed to create storage directory '%s': %w", basePath, err)
 	}
-	return &JSONStore{BasePath: basePath}, nil
+	return &JSONStore{BasePath: basePath, logger: logger}, nil
 }
 
 // GetBasePath returns the base path of the JSON store.
@@ -34,6 +39,8 @@ func (s *JSONStore) GetBasePath() string {
 }
 
 // SaveModule persists the module's metadata to a JSON file.
+// The revision check and the write happen under a per-module lock file, so concurrent
+// writers in this or another process (admin server, CLI) cannot overwrite each other.
 func (js *JSONStore) SaveModule(module *model.Module) error {
 	if module.ID == "" {
 		return fmt.Errorf("module ID cannot be empty")
@@ -42,21 +49,57 @@ func (js *JSONStore) SaveModule(module *model.Module) error {
 	// Ensure module.Directory is set correctly before saving.
 	filePath := filepath.Join(js.BasePath, module.ID+".json")
 
+	unlock, err := lockFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to lock module %s: %w", module.ID, err)
+	}
+	defer unlock()
+
+	stored, err := js.storedRevision(filePath)
+	if err != nil {
+		return err
+	}
+	if stored != module.Revision {
+		return &ConflictError{ModuleID: module.ID, Expected: module.Revision, Actual: stored}
+	}
+
 	// Use MarshalIndent for readable JSON files
-	data, err := json.MarshalIndent(module, "", "  ")
+	next := *module
+	next.Revision++
+	data, err := json.MarshalIndent(&next, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal module %s: %w", module.ID, err)
 	}
 
-	// Use os.WriteFile for simpler file writing
-	err = os.WriteFile(filePath, data, 0644) // Standard file permissions
+	// Write atomically so a crash mid-write cannot leave a truncated metadata file
+	err = fsutils.WriteFileAtomic(filePath, data, 0644) // Standard file permissions
 	if err != nil {
 		return fmt.Errorf("failed to write module file %s: %w", filePath, err)
 	}
-	fmt.Printf("Placeholder: Saved module metadata to %s\n", filePath) // Placeholder log
+	module.Revision = next.Revision
+	js.logger.Debug("Saved module metadata", "moduleID", module.ID, "path", filePath, "revision", module.Revision)
 	return nil
 }
 
+// storedRevision returns the revision of the module file at filePath, or 0 if it does not exist.
+// A file that cannot be decoded also counts as 0 so a corrupt entry can be repaired by saving over it.
+func (js *JSONStore) storedRevision(filePath string) (int, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read module file %s: %w", filePath, err)
+	}
+	var stored struct {
+		Revision int `json:"revision"`
+	}
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return 0, nil
+	}
+	return stored.Revision, nil
+}
+
 // LoadModule retrieves a module's metadata from its JSON file.
 func (js *JSONStore) LoadModule(moduleID string) (*model.Module, error) {
 	if moduleID == "" {
@@ -78,7 +121,7 @@ func (js *JSONStore) LoadModule(moduleID string) (*model.Module, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal module data from %s: %w", filePath, err)
 	}
-	fmt.Printf("Placeholder: Loaded module metadata from %s\n", filePath) // Placeholder log
+	js.logger.Debug("Loaded module metadata", "moduleID", moduleID, "path", filePath)
 	return &module, nil
 }
 
@@ -100,7 +143,7 @@ func (js *JSONStore) GetAllModuleIDs() ([]string, error) {
 			ids = append(ids, id)
 		}
 	}
-	fmt.Printf("Placeholder: Found module IDs: %v\n", ids) // Placeholder log
+	js.logger.Debug("Found module IDs", "count", len(ids))
 	return ids, nil
 }
 
@@ -116,16 +159,18 @@ func (js *JSONStore) DeleteModule(moduleID string) error {
 	if err != nil {
 		// Make it non-fatal if the file doesn't exist (idempotent delete)
 		if os.IsNotExist(err) {
-			fmt.Printf("Placeholder: Module metadata file %s already deleted or never existed.\n", filePath)
+			js.logger.Debug("Module metadata file already deleted or never existed", "moduleID", moduleID, "path", filePath)
 			return nil
 		}
 		return fmt.Errorf("failed to delete module file %s: %w", filePath, err)
 	}
-	fmt.Printf("Placeholder: Deleted module metadata file %s\n", filePath) // Placeholder log
+	js.logger.Debug("Deleted module metadata file", "moduleID", moduleID, "path", filePath)
 	return nil
 }
 
 // ReadAll retrieves metadata for all modules by loading each one individually.
+// Files that cannot be read or decoded are skipped and reported in a *CorruptModulesError
+// returned alongside the modules that did load.
 func (js *JSONStore) ReadAll() ([]*model.Module, error) {
 	ids, err := js.GetAllModuleIDs()
 	if err != nil {
@@ -133,16 +178,93 @@ func (js *JSONStore) ReadAll() ([]*model.Module, error) {
 	}
 
 	modules := make([]*model.Module, 0, len(ids))
+	var corrupt CorruptModulesError
 	for _, id := range ids {
 		module, err := js.LoadModule(id)
 		if err != nil {
-			// If a single module fails to load, we might want to log it and continue,
-			// or return the error immediately. Let's return immediately for now.
-			return nil, fmt.Errorf("failed to load module %s during ReadAll: %w", id, err)
+			corrupt.add(id, err)
+			continue
 		}
 		modules = append(modules, module)
 	}
 
-	fmt.Printf("Placeholder: Loaded %d modules for ReadAll\n", len(modules)) // Placeholder log
-	return modules, nil
+	js.logger.Debug("Loaded modules for ReadAll", "count", len(modules), "skipped", len(corrupt.Modules))
+	return modules, corrupt.orNil()
+}
+
+// snapshotDir returns the directory holding a module's snapshots: BasePath/snapshots/{moduleID}.
+func (js *JSONStore) snapshotDir(moduleID string) string {
+	return filepath.Join(js.BasePath, "snapshots", moduleID)
+}
+
+// SaveSnapshot writes the snapshot to BasePath/snapshots/{moduleID}/{snapshotID}.json.
+func (js *JSONStore) SaveSnapshot(snapshot *model.Snapshot) error {
+	if err := validateSnapshotKey(snapshot.ModuleID, snapshot.ID); err != nil {
+		return err
+	}
+	dir := js.snapshotDir(snapshot.ModuleID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory %s: %w", dir, err)
+	}
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot %s of module %s: %w", snapshot.ID, snapshot.ModuleID, err)
+	}
+	filePath := filepath.Join(dir, snapshot.ID+".json")
+	if err := fsutils.WriteFileAtomic(filePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write snapshot file %s: %w", filePath, err)
+	}
+	return nil
+}
+
+// ListSnapshots returns the module's snapshots, newest first.
+// A module without snapshots returns an empty list.
+func (js *JSONStore) ListSnapshots(moduleID string) ([]model.SnapshotInfo, error) {
+	if err := validateSnapshotKey(moduleID, "list"); err != nil {
+		return nil, err
+	}
+	dir := js.snapshotDir(moduleID)
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []model.SnapshotInfo{}, nil
+		}
+		return nil, fmt.Errorf("failed to read snapshot directory %s: %w", dir, err)
+	}
+
+	infos := make([]model.SnapshotInfo, 0, len(files))
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		snapshot, err := js.LoadSnapshot(moduleID, strings.TrimSuffix(file.Name(), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, snapshot.SnapshotInfo)
+	}
+	sortSnapshotInfos(infos)
+	return infos, nil
+}
+
+// LoadSnapshot reads a single snapshot. A missing snapshot returns an error wrapping os.ErrNotExist.
+func (js *JSONStore) LoadSnapshot(moduleID, snapshotID string) (*model.Snapshot, error) {
+	if err := validateSnapshotKey(moduleID, snapshotID); err != nil {
+		return nil, err
+	}
+	filePath := filepath.Join(js.snapshotDir(moduleID), snapshotID+".json")
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("snapshot %s of module %s not found: %w", snapshotID, moduleID, err)
+		}
+		return nil, fmt.Errorf("failed to read snapshot file %s: %w", filePath, err)
+	}
+
+	var snapshot model.Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal snapshot data from %s: %w", filePath, err)
+	}
+	return &snapshot, nil
 }
@@ -2,25 +2,27 @@ package storage
 
 import (
 	"errors" // Added for errors.Is
+	"fmt"
 	"go-module-builder/internal/model"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
 
 // Helper function to create a sample module for testing
 func createSampleModule(id, name string) *model.Module {
-	now := time.Now()
+	// Use UTC so the value round-trips through JSON with the same Location
+	now := time.Now().UTC()
 	return &model.Module{
-		ID:        id,
-		Name:      name,
-		Directory: filepath.Join("modules", id), // Example path
-		// Status:      "active", // Replaced by IsActive
+		ID:          id,
+		Name:        name,
+		Directory:   filepath.Join("modules", id), // Example path
 		CreatedAt:   now,
 		LastUpdated: now,
-		IsActive:    true,                               // Default to active for tests
+		Status:      model.StatusPublished,              // Default to published for tests
 		Group:       "TestGroup",                        // Sample group
 		Layout:      "layouts/test-layout.html",         // Sample layout path
 		Assets:      []string{"global.css", "logo.png"}, // Sample assets
@@ -36,7 +38,7 @@ func TestNewJSONStore(t *testing.T) {
 	tempDir := t.TempDir() // Creates a temporary directory for the test
 	metadataPath := filepath.Join(tempDir, ".test_metadata")
 
-	store, err := NewJSONStore(metadataPath)
+	store, err := NewJSONStore(metadataPath, nil)
 	if err != nil {
 		t.Fatalf("NewJSONStore() failed: %v", err)
 	}
@@ -59,7 +61,7 @@ func TestNewJSONStore(t *testing.T) {
 func TestSaveLoadModule(t *testing.T) {
 	tempDir := t.TempDir()
 	metadataPath := filepath.Join(tempDir, ".test_metadata")
-	store, err := NewJSONStore(metadataPath)
+	store, err := NewJSONStore(metadataPath, nil)
 	if err != nil {
 		t.Fatalf("NewJSONStore() failed: %v", err)
 	}
@@ -97,8 +99,8 @@ func TestSaveLoadModule(t *testing.T) {
 	}
 
 	// Explicit checks for new fields
-	if loadedModule.IsActive != originalModule.IsActive {
-		t.Errorf("IsActive mismatch: got %v, want %v", loadedModule.IsActive, originalModule.IsActive)
+	if loadedModule.Status != originalModule.Status {
+		t.Errorf("Status mismatch: got %v, want %v", loadedModule.Status, originalModule.Status)
 	}
 	if loadedModule.Group != originalModule.Group {
 		t.Errorf("Group mismatch: got %q, want %q", loadedModule.Group, originalModule.Group)
@@ -118,7 +120,7 @@ func TestSaveLoadModule(t *testing.T) {
 func TestLoadModule_NotFound(t *testing.T) {
 	tempDir := t.TempDir()
 	metadataPath := filepath.Join(tempDir, ".test_metadata")
-	store, err := NewJSONStore(metadataPath)
+	store, err := NewJSONStore(metadataPath, nil)
 	if err != nil {
 		t.Fatalf("NewJSONStore() failed: %v", err)
 	}
@@ -139,7 +141,7 @@ func TestLoadModule_NotFound(t *testing.T) {
 func TestDeleteModule(t *testing.T) {
 	tempDir := t.TempDir()
 	metadataPath := filepath.Join(tempDir, ".test_metadata")
-	store, err := NewJSONStore(metadataPath)
+	store, err := NewJSONStore(metadataPath, nil)
 	if err != nil {
 		t.Fatalf("NewJSONStore() failed: %v", err)
 	}
@@ -185,7 +187,7 @@ func TestDeleteModule(t *testing.T) {
 func TestReadAll(t *testing.T) {
 	tempDir := t.TempDir()
 	metadataPath := filepath.Join(tempDir, ".test_metadata")
-	store, err := NewJSONStore(metadataPath)
+	store, err := NewJSONStore(metadataPath, nil)
 	if err != nil {
 		t.Fatalf("NewJSONStore() failed: %v", err)
 	}
@@ -235,8 +237,8 @@ func TestReadAll(t *testing.T) {
 		}
 
 		// Explicit checks for new fields
-		if loadedMod.IsActive != originalMod.IsActive {
-			t.Errorf("ReadAll() IsActive mismatch for %s: got %v, want %v", loadedMod.ID, loadedMod.IsActive, originalMod.IsActive)
+		if loadedMod.Status != originalMod.Status {
+			t.Errorf("ReadAll() Status mismatch for %s: got %v, want %v", loadedMod.ID, loadedMod.Status, originalMod.Status)
 		}
 		if loadedMod.Group != originalMod.Group {
 			t.Errorf("ReadAll() Group mismatch for %s: got %q, want %q", loadedMod.ID, loadedMod.Group, originalMod.Group)
@@ -252,3 +254,203 @@ func TestReadAll(t *testing.T) {
 		}
 	}
 }
+
+func TestReadAll_CorruptEntry(t *testing.T) {
+	tempDir := t.TempDir()
+	metadataPath := filepath.Join(tempDir, ".test_metadata")
+	store, err := NewJSONStore(metadataPath, nil)
+	if err != nil {
+		t.Fatalf("NewJSONStore() failed: %v", err)
+	}
+
+	if err := store.SaveModule(createSampleModule("good-1", "Good Module")); err != nil {
+		t.Fatalf("SaveModule() failed: %v", err)
+	}
+	// Simulate a file truncated by a crash mid-write
+	if err := os.WriteFile(filepath.Join(metadataPath, "broken-1.json"), []byte(`{"id": "broken-1", "na`), 0644); err != nil {
+		t.Fatalf("Failed to write corrupt metadata file: %v", err)
+	}
+
+	modules, err := store.ReadAll()
+	corrupt, ok := AsCorruptModules(err)
+	if !ok {
+		t.Fatalf("ReadAll() error = %v, want a *CorruptModulesError", err)
+	}
+	if ids := corrupt.IDs(); !reflect.DeepEqual(ids, []string{"broken-1"}) {
+		t.Errorf("CorruptModulesError.IDs() = %v, want [broken-1]", ids)
+	}
+	if len(modules) != 1 || modules[0].ID != "good-1" {
+		t.Errorf("ReadAll() returned %v, want only good-1", modules)
+	}
+}
+
+func TestSaveModule_NoTempFilesLeft(t *testing.T) {
+	metadataPath := filepath.Join(t.TempDir(), ".test_metadata")
+	store, err := NewJSONStore(metadataPath, nil)
+	if err != nil {
+		t.Fatalf("NewJSONStore() failed: %v", err)
+	}
+
+	module := createSampleModule("atomic-1", "Atomic Module")
+	for i := 0; i < 3; i++ {
+		module.Name = fmt.Sprintf("Atomic Module v%d", i)
+		if err := store.SaveModule(module); err != nil {
+			t.Fatalf("SaveModule() failed: %v", err)
+		}
+	}
+
+	entries, err := os.ReadDir(metadataPath)
+	if err != nil {
+		t.Fatalf("Failed to read metadata directory: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "atomic-1.json" {
+		t.Errorf("Metadata directory contains %v, want only atomic-1.json", entries)
+	}
+	loaded, err := store.LoadModule("atomic-1")
+	if err != nil {
+		t.Fatalf("LoadModule() failed: %v", err)
+	}
+	if loaded.Name != "Atomic Module v2" {
+		t.Errorf("LoadModule() name = %q, want the last saved version", loaded.Name)
+	}
+}
+
+func TestSaveModule_StaleRevisionConflict(t *testing.T) {
+	store, err := NewJSONStore(filepath.Join(t.TempDir(), ".test_metadata"), nil)
+	if err != nil {
+		t.Fatalf("NewJSONStore() failed: %v", err)
+	}
+	testStaleRevisionConflict(t, store)
+}
+
+// testStaleRevisionConflict simulates two editors that load the same module and save in turn.
+func testStaleRevisionConflict(t *testing.T, store DataStore) {
+	t.Helper()
+	module := createSampleModule("conflict-1", "Original")
+	if err := store.SaveModule(module); err != nil {
+		t.Fatalf("SaveModule() of a new module failed: %v", err)
+	}
+	if module.Revision != 1 {
+		t.Fatalf("Revision after first save = %d, want 1", module.Revision)
+	}
+
+	first, err := store.LoadModule(module.ID)
+	if err != nil {
+		t.Fatalf("LoadModule() failed: %v", err)
+	}
+	second, err := store.LoadModule(module.ID)
+	if err != nil {
+		t.Fatalf("LoadModule() failed: %v", err)
+	}
+
+	first.Name = "First editor"
+	if err := store.SaveModule(first); err != nil {
+		t.Fatalf("SaveModule() by the first editor failed: %v", err)
+	}
+
+	second.Name = "Second editor"
+	err = store.SaveModule(second)
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("SaveModule() with a stale revision returned %v, want *ConflictError", err)
+	}
+	if conflict.Expected != 1 || conflict.Actual != 2 {
+		t.Errorf("ConflictError = %+v, want Expected 1, Actual 2", conflict)
+	}
+	if second.Revision != 1 {
+		t.Errorf("Revision of rejected module changed to %d", second.Revision)
+	}
+
+	loaded, err := store.LoadModule(module.ID)
+	if err != nil {
+		t.Fatalf("LoadModule() failed: %v", err)
+	}
+	if loaded.Name != "First editor" || loaded.Revision != 2 {
+		t.Errorf("Stored module = %q rev %d, want the first editor's save at rev 2", loaded.Name, loaded.Revision)
+	}
+
+	// A new module with a non-zero revision means it was deleted since it was loaded
+	ghost := createSampleModule("conflict-ghost", "Ghost")
+	ghost.Revision = 3
+	if err := store.SaveModule(ghost); !IsConflict(err) {
+		t.Errorf("SaveModule() of a missing module with revision 3 returned %v, want a conflict", err)
+	}
+}
+
+func TestSaveModule_ConcurrentWriters(t *testing.T) {
+	store, err := NewJSONStore(filepath.Join(t.TempDir(), ".test_metadata"), nil)
+	if err != nil {
+		t.Fatalf("NewJSONStore() failed: %v", err)
+	}
+	module := createSampleModule("race-1", "Race")
+	if err := store.SaveModule(module); err != nil {
+		t.Fatalf("SaveModule() failed: %v", err)
+	}
+
+	// All writers start from the same revision; exactly one may win.
+	const writers = 8
+	results := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		edit := *module
+		edit.Name = fmt.Sprintf("Writer %d", i)
+		go func() { results <- store.SaveModule(&edit) }()
+	}
+	wins := 0
+	for i := 0; i < writers; i++ {
+		err := <-results
+		if err == nil {
+			wins++
+		} else if !IsConflict(err) {
+			t.Errorf("SaveModule() returned unexpected error: %v", err)
+		}
+	}
+	if wins != 1 {
+		t.Errorf("%d concurrent writers succeeded, want exactly 1", wins)
+	}
+}
+
+func TestLoadModule_LegacyIsActive(t *testing.T) {
+	metadataPath := filepath.Join(t.TempDir(), ".test_metadata")
+	store, err := NewJSONStore(metadataPath, nil)
+	if err != nil {
+		t.Fatalf("NewJSONStore() failed: %v", err)
+	}
+	// Metadata written before modules had a status
+	legacy := map[string]string{
+		"live":    `{"id": "live", "directory": "modules/live", "is_active": true}`,
+		"off":     `{"id": "off", "directory": "modules/off", "is_active": false}`,
+		"trashed": `{"id": "trashed", "directory": "modules_removed/trashed", "is_active": false}`,
+		"current": `{"id": "current", "directory": "modules/current", "status": "draft", "is_active": true}`,
+	}
+	for id, content := range legacy {
+		if err := os.WriteFile(filepath.Join(metadataPath, id+".json"), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write legacy metadata: %v", err)
+		}
+	}
+
+	want := map[string]model.ModuleStatus{
+		"live":    model.StatusPublished,
+		"off":     model.StatusDisabled,
+		"trashed": model.StatusArchived,
+		"current": model.StatusDraft, // An explicit status wins
+	}
+	for id, status := range want {
+		module, err := store.LoadModule(id)
+		if err != nil {
+			t.Fatalf("LoadModule(%s) failed: %v", id, err)
+		}
+		if module.Status != status {
+			t.Errorf("LoadModule(%s).Status = %q, want %q", id, module.Status, status)
+		}
+	}
+
+	// The status is written with the next save
+	module, _ := store.LoadModule("off")
+	if err := store.SaveModule(module); err != nil {
+		t.Fatalf("SaveModule() failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(metadataPath, "off.json"))
+	if err != nil || !strings.Contains(string(data), `"status": "disabled"`) || strings.Contains(string(data), "is_active\": false") {
+		t.Errorf("Saved metadata = %s, %v; want the status without the legacy flag", data, err)
+	}
+}
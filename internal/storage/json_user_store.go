@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-module-builder/internal/model"
+	"go-module-builder/pkg/fsutils"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// JSONUserStore implements the UserStore interface using one JSON file per account.
+// The files hold password hashes, so they are only readable by their owner.
+type JSONUserStore struct {
+	// BasePath is the directory where account files ({username}.json) are stored.
+	BasePath string
+}
+
+// NewJSONUserStore creates a new JSONUserStore instance.
+// It ensures the base storage directory exists.
+func NewJSONUserStore(basePath string) (*JSONUserStore, error) {
+	if err := os.MkdirAll(basePath, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create user storage directory '%s': %w", basePath, err)
+	}
+	return &JSONUserStore{BasePath: basePath}, nil
+}
+
+// userPath returns the file of the account, after checking the username is safe to use as a file name.
+func (us *JSONUserStore) userPath(username string) (string, error) {
+	if err := model.ValidateUsername(username); err != nil {
+		return "", err
+	}
+	return filepath.Join(us.BasePath, username+".json"), nil
+}
+
+// SaveUser writes the account to its JSON file.
+func (us *JSONUserStore) SaveUser(user *model.User) error {
+	filePath, err := us.userPath(user.Username)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(user, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal user %s: %w", user.Username, err)
+	}
+	if err := fsutils.WriteFileAtomic(filePath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write user file %s: %w", filePath, err)
+	}
+	return nil
+}
+
+// LoadUser reads an account from its JSON file.
+func (us *JSONUserStore) LoadUser(username string) (*model.User, error) {
+	filePath, err := us.userPath(username)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("user %s: %w", username, ErrUserNotFound)
+		}
+		return nil, fmt.Errorf("failed to read user file %s: %w", filePath, err)
+	}
+	var user model.User
+	if err := json.Unmarshal(data, &user); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal user data from %s: %w", filePath, err)
+	}
+	return &user, nil
+}
+
+// DeleteUser removes the account's JSON file.
+func (us *JSONUserStore) DeleteUser(username string) error {
+	filePath, err := us.userPath(username)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filePath); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("user %s: %w", username, ErrUserNotFound)
+		}
+		return fmt.Errorf("failed to delete user file %s: %w", filePath, err)
+	}
+	return nil
+}
+
+// ReadAllUsers loads every account file in BasePath.
+func (us *JSONUserStore) ReadAllUsers() ([]*model.User, error) {
+	files, err := os.ReadDir(us.BasePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*model.User{}, nil
+		}
+		return nil, fmt.Errorf("failed to read user storage directory %s: %w", us.BasePath, err)
+	}
+
+	users := make([]*model.User, 0, len(files))
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+		user, err := us.LoadUser(strings.TrimSuffix(file.Name(), ".json"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load user during ReadAllUsers: %w", err)
+		}
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Username < users[j].Username })
+	return users, nil
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	// lockTimeout is how long lockFile waits for another writer to release a lock.
+	lockTimeout = 5 * time.Second
+	// lockStaleAfter is the age after which a lock file is assumed to be left over
+	// from a crashed process and is removed. Writes hold the lock for milliseconds.
+	lockStaleAfter = 30 * time.Second
+	lockRetryDelay = 10 * time.Millisecond
+)
+
+// lockFile takes an exclusive lock on path by creating path+".lock". The lock works
+// across processes because file creation with O_EXCL is atomic. The returned function
+// releases the lock.
+func lockFile(path string) (unlock func(), err error) {
+	lockPath := path + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create lock file %s: %w", lockPath, err)
+		}
+
+		if info, statErr := os.Stat(lockPath); statErr == nil && time.Since(info.ModTime()) > lockStaleAfter {
+			os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for lock file %s", lockPath)
+		}
+		time.Sleep(lockRetryDelay)
+	}
+}
@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// MigrateOptions controls how Migrate copies modules between stores.
+type MigrateOptions struct {
+	// DryRun reports what would be copied without writing to the destination.
+	DryRun bool
+	// Overwrite replaces modules that already exist in the destination.
+	// Without it, any ID present in both stores aborts the migration before writing.
+	Overwrite bool
+}
+
+// MigrationReport summarises a Migrate run.
+type MigrationReport struct {
+	Total       int      // Modules read from the source
+	Copied      []string // IDs written to the destination (or that would be, on a dry run)
+	Overwritten []string // Subset of Copied that already existed in the destination
+	Verified    int      // Modules read back from the destination and found identical
+	DryRun      bool
+}
+
+// Migrate copies every module's metadata from src to dst and verifies the result:
+// the destination must contain every source ID and each module must read back with
+// the same fields. dst may be nil on a dry run when the destination does not exist yet.
+func Migrate(src, dst DataStore, opts MigrateOptions) (*MigrationReport, error) {
+	if dst == nil && !opts.DryRun {
+		return nil, fmt.Errorf("destination store is required")
+	}
+
+	modules, err := src.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read modules from source %s: %w", src.GetBasePath(), err)
+	}
+	sort.Slice(modules, func(i, j int) bool { return modules[i].ID < modules[j].ID })
+
+	report := &MigrationReport{Total: len(modules), DryRun: opts.DryRun}
+
+	existing := make(map[string]bool)
+	if dst != nil {
+		ids, err := dst.GetAllModuleIDs()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list modules in destination %s: %w", dst.GetBasePath(), err)
+		}
+		for _, id := range ids {
+			existing[id] = true
+		}
+	}
+
+	for _, mod := range modules {
+		report.Copied = append(report.Copied, mod.ID)
+		if existing[mod.ID] {
+			report.Overwritten = append(report.Overwritten, mod.ID)
+		}
+	}
+	if len(report.Overwritten) > 0 && !opts.Overwrite {
+		return report, fmt.Errorf("%d module(s) already exist in destination (%v); use overwrite to replace them", len(report.Overwritten), report.Overwritten)
+	}
+	if opts.DryRun {
+		return report, nil
+	}
+
+	for _, mod := range modules {
+		// Revisions are per store: base the copy on whatever the destination holds
+		// (nothing for a new module) so SaveModule's conflict check passes.
+		mod.Revision = 0
+		if existing[mod.ID] {
+			if current, err := dst.LoadModule(mod.ID); err == nil {
+				mod.Revision = current.Revision
+			}
+		}
+		if err := dst.SaveModule(mod); err != nil {
+			return report, fmt.Errorf("failed to copy module %s: %w", mod.ID, err)
+		}
+	}
+
+	// --- Verification ---
+	dstIDs, err := dst.GetAllModuleIDs()
+	if err != nil {
+		return report, fmt.Errorf("verification failed: could not list destination modules: %w", err)
+	}
+	if len(dstIDs) < len(modules) {
+		return report, fmt.Errorf("verification failed: destination has %d modules, expected at least %d", len(dstIDs), len(modules))
+	}
+	for _, mod := range modules {
+		copied, err := dst.LoadModule(mod.ID)
+		if err != nil {
+			return report, fmt.Errorf("verification failed: could not load module %s from destination: %w", mod.ID, err)
+		}
+		// Compare the serialised form so times that round-trip to an equivalent
+		// Location still count as equal.
+		want, err := json.Marshal(mod)
+		if err != nil {
+			return report, fmt.Errorf("verification failed: could not marshal source module %s: %w", mod.ID, err)
+		}
+		got, err := json.Marshal(copied)
+		if err != nil {
+			return report, fmt.Errorf("verification failed: could not marshal destination module %s: %w", mod.ID, err)
+		}
+		if !bytes.Equal(want, got) {
+			return report, fmt.Errorf("verification failed: module %s differs between source and destination", mod.ID)
+		}
+		report.Verified++
+	}
+	return report, nil
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func newMigrateSource(t *testing.T, ids ...string) *JSONStore {
+	t.Helper()
+	src, err := NewJSONStore(filepath.Join(t.TempDir(), ".module_metadata"), nil)
+	if err != nil {
+		t.Fatalf("NewJSONStore() failed: %v", err)
+	}
+	for _, id := range ids {
+		if err := src.SaveModule(createSampleModule(id, "Module "+id)); err != nil {
+			t.Fatalf("Setup failed: SaveModule(%s) failed: %v", id, err)
+		}
+	}
+	return src
+}
+
+func TestMigrate_JSONToSQLite(t *testing.T) {
+	src := newMigrateSource(t, "b", "a", "c")
+	dst := newTestSQLiteStore(t)
+
+	report, err := Migrate(src, dst, MigrateOptions{})
+	if err != nil {
+		t.Fatalf("Migrate() failed: %v", err)
+	}
+	if report.Total != 3 || report.Verified != 3 {
+		t.Errorf("Migrate() report Total=%d Verified=%d, want 3/3", report.Total, report.Verified)
+	}
+	if !reflect.DeepEqual(report.Copied, []string{"a", "b", "c"}) {
+		t.Errorf("Migrate() copied %v, want [a b c]", report.Copied)
+	}
+
+	for _, id := range report.Copied {
+		want, _ := src.LoadModule(id)
+		got, err := dst.LoadModule(id)
+		if err != nil {
+			t.Fatalf("LoadModule(%s) from destination failed: %v", id, err)
+		}
+		if want.Name != got.Name || !want.LastUpdated.Equal(got.LastUpdated) || !reflect.DeepEqual(want.Templates, got.Templates) {
+			t.Errorf("Module %s differs after migration.\nSource:      %+v\nDestination: %+v", id, want, got)
+		}
+	}
+}
+
+func TestMigrate_DryRun(t *testing.T) {
+	src := newMigrateSource(t, "a", "b")
+	dst := newTestSQLiteStore(t)
+
+	report, err := Migrate(src, dst, MigrateOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Migrate() dry run failed: %v", err)
+	}
+	if len(report.Copied) != 2 || report.Verified != 0 {
+		t.Errorf("Dry run report Copied=%v Verified=%d, want 2 copied and none verified", report.Copied, report.Verified)
+	}
+	if ids, _ := dst.GetAllModuleIDs(); len(ids) != 0 {
+		t.Errorf("Dry run wrote modules to destination: %v", ids)
+	}
+
+	// A dry run into a destination that doesn't exist yet
+	if _, err := Migrate(src, nil, MigrateOptions{DryRun: true}); err != nil {
+		t.Errorf("Dry run with nil destination failed: %v", err)
+	}
+	if _, err := Migrate(src, nil, MigrateOptions{}); err == nil {
+		t.Error("Migrate() with nil destination did not return an error")
+	}
+}
+
+func TestMigrate_ExistingModules(t *testing.T) {
+	src := newMigrateSource(t, "a", "b")
+	dst := newTestSQLiteStore(t)
+	existing := createSampleModule("a", "Old Name")
+	if err := dst.SaveModule(existing); err != nil {
+		t.Fatalf("Setup failed: SaveModule() failed: %v", err)
+	}
+
+	if _, err := Migrate(src, dst, MigrateOptions{}); err == nil {
+		t.Fatal("Migrate() into a destination with conflicting IDs did not return an error")
+	}
+	if ids, _ := dst.GetAllModuleIDs(); len(ids) != 1 {
+		t.Errorf("Refused migration still wrote to destination: %v", ids)
+	}
+
+	report, err := Migrate(src, dst, MigrateOptions{Overwrite: true})
+	if err != nil {
+		t.Fatalf("Migrate() with overwrite failed: %v", err)
+	}
+	if !reflect.DeepEqual(report.Overwritten, []string{"a"}) {
+		t.Errorf("Migrate() overwritten = %v, want [a]", report.Overwritten)
+	}
+	if got, _ := dst.LoadModule("a"); got.Name != "Module a" {
+		t.Errorf("Overwritten module name = %q, want %q", got.Name, "Module a")
+	}
+}
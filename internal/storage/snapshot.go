@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"fmt"
+	"go-module-builder/internal/model"
+	"sort"
+	"strings"
+)
+
+// validateSnapshotKey rejects module and snapshot IDs that could escape the snapshot
+// directory when used as path components.
+func validateSnapshotKey(moduleID, snapshotID string) error {
+	for _, part := range []string{moduleID, snapshotID} {
+		if part == "" || part == "." || part == ".." || strings.ContainsAny(part, `/\`) {
+			return fmt.Errorf("invalid module or snapshot ID %q", part)
+		}
+	}
+	return nil
+}
+
+// sortSnapshotInfos orders snapshots newest first. IDs are time-based, so they
+// break ties between snapshots taken within the same clock tick.
+func sortSnapshotInfos(infos []model.SnapshotInfo) {
+	sort.SliceStable(infos, func(i, j int) bool {
+		if !infos[i].CreatedAt.Equal(infos[j].CreatedAt) {
+			return infos[i].CreatedAt.After(infos[j].CreatedAt)
+		}
+		return infos[i].ID > infos[j].ID
+	})
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"errors"
+	"go-module-builder/internal/model"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func createSampleSnapshot(moduleID, snapshotID string, createdAt time.Time) *model.Snapshot {
+	return &model.Snapshot{
+		SnapshotInfo: model.SnapshotInfo{
+			ID:        snapshotID,
+			ModuleID:  moduleID,
+			CreatedAt: createdAt,
+			Reason:    "save base.html",
+		},
+		Module: *createSampleModule(moduleID, "Snapshot Module"),
+		Files:  map[string]string{"base.html": "<div>v1</div>", "style.css": "body {}"},
+	}
+}
+
+// testSnapshotRoundTrip exercises the snapshot methods shared by every DataStore backend.
+func testSnapshotRoundTrip(t *testing.T, store DataStore) {
+	t.Helper()
+	base := time.Now().UTC()
+	older := createSampleSnapshot("mod-1", "snap-a", base)
+	newer := createSampleSnapshot("mod-1", "snap-b", base.Add(time.Second))
+	newer.Files["base.html"] = "<div>v2</div>"
+	other := createSampleSnapshot("mod-2", "snap-c", base)
+
+	for _, s := range []*model.Snapshot{older, newer, other} {
+		if err := store.SaveSnapshot(s); err != nil {
+			t.Fatalf("SaveSnapshot(%s) failed: %v", s.ID, err)
+		}
+	}
+
+	infos, err := store.ListSnapshots("mod-1")
+	if err != nil {
+		t.Fatalf("ListSnapshots() failed: %v", err)
+	}
+	if len(infos) != 2 || infos[0].ID != "snap-b" || infos[1].ID != "snap-a" {
+		t.Fatalf("ListSnapshots() = %+v, want snap-b then snap-a", infos)
+	}
+	if infos[0].Reason != newer.Reason || !infos[0].CreatedAt.Equal(newer.CreatedAt) {
+		t.Errorf("ListSnapshots()[0] = %+v, want reason and time of %+v", infos[0], newer.SnapshotInfo)
+	}
+
+	loaded, err := store.LoadSnapshot("mod-1", "snap-b")
+	if err != nil {
+		t.Fatalf("LoadSnapshot() failed: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Files, newer.Files) {
+		t.Errorf("LoadSnapshot() files = %v, want %v", loaded.Files, newer.Files)
+	}
+	if loaded.Module.Name != newer.Module.Name || len(loaded.Module.Templates) != len(newer.Module.Templates) {
+		t.Errorf("LoadSnapshot() module = %+v, want %+v", loaded.Module, newer.Module)
+	}
+
+	if _, err := store.LoadSnapshot("mod-1", "missing"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("LoadSnapshot() for a missing snapshot returned %v, want os.ErrNotExist", err)
+	}
+	if infos, err := store.ListSnapshots("no-history"); err != nil || len(infos) != 0 {
+		t.Errorf("ListSnapshots() for a module without history = %v, %v; want empty list", infos, err)
+	}
+}
+
+func TestJSONStoreSnapshots(t *testing.T) {
+	store, err := NewJSONStore(filepath.Join(t.TempDir(), ".test_metadata"), nil)
+	if err != nil {
+		t.Fatalf("NewJSONStore() failed: %v", err)
+	}
+	testSnapshotRoundTrip(t, store)
+
+	// Snapshots live in a subdirectory and must not show up as modules
+	ids, err := store.GetAllModuleIDs()
+	if err != nil {
+		t.Fatalf("GetAllModuleIDs() failed: %v", err)
+	}
+	if len(ids) != 0 {
+		t.Errorf("GetAllModuleIDs() = %v, want no modules", ids)
+	}
+
+	if err := store.SaveSnapshot(createSampleSnapshot("../escape", "snap", time.Now())); err == nil {
+		t.Error("SaveSnapshot() with a path-like module ID did not return an error")
+	}
+}
+
+func TestSQLiteStoreSnapshots(t *testing.T) {
+	testSnapshotRoundTrip(t, newTestSQLiteStore(t))
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-module-builder/internal/model"
+	"strings"
+)
+
+// sqliteAuditTimeFormat stores audit times in UTC with a fixed width, so they compare
+// correctly as text.
+const sqliteAuditTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+// AppendAudit inserts the entry. The store has no method to update or delete entries.
+func (s *SQLiteStore) AppendAudit(entry *model.AuditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+	_, err = s.db.Exec(`INSERT INTO audit_log (time, actor, action, module_id, data) VALUES (?, ?, ?, ?, ?)`,
+		entry.Time.UTC().Format(sqliteAuditTimeFormat), entry.Actor, entry.Action, entry.ModuleID, string(data))
+	if err != nil {
+		return fmt.Errorf("failed to insert audit entry: %w", err)
+	}
+	return nil
+}
+
+// QueryAudit returns the matching entries, newest first.
+func (s *SQLiteStore) QueryAudit(filter AuditFilter) ([]model.AuditEntry, error) {
+	var where []string
+	var args []any
+	if filter.ModuleID != "" {
+		where = append(where, "module_id = ?")
+		args = append(args, filter.ModuleID)
+	}
+	if filter.Actor != "" {
+		where = append(where, "actor = ?")
+		args = append(args, filter.Actor)
+	}
+	if !filter.Since.IsZero() {
+		where = append(where, "time >= ?")
+		args = append(args, filter.Since.UTC().Format(sqliteAuditTimeFormat))
+	}
+	if !filter.Until.IsZero() {
+		where = append(where, "time < ?")
+		args = append(args, filter.Until.UTC().Format(sqliteAuditTimeFormat))
+	}
+	query := `SELECT data FROM audit_log`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY id DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []model.AuditEntry{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to scan audit row: %w", err)
+		}
+		var entry model.AuditEntry
+		if err := json.Unmarshal([]byte(data), &entry); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal audit entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate audit log: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,352 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-module-builder/internal/model"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "modernc.org/sqlite" // Pure-Go SQLite driver (no cgo), registers "sqlite"
+)
+
+// sqliteMigrations are applied in order; the index+1 is the schema version.
+// Never edit an existing entry, only append new ones.
+var sqliteMigrations = []string{
+	// 1: module metadata. The full module is kept as JSON in data so new model
+	// fields don't need a migration; frequently queried fields get their own columns.
+	`CREATE TABLE modules (
+		id           TEXT PRIMARY KEY,
+		name         TEXT NOT NULL,
+		slug         TEXT NOT NULL DEFAULT '',
+		is_active    INTEGER NOT NULL DEFAULT 0,
+		created_at   TEXT NOT NULL,
+		last_updated TEXT NOT NULL,
+		data         TEXT NOT NULL
+	)`,
+	// 2: slug lookups
+	`CREATE INDEX idx_modules_slug ON modules (slug)`,
+	// 3: module version history
+	`CREATE TABLE snapshots (
+		module_id  TEXT NOT NULL,
+		id         TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		reason     TEXT NOT NULL DEFAULT '',
+		data       TEXT NOT NULL,
+		PRIMARY KEY (module_id, id)
+	)`,
+	// 4: optimistic locking
+	`ALTER TABLE modules ADD COLUMN revision INTEGER NOT NULL DEFAULT 0`,
+	// 5: admin user accounts, kept as JSON like modules
+	`CREATE TABLE users (
+		username TEXT PRIMARY KEY,
+		data     TEXT NOT NULL
+	)`,
+	// 6: append-only audit log of module changes
+	`CREATE TABLE audit_log (
+		id        INTEGER PRIMARY KEY AUTOINCREMENT,
+		time      TEXT NOT NULL,
+		actor     TEXT NOT NULL,
+		action    TEXT NOT NULL,
+		module_id TEXT NOT NULL DEFAULT '',
+		data      TEXT NOT NULL
+	)`,
+	// 7: audit log lookups by module
+	`CREATE INDEX idx_audit_log_module ON audit_log (module_id)`,
+}
+
+// SQLiteStore implements the DataStore, UserStore and AuditLog interfaces on top of a SQLite database file.
+type SQLiteStore struct {
+	db   *sql.DB
+	path string // Path to the database file
+}
+
+// NewSQLiteStore opens (creating if needed) the SQLite database at dbPath
+// and brings its schema up to date.
+func NewSQLiteStore(dbPath string) (*SQLiteStore, error) {
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory for database '%s': %w", dbPath, err)
+	}
+
+	// WAL lets the admin server, public server and CLI read while another process writes;
+	// busy_timeout makes concurrent writers wait instead of failing immediately.
+	dsn := "file:" + dbPath + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database '%s': %w", dbPath, err)
+	}
+
+	store := &SQLiteStore{db: db, path: dbPath}
+	if err := store.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// migrate applies any migrations newer than the database's recorded schema version.
+func (s *SQLiteStore) migrate() error {
+	_, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TEXT NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	var current int
+	if err := s.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for i := current; i < len(sqliteMigrations); i++ {
+		version := i + 1
+		tx, err := s.db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin migration %d: %w", version, err)
+		}
+		if _, err := tx.Exec(sqliteMigrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to apply migration %d: %w", version, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`, version, time.Now().UTC().Format(time.RFC3339)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %d: %w", version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %w", version, err)
+		}
+	}
+	return nil
+}
+
+// SchemaVersion returns the latest migration version applied to the database.
+func (s *SQLiteStore) SchemaVersion() (int, error) {
+	var version int
+	err := s.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
+
+// Close closes the underlying database.
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// GetBasePath returns the path of the database file.
+func (s *SQLiteStore) GetBasePath() string {
+	return s.path
+}
+
+// SaveModule inserts or updates the module's metadata.
+// The revision check is part of the UPDATE's WHERE clause (and the INSERT fails if
+// the row already exists), so concurrent writers cannot overwrite each other.
+func (s *SQLiteStore) SaveModule(module *model.Module) error {
+	if module.ID == "" {
+		return fmt.Errorf("module ID cannot be empty")
+	}
+	next := *module
+	next.Revision++
+	data, err := json.Marshal(&next)
+	if err != nil {
+		return fmt.Errorf("failed to marshal module %s: %w", module.ID, err)
+	}
+	createdAt := module.CreatedAt.Format(time.RFC3339Nano)
+	lastUpdated := module.LastUpdated.Format(time.RFC3339Nano)
+
+	var res sql.Result
+	if module.Revision == 0 {
+		res, err = s.db.Exec(`INSERT INTO modules (id, name, slug, is_active, created_at, last_updated, data, revision)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(id) DO UPDATE SET
+				name = excluded.name,
+				slug = excluded.slug,
+				is_active = excluded.is_active,
+				created_at = excluded.created_at,
+				last_updated = excluded.last_updated,
+				data = excluded.data,
+				revision = excluded.revision
+			WHERE modules.revision = 0`,
+			module.ID, module.Name, module.Slug, module.IsPublished(), createdAt, lastUpdated, string(data), next.Revision)
+		if err != nil {
+			return fmt.Errorf("failed to save module %s: %w", module.ID, err)
+		}
+	} else {
+		res, err = s.db.Exec(`UPDATE modules SET name = ?, slug = ?, is_active = ?, created_at = ?, last_updated = ?, data = ?, revision = ?
+			WHERE id = ? AND revision = ?`,
+			module.Name, module.Slug, module.IsPublished(), createdAt, lastUpdated, string(data), next.Revision,
+			module.ID, module.Revision)
+		if err != nil {
+			return fmt.Errorf("failed to save module %s: %w", module.ID, err)
+		}
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to save module %s: %w", module.ID, err)
+	}
+	if affected == 0 {
+		// The revision check failed: report what is stored now (0 if the module is gone)
+		var stored int
+		err = s.db.QueryRow(`SELECT revision FROM modules WHERE id = ?`, module.ID).Scan(&stored)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to check revision of module %s: %w", module.ID, err)
+		}
+		return &ConflictError{ModuleID: module.ID, Expected: module.Revision, Actual: stored}
+	}
+	module.Revision = next.Revision
+	return nil
+}
+
+// LoadModule retrieves a module's metadata by ID.
+// A missing module returns an error wrapping os.ErrNotExist, like JSONStore.
+func (s *SQLiteStore) LoadModule(moduleID string) (*model.Module, error) {
+	if moduleID == "" {
+		return nil, fmt.Errorf("module ID cannot be empty")
+	}
+	var data string
+	err := s.db.QueryRow(`SELECT data FROM modules WHERE id = ?`, moduleID).Scan(&data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("module %s not found: %w", moduleID, os.ErrNotExist)
+		}
+		return nil, fmt.Errorf("failed to query module %s: %w", moduleID, err)
+	}
+	return unmarshalModuleRow(moduleID, data)
+}
+
+// GetAllModuleIDs returns the IDs of all stored modules.
+func (s *SQLiteStore) GetAllModuleIDs() ([]string, error) {
+	rows, err := s.db.Query(`SELECT id FROM modules ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query module IDs: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan module ID: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate module IDs: %w", err)
+	}
+	return ids, nil
+}
+
+// DeleteModule removes a module's metadata. Deleting a missing module is not an error.
+func (s *SQLiteStore) DeleteModule(moduleID string) error {
+	if moduleID == "" {
+		return fmt.Errorf("module ID cannot be empty")
+	}
+	if _, err := s.db.Exec(`DELETE FROM modules WHERE id = ?`, moduleID); err != nil {
+		return fmt.Errorf("failed to delete module %s: %w", moduleID, err)
+	}
+	return nil
+}
+
+// ReadAll retrieves metadata for all modules. Rows that cannot be decoded are skipped
+// and reported in a *CorruptModulesError returned alongside the modules that did load.
+func (s *SQLiteStore) ReadAll() ([]*model.Module, error) {
+	rows, err := s.db.Query(`SELECT id, data FROM modules ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query modules: %w", err)
+	}
+	defer rows.Close()
+
+	modules := make([]*model.Module, 0)
+	var corrupt CorruptModulesError
+	for rows.Next() {
+		var id, data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, fmt.Errorf("failed to scan module row: %w", err)
+		}
+		module, err := unmarshalModuleRow(id, data)
+		if err != nil {
+			corrupt.add(id, err)
+			continue
+		}
+		modules = append(modules, module)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate modules: %w", err)
+	}
+	return modules, corrupt.orNil()
+}
+
+func unmarshalModuleRow(moduleID, data string) (*model.Module, error) {
+	var module model.Module
+	if err := json.Unmarshal([]byte(data), &module); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal module data for %s: %w", moduleID, err)
+	}
+	return &module, nil
+}
+
+// SaveSnapshot records a version of a module.
+func (s *SQLiteStore) SaveSnapshot(snapshot *model.Snapshot) error {
+	if err := validateSnapshotKey(snapshot.ModuleID, snapshot.ID); err != nil {
+		return err
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot %s of module %s: %w", snapshot.ID, snapshot.ModuleID, err)
+	}
+	_, err = s.db.Exec(`INSERT OR REPLACE INTO snapshots (module_id, id, created_at, reason, data) VALUES (?, ?, ?, ?, ?)`,
+		snapshot.ModuleID, snapshot.ID, snapshot.CreatedAt.Format(time.RFC3339Nano), snapshot.Reason, string(data))
+	if err != nil {
+		return fmt.Errorf("failed to save snapshot %s of module %s: %w", snapshot.ID, snapshot.ModuleID, err)
+	}
+	return nil
+}
+
+// ListSnapshots returns the module's snapshots, newest first.
+func (s *SQLiteStore) ListSnapshots(moduleID string) ([]model.SnapshotInfo, error) {
+	rows, err := s.db.Query(`SELECT id, created_at, reason FROM snapshots WHERE module_id = ?`, moduleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query snapshots of module %s: %w", moduleID, err)
+	}
+	defer rows.Close()
+
+	infos := []model.SnapshotInfo{}
+	for rows.Next() {
+		info := model.SnapshotInfo{ModuleID: moduleID}
+		var createdAt string
+		if err := rows.Scan(&info.ID, &createdAt, &info.Reason); err != nil {
+			return nil, fmt.Errorf("failed to scan snapshot row: %w", err)
+		}
+		if info.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt); err != nil {
+			return nil, fmt.Errorf("invalid created_at for snapshot %s of module %s: %w", info.ID, moduleID, err)
+		}
+		infos = append(infos, info)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate snapshots: %w", err)
+	}
+	sortSnapshotInfos(infos)
+	return infos, nil
+}
+
+// LoadSnapshot retrieves a single snapshot. A missing snapshot returns an error wrapping os.ErrNotExist.
+func (s *SQLiteStore) LoadSnapshot(moduleID, snapshotID string) (*model.Snapshot, error) {
+	var data string
+	err := s.db.QueryRow(`SELECT data FROM snapshots WHERE module_id = ? AND id = ?`, moduleID, snapshotID).Scan(&data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("snapshot %s of module %s not found: %w", snapshotID, moduleID, os.ErrNotExist)
+		}
+		return nil, fmt.Errorf("failed to query snapshot %s of module %s: %w", snapshotID, moduleID, err)
+	}
+	var snapshot model.Snapshot
+	if err := json.Unmarshal([]byte(data), &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal snapshot %s of module %s: %w", snapshotID, moduleID, err)
+	}
+	return &snapshot, nil
+}
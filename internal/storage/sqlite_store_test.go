@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func newTestSQLiteStore(t *testing.T) *SQLiteStore {
+	t.Helper()
+	store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "nested", "modules.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStore() failed: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestNewSQLiteStore_Migrations(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "modules.db")
+	store, err := NewSQLiteStore(dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore() failed: %v", err)
+	}
+	version, err := store.SchemaVersion()
+	if err != nil {
+		t.Fatalf("SchemaVersion() failed: %v", err)
+	}
+	if version != len(sqliteMigrations) {
+		t.Errorf("SchemaVersion() = %d, want %d", version, len(sqliteMigrations))
+	}
+	if store.GetBasePath() != dbPath {
+		t.Errorf("GetBasePath() returned %q, want %q", store.GetBasePath(), dbPath)
+	}
+	store.Close()
+
+	// Reopening an up-to-date database must not re-apply migrations
+	store, err = NewSQLiteStore(dbPath)
+	if err != nil {
+		t.Fatalf("Reopening NewSQLiteStore() failed: %v", err)
+	}
+	defer store.Close()
+	if v, _ := store.SchemaVersion(); v != version {
+		t.Errorf("SchemaVersion() after reopen = %d, want %d", v, version)
+	}
+}
+
+func TestSQLiteSaveLoadModule(t *testing.T) {
+	store := newTestSQLiteStore(t)
+
+	original := createSampleModule("sqlite-mod-1", "SQLite Module")
+	if err := store.SaveModule(original); err != nil {
+		t.Fatalf("SaveModule() failed: %v", err)
+	}
+	loaded, err := store.LoadModule(original.ID)
+	if err != nil {
+		t.Fatalf("LoadModule() failed: %v", err)
+	}
+	if !reflect.DeepEqual(original, loaded) {
+		t.Errorf("LoadModule() loaded module does not match original.\nOriginal: %+v\nLoaded:   %+v", original, loaded)
+	}
+
+	// Saving again updates in place
+	original.Name = "Renamed"
+	original.Slug = "renamed"
+	if err := store.SaveModule(original); err != nil {
+		t.Fatalf("Second SaveModule() failed: %v", err)
+	}
+	loaded, err = store.LoadModule(original.ID)
+	if err != nil {
+		t.Fatalf("LoadModule() after update failed: %v", err)
+	}
+	if loaded.Name != "Renamed" || loaded.Slug != "renamed" {
+		t.Errorf("LoadModule() after update = %q/%q, want Renamed/renamed", loaded.Name, loaded.Slug)
+	}
+
+	if err := store.SaveModule(createSampleModule("", "No ID")); err == nil {
+		t.Error("SaveModule() with empty ID did not return an error")
+	}
+}
+
+func TestSQLiteLoadModule_NotFound(t *testing.T) {
+	store := newTestSQLiteStore(t)
+
+	_, err := store.LoadModule("missing")
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("LoadModule() returned error %v, expected an error wrapping os.ErrNotExist", err)
+	}
+}
+
+func TestSQLiteDeleteAndReadAll(t *testing.T) {
+	store := newTestSQLiteStore(t)
+
+	for _, id := range []string{"b", "a", "c"} {
+		if err := store.SaveModule(createSampleModule(id, "Module "+id)); err != nil {
+			t.Fatalf("Setup failed: SaveModule(%s) failed: %v", id, err)
+		}
+	}
+
+	if err := store.DeleteModule("b"); err != nil {
+		t.Fatalf("DeleteModule() failed: %v", err)
+	}
+	if err := store.DeleteModule("b"); err != nil {
+		t.Fatalf("Second DeleteModule() failed: %v", err)
+	}
+
+	ids, err := store.GetAllModuleIDs()
+	if err != nil {
+		t.Fatalf("GetAllModuleIDs() failed: %v", err)
+	}
+	if !reflect.DeepEqual(ids, []string{"a", "c"}) {
+		t.Errorf("GetAllModuleIDs() = %v, want [a c]", ids)
+	}
+
+	modules, err := store.ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll() failed: %v", err)
+	}
+	if len(modules) != 2 || modules[0].ID != "a" || modules[1].ID != "c" {
+		t.Errorf("ReadAll() returned %+v, want modules a and c", modules)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	tempDir := t.TempDir()
+
+	jsonStore, err := Open(BackendJSON, filepath.Join(tempDir, "meta"), nil)
+	if err != nil {
+		t.Fatalf("Open(json) failed: %v", err)
+	}
+	if _, ok := jsonStore.(*JSONStore); !ok {
+		t.Errorf("Open(json) returned %T, want *JSONStore", jsonStore)
+	}
+
+	sqliteStore, err := Open(BackendSQLite, filepath.Join(tempDir, "modules.db"), nil)
+	if err != nil {
+		t.Fatalf("Open(sqlite) failed: %v", err)
+	}
+	defer sqliteStore.(*SQLiteStore).Close()
+
+	if _, err := Open("mongo", tempDir, nil); err == nil {
+		t.Error("Open() with an unknown backend did not return an error")
+	}
+}
+
+func TestResolvePath(t *testing.T) {
+	root := filepath.Join(string(filepath.Separator), "project")
+	jsonDir := filepath.Join(root, ".module_metadata")
+
+	if got := ResolvePath(root, BackendJSON, jsonDir, "ignored.db"); got != jsonDir {
+		t.Errorf("ResolvePath(json) = %q, want %q", got, jsonDir)
+	}
+	if got, want := ResolvePath(root, BackendSQLite, jsonDir, "data/site.db"), filepath.Join(root, "data", "site.db"); got != want {
+		t.Errorf("ResolvePath(sqlite, relative) = %q, want %q", got, want)
+	}
+	if got, want := ResolvePath(root, BackendSQLite, jsonDir, ""), filepath.Join(jsonDir, DefaultSQLiteFile); got != want {
+		t.Errorf("ResolvePath(sqlite, empty) = %q, want %q", got, want)
+	}
+}
+
+func TestSQLiteSaveModule_StaleRevisionConflict(t *testing.T) {
+	testStaleRevisionConflict(t, newTestSQLiteStore(t))
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-module-builder/internal/model"
+)
+
+// SaveUser inserts or replaces the account.
+func (s *SQLiteStore) SaveUser(user *model.User) error {
+	if err := model.ValidateUsername(user.Username); err != nil {
+		return err
+	}
+	data, err := json.Marshal(user)
+	if err != nil {
+		return fmt.Errorf("failed to marshal user %s: %w", user.Username, err)
+	}
+	if _, err := s.db.Exec(`INSERT OR REPLACE INTO users (username, data) VALUES (?, ?)`, user.Username, string(data)); err != nil {
+		return fmt.Errorf("failed to save user %s: %w", user.Username, err)
+	}
+	return nil
+}
+
+// LoadUser retrieves an account by username.
+func (s *SQLiteStore) LoadUser(username string) (*model.User, error) {
+	var data string
+	err := s.db.QueryRow(`SELECT data FROM users WHERE username = ?`, username).Scan(&data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("user %s: %w", username, ErrUserNotFound)
+		}
+		return nil, fmt.Errorf("failed to query user %s: %w", username, err)
+	}
+	var user model.User
+	if err := json.Unmarshal([]byte(data), &user); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal user data for %s: %w", username, err)
+	}
+	return &user, nil
+}
+
+// DeleteUser removes an account.
+func (s *SQLiteStore) DeleteUser(username string) error {
+	res, err := s.db.Exec(`DELETE FROM users WHERE username = ?`, username)
+	if err != nil {
+		return fmt.Errorf("failed to delete user %s: %w", username, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("user %s: %w", username, ErrUserNotFound)
+	}
+	return nil
+}
+
+// ReadAllUsers retrieves every account, sorted by username.
+func (s *SQLiteStore) ReadAllUsers() ([]*model.User, error) {
+	rows, err := s.db.Query(`SELECT username, data FROM users ORDER BY username`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []*model.User{}
+	for rows.Next() {
+		var username, data string
+		if err := rows.Scan(&username, &data); err != nil {
+			return nil, fmt.Errorf("failed to scan user row: %w", err)
+		}
+		var user model.User
+		if err := json.Unmarshal([]byte(data), &user); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal user data for %s: %w", username, err)
+		}
+		users = append(users, &user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate users: %w", err)
+	}
+	return users, nil
+}
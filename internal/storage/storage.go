@@ -1,11 +1,22 @@
 package storage
 
-import "go-module-builder/internal/model"
+import (
+	"errors"
+	"fmt"
+	"go-module-builder/internal/model"
+	"log/slog"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
 
 // DataStore defines the operations needed for persisting module data.
 // This allows swapping implementations (e.g., JSON files vs. database) later.
 type DataStore interface {
-	// SaveModule persists the module's metadata.
+	// SaveModule persists the module's metadata. module.Revision must match the stored
+	// revision (0 for a new module), otherwise a *ConflictError is returned and nothing
+	// is written. On success module.Revision is incremented to the new stored revision.
 	SaveModule(module *model.Module) error
 
 	// LoadModule retrieves a module's metadata by its ID.
@@ -18,13 +29,334 @@ type DataStore interface {
 	// Consider if file deletion belongs here or in a separate service.
 	DeleteModule(moduleID string) error
 
-	// ReadAll retrieves metadata for all modules.
+	// ReadAll retrieves metadata for all modules. If some entries cannot be loaded it
+	// still returns every module that could, together with a *CorruptModulesError.
 	ReadAll() ([]*model.Module, error)
 
 	// GetBasePath returns the storage base path.
 	GetBasePath() string
 
-	// TODO: Add methods for snapshots/history if needed later
-	// SaveSnapshotMetadata(...) error
-	// LoadSnapshotMetadata(...) (*SnapshotInfo, error)
+	// SaveSnapshot records a version of a module (metadata plus template contents).
+	SaveSnapshot(snapshot *model.Snapshot) error
+
+	// ListSnapshots returns the snapshots recorded for a module, newest first.
+	ListSnapshots(moduleID string) ([]model.SnapshotInfo, error)
+
+	// LoadSnapshot retrieves a single snapshot of a module by its ID.
+	LoadSnapshot(moduleID, snapshotID string) (*model.Snapshot, error)
+}
+
+// CorruptModulesError reports module metadata entries that ReadAll skipped because
+// they could not be read or decoded (e.g., a file truncated by a crash).
+type CorruptModulesError struct {
+	Modules map[string]error // Module ID -> load error
+}
+
+func (e *CorruptModulesError) add(moduleID string, err error) {
+	if e.Modules == nil {
+		e.Modules = make(map[string]error)
+	}
+	e.Modules[moduleID] = err
+}
+
+// orNil returns e if any entry was recorded, so callers can return it as a plain error.
+func (e *CorruptModulesError) orNil() error {
+	if len(e.Modules) == 0 {
+		return nil
+	}
+	return e
+}
+
+// IDs returns the IDs of the skipped modules in sorted order.
+func (e *CorruptModulesError) IDs() []string {
+	ids := make([]string, 0, len(e.Modules))
+	for id := range e.Modules {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (e *CorruptModulesError) Error() string {
+	parts := make([]string, 0, len(e.Modules))
+	for _, id := range e.IDs() {
+		parts = append(parts, fmt.Sprintf("%s: %v", id, e.Modules[id]))
+	}
+	return fmt.Sprintf("%d module metadata entries could not be loaded (%s)", len(e.Modules), strings.Join(parts, "; "))
+}
+
+// AsCorruptModules reports whether err from ReadAll only describes skipped entries,
+// in which case the returned modules are still usable.
+func AsCorruptModules(err error) (*CorruptModulesError, bool) {
+	var corrupt *CorruptModulesError
+	ok := errors.As(err, &corrupt)
+	return corrupt, ok
+}
+
+// CorruptPagesError reports page metadata files that ReadAllPages skipped because
+// they could not be read or decoded.
+type CorruptPagesError struct {
+	Pages map[string]error // Page ID -> load error
+}
+
+func (e *CorruptPagesError) add(pageID string, err error) {
+	if e.Pages == nil {
+		e.Pages = make(map[string]error)
+	}
+	e.Pages[pageID] = err
+}
+
+// orNil returns e if any entry was recorded, so callers can return it as a plain error.
+func (e *CorruptPagesError) orNil() error {
+	if len(e.Pages) == 0 {
+		return nil
+	}
+	return e
+}
+
+// IDs returns the IDs of the skipped pages in sorted order.
+func (e *CorruptPagesError) IDs() []string {
+	ids := make([]string, 0, len(e.Pages))
+	for id := range e.Pages {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (e *CorruptPagesError) Error() string {
+	parts := make([]string, 0, len(e.Pages))
+	for _, id := range e.IDs() {
+		parts = append(parts, fmt.Sprintf("%s: %v", id, e.Pages[id]))
+	}
+	return fmt.Sprintf("%d page metadata entries could not be loaded (%s)", len(e.Pages), strings.Join(parts, "; "))
+}
+
+// AsCorruptPages reports whether err from ReadAllPages only describes skipped entries,
+// in which case the returned pages are still usable.
+func AsCorruptPages(err error) (*CorruptPagesError, bool) {
+	var corrupt *CorruptPagesError
+	ok := errors.As(err, &corrupt)
+	return corrupt, ok
+}
+
+// CorruptAuditError reports audit log lines that QueryAudit skipped because they could
+// not be decoded (e.g., a line cut short by a crash while appending).
+type CorruptAuditError struct {
+	Path  string
+	Lines map[int]error // Line number -> decode error
+}
+
+func (e *CorruptAuditError) add(line int, err error) {
+	if e.Lines == nil {
+		e.Lines = make(map[int]error)
+	}
+	e.Lines[line] = err
+}
+
+// orNil returns e if any line was recorded, so callers can return it as a plain error.
+func (e *CorruptAuditError) orNil() error {
+	if len(e.Lines) == 0 {
+		return nil
+	}
+	return e
+}
+
+// LineNumbers returns the numbers of the skipped lines in ascending order.
+func (e *CorruptAuditError) LineNumbers() []int {
+	lines := make([]int, 0, len(e.Lines))
+	for line := range e.Lines {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+func (e *CorruptAuditError) Error() string {
+	parts := make([]string, 0, len(e.Lines))
+	for _, line := range e.LineNumbers() {
+		parts = append(parts, fmt.Sprintf("line %d: %v", line, e.Lines[line]))
+	}
+	return fmt.Sprintf("%d lines of audit log %s could not be decoded (%s)", len(e.Lines), e.Path, strings.Join(parts, "; "))
+}
+
+// AsCorruptAudit reports whether err from QueryAudit only describes skipped lines, in
+// which case the returned entries are still usable.
+func AsCorruptAudit(err error) (*CorruptAuditError, bool) {
+	var corrupt *CorruptAuditError
+	ok := errors.As(err, &corrupt)
+	return corrupt, ok
+}
+
+// ConflictError is returned by SaveModule when the module was saved by someone else
+// since it was loaded, i.e. the caller's Revision is stale.
+type ConflictError struct {
+	ModuleID string
+	Expected int // Revision the caller based its changes on
+	Actual   int // Revision currently stored
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("module %s was modified concurrently (stored revision %d, save based on revision %d)", e.ModuleID, e.Actual, e.Expected)
+}
+
+// IsConflict reports whether err (or any error it wraps) is a *ConflictError.
+func IsConflict(err error) bool {
+	var conflict *ConflictError
+	return errors.As(err, &conflict)
+}
+
+// PageStore defines the operations needed for persisting page data.
+// Pages reference modules by ID, so they are kept separate from module metadata.
+type PageStore interface {
+	// SavePage persists the page's metadata.
+	SavePage(page *model.Page) error
+
+	// LoadPage retrieves a page's metadata by its ID.
+	LoadPage(pageID string) (*model.Page, error)
+
+	// GetAllPageIDs returns a list of all known page IDs.
+	GetAllPageIDs() ([]string, error)
+
+	// DeletePage removes a page's metadata.
+	DeletePage(pageID string) error
+
+	// ReadAllPages retrieves metadata for all pages. If some entries cannot be loaded it
+	// still returns every page that could, together with a *CorruptPagesError.
+	ReadAllPages() ([]*model.Page, error)
+
+	// GetBasePath returns the storage base path.
+	GetBasePath() string
+}
+
+// ErrUserNotFound is returned by UserStore methods for a username that has no account.
+var ErrUserNotFound = errors.New("user not found")
+
+// UserStore defines the operations needed for persisting admin user accounts.
+type UserStore interface {
+	// SaveUser creates or replaces the user's account.
+	SaveUser(user *model.User) error
+
+	// LoadUser retrieves an account by username, or returns ErrUserNotFound.
+	LoadUser(username string) (*model.User, error)
+
+	// DeleteUser removes an account, or returns ErrUserNotFound.
+	DeleteUser(username string) error
+
+	// ReadAllUsers retrieves every account, sorted by username.
+	ReadAllUsers() ([]*model.User, error)
+}
+
+// AuditFilter selects audit log entries. Zero fields match every entry.
+type AuditFilter struct {
+	ModuleID string
+	Actor    string
+	Since    time.Time // Entries at or after this time
+	Until    time.Time // Entries before this time
+	Limit    int       // Only the newest Limit entries; 0 for all
+}
+
+// Matches reports whether the entry passes the filter (ignoring Limit).
+func (f AuditFilter) Matches(entry *model.AuditEntry) bool {
+	return (f.ModuleID == "" || entry.ModuleID == f.ModuleID) &&
+		(f.Actor == "" || entry.Actor == f.Actor) &&
+		(f.Since.IsZero() || !entry.Time.Before(f.Since)) &&
+		(f.Until.IsZero() || entry.Time.Before(f.Until))
+}
+
+// AuditLog is an append-only record of module changes. Entries cannot be changed or
+// removed through it.
+type AuditLog interface {
+	// AppendAudit adds an entry to the end of the log.
+	AppendAudit(entry *model.AuditEntry) error
+
+	// QueryAudit returns the entries matching filter, newest first. If some entries
+	// cannot be decoded it still returns every one that could, together with a
+	// *CorruptAuditError.
+	QueryAudit(filter AuditFilter) ([]model.AuditEntry, error)
+}
+
+// Supported values for the storage.backend config key.
+const (
+	BackendJSON   = "json"
+	BackendSQLite = "sqlite"
+)
+
+// DefaultSQLiteFile is the database file name used when storage.sqlitePath is not set.
+const DefaultSQLiteFile = "modules.db"
+
+// Open creates the DataStore for the given backend.
+// For BackendJSON path is the metadata directory; for BackendSQLite it is the database file.
+// An empty backend selects BackendJSON. A nil logger discards the store's debug output.
+func Open(backend, path string, logger *slog.Logger) (DataStore, error) {
+	switch backend {
+	case "", BackendJSON:
+		return NewJSONStore(path, logger)
+	case BackendSQLite:
+		return NewSQLiteStore(path)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q (expected %q or %q)", backend, BackendJSON, BackendSQLite)
+	}
+}
+
+// DefaultUsersDir is the directory holding the JSON backend's user accounts when
+// storage.usersDir is not set. The SQLite backend keeps them in its database.
+const DefaultUsersDir = ".user_metadata"
+
+// OpenUsers creates the UserStore for the given backend. For BackendJSON path is the
+// accounts directory; for BackendSQLite it is the database file, shared with the modules.
+// store is the DataStore already opened, if any: a SQLite store on the same file is
+// reused rather than opening the database again.
+func OpenUsers(backend, path string, store DataStore) (UserStore, error) {
+	switch backend {
+	case "", BackendJSON:
+		return NewJSONUserStore(path)
+	case BackendSQLite:
+		return openSQLite(path, store)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q (expected %q or %q)", backend, BackendJSON, BackendSQLite)
+	}
+}
+
+// DefaultAuditLogFile is the JSON Lines file holding the JSON backend's audit log when
+// storage.auditLog is not set. The SQLite backend keeps it in its database.
+const DefaultAuditLogFile = ".audit_log.jsonl"
+
+// OpenAudit creates the AuditLog for the given backend. For BackendJSON path is the log
+// file; for BackendSQLite it is the database file, shared with the modules. store is
+// reused as in OpenUsers.
+func OpenAudit(backend, path string, store DataStore) (AuditLog, error) {
+	switch backend {
+	case "", BackendJSON:
+		return NewJSONAuditLog(path)
+	case BackendSQLite:
+		return openSQLite(path, store)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q (expected %q or %q)", backend, BackendJSON, BackendSQLite)
+	}
+}
+
+// openSQLite returns store if it is a SQLiteStore on the database file at path, so the
+// modules, accounts and audit log share one connection pool, and opens the file otherwise.
+func openSQLite(path string, store DataStore) (*SQLiteStore, error) {
+	if sqlite, ok := store.(*SQLiteStore); ok && sqlite.path == path {
+		return sqlite, nil
+	}
+	return NewSQLiteStore(path)
+}
+
+// ResolvePath returns the location to pass to Open for the backend: jsonDir for
+// BackendJSON, sqlitePath for BackendSQLite. Relative paths are resolved against projectRoot.
+func ResolvePath(projectRoot, backend, jsonDir, sqlitePath string) string {
+	path := jsonDir
+	if backend == BackendSQLite {
+		path = sqlitePath
+		if path == "" {
+			path = filepath.Join(jsonDir, DefaultSQLiteFile)
+		}
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(projectRoot, path)
+	}
+	return path
 }
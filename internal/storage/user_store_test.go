@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"errors"
+	"go-module-builder/internal/model"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestUserStores(t *testing.T) {
+	for _, backend := range []string{BackendJSON, BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "users")
+			if backend == BackendSQLite {
+				path = filepath.Join(t.TempDir(), "modules.db")
+			}
+			store, err := OpenUsers(backend, path, nil)
+			if err != nil {
+				t.Fatalf("OpenUsers(%q) failed: %v", backend, err)
+			}
+			if closer, ok := store.(interface{ Close() error }); ok {
+				t.Cleanup(func() { closer.Close() })
+			}
+
+			if _, err := store.LoadUser("alice"); !errors.Is(err, ErrUserNotFound) {
+				t.Errorf("LoadUser of a missing user = %v, want ErrUserNotFound", err)
+			}
+			if err := store.SaveUser(&model.User{Username: "../alice"}); err == nil {
+				t.Error("SaveUser with an invalid username did not return an error")
+			}
+
+			created := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+			for _, name := range []string{"bob", "alice"} {
+				if err := store.SaveUser(&model.User{Username: name, PasswordHash: "hash-" + name, CreatedAt: created, PasswordChangedAt: created}); err != nil {
+					t.Fatalf("SaveUser(%s) failed: %v", name, err)
+				}
+			}
+			if err := store.SaveUser(&model.User{Username: "bob", PasswordHash: "new-hash", CreatedAt: created, PasswordChangedAt: created}); err != nil {
+				t.Fatalf("SaveUser replacing bob failed: %v", err)
+			}
+			bob, err := store.LoadUser("bob")
+			if err != nil {
+				t.Fatalf("LoadUser failed: %v", err)
+			}
+			if bob.PasswordHash != "new-hash" || !bob.CreatedAt.Equal(created) {
+				t.Errorf("LoadUser(bob) = %+v, want the replaced account", bob)
+			}
+
+			users, err := store.ReadAllUsers()
+			if err != nil {
+				t.Fatalf("ReadAllUsers failed: %v", err)
+			}
+			var names []string
+			for _, u := range users {
+				names = append(names, u.Username)
+			}
+			if !reflect.DeepEqual(names, []string{"alice", "bob"}) {
+				t.Errorf("ReadAllUsers = %v, want [alice bob]", names)
+			}
+
+			if err := store.DeleteUser("alice"); err != nil {
+				t.Fatalf("DeleteUser failed: %v", err)
+			}
+			if err := store.DeleteUser("alice"); !errors.Is(err, ErrUserNotFound) {
+				t.Errorf("Deleting a missing user = %v, want ErrUserNotFound", err)
+			}
+		})
+	}
+}
+
+func TestOpenUsersAndAudit_ReuseSQLiteStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "modules.db")
+	store, err := Open(BackendSQLite, path, nil)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	t.Cleanup(func() { store.(*SQLiteStore).Close() })
+
+	users, err := OpenUsers(BackendSQLite, path, store)
+	if err != nil || users != UserStore(store.(*SQLiteStore)) {
+		t.Errorf("OpenUsers = %p, %v; want the open store %p", users, err, store)
+	}
+	audit, err := OpenAudit(BackendSQLite, path, store)
+	if err != nil || audit != AuditLog(store.(*SQLiteStore)) {
+		t.Errorf("OpenAudit = %p, %v; want the open store %p", audit, err, store)
+	}
+
+	// A different database file gets its own store
+	other, err := OpenUsers(BackendSQLite, filepath.Join(t.TempDir(), "users.db"), store)
+	if err != nil {
+		t.Fatalf("OpenUsers of another file failed: %v", err)
+	}
+	t.Cleanup(func() { other.(*SQLiteStore).Close() })
+	if other == UserStore(store.(*SQLiteStore)) {
+		t.Error("OpenUsers of another file reused the open store")
+	}
+}
@@ -3,67 +3,56 @@ package templating
 import (
 	"bytes"
 	"fmt"
+	"go-module-builder/internal/dataprovider"
+	"go-module-builder/internal/model"
 	"go-module-builder/internal/storage"
 	"html/template"
-	"path/filepath"
+	"net/http"
 	"strings" // Added for error message check
+	"time"
 )
 
-// Engine handles template parsing and execution.
+// Engine renders stored modules for the CLI preview and static export.
 type Engine struct {
-	store storage.DataStore
+	store    storage.DataStore
+	renderer *Renderer
+	data     *dataprovider.Resolver
 }
 
-// NewEngine creates a new template engine.
-func NewEngine(store storage.DataStore) *Engine {
-	return &Engine{store: store}
+// NewEngine creates a new template engine. Modules are rendered with renderer, in
+// strict mode, and receive the data resolved by data as .Data; data may be nil.
+func NewEngine(store storage.DataStore, renderer *Renderer, data *dataprovider.Resolver) *Engine {
+	return &Engine{store: store, renderer: renderer.Strict(), data: data}
 }
 
 // CombineTemplates loads, parses, and executes the templates for a given module
-// to generate a single HTML string output, executing the "page" template.
+// to generate a single HTML string output: the module page as the main server
+// serves it, inside the layout if the renderer has one.
 func (e *Engine) CombineTemplates(moduleID string) (string, error) {
 	// 1. Load module metadata
 	module, err := e.store.LoadModule(moduleID)
 	if err != nil {
 		return "", fmt.Errorf("failed to load module %s: %w", moduleID, err)
 	}
-	if !module.IsActive { // Check IsActive instead of Status
-		return "", fmt.Errorf("cannot preview module %s because it is inactive", moduleID) // Update error message
+	if module.IsArchived() { // Drafts and disabled modules can still be previewed
+		return "", fmt.Errorf("cannot preview module %s because it is archived", moduleID)
 	}
 
-	// 2. Gather all template file paths for this module using Glob
-	templatesDir := filepath.Join(module.Directory, "templates")
-	pattern := filepath.Join(templatesDir, "*.[th][mt][lm]l") // Glob for *.html and *.tmpl
-	htmlFiles, err := filepath.Glob(pattern)
+	// 2. Parse the module's template files into a copy of the layouts
+	tmplSet, err := e.renderer.ParseModule(module, nil)
 	if err != nil {
-		return "", fmt.Errorf("error finding html/tmpl template files for module %s: %w", moduleID, err)
+		return "", fmt.Errorf("failed to prepare templates for module %s: %w", moduleID, err)
 	}
 
-	// Glob specifically for *.css files containing template definitions
-	cssPattern := filepath.Join(templatesDir, "*.css")
-	cssFiles, err := filepath.Glob(cssPattern)
-	if err != nil {
-		return "", fmt.Errorf("error finding css template files for module %s: %w", moduleID, err)
-	}
-
-	// Combine the file lists
-	allTemplateFiles := append(htmlFiles, cssFiles...)
-
-	if len(allTemplateFiles) == 0 {
-		return "", fmt.Errorf("no template files (.html, .tmpl, .css) found in %s", templatesDir)
+	// 3. Load the module's declared data, as if the page was requested
+	data := ModuleInstanceData{Module: module}
+	if data.Data, err = e.moduleData(module, "/"+module.Slug); err != nil {
+		return "", err
 	}
 
-	// 3. Parse all discovered template files together
-	// Use the module ID as a base name for the template set for clarity
-	tmplSet, err := template.New(moduleID).Funcs(nil).ParseFiles(allTemplateFiles...)
-	if err != nil {
-		return "", fmt.Errorf("failed to parse templates from %s: %w", templatesDir, err)
-	}
-
-	// 4. Execute the main "page" template into a buffer
+	// 4. Execute the module page into a buffer
 	var buf bytes.Buffer
-	// Pass module data to the template execution
-	err = tmplSet.ExecuteTemplate(&buf, "page", module)
+	err = e.renderer.RenderModulePage(&buf, tmplSet, data, &LayoutData{})
 	if err != nil {
 		// Check if the error message indicates the template wasn't defined
 		if strings.Contains(err.Error(), "template \"page\" is undefined") || strings.Contains(err.Error(), "template \"page\" not defined") {
@@ -75,3 +64,63 @@ func (e *Engine) CombineTemplates(moduleID string) (string, error) {
 	// 5. Return the resulting HTML string
 	return buf.String(), nil
 }
+
+// RenderRoot renders the site root as the main server serves it: the layout without
+// page content.
+func (e *Engine) RenderRoot() (string, error) {
+	var buf bytes.Buffer
+	if err := e.renderer.RenderLayout(&buf, LayoutData{}); err != nil {
+		return "", fmt.Errorf("failed to render the site root: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// RenderPage renders a composed Page as the main server serves it: a section for each
+// of its module instances whose module is live at now, in order, inside the page's
+// layout. Instances of missing or unpublished modules are left out.
+func (e *Engine) RenderPage(page *model.Page, now time.Time) (string, error) {
+	var content bytes.Buffer
+	for _, instance := range page.SortedModules() {
+		module, err := e.store.LoadModule(instance.ModuleID)
+		if err != nil || !module.IsLive(now) {
+			continue
+		}
+		tmplSet, err := e.renderer.ParseModule(module, nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to prepare templates for module %s on page %s: %w", module.ID, page.Slug, err)
+		}
+		data := ModuleInstanceData{Module: module, Config: instance.Config}
+		if data.Data, err = e.moduleData(module, "/"+page.Slug); err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&content, `<section class="gws-module-instance" data-module-id="%s">`, template.HTMLEscapeString(module.ID))
+		if err := e.renderer.RenderModulePage(&content, tmplSet, data, nil); err != nil {
+			return "", fmt.Errorf("failed to execute template 'page' for module %s on page %s: %w", module.ID, page.Slug, err)
+		}
+		content.WriteString("</section>")
+	}
+
+	var buf bytes.Buffer
+	layoutData := LayoutData{PageContent: PageData{Page: page, RenderedContent: template.HTML(content.String())}}
+	if err := e.renderer.RenderPageLayout(&buf, page, layoutData); err != nil {
+		return "", fmt.Errorf("failed to render page %s: %w", page.Slug, err)
+	}
+	return buf.String(), nil
+}
+
+// moduleData loads the module's declared data as if urlPath was requested, or returns
+// nil without a data resolver.
+func (e *Engine) moduleData(module *model.Module, urlPath string) (any, error) {
+	if e.data == nil {
+		return nil, nil
+	}
+	req, err := http.NewRequest(http.MethodGet, urlPath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build data request for module %s: %w", module.ID, err)
+	}
+	data, err := e.data.Load(req, module)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load data for module %s: %w", module.ID, err)
+	}
+	return data, nil
+}
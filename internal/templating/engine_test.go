@@ -59,6 +59,27 @@ func (m *mockDataStore) GetBasePath() string {
 	return m.basePath
 }
 
+// Snapshot methods are not used by the engine
+func (m *mockDataStore) SaveSnapshot(snapshot *model.Snapshot) error { return nil }
+
+func (m *mockDataStore) ListSnapshots(moduleID string) ([]model.SnapshotInfo, error) {
+	return []model.SnapshotInfo{}, nil
+}
+
+func (m *mockDataStore) LoadSnapshot(moduleID, snapshotID string) (*model.Snapshot, error) {
+	return nil, os.ErrNotExist
+}
+
+// newTestRenderer creates a renderer without layouts for the modules in modulesDir.
+func newTestRenderer(t *testing.T, modulesDir string) *Renderer {
+	t.Helper()
+	renderer, err := NewRenderer(modulesDir, nil, nil)
+	if err != nil {
+		t.Fatalf("NewRenderer failed: %v", err)
+	}
+	return renderer
+}
+
 func TestCombineTemplates(t *testing.T) {
 	// Create a temporary directory for our test module
 	tempDir := t.TempDir()
@@ -85,7 +106,7 @@ func TestCombineTemplates(t *testing.T) {
 	contentHTML := `{{ define "content" }}
 <div class="module-content">
 	<p>This is the content area for {{ .ID }}</p>
-	<p>Active: {{ .IsActive }}</p> {{/* Changed from Status */}}
+	<p>Status: {{ .Status }}</p>
 </div>
 {{ end }}`
 
@@ -116,12 +137,12 @@ func TestCombineTemplates(t *testing.T) {
 		ID:        moduleID,
 		Name:      "Test Module",
 		Directory: moduleDir,
-		IsActive:  true, // Changed from Status: "active"
+		Status:    model.StatusPublished,
 		CreatedAt: time.Now(),
 		Templates: []model.Template{
-			{Name: "base.html", Path: "templates/base.html"},
-			{Name: "content.html", Path: "templates/content.html"},
-			{Name: "style.css", Path: "templates/style.css"},
+			{Name: "base.html", Path: "templates/base.html", IsActive: true},
+			{Name: "content.html", Path: "templates/content.html", IsActive: true},
+			{Name: "style.css", Path: "templates/style.css", IsActive: true},
 		},
 	}
 
@@ -133,7 +154,7 @@ func TestCombineTemplates(t *testing.T) {
 	}
 
 	// Create the engine with our mock store
-	engine := NewEngine(mockStore)
+	engine := NewEngine(mockStore, newTestRenderer(t, tempDir), nil)
 
 	// Execute the test
 	result, err := engine.CombineTemplates(moduleID)
@@ -145,7 +166,7 @@ func TestCombineTemplates(t *testing.T) {
 	expectedStrings := []string{
 		`<h1>Test Module</h1>`,
 		`<p>This is the content area for test-module-123</p>`,
-		`<p>Active: true</p>`, // Changed from Status: active
+		`<p>Status: published</p>`,
 		`.module-page {`,
 		`.module-content {`,
 	}
@@ -163,14 +184,14 @@ func TestCombineTemplates_RemovedModule(t *testing.T) {
 	mockStore := &mockDataStore{
 		modules: map[string]*model.Module{
 			moduleID: {
-				ID:       moduleID,
-				IsActive: false, // Changed from Status: "removed"
+				ID:     moduleID,
+				Status: model.StatusArchived,
 			},
 		},
 	}
 
 	// Create the engine with our mock store
-	engine := NewEngine(mockStore)
+	engine := NewEngine(mockStore, newTestRenderer(t, t.TempDir()), nil)
 
 	// Execute the test
 	_, err := engine.CombineTemplates(moduleID)
@@ -180,7 +201,7 @@ func TestCombineTemplates_RemovedModule(t *testing.T) {
 		t.Fatal("Expected error for removed module, but got nil")
 	}
 
-	expectedErrMsg := "cannot preview module removed-module-456 because it is inactive" // Updated error message check
+	expectedErrMsg := "cannot preview module removed-module-456 because it is archived"
 	if !strings.Contains(err.Error(), expectedErrMsg) {
 		t.Errorf("Expected error message to contain %q, but got: %v", expectedErrMsg, err)
 	}
@@ -216,9 +237,9 @@ func TestCombineTemplates_NoPageTemplate(t *testing.T) {
 		ID:        moduleID,
 		Name:      "No Page Template Module",
 		Directory: moduleDir,
-		IsActive:  true, // Changed from Status: "active"
+		Status:    model.StatusPublished,
 		Templates: []model.Template{
-			{Name: "content.html", Path: "templates/content.html"},
+			{Name: "content.html", Path: "templates/content.html", IsActive: true},
 		},
 	}
 
@@ -230,7 +251,7 @@ func TestCombineTemplates_NoPageTemplate(t *testing.T) {
 	}
 
 	// Create the engine with our mock store
-	engine := NewEngine(mockStore)
+	engine := NewEngine(mockStore, newTestRenderer(t, tempDir), nil)
 
 	// Execute the test
 	_, err := engine.CombineTemplates(moduleID)
@@ -255,7 +276,7 @@ func TestCombineTemplates_ModuleNotFound(t *testing.T) {
 	}
 
 	// Create the engine with our mock store
-	engine := NewEngine(mockStore)
+	engine := NewEngine(mockStore, newTestRenderer(t, t.TempDir()), nil)
 
 	// Execute the test with a non-existent module ID
 	_, err := engine.CombineTemplates("non-existent-id")
@@ -0,0 +1,120 @@
+package templating
+
+import (
+	"fmt"
+	"go-module-builder/internal/model"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	// LayoutsDir is the directory, under the layout templates directory, holding the
+	// named layouts a module can select instead of layout.html.
+	LayoutsDir = "layouts"
+	// DefaultLayout selects layout.html when updating a module's layout; modules store
+	// it as an empty Layout.
+	DefaultLayout = "default"
+)
+
+// LayoutNames returns the file names of the named layouts (*.html) in dir, sorted.
+// A missing dir means there are none.
+func LayoutNames(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil {
+		return nil, fmt.Errorf("error finding layouts in %s: %w", dir, err)
+	}
+	names := make([]string, 0, len(files))
+	for _, f := range files {
+		names = append(names, filepath.Base(f))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ValidateLayout checks that name is the file name of a named layout in dir.
+func ValidateLayout(dir, name string) error {
+	if name == "" || filepath.Base(name) != name || filepath.Ext(name) != ".html" {
+		return fmt.Errorf("invalid layout %q: must be the file name of an .html layout in %s", name, LayoutsDir)
+	}
+	if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("layout %q not found in %s", name, dir)
+		}
+		return fmt.Errorf("checking layout %q: %w", name, err)
+	}
+	return nil
+}
+
+// LoadNamedLayouts parses the named layouts in dir, replacing any loaded before.
+// Each is parsed as layout.html and completed with the other templates of the
+// default layouts it doesn't define itself, so it can use their partials and block
+// defaults.
+func (r *Renderer) LoadNamedLayouts(dir string) error {
+	names, err := LayoutNames(dir)
+	if err != nil {
+		return err
+	}
+	if len(names) > 0 && r.layouts == nil {
+		return fmt.Errorf("named layouts in %s need the default layouts", dir)
+	}
+
+	named := make(map[string]*template.Template, len(names))
+	for _, name := range names {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return fmt.Errorf("failed to read layout %s: %w", name, err)
+		}
+		set, err := template.New(LayoutTemplate).Parse(string(content))
+		if err != nil {
+			return fmt.Errorf("failed to parse layout %s: %w", name, err)
+		}
+		// Executing a template escapes its tree in place, so take the trees from a copy
+		defaults, err := r.layouts.Clone()
+		if err != nil {
+			return fmt.Errorf("failed to clone layout templates for %s: %w", name, err)
+		}
+		for _, t := range defaults.Templates() {
+			if t.Tree == nil || set.Lookup(t.Name()) != nil {
+				continue
+			}
+			if _, err := set.AddParseTree(t.Name(), t.Tree); err != nil {
+				return fmt.Errorf("failed to add %s to layout %s: %w", t.Name(), name, err)
+			}
+		}
+		named[name] = set
+	}
+	r.named = named
+	r.logger.Debug("Loaded named layouts", "dir", dir, "layouts", names)
+	return nil
+}
+
+// RenderPageLayout executes the layout a composed Page selects, as RenderLayout does
+// the default one: the named layout page.Layout, or layout.html if it is empty or
+// names layout.html. An unknown layout is logged and the default used.
+func (r *Renderer) RenderPageLayout(w io.Writer, page *model.Page, data LayoutData) error {
+	set := r.layouts
+	if page.Layout != "" && page.Layout != LayoutTemplate {
+		if named, ok := r.named[page.Layout]; ok {
+			set = named
+		} else {
+			r.logger.Warn("Page layout not found, using the default layout", "page_id", page.ID, "layout", page.Layout)
+		}
+	}
+	return executeLayout(w, set, data)
+}
+
+// layoutFor returns the layouts to parse a module into: its named layout if it sets
+// one, else the default. An unknown layout is logged and the default used.
+func (r *Renderer) layoutFor(module *model.Module) *template.Template {
+	if module.Layout == "" {
+		return r.layouts
+	}
+	if set, ok := r.named[module.Layout]; ok {
+		return set
+	}
+	r.logger.Warn("Module layout not found, using the default layout", "module_id", module.ID, "layout", module.Layout)
+	return r.layouts
+}
@@ -0,0 +1,97 @@
+package templating
+
+import (
+	"bytes"
+	"go-module-builder/internal/model"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadNamedLayouts(t *testing.T) {
+	renderer, module := newRendererFixture(t)
+	layoutsDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(layoutsDir, "landing.html"), []byte(`<main class="landing">{{ template "page" .PageContent }}</main>`), 0644); err != nil {
+		t.Fatalf("Failed to write layout: %v", err)
+	}
+	if err := renderer.LoadNamedLayouts(layoutsDir); err != nil {
+		t.Fatalf("LoadNamedLayouts failed: %v", err)
+	}
+
+	render := func(layout string) string {
+		t.Helper()
+		module.Layout = layout
+		set, err := renderer.ParseModule(module, nil)
+		if err != nil {
+			t.Fatalf("ParseModule failed: %v", err)
+		}
+		var buf bytes.Buffer
+		if err := renderer.RenderModulePage(&buf, set, ModuleInstanceData{Module: module}, &LayoutData{}); err != nil {
+			t.Fatalf("RenderModulePage failed: %v", err)
+		}
+		return buf.String()
+	}
+
+	page := `<style>.module-one {}</style><div><h1>Module One</h1><ul></ul></div>`
+	if got, want := render("landing.html"), `<main class="landing">`+page+`</main>`; got != want {
+		t.Errorf("Page with landing.html = %q, want %q", got, want)
+	}
+	if got, want := render(""), `<html><body>`+page+`</body></html>`; got != want {
+		t.Errorf("Page with the default layout = %q, want %q", got, want)
+	}
+	// An unknown layout falls back to the default
+	if got, want := render("missing.html"), `<html><body>`+page+`</body></html>`; got != want {
+		t.Errorf("Page with an unknown layout = %q, want %q", got, want)
+	}
+}
+
+func TestRenderPageLayout(t *testing.T) {
+	renderer, _ := newRendererFixture(t)
+	layoutsDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(layoutsDir, "landing.html"), []byte(`<main class="landing">{{ template "page" .PageContent }}</main>`), 0644); err != nil {
+		t.Fatalf("Failed to write layout: %v", err)
+	}
+	if err := renderer.LoadNamedLayouts(layoutsDir); err != nil {
+		t.Fatalf("LoadNamedLayouts failed: %v", err)
+	}
+
+	for _, tc := range []struct{ layout, want string }{
+		{"landing.html", `<main class="landing">default</main>`},
+		{"", `<html><body>default</body></html>`},
+		{LayoutTemplate, `<html><body>default</body></html>`},
+		{"missing.html", `<html><body>default</body></html>`}, // Falls back to the default
+	} {
+		var buf bytes.Buffer
+		page := &model.Page{ID: "page-1", Layout: tc.layout}
+		if err := renderer.RenderPageLayout(&buf, page, LayoutData{PageContent: PageData{Page: page}}); err != nil {
+			t.Fatalf("RenderPageLayout(%q) failed: %v", tc.layout, err)
+		}
+		if got := buf.String(); got != tc.want {
+			t.Errorf("Page with layout %q = %q, want %q", tc.layout, got, tc.want)
+		}
+	}
+}
+
+func TestValidateLayout(t *testing.T) {
+	layoutsDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(layoutsDir, "landing.html"), []byte(`{{ template "page" . }}`), 0644); err != nil {
+		t.Fatalf("Failed to write layout: %v", err)
+	}
+
+	names, err := LayoutNames(layoutsDir)
+	if err != nil || len(names) != 1 || names[0] != "landing.html" {
+		t.Errorf("LayoutNames() = %v, %v; want [landing.html]", names, err)
+	}
+	if names, err := LayoutNames(filepath.Join(layoutsDir, "missing")); err != nil || len(names) != 0 {
+		t.Errorf("LayoutNames() of a missing dir = %v, %v; want none", names, err)
+	}
+
+	if err := ValidateLayout(layoutsDir, "landing.html"); err != nil {
+		t.Errorf("ValidateLayout(landing.html) failed: %v", err)
+	}
+	for _, name := range []string{"", "missing.html", "landing", "../layout.html", "sub/landing.html"} {
+		if err := ValidateLayout(layoutsDir, name); err == nil {
+			t.Errorf("ValidateLayout(%q) did not return an error", name)
+		}
+	}
+}
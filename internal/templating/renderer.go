@@ -0,0 +1,279 @@
+// Package templating renders modules. The main server, the admin preview and the
+// CLI preview all go through Renderer, so what is previewed is what is served.
+package templating
+
+import (
+	"bytes"
+	"fmt"
+	"go-module-builder/internal/model"
+	"go-module-builder/pkg/fsutils"
+	"html/template"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// LayoutTemplate is the site layout from web/templates. Its "page" block holds the page content.
+	LayoutTemplate = "layout.html"
+	// PageTemplate wraps a module's rendered sub-templates; a module's base.html defines it.
+	PageTemplate = "page"
+	// BaseTemplateFile is the module template file that defines PageTemplate.
+	BaseTemplateFile = "base.html"
+)
+
+// PageData is passed to the "page" template, both a module's own (base.html) and the
+// layout's "page" block. Embedding the module keeps {{ .Name }} working alongside
+// {{ .Module.Name }}; Module is nil when rendering a composed Page.
+type PageData struct {
+	*model.Module
+	Page            *model.Page   // Set when rendering a composed Page
+	RenderedContent template.HTML // Pre-rendered HTML of the sorted sub-templates
+}
+
+// ModuleInstanceData is passed to a module's sub-templates. Embedding the module
+// keeps {{ .Name }} and {{ .ID }} working, while {{ .Config }} exposes the instance
+// configuration (only set on a Page) and {{ .Data }} the module's provided data.
+type ModuleInstanceData struct {
+	*model.Module
+	Config map[string]any
+	Data   any
+}
+
+// LayoutData holds the data passed to the layout template.
+type LayoutData struct {
+	IsModuleListEnabled bool
+	PageContent         any // Can be nil, []*model.Module, or PageData
+}
+
+// Renderer parses and executes module templates.
+type Renderer struct {
+	modulesDir string                        // Directory holding module folders (modules/{id}/templates)
+	layouts    *template.Template            // Never executed, so it can be cloned for every module set; nil renders without a layout
+	named      map[string]*template.Template // Named layouts by file name, selected by Module.Layout
+	logger     *slog.Logger
+	strict     bool // Fail on a broken sub-template instead of logging and skipping it
+}
+
+// ParseLayouts parses the layout templates (*.html) in dir.
+func ParseLayouts(dir string) (*template.Template, error) {
+	pattern := filepath.Join(dir, "*.html")
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("error finding layout templates matching %s: %w", pattern, err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no layout templates found matching %s", pattern)
+	}
+	layouts, err := template.ParseFiles(files...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse layout templates: %w", err)
+	}
+	return layouts, nil
+}
+
+// NewRenderer creates a Renderer for the modules in modulesDir. It keeps its own copy
+// of layouts, so the caller may go on executing them; layouts may be nil, in which
+// case modules are rendered as fragments only.
+func NewRenderer(modulesDir string, layouts *template.Template, logger *slog.Logger) (*Renderer, error) {
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	r := &Renderer{modulesDir: modulesDir, logger: logger}
+	if layouts != nil {
+		own, err := layouts.Clone()
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy layout templates: %w", err)
+		}
+		r.layouts = own
+	}
+	return r, nil
+}
+
+// Strict returns a copy of the renderer that fails on the first broken sub-template.
+// Previews use it to report errors that the server would log and skip.
+func (r *Renderer) Strict() *Renderer {
+	strict := *r
+	strict.strict = true
+	return &strict
+}
+
+// TemplatesDir returns the directory holding a module's template files.
+func (r *Renderer) TemplatesDir(moduleID string) string {
+	return filepath.Join(r.modulesDir, moduleID, "templates")
+}
+
+// isTemplateFile reports whether a module file is a template (as opposed to e.g. a script).
+func isTemplateFile(name string) bool {
+	switch filepath.Ext(name) {
+	case ".html", ".tmpl", ".css":
+		return true
+	}
+	return false
+}
+
+// ActiveTemplates returns the module's enabled template files (.html, .tmpl, .css) in
+// their configured order. Disabled templates and files not listed in the module's
+// metadata are neither parsed nor rendered.
+func ActiveTemplates(module *model.Module) []model.Template {
+	var active []model.Template
+	for _, t := range module.Templates {
+		if t.IsActive && isTemplateFile(t.Name) {
+			active = append(active, t)
+		}
+	}
+	sort.SliceStable(active, func(i, j int) bool {
+		return active[i].Order < active[j].Order
+	})
+	return active
+}
+
+// ParseModule returns the module's template set: a clone of the module's layout (see
+// LoadNamedLayouts) with its active template files parsed in, in order, under their
+// file names. A template whose name is not a valid template filename (see
+// fsutils.ParseTemplateFilename) fails the module, as its metadata was not written by
+// the builder, and no file outside the templates directory is read.
+// overrides maps file names to content used instead of the file on disk, e.g. unsaved
+// editor changes.
+func (r *Renderer) ParseModule(module *model.Module, overrides map[string]string) (*template.Template, error) {
+	templatesDir := r.TemplatesDir(module.ID)
+	templates := ActiveTemplates(module)
+	if len(templates) == 0 {
+		return nil, fmt.Errorf("module %s has no active template files (.html, .tmpl, .css)", module.ID)
+	}
+
+	var set *template.Template
+	var err error
+	if layouts := r.layoutFor(module); layouts != nil {
+		set, err = layouts.Clone()
+		if err != nil {
+			return nil, fmt.Errorf("failed to clone layout templates: %w", err)
+		}
+	} else {
+		set = template.New(module.ID)
+	}
+
+	for _, t := range templates {
+		file, err := fsutils.ParseTemplateFilename(t.Name)
+		if err != nil {
+			r.logger.Warn("Module metadata lists an invalid template filename", "module_id", module.ID, "template_name", t.Name, "error", err)
+			return nil, fmt.Errorf("module %s: %w", module.ID, err)
+		}
+		content, ok := overrides[t.Name]
+		if !ok {
+			b, err := os.ReadFile(file.Path(templatesDir))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read template %s: %w", t.Name, err)
+			}
+			content = string(b)
+		}
+		if _, err := set.New(t.Name).Parse(content); err != nil {
+			return nil, fmt.Errorf("failed to parse template %s for module %s: %w", t.Name, module.ID, err)
+		}
+	}
+	return set, nil
+}
+
+// SubTemplates returns the module's active HTML/TMPL templates other than base.html,
+// in the order they are rendered into the page.
+func SubTemplates(module *model.Module) []model.Template {
+	var subTemplates []model.Template
+	for _, t := range ActiveTemplates(module) {
+		if t.IsBase || t.Name == BaseTemplateFile {
+			continue
+		}
+		if ext := filepath.Ext(t.Name); ext == ".html" || ext == ".tmpl" {
+			subTemplates = append(subTemplates, t)
+		}
+	}
+	return subTemplates
+}
+
+// RenderTemplate executes the template for a module template file: the template the
+// file defines under its base name (content.html defines "content"), or else the file itself.
+func (r *Renderer) RenderTemplate(w io.Writer, set *template.Template, filename string, data any) error {
+	tmpl := set.Lookup(strings.TrimSuffix(filename, filepath.Ext(filename)))
+	if tmpl == nil {
+		tmpl = set.Lookup(filename)
+	}
+	if tmpl == nil {
+		return fmt.Errorf("no template defined for %s", filename)
+	}
+	if err := tmpl.Execute(w, data); err != nil {
+		return fmt.Errorf("failed executing template for %s: %w", filename, err)
+	}
+	return nil
+}
+
+// RenderSubTemplates executes the module's sub-templates in order and returns the
+// concatenated output. A broken sub-template is logged and left out, so it doesn't
+// blank the page, unless the renderer is strict.
+func (r *Renderer) RenderSubTemplates(set *template.Template, data ModuleInstanceData) (template.HTML, error) {
+	var out bytes.Buffer
+	for _, t := range SubTemplates(data.Module) {
+		var buf bytes.Buffer
+		if err := r.RenderTemplate(&buf, set, t.Name, data); err != nil {
+			if r.strict {
+				return "", fmt.Errorf("module %s: %w", data.Module.ID, err)
+			}
+			r.logger.Error("Error rendering sub-template", "template_name", t.Name, "module_id", data.Module.ID, "module_slug", data.Module.Slug, "error", err)
+			continue
+		}
+		out.Write(buf.Bytes())
+	}
+	return template.HTML(out.String()), nil
+}
+
+// RenderLayout executes the default layout on its own, as the main server does for
+// the site root (PageContent nil) and the module list.
+func (r *Renderer) RenderLayout(w io.Writer, data LayoutData) error {
+	return executeLayout(w, r.layouts, data)
+}
+
+// executeLayout executes a copy of the layout set, so set can still be cloned for
+// module sets.
+func executeLayout(w io.Writer, set *template.Template, data LayoutData) error {
+	if set == nil {
+		return fmt.Errorf("no layout templates loaded")
+	}
+	layouts, err := set.Clone()
+	if err != nil {
+		return fmt.Errorf("failed to clone layout templates: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := layouts.ExecuteTemplate(&buf, LayoutTemplate, data); err != nil {
+		return fmt.Errorf("failed to render layout: %w", err)
+	}
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// RenderModulePage renders a module the way it is served at /{slug}: its sub-templates
+// wrapped by its "page" template. With layout set (and a layout parsed into set) the
+// result is placed in the layout as a full document; otherwise only the "page"
+// fragment is written, as for HTMX requests and module instances on a Page.
+// Nothing is written if rendering fails.
+func (r *Renderer) RenderModulePage(w io.Writer, set *template.Template, data ModuleInstanceData, layout *LayoutData) error {
+	content, err := r.RenderSubTemplates(set, data)
+	if err != nil {
+		return err
+	}
+	pageData := PageData{Module: data.Module, RenderedContent: content}
+
+	var buf bytes.Buffer
+	if layout != nil && set.Lookup(LayoutTemplate) != nil {
+		layoutData := *layout
+		layoutData.PageContent = pageData
+		err = set.ExecuteTemplate(&buf, LayoutTemplate, layoutData)
+	} else {
+		err = set.ExecuteTemplate(&buf, PageTemplate, pageData)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to render module %s: %w", data.Module.ID, err)
+	}
+	_, err = buf.WriteTo(w)
+	return err
+}
@@ -0,0 +1,190 @@
+package templating
+
+import (
+	"bytes"
+	"errors"
+	"go-module-builder/internal/model"
+	"go-module-builder/pkg/fsutils"
+	"html/template"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// newRendererFixture creates a module "mod-1" with a base, two ordered sub-templates
+// and a stylesheet, and a renderer with a minimal layout.
+func newRendererFixture(t *testing.T) (*Renderer, *model.Module) {
+	t.Helper()
+	modulesDir := t.TempDir()
+	templatesDir := filepath.Join(modulesDir, "mod-1", "templates")
+	if err := os.MkdirAll(templatesDir, 0755); err != nil {
+		t.Fatalf("Failed to create templates directory: %v", err)
+	}
+	files := map[string]string{
+		"base.html":  `{{ define "page" }}<style>{{ template "module-style" .Module }}</style><div>{{ .RenderedContent }}</div>{{ end }}`,
+		"style.css":  `{{ define "module-style" }}.{{ .Slug }} {}{{ end }}`,
+		"intro.html": `{{ define "intro" }}<h1>{{ .Name }}</h1>{{ end }}`,
+		"list.tmpl":  `<ul>{{ range .Data }}<li>{{ . }}</li>{{ end }}</ul>`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(templatesDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	layouts := template.Must(template.New(LayoutTemplate).Parse(`<html><body>{{ block "page" .PageContent }}default{{ end }}</body></html>`))
+	renderer, err := NewRenderer(modulesDir, layouts, nil)
+	if err != nil {
+		t.Fatalf("NewRenderer failed: %v", err)
+	}
+	module := &model.Module{
+		ID:   "mod-1",
+		Name: "Module One",
+		Slug: "module-one",
+		Templates: []model.Template{
+			{Name: "base.html", IsBase: true, Order: 0, IsActive: true},
+			{Name: "style.css", Order: 1, IsActive: true},
+			{Name: "list.tmpl", Order: 3, IsActive: true},
+			{Name: "intro.html", Order: 2, IsActive: true},
+		},
+	}
+	return renderer, module
+}
+
+func TestRenderModulePage(t *testing.T) {
+	renderer, module := newRendererFixture(t)
+	set, err := renderer.ParseModule(module, nil)
+	if err != nil {
+		t.Fatalf("ParseModule failed: %v", err)
+	}
+	data := ModuleInstanceData{Module: module, Data: []string{"a", "b"}}
+
+	var doc bytes.Buffer
+	if err := renderer.RenderModulePage(&doc, set, data, &LayoutData{}); err != nil {
+		t.Fatalf("RenderModulePage with layout failed: %v", err)
+	}
+	want := `<html><body><style>.module-one {}</style><div><h1>Module One</h1><ul><li>a</li><li>b</li></ul></div></body></html>`
+	if doc.String() != want {
+		t.Errorf("RenderModulePage with layout = %q, want %q", doc.String(), want)
+	}
+
+	var fragment bytes.Buffer
+	if err := renderer.RenderModulePage(&fragment, set, data, nil); err != nil {
+		t.Fatalf("RenderModulePage without layout failed: %v", err)
+	}
+	if strings.Contains(fragment.String(), "<html>") || !strings.Contains(fragment.String(), "<h1>Module One</h1><ul>") {
+		t.Errorf("RenderModulePage without layout = %q, want the page fragment", fragment.String())
+	}
+}
+
+func TestParseModule_Overrides(t *testing.T) {
+	renderer, module := newRendererFixture(t)
+	set, err := renderer.ParseModule(module, map[string]string{
+		"intro.html": `{{ define "intro" }}<h2>Unsaved</h2>{{ end }}`,
+	})
+	if err != nil {
+		t.Fatalf("ParseModule with overrides failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := renderer.RenderModulePage(&buf, set, ModuleInstanceData{Module: module}, nil); err != nil {
+		t.Fatalf("RenderModulePage failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "<h2>Unsaved</h2>") {
+		t.Errorf("Override was not rendered: %q", buf.String())
+	}
+
+	if _, err := renderer.ParseModule(module, map[string]string{"intro.html": `{{ define "intro" }}`}); err == nil {
+		t.Error("ParseModule did not report a parse error in an override")
+	}
+}
+
+func TestParseModule_InvalidTemplateNames(t *testing.T) {
+	renderer, module := newRendererFixture(t)
+	// A file outside the module that metadata must not be able to pull in
+	secret := filepath.Join(renderer.TemplatesDir(module.ID), "..", "..", "secret.html")
+	if err := os.WriteFile(secret, []byte("SECRET"), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", secret, err)
+	}
+
+	for _, name := range []string{"../../secret.html", "../templates/base.html", "/etc/passwd.html", "sub/intro.html", `..\..\secret.html`} {
+		mod := *module
+		mod.Templates = append(slices.Clone(module.Templates), model.Template{Name: name, Order: 9, IsActive: true})
+		set, err := renderer.ParseModule(&mod, nil)
+		if !errors.Is(err, fsutils.ErrInvalidTemplateFilename) {
+			t.Errorf("ParseModule with template %q: error = %v, want ErrInvalidTemplateFilename", name, err)
+		}
+		if set != nil {
+			t.Errorf("ParseModule with template %q returned a template set", name)
+		}
+	}
+}
+
+func TestRenderSubTemplates_BrokenTemplate(t *testing.T) {
+	renderer, module := newRendererFixture(t)
+	set, err := renderer.ParseModule(module, map[string]string{
+		"intro.html": `{{ define "intro" }}{{ .Missing }}{{ end }}`,
+	})
+	if err != nil {
+		t.Fatalf("ParseModule failed: %v", err)
+	}
+	data := ModuleInstanceData{Module: module, Data: []string{"a"}}
+
+	// The server skips the broken template and renders the rest
+	content, err := renderer.RenderSubTemplates(set, data)
+	if err != nil {
+		t.Fatalf("RenderSubTemplates failed: %v", err)
+	}
+	if string(content) != "<ul><li>a</li></ul>" {
+		t.Errorf("RenderSubTemplates = %q, want only the working template", content)
+	}
+
+	// Previews report it
+	if _, err := renderer.Strict().RenderSubTemplates(set, data); err == nil || !strings.Contains(err.Error(), "intro.html") {
+		t.Errorf("Strict RenderSubTemplates error = %v, want an error naming intro.html", err)
+	}
+}
+
+func TestRenderModulePage_ActiveAndOrder(t *testing.T) {
+	renderer, module := newRendererFixture(t)
+	// A file on disk that is not listed in the metadata is ignored
+	stray := filepath.Join(renderer.TemplatesDir(module.ID), "stray.html")
+	if err := os.WriteFile(stray, []byte(`{{ define "page" }}stray{{ end }}`), 0644); err != nil {
+		t.Fatalf("Failed to write stray template: %v", err)
+	}
+
+	render := func() string {
+		t.Helper()
+		set, err := renderer.ParseModule(module, nil)
+		if err != nil {
+			t.Fatalf("ParseModule failed: %v", err)
+		}
+		var buf bytes.Buffer
+		if err := renderer.RenderModulePage(&buf, set, ModuleInstanceData{Module: module, Data: []string{"a"}}, nil); err != nil {
+			t.Fatalf("RenderModulePage failed: %v", err)
+		}
+		return buf.String()
+	}
+
+	// list.tmpl moved before intro.html
+	module.Templates[2].Order = 1
+	if got := render(); !strings.Contains(got, "<div><ul><li>a</li></ul><h1>Module One</h1></div>") {
+		t.Errorf("Reordered page = %q, want list before intro", got)
+	}
+
+	// A disabled template is neither parsed nor rendered, even if it doesn't parse
+	module.Templates[3].IsActive = false
+	if err := os.WriteFile(filepath.Join(renderer.TemplatesDir(module.ID), "intro.html"), []byte(`{{ define "intro" }}`), 0644); err != nil {
+		t.Fatalf("Failed to write intro.html: %v", err)
+	}
+	if got := render(); strings.Contains(got, "Module One") || !strings.Contains(got, "<ul><li>a</li></ul>") {
+		t.Errorf("Page with intro.html disabled = %q, want only the list", got)
+	}
+
+	// With base.html disabled, the layout's own "page" block is used
+	module.Templates[0].IsActive = false
+	if got := render(); got != "default" {
+		t.Errorf("Page with base.html disabled = %q, want the layout's page block", got)
+	}
+}
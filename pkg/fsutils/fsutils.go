@@ -3,23 +3,38 @@ package fsutils
 import (
 	"fmt"
 	"io" // Added for io.Copy
+	"log/slog"
 	"os"
 	"path/filepath" // Added for filepath.Join
 	"regexp"        // Needed for sanitization
 	"strings"       // Needed for sanitization
+	"sync/atomic"
 )
 
+// logger receives debug records for file operations. It discards everything until SetLogger is called.
+var logger atomic.Pointer[slog.Logger]
+
+func init() {
+	logger.Store(slog.New(slog.DiscardHandler))
+}
+
+// SetLogger sets the logger used by the package. A nil logger restores the default discard logger.
+func SetLogger(l *slog.Logger) {
+	if l == nil {
+		l = slog.New(slog.DiscardHandler)
+	}
+	logger.Store(l)
+}
+
 // CreateDir creates a directory if it doesn't exist.
 func CreateDir(path string) error {
-	// Implementation needed: Use os.MkdirAll
-	fmt.Printf("Placeholder: Would create directory at %s\n", path)
+	logger.Load().Debug("Creating directory", "path", path)
 	return os.MkdirAll(path, 0755) // Use standard permission bits
 }
 
 // CreateFile creates an empty file. Fails if it already exists.
 func CreateFile(path string) error {
-	// Implementation needed: Use os.Create (or os.OpenFile with O_CREATE|O_EXCL)
-	fmt.Printf("Placeholder: Would create empty file at %s\n", path)
+	logger.Load().Debug("Creating empty file", "path", path)
 	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
 	if err != nil {
 		return err
@@ -29,28 +44,68 @@ func CreateFile(path string) error {
 
 // WriteToFile writes content to a file, overwriting if it exists.
 func WriteToFile(path string, content []byte) error {
-	// Implementation needed: Use os.WriteFile
-	fmt.Printf("Placeholder: Would write %d bytes to %s\n", len(content), path)
+	logger.Load().Debug("Writing file", "path", path, "bytes", len(content))
 	return os.WriteFile(path, content, 0644) // Standard file permissions
 }
 
+// WriteFileAtomic replaces the file at path with content so that readers (and a crash
+// at any point) see either the old or the new content, never a partial write.
+// The data is written to a temporary file in the same directory, fsynced, and renamed over path.
+func WriteFileAtomic(path string, content []byte, perm os.FileMode) error {
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	tmp, err := os.CreateTemp(dir, "."+name+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file for %q: %w", path, err)
+	}
+	tmpPath := tmp.Name()
+	// Remove the temporary file on any failure; after a successful rename it no longer exists.
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary file %q: %w", tmpPath, err)
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set permissions on temporary file %q: %w", tmpPath, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync temporary file %q: %w", tmpPath, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file %q: %w", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace %q: %w", path, err)
+	}
+
+	// Persist the rename itself. Directories cannot be opened for syncing on every
+	// platform (e.g. Windows), so this step is best effort.
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
 // ReadFile reads the content of a file.
 func ReadFile(path string) ([]byte, error) {
-	// Implementation needed: Use os.ReadFile
-	fmt.Printf("Placeholder: Would read file from %s\n", path)
+	logger.Load().Debug("Reading file", "path", path)
 	return os.ReadFile(path)
 }
 
 // ScanDir lists files and directories directly under the given path.
 func ScanDir(path string) ([]os.DirEntry, error) {
-	// Implementation needed: Use os.ReadDir
-	fmt.Printf("Placeholder: Would scan directory %s\n", path)
+	logger.Load().Debug("Scanning directory", "path", path)
 	return os.ReadDir(path)
 }
 
 // FileExists checks if a path exists and is a regular file (not a directory).
 func FileExists(path string) bool {
-	fmt.Printf("Placeholder: Would check if %s exists and is a file\n", path)
 	info, err := os.Stat(path)
 	if os.IsNotExist(err) {
 		return false // Path doesn't exist
@@ -69,7 +124,7 @@ func FileExists(path string) bool {
 // It creates the destination directory if it doesn't exist.
 // Existing files in the destination will be overwritten.
 func CopyDir(src, dst string) error {
-	fmt.Printf("Placeholder: Would copy directory from %s to %s\n", src, dst)
+	logger.Load().Debug("Copying directory", "src", src, "dst", dst)
 
 	// Get properties of source dir
 	srcInfo, err := os.Stat(src)
@@ -101,6 +101,39 @@ func TestWriteToFile(t *testing.T) {
 
 }
 
+func TestWriteFileAtomic(t *testing.T) {
+	tempDir := t.TempDir()
+	filePath := filepath.Join(tempDir, "module.json")
+
+	// Test 1: Create, then replace the file
+	for _, content := range []string{`{"v":1}`, `{"v":2}`} {
+		if err := WriteFileAtomic(filePath, []byte(content), 0644); err != nil {
+			t.Fatalf("WriteFileAtomic(%q) returned error: %v", filePath, err)
+		}
+		readContent, err := os.ReadFile(filePath)
+		if err != nil {
+			t.Fatalf("Error reading back file %q: %v", filePath, err)
+		}
+		if string(readContent) != content {
+			t.Fatalf("Read content %q does not match written content %q", readContent, content)
+		}
+	}
+
+	// Test 2: No temporary files are left behind
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		t.Fatalf("Error reading directory %q: %v", tempDir, err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected only the target file in %q, found %d entries", tempDir, len(entries))
+	}
+
+	// Test 3: Writing into a non-existent directory fails
+	if err := WriteFileAtomic(filepath.Join(tempDir, "missing_dir", "module.json"), []byte("x"), 0644); err == nil {
+		t.Error("WriteFileAtomic() into a non-existent directory succeeded, expected error")
+	}
+}
+
 func TestFileExists(t *testing.T) {
 	tempDir := t.TempDir()
 
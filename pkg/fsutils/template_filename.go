@@ -0,0 +1,79 @@
+package fsutils
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// TemplateExtensions are the file extensions a module template may have.
+var TemplateExtensions = []string{".html", ".tmpl", ".css", ".js"}
+
+// MaxTemplateFilenameLength is the longest template filename accepted, in bytes.
+const MaxTemplateFilenameLength = 100
+
+// ErrInvalidTemplateFilename is wrapped by the errors ParseTemplateFilename returns.
+var ErrInvalidTemplateFilename = errors.New("invalid template filename")
+
+// reservedNames are device names Windows won't create files for, whatever the extension.
+var reservedNames = []string{
+	"con", "prn", "aux", "nul",
+	"com1", "com2", "com3", "com4", "com5", "com6", "com7", "com8", "com9",
+	"lpt1", "lpt2", "lpt3", "lpt4", "lpt5", "lpt6", "lpt7", "lpt8", "lpt9",
+}
+
+// TemplateFilename is the name of a file directly inside a module's templates
+// directory. It can only be made by ParseTemplateFilename, so joining it to that
+// directory never leads anywhere else.
+type TemplateFilename struct {
+	name string
+}
+
+// ParseTemplateFilename checks a user-supplied template filename. It must be a single
+// path element of ASCII letters, digits, '.', '-' and '_' that starts with a letter or
+// digit, has no "..", ends in one of TemplateExtensions, is at most
+// MaxTemplateFilenameLength bytes long and is not a reserved device name.
+func ParseTemplateFilename(name string) (TemplateFilename, error) {
+	invalid := func(reason string) (TemplateFilename, error) {
+		return TemplateFilename{}, fmt.Errorf("%w %q: %s", ErrInvalidTemplateFilename, name, reason)
+	}
+	if name == "" {
+		return invalid("it is empty")
+	}
+	if len(name) > MaxTemplateFilenameLength {
+		return invalid(fmt.Sprintf("it is longer than %d characters", MaxTemplateFilenameLength))
+	}
+	for i, c := range []byte(name) {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case (c == '.' || c == '-' || c == '_') && i > 0:
+		case c == '/' || c == '\\':
+			return invalid("it must not contain path separators")
+		default:
+			return invalid("use letters, digits, '.', '-' and '_' only, starting with a letter or digit")
+		}
+	}
+	if strings.Contains(name, "..") {
+		return invalid(`it must not contain ".."`)
+	}
+	if !slices.Contains(TemplateExtensions, filepath.Ext(name)) {
+		return invalid("the extension must be one of " + strings.Join(TemplateExtensions, ", "))
+	}
+	base, _, _ := strings.Cut(name, ".")
+	if slices.Contains(reservedNames, strings.ToLower(base)) {
+		return invalid("it is a reserved name")
+	}
+	return TemplateFilename{name: name}, nil
+}
+
+// String returns the filename.
+func (f TemplateFilename) String() string {
+	return f.name
+}
+
+// Path returns the path of the file in templatesDir.
+func (f TemplateFilename) Path(templatesDir string) string {
+	return filepath.Join(templatesDir, f.name)
+}
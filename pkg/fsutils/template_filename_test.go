@@ -0,0 +1,77 @@
+package fsutils
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseTemplateFilename(t *testing.T) {
+	valid := []string{"base.html", "content.html", "style.css", "card-2.tmpl", "app_main.js", "Hero.Section.html"}
+	for _, name := range valid {
+		if f, err := ParseTemplateFilename(name); err != nil || f.String() != name {
+			t.Errorf("ParseTemplateFilename(%q) = %q, %v; want it accepted", name, f, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"base",
+		"base.go",
+		"base.HTML",
+		".html",
+		"../base.html",
+		"..html",
+		"a..html",
+		"templates/base.html",
+		`templates\base.html`,
+		"/etc/passwd.html",
+		"C:base.html",
+		"base.html\x00.css",
+		"base .html",
+		"-rf.html",
+		"con.html",
+		"LPT1.css",
+		"nul.tar.html",
+		"baïse.html",
+		strings.Repeat("a", MaxTemplateFilenameLength-4) + ".html",
+	}
+	for _, name := range invalid {
+		if f, err := ParseTemplateFilename(name); !errors.Is(err, ErrInvalidTemplateFilename) {
+			t.Errorf("ParseTemplateFilename(%q) = %q, %v; want ErrInvalidTemplateFilename", name, f, err)
+		}
+	}
+}
+
+// FuzzParseTemplateFilename checks that every filename ParseTemplateFilename accepts
+// names a file directly inside the templates directory.
+func FuzzParseTemplateFilename(f *testing.F) {
+	for _, seed := range []string{
+		"base.html", "style.css", "../base.html", "..", ".", "a/../b.html", `..\..\x.html`,
+		"/abs.html", "x.html/", "x.html\x00", "con.html", "a.b.c.tmpl", "%2e%2e%2fx.html", "~root.html",
+	} {
+		f.Add(seed)
+	}
+	templatesDir := filepath.Join("modules", "module-id", "templates")
+
+	f.Fuzz(func(t *testing.T, name string) {
+		file, err := ParseTemplateFilename(name)
+		if err != nil {
+			if !errors.Is(err, ErrInvalidTemplateFilename) {
+				t.Fatalf("ParseTemplateFilename(%q) error %v does not wrap ErrInvalidTemplateFilename", name, err)
+			}
+			return
+		}
+		if file.String() != name {
+			t.Fatalf("ParseTemplateFilename(%q) changed the name to %q", name, file)
+		}
+		path := file.Path(templatesDir)
+		if filepath.Dir(path) != templatesDir || filepath.Base(path) != name {
+			t.Fatalf("Path of %q is %q, outside %s", name, path, templatesDir)
+		}
+		if rel, err := filepath.Rel(templatesDir, path); err != nil || rel != name {
+			t.Fatalf("Path of %q is %q, relative %q (%v)", name, path, rel, err)
+		}
+	})
+}
@@ -0,0 +1,63 @@
+// Package modulehttp is imported by module handlers (modules/{id}/handler.go).
+// The server attaches the module a request was routed to, so a handler can
+// render its own templates, e.g. to answer HTMX requests with a fragment.
+package modulehttp
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+)
+
+// Module describes the module a request was routed to.
+type Module struct {
+	ID   string
+	Slug string
+	Name string
+
+	templates *template.Template // The module's parsed template set; nil if it failed to load
+}
+
+// NewModule is used by the server to describe the module serving a request.
+func NewModule(id, slug, name string, templates *template.Template) *Module {
+	return &Module{ID: id, Slug: slug, Name: name, templates: templates}
+}
+
+// Render executes the named template (e.g. "content" or a template added to the module) with data.
+func (m *Module) Render(w io.Writer, name string, data any) error {
+	if m.templates == nil {
+		return fmt.Errorf("templates for module %s are not loaded", m.ID)
+	}
+	if m.templates.Lookup(name) == nil {
+		return fmt.Errorf("module %s has no template named %q", m.ID, name)
+	}
+	return m.templates.ExecuteTemplate(w, name, data)
+}
+
+// DataFunc is the signature of the optional Data function in a module's handler.go:
+//
+//	func Data(r *http.Request) (any, error)
+//
+// When declared, the server calls it each time it renders the module and passes the
+// result to the module's templates as .Data. FromRequest(r) returns the module.
+type DataFunc func(r *http.Request) (any, error)
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the module.
+func NewContext(ctx context.Context, m *Module) context.Context {
+	return context.WithValue(ctx, contextKey{}, m)
+}
+
+// FromRequest returns the module the server routed the request to.
+func FromRequest(r *http.Request) (*Module, bool) {
+	m, ok := r.Context().Value(contextKey{}).(*Module)
+	return m, ok
+}
+
+// IsHTMX reports whether the request was sent by HTMX and expects an HTML fragment.
+func IsHTMX(r *http.Request) bool {
+	return r.Header.Get("HX-Request") == "true"
+}
@@ -15,6 +15,8 @@ document.addEventListener('DOMContentLoaded', function() {
     let currentEditingFile = null;
     let activeListItem = null;
     let currentModuleID = editorLayoutElement ? editorLayoutElement.dataset.moduleId : null;
+    let currentRevision = editorLayoutElement ? editorLayoutElement.dataset.revision : null; // Sent as If-Match on save
+    const readOnly = editorLayoutElement ? editorLayoutElement.dataset.readOnly === 'true' : false; // Viewers can't save
     let previewTimeout;
 
     // --- Initialization ---
@@ -33,7 +35,8 @@ document.addEventListener('DOMContentLoaded', function() {
             templateList.querySelectorAll('li[data-filename]').forEach(li => {
                 const name = li.dataset.filename;
                 const isBase = li.querySelector('.gws-base-badge') !== null;
-                serverRenderedTemplates.push({ name: name, isBase: isBase });
+                const isActive = li.dataset.active === 'true';
+                serverRenderedTemplates.push({ name: name, isBase: isBase, isActive: isActive });
             });
         }
         
@@ -47,7 +50,18 @@ document.addEventListener('DOMContentLoaded', function() {
             editorLayoutElement: editorLayoutElement, 
             initialTemplates: initialTemplatesForService, 
             onFileSelect: handleFileSelect, 
-            displayMessage: displayDynamicMessage 
+            displayMessage: displayDynamicMessage,
+            readOnly: readOnly
+        });
+
+        HistoryService.init({
+            listElement: document.getElementById('history-list'),
+            diffElement: document.getElementById('history-diff'),
+            refreshButtonElement: document.getElementById('history-refresh-button'),
+            moduleId: currentModuleID,
+            csrfToken: editorLayoutElement ? editorLayoutElement.dataset.csrfToken : '',
+            displayMessage: displayDynamicMessage,
+            readOnly: readOnly
         });
 
         EditorUIManager.init({
@@ -155,9 +169,10 @@ document.addEventListener('DOMContentLoaded', function() {
         }
 
         try {
-            const content = await ApiService.loadTemplateContent(currentModuleID, filename);
-            updateEditorState(content, false, filename); 
-            if(saveChangesButton) saveChangesButton.disabled = false;
+            const result = await ApiService.loadTemplateContent(currentModuleID, filename);
+            if (result.revision) currentRevision = result.revision;
+            updateEditorState(result.content, readOnly, filename); 
+            if(saveChangesButton) saveChangesButton.disabled = readOnly;
             triggerPreview(); 
         } catch (error) {
             updateEditorState(`Error loading file: ${error.message}`, true, filename);
@@ -170,8 +185,9 @@ document.addEventListener('DOMContentLoaded', function() {
         if (!currentEditingFile || !currentModuleID || !EditorService.getInstance()) return;
 
         const content = EditorService.getValue(); 
+        const csrfToken = editorLayoutElement ? editorLayoutElement.dataset.csrfToken : '';
         try {
-            const previewHtml = await ApiService.fetchPreview(currentModuleID, currentEditingFile, content);
+            const previewHtml = await ApiService.fetchPreview(currentModuleID, currentEditingFile, content, csrfToken);
             if(previewPane) previewPane.innerHTML = `<iframe srcdoc="${escapeHtml(previewHtml)}" style="width:100%; height:100%; border:none;"></iframe>`;
         } catch (error) {
             if(previewPane) previewPane.innerHTML = `<p class="gws-preview-error">Preview error: ${escapeHtml(error.message)}</p>`;
@@ -204,8 +220,10 @@ document.addEventListener('DOMContentLoaded', function() {
         }
         
         try {
-            const responseText = await ApiService.saveTemplateContent(currentModuleID, currentEditingFile, content, csrfToken);
-            displayDynamicMessage(responseText || "File saved successfully!", 'success');
+            const result = await ApiService.saveTemplateContent(currentModuleID, currentEditingFile, content, csrfToken, currentRevision);
+            if (result.revision) currentRevision = result.revision;
+            displayDynamicMessage(result.message || "File saved successfully!", 'success');
+            HistoryService.refresh();
         } catch (error) {
             console.error("Error saving file via ApiService:", error);
             displayDynamicMessage(`Error: ${error.message}`, 'error');
@@ -296,6 +314,13 @@ document.addEventListener('DOMContentLoaded', function() {
         // Listen for custom event triggered by HTMX HX-Trigger
         // Prefer listening on a specific container if possible, or document as a fallback.
         // Using document to ensure the event is caught regardless of where HTMX dispatches it.
+        // Adding or removing a template saves the module, so pick up its new revision.
+        document.addEventListener('moduleRevision', function(event) {
+            if (event.detail && event.detail.revision !== undefined) {
+                currentRevision = String(event.detail.revision);
+            }
+        });
+
         document.addEventListener('showMessage', function(event) {
             // Access the properties directly from event.detail, based on console log
             if (event.detail && event.detail.message && event.detail.type) {
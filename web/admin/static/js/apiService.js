@@ -14,45 +14,87 @@ const ApiService = (function() {
         return response.text();
     }
 
+    // Module revisions travel as ETags (e.g. "3"); strip the quotes.
+    function revisionFromResponse(response) {
+        const etag = response.headers.get('ETag');
+        return etag ? etag.replace(/"/g, '') : null;
+    }
+
     async function loadTemplate(moduleId, filename) {
         if (!moduleId || !filename) {
             throw new Error("Module ID and filename are required to load template.");
         }
         const response = await fetch(`/api/admin/modules/${moduleId}/templates/${filename}`);
-        return handleResponse(response);
+        const content = await handleResponse(response);
+        return { content: content, revision: revisionFromResponse(response) };
     }
 
-    async function saveTemplate(moduleId, filename, content, csrfToken) {
+    async function saveTemplate(moduleId, filename, content, csrfToken, revision) {
         if (!moduleId || !filename || content === undefined || !csrfToken) {
             throw new Error("Module ID, filename, content, and CSRF token are required to save template.");
         }
+        const headers = {
+            'Content-Type': 'text/plain',
+            'X-CSRF-Token': csrfToken
+        };
+        if (revision !== null && revision !== undefined && revision !== '') {
+            headers['If-Match'] = `"${revision}"`; // Server answers 409 if the module changed since
+        }
         const response = await fetch(`/api/admin/modules/${moduleId}/templates/${filename}`, {
             method: 'PUT',
-            headers: {
-                'Content-Type': 'text/plain',
-                'X-CSRF-Token': csrfToken
-            },
+            headers: headers,
             body: content,
         });
-        return handleResponse(response); // Expects text response
+        const message = await handleResponse(response); // Expects text response
+        return { message: message, revision: revisionFromResponse(response) };
     }
 
-    async function getPreview(moduleId, filename, content) {
-        if (!moduleId || !filename || content === undefined) {
-            throw new Error("Module ID, filename, and content are required for preview.");
+    async function getPreview(moduleId, filename, content, csrfToken) {
+        if (!moduleId || !filename || content === undefined || !csrfToken) {
+            throw new Error("Module ID, filename, content, and CSRF token are required for preview.");
         }
         const response = await fetch(`/api/admin/preview/${moduleId}`, {
             method: 'POST',
-            headers: { 'Content-Type': 'application/json' },
+            headers: { 'Content-Type': 'application/json', 'X-CSRF-Token': csrfToken },
             body: JSON.stringify({ filename: filename, content: content }),
         });
         return handleResponse(response); // Expects HTML string as text
     }
 
+    async function listSnapshots(moduleId) {
+        if (!moduleId) {
+            throw new Error("Module ID is required to load history.");
+        }
+        const response = await fetch(`/api/admin/modules/${moduleId}/snapshots`);
+        return JSON.parse(await handleResponse(response));
+    }
+
+    async function getSnapshotDiff(moduleId, snapshotId) {
+        if (!moduleId || !snapshotId) {
+            throw new Error("Module ID and snapshot ID are required to compare a snapshot.");
+        }
+        const response = await fetch(`/api/admin/modules/${moduleId}/snapshots/${encodeURIComponent(snapshotId)}/diff`);
+        return JSON.parse(await handleResponse(response));
+    }
+
+    async function restoreSnapshot(moduleId, snapshotId, csrfToken) {
+        if (!moduleId || !snapshotId || !csrfToken) {
+            throw new Error("Module ID, snapshot ID, and CSRF token are required to restore a snapshot.");
+        }
+        const response = await fetch(`/api/admin/modules/${moduleId}/snapshots/${encodeURIComponent(snapshotId)}/restore`, {
+            method: 'POST',
+            headers: { 'X-CSRF-Token': csrfToken },
+        });
+        return handleResponse(response); // Expects text response
+    }
+
     // Public API
     return {
         loadTemplateContent: loadTemplate,
         saveTemplateContent: saveTemplate,
-        fetchPreview: getPreview
+        fetchPreview: getPreview,
+        listSnapshots: listSnapshots,
+        fetchSnapshotDiff: getSnapshotDiff,
+        restoreSnapshot: restoreSnapshot
     };
 })();
\ No newline at end of file
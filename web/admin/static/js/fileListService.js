@@ -13,6 +13,11 @@ const FileListService = (function() {
     let onFileSelectCallback = function(filename) { console.warn("onFileSelectCallback not implemented in FileListService for", filename); };
     // Callback to display dynamic messages
     let displayDynamicMessageCallback = function(message, type) { console.warn(`Dynamic message: ${type} - ${message}`); alert(`${type}: ${message}`); };
+    // List item being dragged to a new position
+    let draggedItem = null;
+    // Viewers get the list without the enable/disable and remove forms, and can't reorder it
+    let readOnly = false;
+
 
 
     function renderTemplateList(templates) {
@@ -32,6 +37,11 @@ const FileListService = (function() {
         templates.forEach(tmpl => {
             const li = document.createElement('li');
             li.dataset.filename = tmpl.name;
+            li.dataset.active = tmpl.isActive ? 'true' : 'false';
+            li.draggable = !readOnly;
+            if (!tmpl.isActive) {
+                li.classList.add('gws-template-disabled');
+            }
             
             const nameSpan = document.createElement('span');
             nameSpan.textContent = tmpl.name;
@@ -43,7 +53,48 @@ const FileListService = (function() {
                 nameSpan.appendChild(badge); // Append badge to nameSpan
             }
             li.appendChild(nameSpan); // Append nameSpan (which might contain badge) to li
+            li.style.display = 'flex';
+            li.style.justifyContent = 'space-between';
+            li.style.alignItems = 'center';
+            if (readOnly) {
+                templateListElement.appendChild(li);
+                return;
+            }
             
+            const actions = document.createElement('span');
+            actions.className = 'gws-template-actions';
+
+            // Enabling/disabling is a plain HTMX form post, like removal
+            const toggleForm = document.createElement('form');
+            toggleForm.action = `/admin/modules/edit/${currentModuleId}/toggle-template/${tmpl.name}`;
+            toggleForm.method = 'POST';
+            toggleForm.className = 'gws-inline-form toggle-template-form';
+            toggleForm.style.margin = '0';
+            toggleForm.setAttribute('hx-post', `/admin/modules/edit/${currentModuleId}/toggle-template/${tmpl.name}`);
+            toggleForm.setAttribute('hx-target', '#template-file-list');
+            toggleForm.setAttribute('hx-swap', 'innerHTML');
+
+            const toggleCsrfInput = document.createElement('input');
+            toggleCsrfInput.type = 'hidden';
+            toggleCsrfInput.name = 'csrf_token';
+            toggleCsrfInput.value = getCsrfToken();
+            toggleForm.appendChild(toggleCsrfInput);
+
+            const activeInput = document.createElement('input');
+            activeInput.type = 'hidden';
+            activeInput.name = 'active';
+            activeInput.value = tmpl.isActive ? 'false' : 'true';
+            toggleForm.appendChild(activeInput);
+
+            const toggleButton = document.createElement('button');
+            toggleButton.type = 'submit';
+            toggleButton.style.fontSize = '0.7rem';
+            toggleButton.style.padding = '0.2rem 0.5rem';
+            toggleButton.style.lineHeight = '1.2';
+            toggleButton.textContent = tmpl.isActive ? 'Disable' : 'Enable';
+            toggleForm.appendChild(toggleButton);
+            actions.appendChild(toggleForm);
+
             const removeForm = document.createElement('form');
             // Action and method are still useful for non-JS fallback, though HTMX will override
             removeForm.action = `/admin/modules/edit/${currentModuleId}/remove-template/${tmpl.name}`;
@@ -67,7 +118,7 @@ const FileListService = (function() {
             const csrfInput = document.createElement('input');
             csrfInput.type = 'hidden';
             csrfInput.name = 'csrf_token';
-            csrfInput.value = csrfToken || (editorLayoutElementRef ? editorLayoutElementRef.dataset.csrfToken : '');
+            csrfInput.value = getCsrfToken();
             removeForm.appendChild(csrfInput);
 
             const removeButton = document.createElement('button');
@@ -79,10 +130,8 @@ const FileListService = (function() {
             removeButton.textContent = 'Remove';
             removeForm.appendChild(removeButton);
             
-            li.appendChild(removeForm);
-            li.style.display = 'flex';
-            li.style.justifyContent = 'space-between';
-            li.style.alignItems = 'center';
+            actions.appendChild(removeForm);
+            li.appendChild(actions);
 
             // Event listener for file selection will be delegated from templateListElement
             
@@ -95,6 +144,55 @@ const FileListService = (function() {
         }
     }
 
+    function getCsrfToken() {
+        return csrfToken || (editorLayoutElementRef ? editorLayoutElementRef.dataset.csrfToken : '');
+    }
+
+    // Drag and drop reordering. Dropping an item sends the new order of all
+    // templates; the server responds with the re-rendered list.
+    function handleDragStart(event) {
+        draggedItem = event.target.closest('li[data-filename]');
+        if (!draggedItem) return;
+        event.dataTransfer.effectAllowed = 'move';
+        event.dataTransfer.setData('text/plain', draggedItem.dataset.filename);
+        draggedItem.classList.add('gws-dragging');
+    }
+
+    function handleDragOver(event) {
+        const target = event.target.closest('li[data-filename]');
+        if (!draggedItem || !target || target === draggedItem) return;
+        event.preventDefault();
+        // Insert before or after the hovered item depending on the pointer position
+        const rect = target.getBoundingClientRect();
+        if (event.clientY < rect.top + rect.height / 2) {
+            templateListElement.insertBefore(draggedItem, target);
+        } else {
+            templateListElement.insertBefore(draggedItem, target.nextSibling);
+        }
+    }
+
+    function handleDrop(event) {
+        if (!draggedItem) return;
+        event.preventDefault();
+        const order = Array.from(templateListElement.querySelectorAll('li[data-filename]')).map(li => li.dataset.filename);
+        if (typeof htmx === 'undefined') {
+            displayDynamicMessageCallback('Cannot save the template order: HTMX is not loaded.', 'error');
+            return;
+        }
+        htmx.ajax('POST', `/admin/modules/edit/${currentModuleId}/reorder-templates`, {
+            target: '#template-file-list',
+            swap: 'innerHTML',
+            values: { csrf_token: getCsrfToken(), order: order }
+        });
+    }
+
+    function handleDragEnd() {
+        if (draggedItem) {
+            draggedItem.classList.remove('gws-dragging');
+        }
+        draggedItem = null;
+    }
+
     // handleAddTemplateFormSubmit and handleRemoveTemplateFormSubmit functions
     // are no longer needed as HTMX handles these form submissions directly.
     // Their event listeners were previously commented out.
@@ -107,6 +205,7 @@ const FileListService = (function() {
             currentModuleId = options.moduleId;
             csrfToken = options.csrfToken; // General CSRF for new forms
             editorLayoutElementRef = options.editorLayoutElement; // For CSRF if needed by dynamic forms
+            readOnly = options.readOnly === true;
             
             if (typeof options.onFileSelect === 'function') {
                 onFileSelectCallback = options.onFileSelect;
@@ -117,7 +216,7 @@ const FileListService = (function() {
 
             if (addTemplateFormElement) {
                 // Event listener for addTemplateFormElement was removed as HTMX handles submission.
-            } else {
+            } else if (!readOnly) {
                 console.warn("Add template form element not provided to FileListService.");
             }
             
@@ -130,12 +229,19 @@ const FileListService = (function() {
             if (templateListElement && typeof onFileSelectCallback === 'function') {
                 templateListElement.addEventListener('click', function(event) {
                     const listItem = event.target.closest('li[data-filename]');
-                    if (listItem && !event.target.closest('form.remove-template-form')) {
+                    if (listItem && !event.target.closest('form.remove-template-form, form.toggle-template-form')) {
                         const filename = listItem.dataset.filename;
                         onFileSelectCallback(filename, listItem);
                     }
                 });
             }
+
+            if (templateListElement) {
+                templateListElement.addEventListener('dragstart', handleDragStart);
+                templateListElement.addEventListener('dragover', handleDragOver);
+                templateListElement.addEventListener('drop', handleDrop);
+                templateListElement.addEventListener('dragend', handleDragEnd);
+            }
         },
         renderList: renderTemplateList // Expose for external updates if needed
     };
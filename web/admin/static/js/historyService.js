@@ -0,0 +1,148 @@
+// historyService.js
+
+const HistoryService = (function() {
+    'use strict';
+
+    let listElement = null;
+    let diffElement = null;
+    let refreshButtonElement = null;
+    let currentModuleId = null;
+    let csrfToken = null;
+    let readOnly = false; // Viewers can compare snapshots but not restore them
+
+    // Callback to display dynamic messages
+    let displayDynamicMessageCallback = function(message, type) { console.warn(`Dynamic message: ${type} - ${message}`); };
+
+    function formatDate(isoString) {
+        const date = new Date(isoString);
+        return isNaN(date) ? isoString : date.toLocaleString();
+    }
+
+    function renderSnapshots(snapshots) {
+        listElement.innerHTML = '';
+        if (!snapshots || snapshots.length === 0) {
+            const li = document.createElement('li');
+            li.textContent = 'No history recorded yet.';
+            listElement.appendChild(li);
+            return;
+        }
+
+        snapshots.forEach(snap => {
+            const li = document.createElement('li');
+            li.dataset.snapshotId = snap.id;
+
+            const label = document.createElement('span');
+            label.className = 'gws-history-label';
+            label.textContent = formatDate(snap.createdAt);
+            const reason = document.createElement('small');
+            reason.textContent = snap.reason || '';
+            label.appendChild(reason);
+            li.appendChild(label);
+
+            const diffButton = document.createElement('button');
+            diffButton.type = 'button';
+            diffButton.textContent = 'Diff';
+            diffButton.addEventListener('click', () => showDiff(snap.id));
+
+            const restoreButton = document.createElement('button');
+            restoreButton.type = 'button';
+            restoreButton.className = 'btn-danger';
+            restoreButton.textContent = 'Restore';
+            restoreButton.addEventListener('click', () => restore(snap.id));
+
+            li.appendChild(diffButton);
+            if (!readOnly) li.appendChild(restoreButton);
+            listElement.appendChild(li);
+        });
+    }
+
+    async function refresh() {
+        try {
+            const snapshots = await ApiService.listSnapshots(currentModuleId);
+            renderSnapshots(snapshots);
+        } catch (error) {
+            console.error("Error loading module history via ApiService:", error);
+            displayDynamicMessageCallback(`Failed to load history: ${error.message}`, 'error');
+        }
+    }
+
+    function renderDiff(diff) {
+        diffElement.innerHTML = '';
+        if (diff.fields.length === 0 && diff.files.length === 0) {
+            diffElement.textContent = 'No differences from the current version.';
+            diffElement.style.display = 'block';
+            return;
+        }
+
+        diff.fields.forEach(field => {
+            const p = document.createElement('p');
+            p.textContent = `${field.field}: "${field.snapshot}" → "${field.current}"`;
+            diffElement.appendChild(p);
+        });
+        diff.files.forEach(file => {
+            const heading = document.createElement('h5');
+            heading.textContent = `${file.name} (${file.status})`;
+            diffElement.appendChild(heading);
+
+            const pre = document.createElement('pre');
+            file.lines.forEach(line => {
+                const span = document.createElement('span');
+                if (line.startsWith('+')) span.className = 'gws-diff-add';
+                else if (line.startsWith('-')) span.className = 'gws-diff-del';
+                span.textContent = line + '\n';
+                pre.appendChild(span);
+            });
+            diffElement.appendChild(pre);
+        });
+        diffElement.style.display = 'block';
+    }
+
+    async function showDiff(snapshotId) {
+        try {
+            const diff = await ApiService.fetchSnapshotDiff(currentModuleId, snapshotId);
+            renderDiff(diff);
+        } catch (error) {
+            console.error("Error loading snapshot diff via ApiService:", error);
+            displayDynamicMessageCallback(`Failed to compare snapshot: ${error.message}`, 'error');
+        }
+    }
+
+    async function restore(snapshotId) {
+        if (!confirm('Restore this version? The current version is kept in the history.')) {
+            return;
+        }
+        try {
+            await ApiService.restoreSnapshot(currentModuleId, snapshotId, csrfToken);
+            // Template list and editor contents may have changed; reload the whole editor.
+            window.location.reload();
+        } catch (error) {
+            console.error("Error restoring snapshot via ApiService:", error);
+            displayDynamicMessageCallback(`Failed to restore snapshot: ${error.message}`, 'error');
+        }
+    }
+
+    // Public API
+    return {
+        init: function(options) {
+            listElement = options.listElement;
+            diffElement = options.diffElement;
+            refreshButtonElement = options.refreshButtonElement;
+            currentModuleId = options.moduleId;
+            csrfToken = options.csrfToken;
+            readOnly = options.readOnly === true;
+            if (typeof options.displayMessage === 'function') {
+                displayDynamicMessageCallback = options.displayMessage;
+            }
+
+            if (!listElement || !diffElement) {
+                console.warn("HistoryService: History panel elements not found, history disabled.");
+                return;
+            }
+            if (refreshButtonElement) {
+                refreshButtonElement.addEventListener('click', refresh);
+            }
+            refresh();
+        },
+        refresh: refresh
+    };
+})();